@@ -0,0 +1,193 @@
+package pubsub
+
+import (
+	"sync"
+	"time"
+)
+
+// defaultAckTimeout is how long a Delivery has to be acknowledged when
+// WithAckTimeout is not used.
+const defaultAckTimeout = 30 * time.Second
+
+// AckedSubscription is a Subscription whose deliveries have to be
+// acknowledged. When a Subscription implements it, WriteDelivery is used
+// instead of Write and the data is redelivered until the Delivery is
+// acknowledged (see WithAckTimeout), it runs out of attempts (see
+// WithMaxAttempts) or the subscription is removed. This gives at-least-once
+// delivery, so an AckedSubscription should expect to be written the same
+// data more than once. Redeliveries are written from their own goroutines
+// and may happen concurrently with other writes.
+type AckedSubscription interface {
+	Subscription
+	WriteDelivery(d Delivery)
+}
+
+// Delivery is data that was written to an AckedSubscription.
+type Delivery struct {
+	Data interface{}
+
+	// Attempt is 1 for the first delivery of the data and is incremented
+	// for each redelivery.
+	Attempt int
+
+	p *pendingDelivery
+}
+
+// Ack acknowledges the delivery so that the data is no longer redelivered.
+// It is safe to invoke multiple times and with any attempt of the data.
+func (d Delivery) Ack() {
+	if d.p == nil {
+		return
+	}
+	d.p.a.ack(d.p)
+}
+
+// WithAckTimeout configures how long an AckedSubscription has to
+// acknowledge a Delivery before the data is redelivered. It defaults to 30
+// seconds.
+func WithAckTimeout(d time.Duration) SubscribeOption {
+	return subscribeConfigFunc(func(c *subscribeConfig) {
+		c.ackTimeout = d
+	})
+}
+
+// WithMaxAttempts configures how many times an AckedSubscription is
+// written the same data. Once the Delivery of the last attempt has not
+// been acknowledged in time (see WithAckTimeout), the data is given up on
+// and written to the PubSub's dead letter Subscription (see
+// WithDeadLetterSubscription) instead. It defaults to 0, which redelivers
+// the data until it is acknowledged.
+func WithMaxAttempts(n int) SubscribeOption {
+	return subscribeConfigFunc(func(c *subscribeConfig) {
+		c.maxAttempts = n
+	})
+}
+
+// WithMaxInFlight configures how many Deliveries an AckedSubscription may
+// have that are not acknowledged yet. Data that is written to it while it
+// has that many is dropped and written to the PubSub's dead letter
+// Subscription (see WithDeadLetterSubscription) instead. It defaults to 0,
+// which does not bound them.
+func WithMaxInFlight(n int) SubscribeOption {
+	return subscribeConfigFunc(func(c *subscribeConfig) {
+		c.maxInFlight = n
+	})
+}
+
+// ackedSubscription implements Subscription by writing Deliveries to an
+// AckedSubscription and redelivering the ones that are not acknowledged in
+// time.
+type ackedSubscription struct {
+	sub         AckedSubscription
+	timeout     time.Duration
+	maxAttempts int
+	maxInFlight int
+	deadLetter  Subscription
+	clock       Clock
+
+	mu      sync.Mutex
+	pending map[*pendingDelivery]struct{}
+	stopped bool
+}
+
+// pendingDelivery is data that has not been acknowledged yet.
+type pendingDelivery struct {
+	a    *ackedSubscription
+	data interface{}
+
+	// The fields below are guarded by a's mutex. acked is also set once
+	// the data has been given up on.
+	attempt int
+	timer   Timer
+	acked   bool
+}
+
+func newAckedSubscription(sub AckedSubscription, c subscribeConfig, p *PubSub) *ackedSubscription {
+	timeout := c.ackTimeout
+	if timeout <= 0 {
+		timeout = defaultAckTimeout
+	}
+
+	return &ackedSubscription{
+		sub:         sub,
+		timeout:     timeout,
+		maxAttempts: c.maxAttempts,
+		maxInFlight: c.maxInFlight,
+		deadLetter:  p.deadLetter,
+		clock:       p.clock,
+		pending:     make(map[*pendingDelivery]struct{}),
+	}
+}
+
+// Write implements Subscription.
+func (a *ackedSubscription) Write(data interface{}) {
+	a.deliver(&pendingDelivery{a: a, data: data})
+}
+
+// deliver writes the data and schedules its redelivery.
+func (a *ackedSubscription) deliver(p *pendingDelivery) {
+	a.mu.Lock()
+	if a.stopped || p.acked {
+		a.mu.Unlock()
+		return
+	}
+
+	full := p.attempt == 0 && a.maxInFlight > 0 && len(a.pending) >= a.maxInFlight
+	if full || (a.maxAttempts > 0 && p.attempt >= a.maxAttempts) {
+		p.acked = true
+		delete(a.pending, p)
+		a.mu.Unlock()
+
+		if a.deadLetter != nil {
+			a.deadLetter.Write(p.data)
+		}
+		return
+	}
+
+	p.attempt++
+	d := Delivery{Data: p.data, Attempt: p.attempt, p: p}
+
+	// The redelivery is scheduled before writing, as the Subscription
+	// might acknowledge the data before WriteDelivery returns.
+	p.timer = a.clock.AfterFunc(a.timeout, func() {
+		a.deliver(p)
+	})
+	a.pending[p] = struct{}{}
+	a.mu.Unlock()
+
+	a.sub.WriteDelivery(d)
+}
+
+func (a *ackedSubscription) ack(p *pendingDelivery) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if p.acked {
+		return
+	}
+	p.acked = true
+
+	if p.timer != nil {
+		p.timer.Stop()
+	}
+	delete(a.pending, p)
+}
+
+// stop cancels every pending redelivery. It is invoked once the
+// subscription has been removed.
+func (a *ackedSubscription) stop() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.stopped = true
+	for p := range a.pending {
+		p.timer.Stop()
+		delete(a.pending, p)
+	}
+}
+
+// Close implements Closer.
+func (a *ackedSubscription) Close() {
+	a.stop()
+	closeSubscription(a.sub)
+}
@@ -0,0 +1,158 @@
+package pubsub_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+func TestPubSubAck(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) TC {
+		clock := newSpyClock()
+		return TC{
+			T:     t,
+			p:     pubsub.New(pubsub.WithClock(clock)),
+			clock: clock,
+		}
+	})
+
+	o.Spec("it redelivers data until it is acknowledged", func(t TC) {
+		sub := newSpyAckedSubscription(false)
+		t.p.Subscribe(sub, pubsub.WithAckTimeout(time.Second))
+
+		t.p.Publish(1, pubsub.LinearTreeTraverser(nil))
+		Expect(t, sub.attempts()).To(Equal([]int{1}))
+
+		t.clock.advance(999 * time.Millisecond)
+		Expect(t, sub.attempts()).To(Equal([]int{1}))
+
+		t.clock.advance(time.Millisecond)
+		Expect(t, sub.attempts()).To(Equal([]int{1, 2}))
+
+		sub.deliveries()[1].Ack()
+		t.clock.advance(time.Minute)
+		Expect(t, sub.attempts()).To(Equal([]int{1, 2}))
+		Expect(t, sub.deliveries()[1].Data).To(Equal(1))
+	})
+
+	o.Spec("it does not redeliver data that is acknowledged while written", func(t TC) {
+		sub := newSpyAckedSubscription(true)
+		t.p.Subscribe(sub)
+
+		t.p.Publish(1, pubsub.LinearTreeTraverser(nil))
+		t.p.Publish(2, pubsub.LinearTreeTraverser(nil))
+		t.clock.advance(time.Hour)
+
+		Expect(t, sub.attempts()).To(Equal([]int{1, 1}))
+	})
+
+	o.Spec("it defaults to a 30 second timeout", func(t TC) {
+		sub := newSpyAckedSubscription(false)
+		t.p.Subscribe(sub)
+
+		t.p.Publish(1, pubsub.LinearTreeTraverser(nil))
+		t.clock.advance(29 * time.Second)
+		Expect(t, sub.attempts()).To(Equal([]int{1}))
+
+		t.clock.advance(time.Second)
+		Expect(t, sub.attempts()).To(Equal([]int{1, 2}))
+	})
+
+	o.Spec("it gives up on data after the maximum attempts", func(t TC) {
+		deadLetter := newSpySubscrption()
+		t.p = pubsub.New(pubsub.WithClock(t.clock), pubsub.WithDeadLetterSubscription(deadLetter))
+		sub := newSpyAckedSubscription(false)
+		t.p.Subscribe(sub, pubsub.WithAckTimeout(time.Second), pubsub.WithMaxAttempts(2))
+
+		t.p.Publish(1, pubsub.LinearTreeTraverser(nil))
+		t.clock.advance(time.Second)
+		Expect(t, sub.attempts()).To(Equal([]int{1, 2}))
+		Expect(t, deadLetter.Len()).To(Equal(0))
+
+		t.clock.advance(time.Second)
+		Expect(t, deadLetter.Data()).To(Equal([]interface{}{1}))
+
+		t.clock.advance(time.Minute)
+		sub.deliveries()[1].Ack()
+		Expect(t, sub.attempts()).To(Equal([]int{1, 2}))
+		Expect(t, deadLetter.Len()).To(Equal(1))
+	})
+
+	o.Spec("it drops data while too many deliveries are in flight", func(t TC) {
+		deadLetter := newSpySubscrption()
+		t.p = pubsub.New(pubsub.WithClock(t.clock), pubsub.WithDeadLetterSubscription(deadLetter))
+		sub := newSpyAckedSubscription(false)
+		t.p.Subscribe(sub, pubsub.WithMaxInFlight(2))
+
+		for i := 0; i < 3; i++ {
+			t.p.Publish(i, pubsub.LinearTreeTraverser(nil))
+		}
+		Expect(t, sub.attempts()).To(Equal([]int{1, 1}))
+		Expect(t, deadLetter.Data()).To(Equal([]interface{}{2}))
+
+		sub.deliveries()[0].Ack()
+		t.p.Publish(3, pubsub.LinearTreeTraverser(nil))
+		Expect(t, sub.deliveries()[2].Data).To(Equal(3))
+		Expect(t, deadLetter.Len()).To(Equal(1))
+	})
+
+	o.Spec("it stops redelivering once unsubscribed", func(t TC) {
+		sub := newSpyAckedSubscription(false)
+		unsubscribe := t.p.Subscribe(sub, pubsub.WithAckTimeout(time.Second))
+
+		t.p.Publish(1, pubsub.LinearTreeTraverser(nil))
+		unsubscribe()
+		t.clock.advance(time.Minute)
+
+		Expect(t, sub.attempts()).To(Equal([]int{1}))
+	})
+}
+
+// spyAckedSubscription implements pubsub.AckedSubscription. It acknowledges
+// each delivery while it is written if autoAck is set.
+type spyAckedSubscription struct {
+	mu      sync.Mutex
+	autoAck bool
+	ds      []pubsub.Delivery
+}
+
+func newSpyAckedSubscription(autoAck bool) *spyAckedSubscription {
+	return &spyAckedSubscription{autoAck: autoAck}
+}
+
+func (s *spyAckedSubscription) Write(data interface{}) {
+	panic("Write should not be invoked")
+}
+
+func (s *spyAckedSubscription) WriteDelivery(d pubsub.Delivery) {
+	s.mu.Lock()
+	s.ds = append(s.ds, d)
+	s.mu.Unlock()
+
+	if s.autoAck {
+		d.Ack()
+	}
+}
+
+func (s *spyAckedSubscription) deliveries() []pubsub.Delivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]pubsub.Delivery(nil), s.ds...)
+}
+
+func (s *spyAckedSubscription) attempts() []int {
+	var attempts []int
+	for _, d := range s.deliveries() {
+		attempts = append(attempts, d.Attempt)
+	}
+	return attempts
+}
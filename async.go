@@ -0,0 +1,294 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+)
+
+// OverflowStrategy determines what happens when data is written to a
+// subscription whose queue is full.
+type OverflowStrategy int
+
+const (
+	// OverflowBlock blocks the publisher until there is room in the queue.
+	OverflowBlock OverflowStrategy = iota
+
+	// OverflowDrop drops the new data when the queue is full.
+	OverflowDrop
+
+	// OverflowDropOldest drops the oldest queued data to make room for the
+	// new data when the queue is full.
+	OverflowDropOldest
+
+	// OverflowDisconnect drops the new data and removes the subscription
+	// from the PubSub when the queue is full. Data that was already queued
+	// is still written.
+	OverflowDisconnect
+)
+
+// WithAsyncDelivery configures a PubSub to give each subscription its own
+// goroutine and a queue that holds up to bufferSize entries. Publish then
+// only has to enqueue the data, so a slow subscription does not block the
+// publisher or other subscriptions. The given OverflowStrategy determines
+// what happens when a subscription's queue is full.
+//
+// Each subscription's queue is FIFO, so it receives data in the order it
+// was enqueued for it. Data that is published concurrently is only enqueued
+// in publish order with WithOrderedDelivery.
+//
+// When a subscription is unsubscribed, any data already queued is still
+// written to it.
+func WithAsyncDelivery(bufferSize int, s OverflowStrategy) PubSubOption {
+	return pubsubConfigFunc(func(p *PubSub) {
+		p.asyncBufferSize = bufferSize
+		p.overflow = s
+	})
+}
+
+// queuedSubscription implements Subscription by writing to a queue that is
+// drained by its own goroutine.
+type queuedSubscription struct {
+	sub      Subscription
+	q        chan interface{}
+	strategy OverflowStrategy
+
+	// errs is set when sub reports the errors of an ErrSubscription.
+	errs bool
+
+	// disconnect is used by OverflowDisconnect to remove the subscription.
+	disconnect     func()
+	disconnectOnce sync.Once
+
+	stopOnce sync.Once
+
+	// dropped is invoked each time data is dropped. It may be nil.
+	dropped func()
+
+	// panicked is invoked if the subscription panics, before the panic is
+	// resumed. It may be nil.
+	panicked func(r interface{})
+
+	// enqueued and dequeued are invoked each time data is added to or
+	// removed from the queue. They may be nil.
+	enqueued func()
+	dequeued func()
+
+	// notify is set when the PubSub is closed. The subscription is then
+	// closed once the queue is drained.
+	notify bool
+
+	// done is closed once the queue is drained after it was closed.
+	done chan struct{}
+}
+
+// newQueuedSubscription returns nil if the subscription should not be
+// queued. errs is set if the subscription wraps an ErrSubscription.
+func (s *PubSub) newQueuedSubscription(sub Subscription, c subscribeConfig, errs bool) *queuedSubscription {
+	if s.asyncBufferSize <= 0 && c.overflow == nil {
+		return nil
+	}
+
+	size := c.bufferSize
+	if size <= 0 {
+		size = s.asyncBufferSize
+	}
+	if size <= 0 {
+		size = defaultBufferSize
+	}
+
+	strategy := s.overflow
+	if c.overflow != nil {
+		strategy = *c.overflow
+	}
+
+	q := &queuedSubscription{
+		sub:      sub,
+		q:        make(chan interface{}, size),
+		strategy: strategy,
+		errs:     errs,
+		done:     make(chan struct{}),
+	}
+	if s.metrics != nil || s.logger != nil || s.hooks.sys != nil {
+		q.dropped = func() {
+			if s.metrics != nil {
+				s.metrics.Dropped(c.path)
+			}
+			s.debug(context.Background(), "dropped", pathAttr(c.path))
+			s.hooks.recordDrop(c.path)
+		}
+	}
+	if s.logger != nil {
+		q.panicked = func(r interface{}) {
+			s.debug(context.Background(), "panicked", pathAttr(c.path), "panic", r)
+		}
+	}
+	if m, ok := s.metrics.(QueueMetrics); ok {
+		q.enqueued = func() {
+			m.Enqueued(c.path)
+		}
+		q.dequeued = func() {
+			m.Dequeued(c.path)
+		}
+	}
+	go q.run()
+
+	return q
+}
+
+// Write implements Subscription.
+func (q *queuedSubscription) Write(data interface{}) {
+	q.write(data)
+}
+
+// writeCtx is like write, but if the context belongs to a PublishSync,
+// its completion is notified once the data is written or dropped.
+func (q *queuedSubscription) writeCtx(ctx context.Context, data interface{}) (bool, int) {
+	c := completionFrom(ctx)
+	if c == nil {
+		return q.write(data)
+	}
+
+	c.wg.Add(1)
+	d := completionData{data: data, c: c}
+	ok, dropped := q.write(d)
+	if !ok {
+		d.done(ErrDropped)
+	}
+	return ok, dropped
+}
+
+// write enqueues the data. It returns if the data was enqueued and how
+// many entries were dropped.
+func (q *queuedSubscription) write(data interface{}) (bool, int) {
+	// The data is counted as enqueued before it is, as the queue's
+	// goroutine may dequeue it right away.
+	if q.enqueued != nil {
+		q.enqueued()
+	}
+
+	ok, dropped := q.enqueue(data)
+	if !ok {
+		q.dequeue()
+	}
+	return ok, dropped
+}
+
+func (q *queuedSubscription) enqueue(data interface{}) (bool, int) {
+	switch q.strategy {
+	case OverflowDrop:
+		select {
+		case q.q <- data:
+			return true, 0
+		default:
+			q.drop()
+			return false, 1
+		}
+	case OverflowDropOldest:
+		var dropped int
+		for {
+			select {
+			case q.q <- data:
+				return true, dropped
+			default:
+			}
+
+			select {
+			case old := <-q.q:
+				if d, ok := old.(completionData); ok {
+					d.done(ErrDropped)
+				}
+				q.drop()
+				q.dequeue()
+				dropped++
+			default:
+			}
+		}
+	case OverflowDisconnect:
+		select {
+		case q.q <- data:
+			return true, 0
+		default:
+			q.drop()
+
+			// The disconnect has to wait for the lock that the publisher
+			// is holding.
+			q.disconnectOnce.Do(func() {
+				go q.disconnect()
+			})
+			return false, 1
+		}
+	default:
+		q.q <- data
+		return true, 0
+	}
+}
+
+func (q *queuedSubscription) drop() {
+	if q.dropped != nil {
+		q.dropped()
+	}
+}
+
+func (q *queuedSubscription) dequeue() {
+	if q.dequeued != nil {
+		q.dequeued()
+	}
+}
+
+func (q *queuedSubscription) run() {
+	defer close(q.done)
+
+	if q.panicked != nil {
+		defer func() {
+			if r := recover(); r != nil {
+				q.panicked(r)
+				panic(r)
+			}
+		}()
+	}
+
+	for data := range q.q {
+		q.dequeue()
+		if d, ok := data.(completionData); ok {
+			d.done(q.writeSub(d.data))
+			continue
+		}
+		q.writeSub(data)
+	}
+
+	if q.notify {
+		closeSubscription(q.sub)
+	}
+}
+
+// writeSub writes dequeued data to the underlying subscription. It returns
+// the error of an ErrSubscription.
+func (q *queuedSubscription) writeSub(data interface{}) error {
+	if d, ok := data.(pathData); ok {
+		q.sub.(PathAwareSubscription).WritePath(d.data, d.path)
+		return nil
+	}
+
+	if q.errs {
+		return q.sub.(errWriter).writeErr(data)
+	}
+	q.sub.Write(data)
+	return nil
+}
+
+// Close implements Closer. The underlying subscription is closed once the
+// queue is drained.
+func (q *queuedSubscription) Close() {
+	q.stopOnce.Do(func() {
+		q.notify = true
+		close(q.q)
+	})
+}
+
+// stop must only be invoked once the queuedSubscription can no longer be
+// written to.
+func (q *queuedSubscription) stop() {
+	q.stopOnce.Do(func() {
+		close(q.q)
+	})
+}
@@ -0,0 +1,173 @@
+package pubsub_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+func TestPubSubAsyncDelivery(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.Spec("it does not block the publisher", func(t *testing.T) {
+		p := pubsub.New(pubsub.WithAsyncDelivery(5, pubsub.OverflowBlock))
+		block := make(chan struct{})
+		sub := newSpySubscrption()
+		p.Subscribe(pubsub.SubscriptionFunc(func(data interface{}) {
+			<-block
+			sub.Write(data)
+		}))
+
+		for i := 0; i < 5; i++ {
+			p.Publish(i, pubsub.LinearTreeTraverser(nil))
+		}
+		close(block)
+
+		Expect(t, sub.Len).To(ViaPolling(Equal(5)))
+		Expect(t, sub.Data()).To(Equal([]interface{}{0, 1, 2, 3, 4}))
+	})
+
+	o.Spec("it drops data when the queue is full", func(t *testing.T) {
+		p := pubsub.New(pubsub.WithAsyncDelivery(1, pubsub.OverflowDrop))
+		block := make(chan struct{})
+		sub := newSpySubscrption()
+		p.Subscribe(pubsub.SubscriptionFunc(func(data interface{}) {
+			<-block
+			sub.Write(data)
+		}))
+
+		for i := 0; i < 100; i++ {
+			p.Publish(i, pubsub.LinearTreeTraverser(nil))
+		}
+		close(block)
+
+		Expect(t, sub.Len).To(ViaPolling(BeAbove(0)))
+		Expect(t, sub.Len()).To(BeBelow(100))
+	})
+
+	o.Spec("it writes queued data after unsubscribing", func(t *testing.T) {
+		p := pubsub.New(pubsub.WithAsyncDelivery(5, pubsub.OverflowBlock))
+		block := make(chan struct{})
+		sub := newSpySubscrption()
+		unsubscribe := p.Subscribe(pubsub.SubscriptionFunc(func(data interface{}) {
+			<-block
+			sub.Write(data)
+		}))
+
+		p.Publish("some-data", pubsub.LinearTreeTraverser(nil))
+		unsubscribe()
+		p.Publish("other-data", pubsub.LinearTreeTraverser(nil))
+		close(block)
+
+		Expect(t, sub.Len).To(ViaPolling(Equal(1)))
+		Expect(t, sub.Data()).To(Equal([]interface{}{"some-data"}))
+	})
+
+	o.Spec("it waits for queued data when draining on unsubscribe", func(t *testing.T) {
+		p := pubsub.New(pubsub.WithAsyncDelivery(5, pubsub.OverflowBlock))
+		block := make(chan struct{})
+		sub := newSpySubscrption()
+		unsubscribe := p.Subscribe(pubsub.SubscriptionFunc(func(data interface{}) {
+			<-block
+			sub.Write(data)
+		}), pubsub.WithDrainOnUnsubscribe(), pubsub.WithBatching(2, 0))
+
+		for i := 0; i < 3; i++ {
+			p.Publish(i, pubsub.LinearTreeTraverser(nil))
+		}
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			unsubscribe()
+		}()
+
+		select {
+		case <-done:
+			t.Fatal("expected unsubscribe to wait for the queued data")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		close(block)
+		<-done
+		Expect(t, sub.Data()).To(Equal([]interface{}{
+			[]interface{}{0, 1},
+			[]interface{}{2},
+		}))
+	})
+}
+
+func TestPubSubOverflowStrategy(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	setup := func(t *testing.T, s pubsub.OverflowStrategy) (*pubsub.PubSub, *spySubscription, chan struct{}) {
+		p := pubsub.New()
+		block := make(chan struct{})
+		started := make(chan struct{}, 1)
+		sub := newSpySubscrption()
+		p.Subscribe(pubsub.SubscriptionFunc(func(data interface{}) {
+			select {
+			case started <- struct{}{}:
+			default:
+			}
+			<-block
+			sub.Write(data)
+		}),
+			pubsub.WithOverflowStrategy(s),
+			pubsub.WithBufferSize(2),
+		)
+
+		// Wait for the first write to be taken off of the queue so that
+		// the queue's contents are predictable.
+		p.Publish(0, pubsub.LinearTreeTraverser(nil))
+		<-started
+
+		return p, sub, block
+	}
+
+	o.Spec("it drops the newest data", func(t *testing.T) {
+		p, sub, block := setup(t, pubsub.OverflowDrop)
+		for i := 1; i < 10; i++ {
+			p.Publish(i, pubsub.LinearTreeTraverser(nil))
+		}
+		close(block)
+
+		Expect(t, sub.Len).To(ViaPolling(Equal(3)))
+		Expect(t, sub.Data()).To(Equal([]interface{}{0, 1, 2}))
+	})
+
+	o.Spec("it drops the oldest data", func(t *testing.T) {
+		p, sub, block := setup(t, pubsub.OverflowDropOldest)
+		for i := 1; i < 10; i++ {
+			p.Publish(i, pubsub.LinearTreeTraverser(nil))
+		}
+		close(block)
+
+		Expect(t, sub.Len).To(ViaPolling(Equal(3)))
+		Expect(t, sub.Data()).To(Equal([]interface{}{0, 8, 9}))
+	})
+
+	o.Spec("it disconnects the subscription", func(t *testing.T) {
+		p, sub, block := setup(t, pubsub.OverflowDisconnect)
+		for i := 1; i < 10; i++ {
+			p.Publish(i, pubsub.LinearTreeTraverser(nil))
+		}
+		close(block)
+		Expect(t, sub.Len).To(ViaPolling(Equal(3)))
+		Expect(t, sub.Data()).To(Equal([]interface{}{0, 1, 2}))
+
+		// The subscription is removed once the publisher releases the
+		// lock, after which nothing else is written to it.
+		Expect(t, func() int { return p.Subscriptions() }).To(ViaPolling(Equal(0)))
+		p.Publish("other-data", pubsub.LinearTreeTraverser(nil))
+		Expect(t, sub.Data()).To(Equal([]interface{}{0, 1, 2}))
+	})
+}
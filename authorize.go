@@ -0,0 +1,40 @@
+package pubsub
+
+import "context"
+
+// Authorizer decides which subscribes and publishes a PubSub allows (e.g.,
+// path based ACLs). Its methods are invoked concurrently.
+type Authorizer interface {
+	// AuthorizeSubscribe is invoked before a subscription is added at the
+	// given path. The context is the one given to WithContext (if any). If
+	// it returns an error, the subscription is not added.
+	AuthorizeSubscribe(ctx context.Context, path []string) error
+
+	// AuthorizePublish is invoked before data is traversed. The context is
+	// the one given to PublishCtx. If it returns an error, the data is not
+	// published and PublishCtx returns the error.
+	AuthorizePublish(ctx context.Context, data interface{}) error
+}
+
+// WithAuthorizer configures the PubSub to consult the Authorizer for each
+// Subscribe and Publish. A subscription that is rejected is closed (see
+// Closer), and SubscribeErr returns the Authorizer's error. Subscribes and
+// publishes that a mounted PubSub is given through its parent are
+// authorized by both (the child is given the path relative to the mount).
+func WithAuthorizer(a Authorizer) PubSubOption {
+	return pubsubConfigFunc(func(p *PubSub) {
+		p.authorizer = a
+	})
+}
+
+func (s *PubSub) authorizeSubscribe(c subscribeConfig) error {
+	if s.authorizer == nil {
+		return nil
+	}
+
+	ctx := c.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return s.authorizer.AuthorizeSubscribe(ctx, c.path)
+}
@@ -0,0 +1,120 @@
+package pubsub_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+type TA struct {
+	*testing.T
+	p          *pubsub.PubSub
+	authorizer *spyAuthorizer
+}
+
+func TestPubSubAuthorizer(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) TA {
+		a := newSpyAuthorizer()
+		return TA{
+			T:          t,
+			p:          pubsub.New(pubsub.WithAuthorizer(a)),
+			authorizer: a,
+		}
+	})
+
+	o.Spec("it rejects unauthorized subscriptions", func(t TA) {
+		sub := newSpyCloser()
+		_, err := t.p.SubscribeErr(sub, pubsub.WithPath([]string{"private"}))
+		Expect(t, err).To(Equal(errUnauthorized))
+		Expect(t, sub.closed).To(Equal(1))
+		Expect(t, t.p.Paths()).To(HaveLen(0))
+
+		t.p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"private", "x"}))
+		Expect(t, t.p.Paths()).To(HaveLen(0))
+	})
+
+	o.Spec("it adds authorized subscriptions", func(t TA) {
+		sub := newSpySubscrption()
+		unsubscribe, err := t.p.SubscribeErr(sub, pubsub.WithPath([]string{"public"}))
+		Expect(t, err).To(BeNil())
+
+		t.p.Publish(1, pubsub.LinearTreeTraverser([]string{"public"}))
+		Expect(t, sub.Data()).To(Equal([]interface{}{1}))
+
+		unsubscribe()
+		Expect(t, t.p.Paths()).To(HaveLen(0))
+	})
+
+	o.Spec("it gives the subscription's context to the Authorizer", func(t TA) {
+		ctx := context.WithValue(context.Background(), ctxKey{}, "user")
+		t.p.Subscribe(newSpySubscrption(), pubsub.WithContext(ctx), pubsub.WithPath([]string{"public"}))
+		Expect(t, t.authorizer.subscribeCtx.Value(ctxKey{})).To(Equal("user"))
+	})
+
+	o.Spec("it rejects unauthorized publishes", func(t TA) {
+		sub := newSpySubscrption()
+		t.p.Subscribe(sub, pubsub.WithPath([]string{"public"}))
+
+		ctx := context.WithValue(context.Background(), ctxKey{}, "user")
+		_, err := t.p.PublishCtx(ctx, "secret", pubsub.LinearTreeTraverser([]string{"public"}))
+		Expect(t, err).To(Equal(errUnauthorized))
+		Expect(t, t.authorizer.publishCtx.Value(ctxKey{})).To(Equal("user"))
+
+		r, err := t.p.PublishCtx(ctx, "ok", pubsub.LinearTreeTraverser([]string{"public"}))
+		Expect(t, err).To(BeNil())
+		Expect(t, r.Delivered).To(Equal(1))
+		Expect(t, sub.Data()).To(Equal([]interface{}{"ok"}))
+	})
+
+	o.Spec("it rejects unauthorized subscriptions in a Batch", func(t TA) {
+		sub := newSpyCloser()
+		b := t.p.Batch()
+		rejected := b.Subscribe(sub, pubsub.WithPath([]string{"private"}))
+		b.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"public"}))
+		b.Unsubscribe(rejected)
+		b.Commit()
+
+		Expect(t, sub.closed).To(Equal(1))
+		Expect(t, t.p.Paths()).To(Equal([][]string{{"public"}}))
+	})
+}
+
+type ctxKey struct{}
+
+var errUnauthorized = errors.New("unauthorized")
+
+// spyAuthorizer only allows subscriptions beneath "public" and rejects the
+// data "secret". It records the contexts it was given.
+type spyAuthorizer struct {
+	subscribeCtx context.Context
+	publishCtx   context.Context
+}
+
+func newSpyAuthorizer() *spyAuthorizer {
+	return &spyAuthorizer{}
+}
+
+func (s *spyAuthorizer) AuthorizeSubscribe(ctx context.Context, path []string) error {
+	s.subscribeCtx = ctx
+	if len(path) == 0 || path[0] != "public" {
+		return errUnauthorized
+	}
+	return nil
+}
+
+func (s *spyAuthorizer) AuthorizePublish(ctx context.Context, data interface{}) error {
+	s.publishCtx = ctx
+	if data == "secret" {
+		return errUnauthorized
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package pubsub
+
+import (
+	"sync"
+	"time"
+)
+
+// batcher gathers data into batches. Batches are flushed in order.
+type batcher struct {
+	maxSize  int
+	maxDelay time.Duration
+	flush    func(batch []interface{})
+	clock    Clock
+
+	mu      sync.Mutex
+	batch   []interface{}
+	timer   Timer
+	gen     int
+	stopped bool
+}
+
+func newBatcher(maxSize int, maxDelay time.Duration, clock Clock, flush func(batch []interface{})) *batcher {
+	return &batcher{
+		maxSize:  maxSize,
+		maxDelay: maxDelay,
+		flush:    flush,
+		clock:    clock,
+	}
+}
+
+func (b *batcher) add(data interface{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.stopped {
+		return
+	}
+
+	b.batch = append(b.batch, data)
+	if len(b.batch) >= b.maxSize {
+		b.flushLocked()
+		return
+	}
+
+	if len(b.batch) == 1 && b.maxDelay > 0 {
+		gen := b.gen
+		b.timer = b.clock.AfterFunc(b.maxDelay, func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+
+			// The batch may have already been flushed.
+			if gen == b.gen && !b.stopped {
+				b.flushLocked()
+			}
+		})
+	}
+}
+
+// stop flushes any partial batch. Anything added afterwards is dropped.
+func (b *batcher) stop() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.stopped {
+		return
+	}
+
+	b.flushLocked()
+	b.stopped = true
+}
+
+func (b *batcher) flushLocked() {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	b.gen++
+
+	if len(b.batch) == 0 {
+		return
+	}
+
+	batch := b.batch
+	b.batch = nil
+	b.flush(batch)
+}
@@ -38,6 +38,24 @@ func BenchmarkPublishingStructs(b *testing.B) {
 	}
 }
 
+func BenchmarkPublishingFlatPaths(b *testing.B) {
+	b.StopTimer()
+	p := pubsub.New()
+	for i := 0; i < 100; i++ {
+		p.Subscribe(newSpySubscrption(), pubsub.WithPath(randPath()))
+	}
+	var ts []pubsub.TreeTraverser
+	for _, path := range randData()[:1000] {
+		ts = append(ts, newFlatTraverser(path))
+	}
+	b.ReportAllocs()
+	b.StartTimer()
+
+	for i := 0; i < b.N; i++ {
+		p.Publish("data", ts[i%len(ts)])
+	}
+}
+
 func BenchmarkSubscriptions(b *testing.B) {
 	b.StopTimer()
 	p := pubsub.New()
@@ -54,6 +72,48 @@ func BenchmarkSubscriptions(b *testing.B) {
 	})
 }
 
+func BenchmarkSubscribingToOnePath(b *testing.B) {
+	p := pubsub.New()
+	for i := 0; i < b.N; i++ {
+		p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"a", "b"}))
+	}
+}
+
+func BenchmarkPublishingRanges(b *testing.B) {
+	b.StopTimer()
+	p := pubsub.New()
+	for i := 0; i < 1000; i++ {
+		p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{
+			"latency", pubsub.Range(float64(i*10), float64(i*10+10)),
+		}))
+	}
+	var data []pubsub.LinearTreeTraverser
+	for i := 0; i < 100; i++ {
+		data = append(data, pubsub.LinearTreeTraverser([]string{"latency", pubsub.Number(rand.Float64() * 10000)}))
+	}
+	b.StartTimer()
+
+	for i := 0; i < b.N; i++ {
+		p.Publish("data", data[i%len(data)])
+	}
+}
+
+func BenchmarkPublishingDeduplicated(b *testing.B) {
+	b.StopTimer()
+	p := pubsub.New(pubsub.WithSubscriptionDeduplication())
+	for i := 0; i < 1000; i++ {
+		sub := newSpySubscrption()
+		p.Subscribe(sub, pubsub.WithPath([]string{"a"}))
+		p.Subscribe(sub, pubsub.WithPath([]string{"a", "b"}))
+	}
+	b.ReportAllocs()
+	b.StartTimer()
+
+	for i := 0; i < b.N; i++ {
+		p.Publish("data", pubsub.LinearTreeTraverser([]string{"a", "b"}))
+	}
+}
+
 func BenchmarkPublishingParallel(b *testing.B) {
 	b.StopTimer()
 	p := pubsub.New()
@@ -72,6 +132,21 @@ func BenchmarkPublishingParallel(b *testing.B) {
 	})
 }
 
+func BenchmarkPublishingParallelShards(b *testing.B) {
+	b.StopTimer()
+	p := pubsub.New()
+	for i := 0; i < 10; i++ {
+		p.Subscribe(pubsub.SubscriptionFunc(func(interface{}) {}), pubsub.WithShardID("a"))
+	}
+	b.StartTimer()
+
+	b.RunParallel(func(b *testing.PB) {
+		for b.Next() {
+			p.Publish("data", pubsub.LinearTreeTraverser(nil))
+		}
+	})
+}
+
 func BenchmarkPublishingParallelStructs(b *testing.B) {
 	b.StopTimer()
 	p := pubsub.New()
@@ -173,6 +248,25 @@ func randData() [][]string {
 	return r
 }
 
+// flatTraverser traverses a single path with FlatPaths that are built
+// ahead of time.
+type flatTraverser struct {
+	paths []pubsub.Paths
+}
+
+func newFlatTraverser(path []string) *flatTraverser {
+	t := &flatTraverser{}
+	for _, p := range path {
+		t.paths = append(t.paths, pubsub.FlatPaths{p})
+	}
+	t.paths = append(t.paths, pubsub.FlatPaths(nil))
+	return t
+}
+
+func (t *flatTraverser) Traverse(data interface{}, currentPath []string) pubsub.Paths {
+	return t.paths[len(currentPath)]
+}
+
 type someType struct {
 	a string
 	b string
@@ -0,0 +1,87 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+)
+
+// SubscribeChan will add a subscription to the PubSub that writes to the
+// returned channel instead of requiring a Subscription. The channel is
+// buffered (see WithBufferSize). If the buffer is full, publishing will
+// block until the data is read or the subscription is removed.
+//
+// The channel is closed once the returned Unsubscriber is invoked, the
+// context given to WithContext is done or the PubSub is closed. It is safe
+// to invoke the Unsubscriber while a publish is blocked on the channel.
+func (s *PubSub) SubscribeChan(opts ...SubscribeOption) (<-chan interface{}, Unsubscriber) {
+	c := newSubscribeConfig(opts)
+	if c.bufferSize <= 0 {
+		c.bufferSize = defaultBufferSize
+	}
+
+	sub := &chanSubscription{
+		c:    make(chan interface{}, c.bufferSize),
+		done: make(chan struct{}),
+	}
+	unsubscribe := s.Subscribe(sub, opts...)
+
+	closeChan := func() {
+		// Release any blocked writers before waiting on the lock to remove
+		// the subscription.
+		sub.release()
+		unsubscribe()
+		sub.Close()
+	}
+
+	if c.ctx != nil {
+		context.AfterFunc(c.ctx, closeChan)
+	}
+
+	return sub.c, closeChan
+}
+
+// chanSubscription implements Subscription by writing to a channel.
+type chanSubscription struct {
+	mu     sync.RWMutex
+	c      chan interface{}
+	closed bool
+
+	done     chan struct{}
+	doneOnce sync.Once
+}
+
+// Write implements Subscription.
+func (s *chanSubscription) Write(data interface{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return
+	}
+
+	select {
+	case s.c <- data:
+	case <-s.done:
+	}
+}
+
+// Close implements Closer. It closes the channel.
+func (s *chanSubscription) Close() {
+	s.release()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+	s.closed = true
+	close(s.c)
+}
+
+// release unblocks any writers and drops any future writes.
+func (s *chanSubscription) release() {
+	s.doneOnce.Do(func() {
+		close(s.done)
+	})
+}
@@ -0,0 +1,69 @@
+package pubsub_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+func TestPubSubSubscribeChan(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+	o.BeforeEach(func(t *testing.T) TPS {
+		return TPS{
+			T: t,
+			p: pubsub.New(),
+		}
+	})
+
+	o.Spec("it writes data to the channel", func(t TPS) {
+		c, _ := t.p.SubscribeChan(pubsub.WithPath([]string{"a"}))
+
+		t.p.Publish("some-data", pubsub.LinearTreeTraverser([]string{"a"}))
+		t.p.Publish("other-data", pubsub.LinearTreeTraverser([]string{"b"}))
+
+		Expect(t, c).To(HaveLen(1))
+		Expect(t, <-c).To(Equal("some-data"))
+	})
+
+	o.Spec("it closes the channel when unsubscribed", func(t TPS) {
+		c, unsubscribe := t.p.SubscribeChan()
+		unsubscribe()
+		unsubscribe()
+
+		_, ok := <-c
+		Expect(t, ok).To(BeFalse())
+
+		t.p.Publish("some-data", pubsub.LinearTreeTraverser(nil))
+	})
+
+	o.Spec("it closes the channel when the context is done", func(t TPS) {
+		ctx, cancel := context.WithCancel(context.Background())
+		c, _ := t.p.SubscribeChan(pubsub.WithContext(ctx))
+		cancel()
+
+		_, ok := <-c
+		Expect(t, ok).To(BeFalse())
+	})
+
+	o.Spec("it unblocks a publish when unsubscribed", func(t TPS) {
+		c, unsubscribe := t.p.SubscribeChan(pubsub.WithBufferSize(1))
+		t.p.Publish("data-1", pubsub.LinearTreeTraverser(nil))
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			t.p.Publish("data-2", pubsub.LinearTreeTraverser(nil))
+		}()
+
+		unsubscribe()
+		<-done
+
+		Expect(t, <-c).To(Equal("data-1"))
+	})
+}
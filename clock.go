@@ -0,0 +1,41 @@
+package pubsub
+
+import "time"
+
+// Clock is used by a PubSub for anything time based (e.g., WithTTL,
+// WithDebounce, WithThrottle, WithBatching and the publish durations given
+// to Metrics). It can be replaced with WithClock (e.g., to make tests
+// deterministic). It defaults to the system clock.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// AfterFunc invokes f in its own goroutine once the duration has
+	// elapsed. The returned Timer can be used to cancel it.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is returned by Clock.AfterFunc.
+type Timer interface {
+	// Stop prevents the Timer from firing. It returns false if the Timer
+	// has already fired or been stopped.
+	Stop() bool
+}
+
+// WithClock configures the Clock that a PubSub uses.
+func WithClock(c Clock) PubSubOption {
+	return pubsubConfigFunc(func(p *PubSub) {
+		p.clock = c
+	})
+}
+
+// systemClock implements Clock with the time package.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
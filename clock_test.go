@@ -0,0 +1,151 @@
+package pubsub_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+type TC struct {
+	*testing.T
+	p            *pubsub.PubSub
+	clock        *spyClock
+	subscription *spySubscription
+}
+
+func TestPubSubClock(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) TC {
+		clock := newSpyClock()
+		return TC{
+			T:            t,
+			p:            pubsub.New(pubsub.WithClock(clock)),
+			clock:        clock,
+			subscription: newSpySubscrption(),
+		}
+	})
+
+	o.Spec("it expires subscriptions with the clock", func(t TC) {
+		t.p.Subscribe(t.subscription, pubsub.WithTTL(time.Minute))
+
+		t.clock.advance(59 * time.Second)
+		Expect(t, t.p.Subscriptions()).To(Equal(1))
+
+		t.clock.advance(time.Second)
+		Expect(t, t.p.Subscriptions()).To(Equal(0))
+	})
+
+	o.Spec("it debounces with the clock", func(t TC) {
+		t.p.Subscribe(t.subscription, pubsub.WithDebounce(time.Second))
+
+		t.p.Publish(1, pubsub.LinearTreeTraverser(nil))
+		t.clock.advance(500 * time.Millisecond)
+		t.p.Publish(2, pubsub.LinearTreeTraverser(nil))
+		t.clock.advance(500 * time.Millisecond)
+		Expect(t, t.subscription.Len()).To(Equal(0))
+
+		t.clock.advance(500 * time.Millisecond)
+		Expect(t, t.subscription.Data()).To(Equal([]interface{}{2}))
+	})
+
+	o.Spec("it flushes batches with the clock", func(t TC) {
+		t.p.Subscribe(t.subscription, pubsub.WithBatching(10, time.Second))
+
+		t.p.Publish(1, pubsub.LinearTreeTraverser(nil))
+		Expect(t, t.subscription.Len()).To(Equal(0))
+
+		t.clock.advance(time.Second)
+		Expect(t, t.subscription.Data()).To(Equal([]interface{}{[]interface{}{1}}))
+	})
+
+	o.Spec("it measures publishes with the clock", func(t TC) {
+		m := newSpyMetrics()
+		p := pubsub.New(pubsub.WithClock(t.clock), pubsub.WithMetrics(m))
+		p.Subscribe(t.subscription)
+
+		p.Publish(1, pubsub.LinearTreeTraverser(nil))
+		Expect(t, m.durations()).To(Equal([]time.Duration{0}))
+	})
+}
+
+// spyClock is a Clock that only moves when it is advanced. Timers are
+// invoked synchronously by advance.
+type spyClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*spyTimer
+}
+
+func newSpyClock() *spyClock {
+	return &spyClock{now: time.Unix(0, 0)}
+}
+
+func (c *spyClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *spyClock) AfterFunc(d time.Duration, f func()) pubsub.Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &spyTimer{c: c, at: c.now.Add(d), f: f}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+func (c *spyClock) advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+
+	var due []*spyTimer
+	timers := c.timers[:0]
+	for _, t := range c.timers {
+		if t.at.After(c.now) {
+			timers = append(timers, t)
+			continue
+		}
+		due = append(due, t)
+	}
+	c.timers = timers
+	c.mu.Unlock()
+
+	for _, t := range due {
+		t.f()
+	}
+}
+
+// pending returns the number of timers that have not fired or been stopped.
+func (c *spyClock) pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+type spyTimer struct {
+	c  *spyClock
+	at time.Time
+	f  func()
+}
+
+func (t *spyTimer) Stop() bool {
+	t.c.mu.Lock()
+	defer t.c.mu.Unlock()
+
+	for i, x := range t.c.timers {
+		if x == t {
+			t.c.timers = append(t.c.timers[:i], t.c.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package pubsub_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+func TestPubSubClose(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+	o.BeforeEach(func(t *testing.T) TPS {
+		return TPS{
+			T: t,
+			p: pubsub.New(),
+		}
+	})
+
+	o.Spec("it removes every subscription", func(t TPS) {
+		sub1 := newSpySubscrption()
+		sub2 := newSpySubscrption()
+		unsubscribe := t.p.Subscribe(sub1, pubsub.WithPath([]string{"a"}))
+		t.p.Subscribe(sub2, pubsub.WithPath([]string{"a", "b"}))
+
+		t.p.Close()
+		t.p.Close()
+
+		_, err := t.p.PublishCtx(context.Background(), "some-data", pubsub.LinearTreeTraverser([]string{"a", "b"}))
+		Expect(t, err).To(Equal(pubsub.ErrClosed))
+		Expect(t, sub1.data).To(HaveLen(0))
+		Expect(t, sub2.data).To(HaveLen(0))
+
+		unsubscribe()
+		unsubscribe()
+	})
+
+	o.Spec("it notifies each Closer", func(t TPS) {
+		sub1 := newSpyCloser()
+		sub2 := newSpyCloser()
+		t.p.Subscribe(sub1, pubsub.WithPath([]string{"a"}))
+		t.p.Close()
+		t.p.Subscribe(sub2, pubsub.WithPath([]string{"a"}))
+
+		Expect(t, sub1.closed).To(Equal(1))
+		Expect(t, sub2.closed).To(Equal(1))
+	})
+
+	o.Spec("it notifies each Closer after draining the queue", func(t TPS) {
+		p := pubsub.New(pubsub.WithAsyncDelivery(5, pubsub.OverflowBlock))
+		c, _ := p.SubscribeChan()
+		p.Publish("some-data", pubsub.LinearTreeTraverser(nil))
+		p.Close()
+
+		var data []interface{}
+		for d := range c {
+			data = append(data, d)
+		}
+		Expect(t, data).To(Equal([]interface{}{"some-data"}))
+	})
+}
+
+type spyCloser struct {
+	*spySubscription
+	closed int
+}
+
+func newSpyCloser() *spyCloser {
+	return &spyCloser{
+		spySubscription: newSpySubscrption(),
+	}
+}
+
+func (s *spyCloser) Close() {
+	s.closed++
+}
@@ -0,0 +1,86 @@
+package cluster
+
+import (
+	"hash/fnv"
+	"strings"
+)
+
+// bloomHashes is the number of bits that are set for each key.
+const bloomHashes = 4
+
+// bloom is a bloom filter of paths.
+type bloom []byte
+
+func newBloom(bits int) bloom {
+	return make(bloom, (bits+7)/8)
+}
+
+func (b bloom) add(path []string) {
+	for _, i := range b.indexes(path) {
+		b[i/8] |= 1 << (i % 8)
+	}
+}
+
+// mayContain reports if the path might have been added. It is false for an
+// empty (e.g., unknown) filter.
+func (b bloom) mayContain(path []string) bool {
+	if len(b) == 0 {
+		return false
+	}
+
+	for _, i := range b.indexes(path) {
+		if b[i/8]&(1<<(i%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// indexes returns the bits of the path. They are derived from a single
+// hash (see Kirsch and Mitzenmacher, "Less Hashing, Same Performance").
+func (b bloom) indexes(path []string) [bloomHashes]uint64 {
+	h := fnv.New64a()
+	for _, segment := range path {
+		h.Write([]byte(segment))
+		h.Write([]byte{0xff})
+	}
+	sum := h.Sum64()
+	h1, h2 := sum&0xffffffff, sum>>32
+
+	var idx [bloomHashes]uint64
+	m := uint64(len(b)) * 8
+	for i := range idx {
+		idx[i] = (h1 + uint64(i)*h2) % m
+	}
+	return idx
+}
+
+// digest returns a bloom filter of the given subscription paths. A path is
+// added up to its first pattern segment, so a publish matches the digest
+// if any prefix of its path was added (see digestMatches). This can only
+// result in false positives.
+func digest(paths [][]string, bits int) bloom {
+	b := newBloom(bits)
+	for _, path := range paths {
+		for i, segment := range path {
+			// Pattern segments (e.g., pubsub.Any) start with a NUL byte.
+			if strings.HasPrefix(segment, "\x00") {
+				path = path[:i]
+				break
+			}
+		}
+		b.add(path)
+	}
+	return b
+}
+
+// digestMatches reports if a subscription of the digest might match the
+// published path.
+func digestMatches(b bloom, path []string) bool {
+	for i := 0; i <= len(path); i++ {
+		if b.mayContain(path[:i]) {
+			return true
+		}
+	}
+	return false
+}
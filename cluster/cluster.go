@@ -0,0 +1,106 @@
+// Package cluster connects PubSubs of different processes into a cluster.
+// It is experimental and its API may change.
+//
+// The nodes of a cluster gossip their membership along with a digest of
+// the paths that their PubSub has subscriptions for. The digest is a bloom
+// filter, so it is small regardless of how many subscriptions there are.
+// Data that is published through a Node is only sent to the peers whose
+// digest indicates that they might have a matching subscription.
+package cluster
+
+import (
+	"context"
+	"time"
+
+	"github.com/apoydence/pubsub/codec"
+)
+
+// Transport sends messages to the other nodes of a cluster. Each node is
+// identified by its address. The messages must be given to the Receive
+// method of the Node at that address (see HTTPTransport and Node.ServeHTTP).
+type Transport interface {
+	Send(ctx context.Context, addr string, msg []byte) error
+}
+
+// Codec converts published data to and from the bytes that are sent to
+// other nodes. See the codec package for implementations.
+type Codec = codec.Codec
+
+// Option is used to configure a Node.
+type Option interface {
+	configure(*config)
+}
+
+type configFunc func(*config)
+
+func (f configFunc) configure(c *config) {
+	f(c)
+}
+
+type config struct {
+	interval   time.Duration
+	timeout    time.Duration
+	fanout     int
+	digestBits int
+	errorf     func(err error)
+}
+
+func newConfig(opts []Option) config {
+	c := config{
+		interval:   time.Second,
+		timeout:    10 * time.Second,
+		fanout:     3,
+		digestBits: 8192,
+		errorf:     func(error) {},
+	}
+
+	for _, o := range opts {
+		o.configure(&c)
+	}
+
+	return c
+}
+
+// WithGossipInterval configures how often Run gossips with other nodes. It
+// defaults to a second.
+func WithGossipInterval(d time.Duration) Option {
+	return configFunc(func(c *config) {
+		c.interval = d
+	})
+}
+
+// WithFailureTimeout configures how long a peer is considered a member of
+// the cluster after the last news about it. It should be several times the
+// gossip interval. It defaults to 10 seconds.
+func WithFailureTimeout(d time.Duration) Option {
+	return configFunc(func(c *config) {
+		c.timeout = d
+	})
+}
+
+// WithFanout configures how many peers a Node gossips with each round. It
+// defaults to 3.
+func WithFanout(n int) Option {
+	return configFunc(func(c *config) {
+		c.fanout = n
+	})
+}
+
+// WithDigestSize configures the number of bits of a Node's digest. Larger
+// digests result in fewer publishes being sent to peers without matching
+// subscriptions. Nodes of a cluster may use different sizes. It defaults
+// to 8192.
+func WithDigestSize(bits int) Option {
+	return configFunc(func(c *config) {
+		c.digestBits = bits
+	})
+}
+
+// WithErrorFunc configures a Node to invoke the given function when it can
+// not gossip with a peer. Errors of Publish and Receive are returned
+// instead.
+func WithErrorFunc(f func(err error)) Option {
+	return configFunc(func(c *config) {
+		c.errorf = f
+	})
+}
@@ -0,0 +1,68 @@
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// maxMessageSize is the largest message that ServeHTTP accepts.
+const maxMessageSize = 1 << 20
+
+// HTTPTransport returns a Transport that POSTs each message to the address
+// of the node, which must be the URL of its Node's handler (see
+// Node.ServeHTTP). If the client is nil, http.DefaultClient is used.
+func HTTPTransport(client *http.Client) Transport {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return httpTransport{client: client}
+}
+
+type httpTransport struct {
+	client *http.Client
+}
+
+func (t httpTransport) Send(ctx context.Context, addr string, msg []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, addr, bytes.NewReader(msg))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	return nil
+}
+
+// ServeHTTP implements http.Handler. It handles the messages that other
+// nodes send with HTTPTransport.
+func (n *Node) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	msg, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxMessageSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if err := n.Receive(msg); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
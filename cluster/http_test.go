@@ -0,0 +1,76 @@
+package cluster_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/apoydence/pubsub/cluster"
+	"github.com/apoydence/pubsub/codec"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+type TH struct {
+	*testing.T
+	p1, p2 *pubsub.PubSub
+	n1, n2 *cluster.Node
+	server *httptest.Server
+}
+
+func TestHTTP(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) TH {
+		mux := http.NewServeMux()
+		server := httptest.NewServer(mux)
+		t.Cleanup(server.Close)
+
+		p1, p2 := pubsub.New(), pubsub.New()
+		transport := cluster.HTTPTransport(server.Client())
+		n1 := cluster.NewNode(p1, transport, codec.JSON[string](), server.URL+"/1")
+		n2 := cluster.NewNode(p2, transport, codec.JSON[string](), server.URL+"/2")
+		mux.Handle("/1", n1)
+		mux.Handle("/2", n2)
+
+		return TH{
+			T:      t,
+			p1:     p1,
+			p2:     p2,
+			n1:     n1,
+			n2:     n2,
+			server: server,
+		}
+	})
+
+	o.Spec("it publishes to peers over HTTP", func(t TH) {
+		sub := newSpySubscription()
+		t.p2.Subscribe(sub, pubsub.WithPath([]string{"x"}))
+
+		t.n2.Join(t.server.URL + "/1")
+		t.n2.Gossip(context.Background())
+		Expect(t, t.n1.Peers()).To(Equal([]string{t.server.URL + "/2"}))
+
+		_, err := t.n1.Publish(context.Background(), "v", []string{"x"})
+		Expect(t, err).To(BeNil())
+		Expect(t, sub.values()).To(Equal([]interface{}{"v"}))
+	})
+
+	o.Spec("it rejects invalid requests", func(t TH) {
+		resp, err := http.Get(t.server.URL + "/1")
+		Expect(t, err).To(BeNil())
+		resp.Body.Close()
+		Expect(t, resp.StatusCode).To(Equal(http.StatusMethodNotAllowed))
+
+		resp, err = http.Post(t.server.URL+"/1", "application/json", strings.NewReader("invalid"))
+		Expect(t, err).To(BeNil())
+		resp.Body.Close()
+		Expect(t, resp.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+}
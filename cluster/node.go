@@ -0,0 +1,284 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/apoydence/pubsub"
+)
+
+const (
+	gossipMessage  = "gossip"
+	publishMessage = "publish"
+)
+
+// message is sent between nodes.
+type message struct {
+	Type string `json:"type"`
+
+	// Members is set for a gossip message. It includes the sender.
+	Members []memberState `json:"members,omitempty"`
+
+	// Path and Data are set for a publish message.
+	Path []string `json:"path,omitempty"`
+	Data []byte   `json:"data,omitempty"`
+}
+
+// memberState is what a node knows about a member of the cluster.
+type memberState struct {
+	Addr string `json:"addr"`
+
+	// Version is increased by the member each time it gossips, so newer
+	// news about the member replaces older news.
+	Version uint64 `json:"version"`
+	Digest  []byte `json:"digest"`
+}
+
+type member struct {
+	version uint64
+	digest  bloom
+	updated time.Time
+}
+
+// Node is a member of a cluster. Data that is published through a Node is
+// published to its PubSub and sent to the peers that might have matching
+// subscriptions. It should be constructed with NewNode.
+type Node struct {
+	p     *pubsub.PubSub
+	t     Transport
+	codec Codec
+	addr  string
+	c     config
+
+	mu      sync.Mutex
+	version uint64
+	digest  bloom
+	members map[string]*member
+	seeds   []string
+}
+
+// NewNode constructs a new Node with the given address, which the other
+// nodes use to send messages to it. The Codec must match the one the other
+// nodes use. The Node does not gossip until Run (or Gossip) is invoked.
+func NewNode(p *pubsub.PubSub, t Transport, codec Codec, addr string, opts ...Option) *Node {
+	return &Node{
+		p:     p,
+		t:     t,
+		codec: codec,
+		addr:  addr,
+		c:     newConfig(opts),
+
+		// The version starts at the current time so that news of a
+		// restarted node is newer than that of its previous run.
+		version: uint64(time.Now().UnixNano()),
+		members: make(map[string]*member),
+	}
+}
+
+// Join configures the addresses of nodes that the Node gossips with until
+// they are members of the cluster, so that the Node can join it. It can be
+// invoked again, e.g., after a network partition.
+func (n *Node) Join(addrs ...string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for _, addr := range addrs {
+		if addr != n.addr && !slices.Contains(n.seeds, addr) {
+			n.seeds = append(n.seeds, addr)
+		}
+	}
+}
+
+// Peers returns the addresses of the other members of the cluster in
+// sorted order.
+func (n *Node) Peers() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.expire()
+	peers := make([]string, 0, len(n.members))
+	for addr := range n.members {
+		peers = append(peers, addr)
+	}
+	slices.Sort(peers)
+	return peers
+}
+
+// Run gossips with other nodes each gossip interval (see
+// WithGossipInterval) until the context is done, and returns the context's
+// error.
+func (n *Node) Run(ctx context.Context) error {
+	t := time.NewTicker(n.c.interval)
+	defer t.Stop()
+
+	for {
+		n.Gossip(ctx)
+
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Gossip updates the Node's digest and sends what it knows about the
+// cluster to some of its peers (see WithFanout) and to one of the nodes
+// given to Join that is not a member. Run invokes it periodically.
+func (n *Node) Gossip(ctx context.Context) {
+	msg, addrs, err := n.prepareGossip()
+	if err != nil {
+		n.c.errorf(err)
+		return
+	}
+
+	for _, addr := range addrs {
+		if err := n.t.Send(ctx, addr, msg); err != nil {
+			n.c.errorf(fmt.Errorf("failed to gossip with %s: %w", addr, err))
+		}
+	}
+}
+
+func (n *Node) prepareGossip() ([]byte, []string, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.version++
+	n.digest = digest(n.p.Paths(), n.c.digestBits)
+	n.expire()
+
+	states := []memberState{{Addr: n.addr, Version: n.version, Digest: n.digest}}
+	var peers, seeds []string
+	for addr, m := range n.members {
+		states = append(states, memberState{Addr: addr, Version: m.version, Digest: m.digest})
+		peers = append(peers, addr)
+	}
+	for _, addr := range n.seeds {
+		if n.members[addr] == nil {
+			seeds = append(seeds, addr)
+		}
+	}
+
+	rand.Shuffle(len(peers), func(i, j int) { peers[i], peers[j] = peers[j], peers[i] })
+	if len(peers) > n.c.fanout {
+		peers = peers[:n.c.fanout]
+	}
+	if len(seeds) > 0 {
+		peers = append(peers, seeds[rand.IntN(len(seeds))])
+	}
+
+	msg, err := json.Marshal(message{Type: gossipMessage, Members: states})
+	return msg, peers, err
+}
+
+// expire removes the members without news for longer than the failure
+// timeout. It must be invoked while holding the mutex.
+func (n *Node) expire() {
+	for addr, m := range n.members {
+		if time.Since(m.updated) > n.c.timeout {
+			delete(n.members, addr)
+		}
+	}
+}
+
+// Publish publishes the data to the given path of the Node's PubSub and
+// sends it to each peer whose digest indicates that it might have a
+// matching subscription. The peers publish the data to their PubSub
+// without sending it on. It returns the result of the local publish and
+// the errors of sending the data (see errors.Join).
+func (n *Node) Publish(ctx context.Context, data interface{}, path []string) (pubsub.PublishResult, error) {
+	result, err := n.p.PublishCtx(ctx, data, pubsub.LinearTreeTraverser(path))
+	if err != nil {
+		return result, err
+	}
+
+	addrs := n.matchingPeers(path)
+	if len(addrs) == 0 {
+		return result, nil
+	}
+
+	b, err := n.codec.Marshal(data)
+	if err != nil {
+		return result, err
+	}
+
+	msg, err := json.Marshal(message{Type: publishMessage, Path: path, Data: b})
+	if err != nil {
+		return result, err
+	}
+
+	var errs []error
+	for _, addr := range addrs {
+		if err := n.t.Send(ctx, addr, msg); err != nil {
+			errs = append(errs, fmt.Errorf("failed to publish to %s: %w", addr, err))
+		}
+	}
+	return result, errors.Join(errs...)
+}
+
+func (n *Node) matchingPeers(path []string) []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.expire()
+	var addrs []string
+	for addr, m := range n.members {
+		if digestMatches(m.digest, path) {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+// Receive handles a message that another node sent via the Transport.
+func (n *Node) Receive(msg []byte) error {
+	var m message
+	if err := json.Unmarshal(msg, &m); err != nil {
+		return err
+	}
+
+	switch m.Type {
+	case gossipMessage:
+		n.merge(m.Members)
+		return nil
+	case publishMessage:
+		d, err := n.codec.Unmarshal(m.Data)
+		if err != nil {
+			return err
+		}
+		_, err = n.p.PublishCtx(context.Background(), d, pubsub.LinearTreeTraverser(m.Path))
+		return err
+	default:
+		return fmt.Errorf("unknown message type %q", m.Type)
+	}
+}
+
+// merge records the news about members that is newer than what the Node
+// knows.
+func (n *Node) merge(states []memberState) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	now := time.Now()
+	for _, s := range states {
+		if s.Addr == n.addr {
+			continue
+		}
+
+		if m := n.members[s.Addr]; m != nil && m.version >= s.Version {
+			continue
+		}
+
+		n.members[s.Addr] = &member{
+			version: s.Version,
+			digest:  bloom(s.Digest),
+			updated: now,
+		}
+	}
+}
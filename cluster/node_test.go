@@ -0,0 +1,209 @@
+package cluster_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apoydence/pubsub"
+	"github.com/apoydence/pubsub/cluster"
+	"github.com/apoydence/pubsub/codec"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+type TC struct {
+	*testing.T
+	net     *fakeNetwork
+	ps      map[string]*pubsub.PubSub
+	nodes   map[string]*cluster.Node
+	gossip  func()
+	addNode func(addr string, opts ...cluster.Option)
+}
+
+func TestNode(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) TC {
+		tc := TC{
+			T:     t,
+			net:   newFakeNetwork(),
+			ps:    make(map[string]*pubsub.PubSub),
+			nodes: make(map[string]*cluster.Node),
+		}
+
+		tc.addNode = func(addr string, opts ...cluster.Option) {
+			p := pubsub.New()
+			n := cluster.NewNode(p, tc.net, codec.JSON[string](), addr, opts...)
+			n.Join("a")
+			tc.net.add(addr, n)
+			tc.ps[addr] = p
+			tc.nodes[addr] = n
+		}
+
+		tc.gossip = func() {
+			for i := 0; i < 3; i++ {
+				for _, n := range tc.nodes {
+					n.Gossip(context.Background())
+				}
+			}
+		}
+
+		tc.addNode("a")
+		tc.addNode("b")
+		tc.addNode("c")
+		return tc
+	})
+
+	o.Spec("the nodes find each other via the nodes they joined", func(t TC) {
+		t.gossip()
+
+		Expect(t, t.nodes["a"].Peers()).To(Equal([]string{"b", "c"}))
+		Expect(t, t.nodes["b"].Peers()).To(Equal([]string{"a", "c"}))
+		Expect(t, t.nodes["c"].Peers()).To(Equal([]string{"a", "b"}))
+	})
+
+	o.Spec("it only sends publishes to peers with matching subscriptions", func(t TC) {
+		sub := newSpySubscription()
+		t.ps["c"].Subscribe(sub, pubsub.WithPath([]string{"x"}))
+		t.gossip()
+
+		_, err := t.nodes["a"].Publish(context.Background(), "v", []string{"x", "y"})
+		Expect(t, err).To(BeNil())
+		_, err = t.nodes["a"].Publish(context.Background(), "w", []string{"z"})
+		Expect(t, err).To(BeNil())
+
+		Expect(t, sub.values()).To(Equal([]interface{}{"v"}))
+		Expect(t, t.net.publishes("b")).To(Equal(0))
+		Expect(t, t.net.publishes("c")).To(Equal(1))
+	})
+
+	o.Spec("it sends publishes to peers with matching pattern subscriptions", func(t TC) {
+		sub := newSpySubscription()
+		t.ps["b"].Subscribe(sub, pubsub.WithPath([]string{"x", pubsub.Any, "z"}))
+		t.gossip()
+
+		t.nodes["c"].Publish(context.Background(), "v", []string{"x", "y", "z"})
+		Expect(t, sub.values()).To(Equal([]interface{}{"v"}))
+	})
+
+	o.Spec("it publishes locally", func(t TC) {
+		sub := newSpySubscription()
+		t.ps["a"].Subscribe(sub)
+
+		result, err := t.nodes["a"].Publish(context.Background(), "v", []string{"x"})
+		Expect(t, err).To(BeNil())
+		Expect(t, result.Delivered).To(Equal(1))
+		Expect(t, sub.values()).To(Equal([]interface{}{"v"}))
+	})
+
+	o.Spec("it returns the errors of sending publishes", func(t TC) {
+		t.ps["b"].Subscribe(newSpySubscription())
+		t.gossip()
+
+		t.net.fail("b")
+		_, err := t.nodes["a"].Publish(context.Background(), "v", []string{"x"})
+		Expect(t, err).To(Not(BeNil()))
+	})
+
+	o.Spec("it removes peers without news after the failure timeout", func(t TC) {
+		t.addNode("d", cluster.WithFailureTimeout(200*time.Millisecond))
+		t.gossip()
+		Expect(t, t.nodes["d"].Peers()).To(HaveLen(3))
+
+		Expect(t, t.nodes["d"].Peers).To(ViaPolling(HaveLen(0)))
+	})
+
+	o.Spec("it reports peers it can not gossip with", func(t TC) {
+		errs := make(chan error, 10)
+		t.addNode("d", cluster.WithErrorFunc(func(err error) { errs <- err }))
+		t.net.fail("a")
+
+		t.nodes["d"].Gossip(context.Background())
+		Expect(t, <-errs).To(Not(BeNil()))
+	})
+
+	o.Spec("it rejects invalid messages", func(t TC) {
+		Expect(t, t.nodes["a"].Receive([]byte("invalid"))).To(Not(BeNil()))
+		Expect(t, t.nodes["a"].Receive([]byte(`{"type":"unknown"}`))).To(Not(BeNil()))
+	})
+}
+
+type spySubscription struct {
+	mu   sync.Mutex
+	data []interface{}
+}
+
+func newSpySubscription() *spySubscription {
+	return &spySubscription{}
+}
+
+func (s *spySubscription) Write(data interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data = append(s.data, data)
+}
+
+func (s *spySubscription) values() []interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.data
+}
+
+// fakeNetwork delivers messages to the nodes synchronously.
+type fakeNetwork struct {
+	mu      sync.Mutex
+	nodes   map[string]*cluster.Node
+	failed  map[string]bool
+	publish map[string]int
+}
+
+func newFakeNetwork() *fakeNetwork {
+	return &fakeNetwork{
+		nodes:   make(map[string]*cluster.Node),
+		failed:  make(map[string]bool),
+		publish: make(map[string]int),
+	}
+}
+
+func (n *fakeNetwork) add(addr string, node *cluster.Node) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.nodes[addr] = node
+}
+
+// fail makes sending messages to the given address fail.
+func (n *fakeNetwork) fail(addr string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.failed[addr] = true
+}
+
+// publishes returns the number of publish messages that were sent to the
+// given address.
+func (n *fakeNetwork) publishes(addr string) int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.publish[addr]
+}
+
+func (n *fakeNetwork) Send(ctx context.Context, addr string, msg []byte) error {
+	n.mu.Lock()
+	node := n.nodes[addr]
+	if n.failed[addr] || node == nil {
+		n.mu.Unlock()
+		return errors.New("unreachable")
+	}
+	if bytes.HasPrefix(msg, []byte(`{"type":"publish"`)) {
+		n.publish[addr]++
+	}
+	n.mu.Unlock()
+
+	return node.Receive(msg)
+}
@@ -0,0 +1,72 @@
+// Package codec converts published data to and from bytes so that it can
+// be sent over a network or stored. The subpackages that do so (e.g.,
+// pubsubgrpc and pubsubnats) accept any Codec, so the wire format is not
+// fixed. Codecs that require other modules are in their own subpackages
+// (e.g., codec/protobuf).
+package codec
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+)
+
+// Codec converts published data to and from bytes. Implementations must be
+// safe to use concurrently.
+type Codec interface {
+	Marshal(data interface{}) ([]byte, error)
+	Unmarshal(b []byte) (interface{}, error)
+}
+
+// JSON returns a Codec that encodes data as JSON. The data is decoded into
+// a value of type T.
+func JSON[T any]() Codec {
+	return jsonCodec[T]{}
+}
+
+type jsonCodec[T any] struct{}
+
+func (jsonCodec[T]) Marshal(data interface{}) ([]byte, error) {
+	return json.Marshal(data)
+}
+
+func (jsonCodec[T]) Unmarshal(b []byte) (interface{}, error) {
+	var v T
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Gob returns a Codec that encodes data with encoding/gob. The data is
+// decoded into a value of type T. If T is an interface type, the concrete
+// types must be registered with gob.Register.
+func Gob[T any]() Codec {
+	return gobCodec[T]{}
+}
+
+type gobCodec[T any] struct{}
+
+func (gobCodec[T]) Marshal(data interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+
+	// Encoding a pointer to the data as a T makes interface types work
+	// the same as concrete ones.
+	v, ok := data.(T)
+	if !ok {
+		return nil, fmt.Errorf("codec: can not encode %T as %T", data, v)
+	}
+	if err := gob.NewEncoder(&buf).Encode(&v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobCodec[T]) Unmarshal(b []byte) (interface{}, error) {
+	var v T
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
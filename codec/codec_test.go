@@ -0,0 +1,70 @@
+package codec_test
+
+import (
+	"encoding/gob"
+	"testing"
+
+	"github.com/apoydence/pubsub/codec"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+type event struct {
+	Name  string
+	Count int
+}
+
+type TC struct {
+	*testing.T
+}
+
+func TestCodec(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) TC {
+		return TC{T: t}
+	})
+
+	o.Spec("JSON encodes and decodes data", func(t TC) {
+		c := codec.JSON[event]()
+		b, err := c.Marshal(event{Name: "a", Count: 1})
+		Expect(t, err).To(BeNil())
+		Expect(t, string(b)).To(Equal(`{"Name":"a","Count":1}`))
+
+		d, err := c.Unmarshal(b)
+		Expect(t, err).To(BeNil())
+		Expect(t, d).To(Equal(event{Name: "a", Count: 1}))
+
+		_, err = c.Unmarshal([]byte("invalid"))
+		Expect(t, err).To(Not(BeNil()))
+	})
+
+	o.Spec("Gob encodes and decodes data", func(t TC) {
+		c := codec.Gob[event]()
+		b, err := c.Marshal(event{Name: "a", Count: 1})
+		Expect(t, err).To(BeNil())
+
+		d, err := c.Unmarshal(b)
+		Expect(t, err).To(BeNil())
+		Expect(t, d).To(Equal(event{Name: "a", Count: 1}))
+	})
+
+	o.Spec("Gob encodes registered types as interfaces", func(t TC) {
+		gob.Register(event{})
+		c := codec.Gob[interface{}]()
+		b, err := c.Marshal(event{Name: "a", Count: 1})
+		Expect(t, err).To(BeNil())
+
+		d, err := c.Unmarshal(b)
+		Expect(t, err).To(BeNil())
+		Expect(t, d).To(Equal(event{Name: "a", Count: 1}))
+	})
+
+	o.Spec("Gob returns an error for data of another type", func(t TC) {
+		_, err := codec.Gob[event]().Marshal("a")
+		Expect(t, err).To(Not(BeNil()))
+	})
+}
@@ -0,0 +1,43 @@
+// Package protobuf provides a codec.Codec that encodes data with the
+// protobuf wire format. It is kept apart from the codec package so that
+// only the users of it depend on the protobuf module.
+package protobuf
+
+import (
+	"fmt"
+
+	"github.com/apoydence/pubsub/codec"
+	"google.golang.org/protobuf/proto"
+)
+
+// New returns a Codec that encodes data with the protobuf wire format. The
+// data must be a proto.Message and is decoded into a new *T:
+//
+//	protobuf.New[examplepb.Event]()
+func New[T any, PT interface {
+	*T
+	proto.Message
+}]() codec.Codec {
+	return protobufCodec[T, PT]{}
+}
+
+type protobufCodec[T any, PT interface {
+	*T
+	proto.Message
+}] struct{}
+
+func (protobufCodec[T, PT]) Marshal(data interface{}) ([]byte, error) {
+	m, ok := data.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("protobuf: %T is not a proto.Message", data)
+	}
+	return proto.Marshal(m)
+}
+
+func (protobufCodec[T, PT]) Unmarshal(b []byte) (interface{}, error) {
+	m := PT(new(T))
+	if err := proto.Unmarshal(b, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
@@ -0,0 +1,39 @@
+package protobuf_test
+
+import (
+	"testing"
+
+	"github.com/apoydence/pubsub/codec/protobuf"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestProtobuf(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.Spec("it encodes and decodes messages", func(t *testing.T) {
+		c := protobuf.New[wrapperspb.StringValue]()
+		b, err := c.Marshal(wrapperspb.String("a"))
+		Expect(t, err).To(BeNil())
+
+		d, err := c.Unmarshal(b)
+		Expect(t, err).To(BeNil())
+		v, ok := d.(*wrapperspb.StringValue)
+		Expect(t, ok).To(BeTrue())
+		Expect(t, v.GetValue()).To(Equal("a"))
+	})
+
+	o.Spec("it returns an error for data that is not a proto.Message", func(t *testing.T) {
+		_, err := protobuf.New[wrapperspb.StringValue]().Marshal("a")
+		Expect(t, err).To(Not(BeNil()))
+	})
+
+	o.Spec("it returns an error for data it can not decode", func(t *testing.T) {
+		_, err := protobuf.New[wrapperspb.StringValue]().Unmarshal([]byte{0xff})
+		Expect(t, err).To(Not(BeNil()))
+	})
+}
@@ -0,0 +1,430 @@
+package pubsub
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/apoydence/pubsub/internal/intern"
+	"github.com/apoydence/pubsub/internal/node"
+)
+
+// tree is a version of the subscription tree. Once a version is stored in
+// the PubSub it is never modified, so publishes can use it without holding
+// a lock. Changes are made by cloning the nodes along the changed paths
+// (see treeTxn) and storing a new version.
+type tree struct {
+	root   *node.Node
+	closed bool
+
+	// readers is the number of publishes that are using the version.
+	readers int64
+
+	// waiting is the number of goroutines that are waiting for the version
+	// to have no readers (see wait). The last reader then closes idle.
+	waiting atomic.Int32
+	mu      sync.Mutex
+	idle    chan struct{}
+}
+
+// acquire returns the current version of the tree. It must be released
+// once the caller is done with it.
+func (s *PubSub) acquire() *tree {
+	for {
+		t := s.tree.Load()
+		atomic.AddInt64(&t.readers, 1)
+
+		// If a new version was stored in the meantime, a writer might have
+		// already seen that there are no readers.
+		if s.tree.Load() == t {
+			return t
+		}
+		t.release()
+	}
+}
+
+func (t *tree) release() {
+	// As waiting is incremented before readers is checked (see wait),
+	// either the waiter sees that there are no readers or idle is closed.
+	if atomic.AddInt64(&t.readers, -1) > 0 || t.waiting.Load() == 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.idle != nil {
+		close(t.idle)
+		t.idle = nil
+	}
+}
+
+// wait blocks until no publishes are using the version.
+func (t *tree) wait() {
+	if atomic.LoadInt64(&t.readers) == 0 {
+		return
+	}
+
+	t.waiting.Add(1)
+	defer t.waiting.Add(-1)
+
+	t.mu.Lock()
+	if t.idle == nil {
+		t.idle = make(chan struct{})
+	}
+	idle := t.idle
+	t.mu.Unlock()
+
+	if atomic.LoadInt64(&t.readers) == 0 {
+		return
+	}
+	<-idle
+}
+
+// lockTree acquires the write lock for the subtrees of the given paths and
+// starts a new version of the tree. Without any paths, the whole tree is
+// locked.
+func (s *PubSub) lockTree(paths ...[]string) *treeTxn {
+	if len(paths) == 0 || s.subtreeLocks == nil {
+		return s.lockSubtrees(nil)
+	}
+
+	return s.lockSubtrees(s.subtreesOf(nil, paths))
+}
+
+// lockSubscriber is like lockTree, but it also locks the subtree that the
+// subscriber is in. The subscriber can not be moved to another subtree
+// until the lock is released.
+func (s *PubSub) lockSubscriber(sr *subscriber, paths ...[]string) *treeTxn {
+	if s.subtreeLocks == nil {
+		return s.lockSubtrees(nil)
+	}
+
+	// The aliases are removed along with the subscriber.
+	if len(sr.aliases) > 0 {
+		paths = append(paths, sr.aliasPaths()...)
+	}
+
+	for {
+		i := int(sr.subtree.Load())
+		t := s.lockSubtrees(s.subtreesOf([]int{i}, paths))
+		if int(sr.subtree.Load()) == i {
+			return t
+		}
+
+		// The subscriber was moved while waiting for the lock.
+		s.unlockTree(t)
+	}
+}
+
+// lockSubtrees locks the given subtrees (or every subtree if nil). They are
+// always locked in order so that transactions can't deadlock.
+func (s *PubSub) lockSubtrees(subtrees []int) *treeTxn {
+	switch {
+	case s.subtreeLocks == nil:
+		s.mu.Lock()
+	case subtrees == nil:
+		for i := range s.subtreeLocks {
+			s.subtreeLocks[i].Lock()
+		}
+	default:
+		slices.Sort(subtrees)
+		subtrees = slices.Compact(subtrees)
+		for _, i := range subtrees {
+			s.subtreeLocks[i].Lock()
+		}
+	}
+
+	cur := s.tree.Load()
+	return &treeTxn{
+		root:     cur.root,
+		base:     cur.root,
+		closed:   cur.closed,
+		fresh:    make(map[*node.Node]bool),
+		subtrees: subtrees,
+		stats:    s.nodeStats,
+		segments: &s.segments,
+	}
+}
+
+// subtreesOf appends the subtree of each path to subtrees.
+func (s *PubSub) subtreesOf(subtrees []int, paths [][]string) []int {
+	for _, p := range paths {
+		subtrees = append(subtrees, s.subtreeOfPath(p))
+	}
+	return subtrees
+}
+
+// subtreeOfPath returns the subtree lock that guards the path. The root
+// path has its own subtree.
+func (s *PubSub) subtreeOfPath(path []string) int {
+	if len(path) == 0 {
+		return 0
+	}
+	return s.subtreeOf(path[0])
+}
+
+// subtreeOf returns the subtree lock that guards the top-level child.
+func (s *PubSub) subtreeOf(key string) int {
+	if s.subtreeLocks == nil {
+		return 0
+	}
+
+	// FNV-1a
+	h := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= 16777619
+	}
+	return 1 + int(h%uint32(len(s.subtreeLocks)-1))
+}
+
+// unlockTree stores the new version of the tree (if it changed) and
+// releases the write lock. It returns the older versions that publishes
+// might still be using. They can be waited on (see waitForPublishes) to
+// ensure that removed subscriptions are no longer written to.
+func (s *PubSub) unlockTree(t *treeTxn) []*tree {
+	s.commitMu.Lock()
+	cur := s.tree.Load()
+	if t.root != t.base || t.closed != cur.closed {
+		root := t.root
+		if cur.root != t.base {
+			root = s.merge(t, cur.root)
+		}
+		t.indexPatterns()
+		s.tree.Store(&tree{root: root, closed: t.closed || cur.closed})
+
+		// A retired version without readers can never gain any.
+		retired := s.retired[:0]
+		for _, r := range s.retired {
+			if atomic.LoadInt64(&r.readers) > 0 {
+				retired = append(retired, r)
+			}
+		}
+		s.retired = append(retired, cur)
+	}
+	inUse := append([]*tree(nil), s.retired...)
+	s.commitMu.Unlock()
+
+	switch {
+	case s.subtreeLocks == nil:
+		s.mu.Unlock()
+	case t.subtrees == nil:
+		for i := range s.subtreeLocks {
+			s.subtreeLocks[i].Unlock()
+		}
+	default:
+		for _, i := range t.subtrees {
+			s.subtreeLocks[i].Unlock()
+		}
+	}
+
+	// Without a mutex, the caller is responsible for synchronizing with
+	// publishes (and might be one).
+	if _, ok := s.mu.(nopLock); ok {
+		return nil
+	}
+
+	return inUse
+}
+
+// merge combines the subtrees that the transaction locked with the rest of
+// the current version of the tree, which other transactions might have
+// changed in the meantime. It must be invoked while holding commitMu.
+func (s *PubSub) merge(t *treeTxn, cur *node.Node) *node.Node {
+	// The root's subscriptions belong to the root subtree.
+	from, root := t.root, t.clone(cur)
+	if t.locked(0) {
+		from, root = cur, t.clone(t.root)
+	}
+
+	var keys []string
+	root.ForEachChild(func(key string, _ *node.Node) {
+		keys = append(keys, key)
+	})
+	from.ForEachChild(func(key string, _ *node.Node) {
+		keys = append(keys, key)
+	})
+
+	for _, key := range keys {
+		if t.locked(s.subtreeOf(key)) == t.locked(0) {
+			continue
+		}
+
+		if child := from.FetchChild(key); child != nil {
+			root.SetChild(key, child)
+			continue
+		}
+		root.DeleteChild(key)
+	}
+
+	return root
+}
+
+func waitForPublishes(ts []*tree) {
+	for _, t := range ts {
+		t.wait()
+	}
+}
+
+// afterPublishes invokes f once the publishes that are using the given
+// versions are done (see waitForPublishes). If there are any, it does not
+// wait for them, as it might have been invoked from within one of them
+// (e.g., by a Subscription that closes the PubSub). f is instead invoked in
+// its own goroutine once they are done.
+func afterPublishes(ts []*tree, f func()) {
+	if !publishing(ts) {
+		f()
+		return
+	}
+
+	go func() {
+		waitForPublishes(ts)
+		f()
+	}()
+}
+
+func publishing(ts []*tree) bool {
+	for _, t := range ts {
+		if atomic.LoadInt64(&t.readers) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// treeTxn builds a new version of the tree. Nodes are cloned the first time
+// they are changed, so versions that publishes are using are never
+// modified. It must only be used while holding the write lock.
+type treeTxn struct {
+	root   *node.Node
+	closed bool
+
+	// base is the root the transaction started with and subtrees are the
+	// subtree locks it holds (nil means all of them).
+	base     *node.Node
+	subtrees []int
+
+	// fresh holds the nodes that were created by the transaction and can
+	// therefore be changed.
+	fresh map[*node.Node]bool
+
+	// stats is set if new nodes should have Stats (see WithNodeStats).
+	stats bool
+
+	// segments interns the keys of the nodes that are added to the tree.
+	// They are released once the nodes are pruned.
+	segments *intern.Table
+
+	// evicted holds the subscribers that were removed to make room for
+	// others or because they were idle (see evictLocked).
+	evicted []eviction
+}
+
+// locked reports whether the transaction holds the subtree lock.
+func (t *treeTxn) locked(subtree int) bool {
+	return t.subtrees == nil || slices.Contains(t.subtrees, subtree)
+}
+
+// fetch returns the node at the path (or nil). It must not be changed.
+func (t *treeTxn) fetch(path []string) *node.Node {
+	n := t.root
+	for _, p := range path {
+		n = n.FetchChild(p)
+		if n == nil {
+			return nil
+		}
+	}
+	return n
+}
+
+// nodes returns each node along the path (starting with the root) in a
+// state that can be changed. Missing nodes are created.
+func (t *treeTxn) nodes(path []string) []*node.Node {
+	t.root = t.clone(t.root)
+
+	ns := make([]*node.Node, 0, len(path)+1)
+	ns = append(ns, t.root)
+
+	n := t.root
+	for _, p := range path {
+		child := n.FetchChild(p)
+		if child == nil {
+			p = t.segments.Intern(p)
+			child = node.New()
+			if m := newSegmentMatcher(p); m != nil {
+				child.SetData(m)
+			}
+			if t.stats {
+				child.EnableStats()
+			}
+			t.fresh[child] = true
+		} else {
+			child = t.clone(child)
+		}
+		n.SetChild(p, child)
+
+		n = child
+		ns = append(ns, n)
+	}
+
+	return ns
+}
+
+// node returns the node at the path in a state that can be changed.
+func (t *treeTxn) node(path []string) *node.Node {
+	ns := t.nodes(path)
+	return ns[len(ns)-1]
+}
+
+func (t *treeTxn) clone(n *node.Node) *node.Node {
+	if t.fresh[n] {
+		return n
+	}
+
+	c := n.Clone()
+	t.fresh[c] = true
+	return c
+}
+
+// removeSubscription removes the subscription from the node at the path
+// and prunes any nodes that are no longer needed.
+func (t *treeTxn) removeSubscription(id int64, path []string) {
+	if t.fetch(path) == nil {
+		return
+	}
+
+	ns := t.nodes(path)
+	ns[len(ns)-1].DeleteSubscription(id)
+
+	for i := len(path); i > 0; i-- {
+		n := ns[i]
+		// Mounts are kept, while a pattern's segmentMatcher is recreated
+		// along with its node.
+		_, mount := n.Data().(*PubSub)
+		if n.ChildLen() > 0 || n.SubscriptionLen() > 0 || mount {
+			return
+		}
+		ns[i-1].DeleteChild(path[i-1])
+		t.segments.Release(path[i-1])
+	}
+}
+
+// indexPatterns indexes the pattern children (see newPatternIndex) of each
+// node the transaction changed.
+func (t *treeTxn) indexPatterns() {
+	for n := range t.fresh {
+		if n.PatternLen() > 0 && n.Index() == nil {
+			n.SetIndex(newPatternIndex(n))
+		}
+	}
+}
+
+// reset replaces the tree with an empty one.
+func (t *treeTxn) reset() {
+	t.segments.Reset()
+	t.root = node.New()
+	if t.stats {
+		t.root.EnableStats()
+	}
+	t.fresh[t.root] = true
+}
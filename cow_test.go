@@ -0,0 +1,287 @@
+package pubsub_test
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+func TestPubSubCopyOnWrite(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) TPS {
+		return TPS{
+			T: t,
+			p: pubsub.New(),
+		}
+	})
+
+	o.Spec("a subscription can subscribe while being published to", func(t TPS) {
+		sub := newSpySubscrption()
+		var once sync.Once
+		t.p.Subscribe(pubsub.SubscriptionFunc(func(data interface{}) {
+			once.Do(func() {
+				t.p.Subscribe(sub)
+			})
+		}))
+
+		t.p.Publish("a", pubsub.LinearTreeTraverser(nil))
+		Expect(t, sub.Len()).To(Equal(0))
+
+		t.p.Publish("b", pubsub.LinearTreeTraverser(nil))
+		Expect(t, sub.Data()).To(Equal([]interface{}{"b"}))
+	})
+
+	o.Spec("a subscription can subscribe while retained data is written to it", func(t TPS) {
+		t.p.Publish("a", pubsub.LinearTreeTraverser([]string{"a"}), pubsub.WithRetain())
+
+		sub := newSpySubscrption()
+		var once sync.Once
+		t.p.Subscribe(pubsub.SubscriptionFunc(func(data interface{}) {
+			once.Do(func() {
+				t.p.Subscribe(sub, pubsub.WithPath([]string{"a"}))
+			})
+		}), pubsub.WithPath([]string{"a"}))
+
+		Expect(t, sub.Data()).To(Equal([]interface{}{"a"}))
+	})
+
+	o.Spec("a subscription can publish while replayed data is written to it", func(t TPS) {
+		p := pubsub.New(pubsub.WithReplayBuffer(2))
+		p.Publish(1, pubsub.LinearTreeTraverser(nil))
+
+		sub := newSpySubscrption()
+		p.Subscribe(pubsub.SubscriptionFunc(func(data interface{}) {
+			sub.Write(data)
+			if data == 1 {
+				p.Publish(2, pubsub.LinearTreeTraverser(nil))
+			}
+		}), pubsub.WithReplay(2))
+
+		Expect(t, sub.Data()).To(Equal([]interface{}{1, 2}))
+	})
+
+	o.Spec("it does not write to a subscription after unsubscribe returns", func(t TPS) {
+		var (
+			unsubscribed int32
+			late         int32
+		)
+		unsubscribe := t.p.Subscribe(pubsub.SubscriptionFunc(func(data interface{}) {
+			if atomic.LoadInt32(&unsubscribed) == 1 {
+				atomic.AddInt32(&late, 1)
+			}
+		}))
+
+		done := make(chan struct{})
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					select {
+					case <-done:
+						return
+					default:
+						t.p.Publish("data", pubsub.LinearTreeTraverser(nil))
+					}
+				}
+			}()
+		}
+
+		// Let the publishers get going.
+		for i := 0; i < 100; i++ {
+			t.p.Subscribe(newSpySubscrption())()
+		}
+
+		unsubscribe()
+		atomic.StoreInt32(&unsubscribed, 1)
+
+		for i := 0; i < 100; i++ {
+			t.p.Publish("data", pubsub.LinearTreeTraverser(nil))
+		}
+		close(done)
+		wg.Wait()
+
+		Expect(t, atomic.LoadInt32(&late)).To(Equal(int32(0)))
+	})
+
+	o.Spec("a subscription can unsubscribe itself while being published to", func(t TPS) {
+		sub := newSpySubscrption()
+		var unsubscribe pubsub.Unsubscriber
+		unsubscribe = t.p.Subscribe(pubsub.SubscriptionFunc(func(data interface{}) {
+			sub.Write(data)
+			unsubscribe()
+		}))
+
+		t.p.Publish("a", pubsub.LinearTreeTraverser(nil))
+		t.p.Publish("b", pubsub.LinearTreeTraverser(nil))
+
+		Expect(t, sub.Data()).To(Equal([]interface{}{"a"}))
+		Expect(t, t.p.Subscriptions()).To(Equal(0))
+	})
+
+	o.Spec("a subscription can unsubscribe itself from another goroutine", func(t TPS) {
+		sub := newSpySubscrption()
+		var unsubscribe pubsub.Unsubscriber
+		unsubscribe = t.p.Subscribe(pubsub.SubscriptionFunc(func(data interface{}) {
+			sub.Write(data)
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				unsubscribe()
+			}()
+			<-done
+		}))
+
+		t.p.Publish("a", pubsub.LinearTreeTraverser(nil))
+		t.p.Publish("b", pubsub.LinearTreeTraverser(nil))
+
+		Expect(t, sub.Data()).To(Equal([]interface{}{"a"}))
+		Expect(t, t.p.Subscriptions()).To(Equal(0))
+	})
+
+	o.Spec("a subscription can unsubscribe others while being published to", func(t TPS) {
+		sub := newSpySubscrption()
+		var unsubscribe pubsub.Unsubscriber
+		t.p.Subscribe(pubsub.SubscriptionFunc(func(data interface{}) {
+			unsubscribe()
+		}))
+		unsubscribe = t.p.Subscribe(sub, pubsub.WithPath([]string{"a"}))
+
+		t.p.Publish("data", pubsub.LinearTreeTraverser([]string{"a"}))
+		Expect(t, sub.Len()).To(Equal(0))
+		Expect(t, t.p.Subscriptions("a")).To(Equal(0))
+	})
+
+	o.Spec("an async subscription can unsubscribe itself", func(t TPS) {
+		c, _ := t.p.SubscribeChan(pubsub.WithPath([]string{"a"}), pubsub.WithBufferSize(1))
+
+		var unsubscribe pubsub.Unsubscriber
+		unsubscribe = t.p.Subscribe(pubsub.SubscriptionFunc(func(data interface{}) {
+			unsubscribe()
+		}), pubsub.WithPath([]string{"a"}), pubsub.WithBufferSize(1), pubsub.WithOverflowStrategy(pubsub.OverflowBlock))
+
+		for i := 0; i < 10; i++ {
+			t.p.Publish("data", pubsub.LinearTreeTraverser([]string{"a"}))
+			<-c
+		}
+		Expect(t, func() int {
+			return t.p.Subscriptions("a")
+		}).To(ViaPolling(Equal(1)))
+	})
+
+	o.Spec("a subscription can close the PubSub while being published to", func(t TPS) {
+		c, _ := t.p.SubscribeChan(pubsub.WithPath([]string{"a"}))
+		t.p.Subscribe(pubsub.SubscriptionFunc(func(data interface{}) {
+			t.p.Close()
+		}))
+
+		t.p.Publish("data", pubsub.LinearTreeTraverser([]string{"a"}))
+
+		var data []interface{}
+		for d := range c {
+			data = append(data, d)
+		}
+		Expect(t, data).To(HaveLen(1))
+		Expect(t, t.p.Subscriptions()).To(Equal(0))
+	})
+
+	o.Spec("publishes use the version of the tree from when they started", func(t TPS) {
+		sub := newSpySubscrption()
+		t.p.Subscribe(pubsub.SubscriptionFunc(func(data interface{}) {
+			t.p.Subscribe(sub, pubsub.WithPath([]string{"a"}))
+		}))
+
+		t.p.Publish("data", pubsub.LinearTreeTraverser([]string{"a"}))
+		Expect(t, sub.Len()).To(Equal(0))
+		Expect(t, t.p.Subscriptions("a")).To(Equal(1))
+	})
+}
+
+func TestPubSubSubtreeLocking(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.Spec("subscribes to disjoint namespaces do not wait on each other", func(t *testing.T) {
+		started := make(chan struct{})
+		block := make(chan struct{})
+		p := pubsub.New(
+			pubsub.WithSubtreeLocking(),
+			pubsub.WithSubscribeInterceptor(func(path []string, next pubsub.Subscription) pubsub.Subscription {
+				if path[0] == "a" {
+					close(started)
+					<-block
+				}
+				return next
+			}),
+		)
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"a"}))
+		}()
+		<-started
+
+		sub := newSpySubscrption()
+		p.Subscribe(sub, pubsub.WithPath([]string{"b"}))
+		p.Publish("data", pubsub.LinearTreeTraverser([]string{"b"}))
+		Expect(t, sub.Data()).To(Equal([]interface{}{"data"}))
+
+		close(block)
+		<-done
+		Expect(t, p.Subscriptions("a")).To(Equal(1))
+		Expect(t, p.Subscriptions("b")).To(Equal(1))
+	})
+
+	o.Spec("it keeps concurrent changes to different subtrees", func(t *testing.T) {
+		p := pubsub.New(pubsub.WithSubtreeLocking())
+
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func(key string) {
+				defer wg.Done()
+				for j := 0; j < 50; j++ {
+					p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{key, "x"}))()
+				}
+				p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{key}))
+				p.Subscribe(newSpySubscrption())
+			}(string(rune('a' + i)))
+		}
+		wg.Wait()
+
+		Expect(t, p.Subscriptions()).To(Equal(20))
+		for i := 0; i < 20; i++ {
+			key := string(rune('a' + i))
+			Expect(t, p.Subscriptions(key)).To(Equal(1))
+			Expect(t, p.Subscriptions(key, "x")).To(Equal(0))
+		}
+	})
+
+	o.Spec("it moves subscriptions between subtrees", func(t *testing.T) {
+		p := pubsub.New(pubsub.WithSubtreeLocking())
+		sub := newSpySubscrption()
+		h := p.SubscribeHandle(sub, pubsub.WithPath([]string{"a"}))
+
+		h.Move([]string{"b"})
+		p.Publish("data", pubsub.LinearTreeTraverser([]string{"a"}))
+		p.Publish("other", pubsub.LinearTreeTraverser([]string{"b"}))
+
+		Expect(t, sub.Data()).To(Equal([]interface{}{"other"}))
+		Expect(t, p.Subscriptions("a")).To(Equal(0))
+
+		h.Unsubscribe()
+		Expect(t, p.Subscriptions("b")).To(Equal(0))
+	})
+}
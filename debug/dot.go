@@ -0,0 +1,87 @@
+// Package debug provides tools to inspect a PubSub while debugging.
+package debug
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/apoydence/pubsub"
+)
+
+// WriteDOT writes the subscription tree of the PubSub to w in the DOT
+// language (Graphviz). Each node is labeled with its path segment, the
+// number of subscriptions and the size of each shard group.
+func WriteDOT(w io.Writer, p *pubsub.PubSub) error {
+	type dotNode struct {
+		path     []string
+		subCount int
+	}
+
+	var nodes []dotNode
+	p.Walk(func(path []string, subCount int) {
+		nodes = append(nodes, dotNode{path: path, subCount: subCount})
+	})
+
+	ids := make(map[string]int)
+	var b strings.Builder
+	b.WriteString("digraph pubsub {\n")
+	for i, n := range nodes {
+		key := strings.Join(n.path, "\x00")
+		ids[key] = i
+
+		label := []string{"(root)"}
+		if len(n.path) > 0 {
+			label[0] = segmentName(n.path[len(n.path)-1])
+		}
+		label = append(label, fmt.Sprintf("subscriptions: %d", n.subCount))
+		label = append(label, shardLabels(p.ShardGroups(n.path...))...)
+
+		fmt.Fprintf(&b, "\tn%d [label=\"%s\"];\n", i, escape(label))
+
+		if len(n.path) > 0 {
+			parent := strings.Join(n.path[:len(n.path)-1], "\x00")
+			fmt.Fprintf(&b, "\tn%d -> n%d;\n", ids[parent], i)
+		}
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func segmentName(s string) string {
+	switch s {
+	case pubsub.Any:
+		return "<any>"
+	case pubsub.Rest:
+		return "<rest>"
+	default:
+		return s
+	}
+}
+
+func shardLabels(groups map[string]int) []string {
+	var labels []string
+	for shardID, count := range groups {
+		if shardID == "" {
+			continue
+		}
+		labels = append(labels, fmt.Sprintf("shard %s: %d", shardID, count))
+	}
+	sort.Strings(labels)
+
+	return labels
+}
+
+// escape joins the lines of a label and escapes them for a quoted DOT
+// string.
+func escape(lines []string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	for i, l := range lines {
+		lines[i] = r.Replace(l)
+	}
+
+	return strings.Join(lines, `\n`)
+}
@@ -0,0 +1,43 @@
+package debug_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/apoydence/pubsub/debug"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+func TestWriteDOT(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.Spec("it writes the tree", func(t *testing.T) {
+		p := pubsub.New()
+		sub := pubsub.SubscriptionFunc(func(interface{}) {})
+		p.Subscribe(sub, pubsub.WithPath([]string{"a", "b"}))
+		p.Subscribe(sub, pubsub.WithPath([]string{"a", "b"}), pubsub.WithShardID("1"))
+		p.Subscribe(sub, pubsub.WithPath([]string{"a", pubsub.Any}), pubsub.WithShardID("1"))
+		p.Subscribe(sub, pubsub.WithPath([]string{`"c"`}))
+
+		var buf bytes.Buffer
+		err := debug.WriteDOT(&buf, p)
+		Expect(t, err == nil).To(BeTrue())
+		Expect(t, buf.String()).To(Equal(`digraph pubsub {
+	n0 [label="(root)\nsubscriptions: 0"];
+	n1 [label="\"c\"\nsubscriptions: 1"];
+	n0 -> n1;
+	n2 [label="a\nsubscriptions: 0"];
+	n0 -> n2;
+	n3 [label="<any>\nsubscriptions: 1\nshard 1: 1"];
+	n2 -> n3;
+	n4 [label="b\nsubscriptions: 2\nshard 1: 1"];
+	n2 -> n4;
+}
+`))
+	})
+}
@@ -0,0 +1,125 @@
+package pubsub
+
+import (
+	"sync"
+	"time"
+)
+
+// WithDeduplication configures a subscription to only be written the first
+// message with a given ID (as returned by idFn) within the window. Any
+// repeats of it that are published before the window has passed are
+// dropped. The window starts when the message is first written and is
+// measured with the PubSub's Clock. Messages with an empty ID are never
+// deduplicated.
+func WithDeduplication(idFn func(data interface{}) string, window time.Duration) SubscribeOption {
+	return subscribeConfigFunc(func(c *subscribeConfig) {
+		c.dedupeID = idFn
+		c.dedupeWindow = window
+	})
+}
+
+// WithSubscriptionDeduplication configures a PubSub to write each publish
+// at most once to each Subscription, even if the Subscription was
+// subscribed at several paths that match it (or that the TreeTraverser
+// branches to). Subscriptions are told apart with ==, so ones that are not
+// comparable (e.g., a SubscriptionFunc) are never deduplicated. Like
+// WithPaths, it does not extend to shard groups.
+func WithSubscriptionDeduplication() PubSubOption {
+	return pubsubConfigFunc(func(p *PubSub) {
+		p.dedupeSubscriptions = true
+	})
+}
+
+// writeOnce returns false if a subscriber with the same key (see
+// subscriber.once) has already been written to during the publish. Like
+// visit, the keys are kept in a slice until there are too many and then in
+// a map.
+func (p *publish) writeOnce(key interface{}) bool {
+	if len(p.writtenMap) > 0 {
+		if _, ok := p.writtenMap[key]; ok {
+			return false
+		}
+		p.writtenMap[key] = struct{}{}
+		return true
+	}
+
+	for _, x := range p.written {
+		if x == key {
+			return false
+		}
+	}
+
+	if len(p.written) < maxVisited {
+		p.written = append(p.written, key)
+		return true
+	}
+
+	if p.writtenMap == nil {
+		p.writtenMap = make(map[interface{}]struct{}, 2*maxVisited)
+	}
+	for _, x := range p.written {
+		p.writtenMap[x] = struct{}{}
+	}
+	p.writtenMap[key] = struct{}{}
+
+	return true
+}
+
+// deduper remembers the IDs of the messages that were written to a
+// subscription within the window.
+type deduper struct {
+	id     func(data interface{}) string
+	window time.Duration
+	clock  Clock
+
+	mu   sync.Mutex
+	seen map[string]time.Time
+
+	// order holds the IDs in the order they were seen so that they can be
+	// forgotten once the window has passed.
+	order []seenID
+}
+
+type seenID struct {
+	id string
+	at time.Time
+}
+
+func newDeduper(c subscribeConfig, clock Clock) *deduper {
+	if c.dedupeID == nil || c.dedupeWindow <= 0 {
+		return nil
+	}
+
+	return &deduper{
+		id:     c.dedupeID,
+		window: c.dedupeWindow,
+		clock:  clock,
+		seen:   make(map[string]time.Time),
+	}
+}
+
+// duplicate reports if a message with the same ID was seen within the
+// window. Otherwise the message's ID is remembered.
+func (d *deduper) duplicate(data interface{}) bool {
+	id := d.id(data)
+	if id == "" {
+		return false
+	}
+	now := d.clock.Now()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for len(d.order) > 0 && !now.Before(d.order[0].at.Add(d.window)) {
+		delete(d.seen, d.order[0].id)
+		d.order = d.order[1:]
+	}
+
+	if _, ok := d.seen[id]; ok {
+		return true
+	}
+
+	d.seen[id] = now
+	d.order = append(d.order, seenID{id: id, at: now})
+	return false
+}
@@ -0,0 +1,144 @@
+package pubsub_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+func TestPubSubDeduplication(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) TC {
+		clock := newSpyClock()
+		return TC{
+			T:            t,
+			p:            pubsub.New(pubsub.WithClock(clock)),
+			clock:        clock,
+			subscription: newSpySubscrption(),
+		}
+	})
+
+	id := func(data interface{}) string {
+		return data.(string)[:1]
+	}
+
+	o.Spec("it drops repeated messages within the window", func(t TC) {
+		t.p.Subscribe(t.subscription, pubsub.WithDeduplication(id, time.Minute))
+
+		t.p.Publish("a1", pubsub.LinearTreeTraverser(nil))
+		t.p.Publish("b1", pubsub.LinearTreeTraverser(nil))
+		t.clock.advance(59 * time.Second)
+		t.p.Publish("a2", pubsub.LinearTreeTraverser(nil))
+		t.clock.advance(time.Second)
+		t.p.Publish("a3", pubsub.LinearTreeTraverser(nil))
+		t.p.Publish("b2", pubsub.LinearTreeTraverser(nil))
+
+		Expect(t, t.subscription.Data()).To(Equal([]interface{}{"a1", "b1", "a3", "b2"}))
+	})
+
+	o.Spec("it deduplicates per subscription", func(t TC) {
+		other := newSpySubscrption()
+		t.p.Subscribe(t.subscription, pubsub.WithDeduplication(id, time.Minute))
+		t.p.Subscribe(other, pubsub.WithDeduplication(id, time.Minute), pubsub.WithPath([]string{"x"}))
+
+		t.p.Publish("a1", pubsub.LinearTreeTraverser(nil))
+		t.p.Publish("a2", pubsub.LinearTreeTraverser([]string{"x"}))
+
+		Expect(t, t.subscription.Data()).To(Equal([]interface{}{"a1"}))
+		Expect(t, other.Data()).To(Equal([]interface{}{"a2"}))
+	})
+
+	o.Spec("it does not deduplicate messages without an ID", func(t TC) {
+		t.p.Subscribe(t.subscription, pubsub.WithDeduplication(func(interface{}) string { return "" }, time.Minute))
+
+		t.p.Publish("a1", pubsub.LinearTreeTraverser(nil))
+		t.p.Publish("a1", pubsub.LinearTreeTraverser(nil))
+
+		Expect(t, t.subscription.Len()).To(Equal(2))
+	})
+
+	o.Spec("it only remembers messages that pass the filters", func(t TC) {
+		t.p.Subscribe(t.subscription,
+			pubsub.WithFilter(func(data interface{}) bool { return data != "a1" }),
+			pubsub.WithDeduplication(id, time.Minute),
+		)
+
+		t.p.Publish("a1", pubsub.LinearTreeTraverser(nil))
+		t.p.Publish("a2", pubsub.LinearTreeTraverser(nil))
+
+		Expect(t, t.subscription.Data()).To(Equal([]interface{}{"a2"}))
+	})
+}
+
+func TestPubSubSubscriptionDeduplication(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.Spec("it writes each subscription at most once per publish", func(t *testing.T) {
+		for _, opts := range [][]pubsub.PubSubOption{
+			nil,
+			{pubsub.WithFanoutConcurrency(2)},
+		} {
+			p := pubsub.New(append(opts, pubsub.WithSubscriptionDeduplication())...)
+			sub := newSpySubscrption()
+			p.Subscribe(sub, pubsub.WithPath([]string{"a"}))
+			p.Subscribe(sub, pubsub.WithPath([]string{"a", "b"}))
+			p.Subscribe(sub, pubsub.WithPath([]string{pubsub.Any, "b"}))
+
+			r, _ := p.PublishCtx(context.Background(), 1, pubsub.LinearTreeTraverser([]string{"a", "b"}))
+			Expect(t, r.Matched).To(Equal(3))
+			Expect(t, r.Delivered).To(Equal(1))
+			Expect(t, sub.Data()).To(Equal([]interface{}{1}))
+		}
+	})
+
+	o.Spec("it writes many subscriptions once per publish", func(t *testing.T) {
+		p := pubsub.New(pubsub.WithSubscriptionDeduplication())
+		var subs []*spySubscription
+		for i := 0; i < 100; i++ {
+			sub := newSpySubscrption()
+			p.Subscribe(sub, pubsub.WithPath([]string{"a"}))
+			p.Subscribe(sub, pubsub.WithPath([]string{"a", "b"}))
+			subs = append(subs, sub)
+		}
+
+		for i := 0; i < 2; i++ {
+			r, _ := p.PublishCtx(context.Background(), i, pubsub.LinearTreeTraverser([]string{"a", "b"}))
+			Expect(t, r.Delivered).To(Equal(100))
+		}
+
+		for _, sub := range subs {
+			Expect(t, sub.Data()).To(Equal([]interface{}{0, 1}))
+		}
+	})
+
+	o.Spec("it writes each subscription once per match by default", func(t *testing.T) {
+		p := pubsub.New()
+		sub := newSpySubscrption()
+		p.Subscribe(sub, pubsub.WithPath([]string{"a"}))
+		p.Subscribe(sub, pubsub.WithPath([]string{"a", "b"}))
+
+		p.Publish(1, pubsub.LinearTreeTraverser([]string{"a", "b"}))
+		Expect(t, sub.Data()).To(Equal([]interface{}{1, 1}))
+	})
+
+	o.Spec("it does not deduplicate subscriptions that are not comparable", func(t *testing.T) {
+		p := pubsub.New(pubsub.WithSubscriptionDeduplication())
+		var count int
+		sub := pubsub.SubscriptionFunc(func(interface{}) { count++ })
+		p.Subscribe(sub, pubsub.WithPath([]string{"a"}))
+		p.Subscribe(sub, pubsub.WithPath([]string{"a", "b"}))
+
+		p.Publish(1, pubsub.LinearTreeTraverser([]string{"a", "b"}))
+		Expect(t, count).To(Equal(2))
+	})
+}
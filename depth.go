@@ -0,0 +1,50 @@
+package pubsub
+
+import "errors"
+
+// defaultMaxTraversalDepth is the maximum depth of a traversal when
+// WithMaxTraversalDepth is not used.
+const defaultMaxTraversalDepth = 1024
+
+// ErrMaxTraversalDepth is returned by PublishCtx when the TreeTraverser
+// traversed deeper than the maximum depth (see WithMaxTraversalDepth).
+var ErrMaxTraversalDepth = errors.New("traversal exceeded the maximum depth")
+
+// WithMaxTraversalDepth configures how many segments deep a publish may
+// traverse. Once a path reaches the depth, the TreeTraverser is not asked
+// for any further paths beneath it and the publish reports
+// ErrMaxTraversalDepth (see WithTraversalErrorFunc). This guards against a
+// TreeTraverser that never returns empty Paths, which would otherwise
+// traverse forever when data is retained or replayed. It defaults to 1024.
+// A depth of zero or less disables the guard.
+func WithMaxTraversalDepth(n int) PubSubOption {
+	return pubsubConfigFunc(func(p *PubSub) {
+		p.maxTraversalDepth = n
+	})
+}
+
+// WithTraversalErrorFunc configures a function that is invoked when a
+// publish can not be traversed (e.g., with ErrMaxTraversalDepth). It is
+// given the published data and a copy of the path where the traversal was
+// stopped. It is invoked at most once per publish.
+func WithTraversalErrorFunc(f func(data interface{}, path []string, err error)) PubSubOption {
+	return pubsubConfigFunc(func(p *PubSub) {
+		p.traversalErr = f
+	})
+}
+
+// exceededDepth reports if the path has reached the maximum depth. If so,
+// the publish's error is set.
+func (s *PubSub) exceededDepth(p *publish, l []string) bool {
+	if s.maxTraversalDepth <= 0 || len(l) < s.maxTraversalDepth {
+		return false
+	}
+
+	if p.err == nil {
+		p.err = ErrMaxTraversalDepth
+		if s.traversalErr != nil {
+			s.traversalErr(p.data, append([]string(nil), l...), p.err)
+		}
+	}
+	return true
+}
@@ -0,0 +1,65 @@
+package pubsub_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+func TestPubSubTraversalDepth(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	// endless never returns empty Paths.
+	endless := pubsub.TreeTraverserFunc(func(data interface{}, path []string) pubsub.Paths {
+		return pubsub.FlatPaths([]string{strconv.Itoa(len(path))})
+	})
+
+	o.Spec("it stops traversing at the maximum depth", func(t *testing.T) {
+		var (
+			paths [][]string
+			errs  []error
+		)
+		p := pubsub.New(
+			pubsub.WithMaxTraversalDepth(3),
+			pubsub.WithTraversalErrorFunc(func(data interface{}, path []string, err error) {
+				paths = append(paths, path)
+				errs = append(errs, err)
+			}),
+		)
+		sub := newSpySubscrption()
+		p.Subscribe(sub, pubsub.WithPath([]string{"0", "1", "2"}))
+
+		_, err := p.PublishCtx(context.Background(), "data", endless, pubsub.WithRetain())
+		Expect(t, errors.Is(err, pubsub.ErrMaxTraversalDepth)).To(BeTrue())
+		Expect(t, sub.Data()).To(Equal([]interface{}{"data"}))
+		Expect(t, paths).To(Equal([][]string{{"0", "1", "2"}}))
+		Expect(t, errs).To(Equal([]error{pubsub.ErrMaxTraversalDepth}))
+	})
+
+	o.Spec("it has a default maximum depth", func(t *testing.T) {
+		p := pubsub.New()
+
+		_, err := p.PublishCtx(context.Background(), "data", endless, pubsub.WithRetain())
+		Expect(t, errors.Is(err, pubsub.ErrMaxTraversalDepth)).To(BeTrue())
+	})
+
+	o.Spec("it does not report traversals within the maximum depth", func(t *testing.T) {
+		p := pubsub.New(
+			pubsub.WithMaxTraversalDepth(3),
+			pubsub.WithTraversalErrorFunc(func(data interface{}, path []string, err error) {
+				panic("unexpected traversal error")
+			}),
+		)
+
+		_, err := p.PublishCtx(context.Background(), "data", pubsub.LinearTreeTraverser([]string{"a", "b"}), pubsub.WithRetain())
+		Expect(t, err).To(BeNil())
+	})
+}
@@ -0,0 +1,41 @@
+package pubsub
+
+import "github.com/apoydence/pubsub/internal/node"
+
+// deterministicSeed seeds the source of randomness of a PubSub with
+// WithDeterministic.
+const deterministicSeed = 1
+
+// WithDeterministic configures a PubSub to behave the same way each time it
+// is given the same subscriptions and publishes, so that tests and
+// debugging sessions are reproducible:
+//
+//   - Subscriptions at a node are written to in a fixed order: by priority
+//     (see WithPriority), then the subscriptions without a shardID in the
+//     order they were added to the node, then the shard groups by shardID. The same
+//     order is used by Match, SubscriptionInfos, Snapshot, eviction and
+//     Close.
+//   - Shard groups that are gathered with WithCrossNodeSharding are written
+//     to in order of their shardIDs.
+//   - Unless WithRandSource is given, the source of randomness (used by the
+//     default RandSharding and WithSampleRate) has a fixed seed.
+//
+// The tree is traversed in the order of the paths that the TreeTraverser
+// returns and Walk always visits siblings in sorted order. Writes that
+// happen concurrently (e.g., with WithAsyncDelivery or
+// WithFanoutConcurrency) are not ordered.
+func WithDeterministic() PubSubOption {
+	return pubsubConfigFunc(func(p *PubSub) {
+		p.deterministic = true
+	})
+}
+
+// forEachSubscription invokes f for the subscriptions of the node in the
+// order they are written to.
+func (s *PubSub) forEachSubscription(n *node.Node, f func(shardID string, ss []node.SubscriptionEnvelope)) {
+	if s.deterministic {
+		n.ForEachSubscriptionSorted(f)
+		return
+	}
+	n.ForEachSubscriptionByPriority(f)
+}
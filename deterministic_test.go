@@ -0,0 +1,96 @@
+package pubsub_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+func TestPubSubDeterministic(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	// run subscribes to a node with several shard groups and returns the
+	// order in which they were written to.
+	run := func(opts ...pubsub.PubSubOption) []string {
+		p := pubsub.New(append([]pubsub.PubSubOption{pubsub.WithDeterministic()}, opts...)...)
+
+		var (
+			mu    sync.Mutex
+			names []string
+		)
+		record := func(name string) pubsub.Subscription {
+			return pubsub.SubscriptionFunc(func(interface{}) {
+				mu.Lock()
+				defer mu.Unlock()
+				names = append(names, name)
+			})
+		}
+
+		for _, shardID := range []string{"d", "b", "c", "a"} {
+			p.Subscribe(record("shard-"+shardID), pubsub.WithShardID(shardID))
+			p.Subscribe(record(shardID))
+		}
+
+		p.Publish("data", pubsub.LinearTreeTraverser(nil))
+		return names
+	}
+
+	o.Spec("it writes to the subscriptions in a fixed order", func(t *testing.T) {
+		for i := 0; i < 10; i++ {
+			Expect(t, run()).To(Equal([]string{
+				"d", "b", "c", "a",
+				"shard-a", "shard-b", "shard-c", "shard-d",
+			}))
+		}
+	})
+
+	o.Spec("it writes to cross-node shard groups in a fixed order", func(t *testing.T) {
+		for i := 0; i < 10; i++ {
+			Expect(t, run(pubsub.WithCrossNodeSharding())).To(Equal([]string{
+				"d", "b", "c", "a",
+				"shard-a", "shard-b", "shard-c", "shard-d",
+			}))
+		}
+	})
+
+	o.Spec("it shards with a fixed seed", func(t *testing.T) {
+		shard := func() []int {
+			p := pubsub.New(pubsub.WithDeterministic())
+			var writes []int
+			for i := 0; i < 3; i++ {
+				i := i
+				p.Subscribe(pubsub.SubscriptionFunc(func(interface{}) {
+					writes = append(writes, i)
+				}), pubsub.WithShardID("a"))
+			}
+
+			for i := 0; i < 20; i++ {
+				p.Publish(i, pubsub.LinearTreeTraverser(nil))
+			}
+			return writes
+		}
+
+		w := shard()
+		Expect(t, w).To(HaveLen(20))
+		Expect(t, shard()).To(Equal(w))
+	})
+
+	o.Spec("it lists the subscriptions in the order they are written to", func(t *testing.T) {
+		p := pubsub.New(pubsub.WithDeterministic())
+		for _, shardID := range []string{"b", "", "a"} {
+			p.Subscribe(newSpySubscrption(), pubsub.WithShardID(shardID))
+		}
+
+		var shardIDs []string
+		for _, info := range p.SubscriptionInfos() {
+			shardIDs = append(shardIDs, info.ShardID)
+		}
+		Expect(t, shardIDs).To(Equal([]string{"", "a", "b"}))
+	})
+}
@@ -0,0 +1,115 @@
+package pubsub
+
+import "fmt"
+
+// ErrSubscription is a Subscription whose writes can fail. When a
+// Subscription implements it, WriteErr is used instead of Write. A failed
+// write is retried (see WithRetries) and, if it still fails, the failure is
+// reported:
+//
+//   - The error is included in the PublishResult's Errors or, if the write
+//     was queued (see WithAsyncDelivery), in the error of PublishSync.
+//   - The error handler is invoked (see WithErrorHandler).
+//   - The data is written to the dead letter subscription (see
+//     WithDeadLetterSubscription).
+//
+// The errors of shard groups are only reported to the error handler and
+// the dead letter subscription.
+type ErrSubscription interface {
+	Subscription
+	WriteErr(data interface{}) error
+}
+
+// ErrSubscriptionFunc is an adapter to allow ordinary functions to be an
+// ErrSubscription.
+type ErrSubscriptionFunc func(data interface{}) error
+
+// Write implements Subscription.
+func (f ErrSubscriptionFunc) Write(data interface{}) {
+	f(data)
+}
+
+// WriteErr implements ErrSubscription.
+func (f ErrSubscriptionFunc) WriteErr(data interface{}) error {
+	return f(data)
+}
+
+// SubscriptionError is the error of an ErrSubscription whose write failed.
+type SubscriptionError struct {
+	// Path is the path of the subscription.
+	Path []string
+
+	// Err is the error of the last attempt.
+	Err error
+}
+
+// Error implements error.
+func (e *SubscriptionError) Error() string {
+	return fmt.Sprintf("subscription %v: %v", e.Path, e.Err)
+}
+
+// Unwrap returns the error of the last attempt.
+func (e *SubscriptionError) Unwrap() error {
+	return e.Err
+}
+
+// WithRetries configures how many times a failed write to an
+// ErrSubscription is retried before the failure is reported. The retries
+// are immediate. It defaults to 0.
+func WithRetries(n int) SubscribeOption {
+	return subscribeConfigFunc(func(c *subscribeConfig) {
+		c.retries = n
+	})
+}
+
+// WithErrorHandler configures a PubSub to invoke f with the data and the
+// SubscriptionError each time a write to an ErrSubscription fails (after
+// its retries). It is invoked by the writer, so it must not block.
+func WithErrorHandler(f func(data interface{}, err *SubscriptionError)) PubSubOption {
+	return pubsubConfigFunc(func(p *PubSub) {
+		p.errorHandler = f
+	})
+}
+
+// errWriter is implemented by the subscriptions that report the error of
+// an ErrSubscription.
+type errWriter interface {
+	writeErr(data interface{}) error
+}
+
+// errSubscription implements Subscription by writing to an ErrSubscription
+// and retrying and reporting the writes that fail.
+type errSubscription struct {
+	sub     ErrSubscription
+	path    []string
+	retries int
+	p       *PubSub
+}
+
+// Write implements Subscription.
+func (e *errSubscription) Write(data interface{}) {
+	e.writeErr(data)
+}
+
+func (e *errSubscription) writeErr(data interface{}) error {
+	var err error
+	for i := 0; i <= e.retries; i++ {
+		if err = e.sub.WriteErr(data); err == nil {
+			return nil
+		}
+	}
+
+	serr := &SubscriptionError{Path: e.path, Err: err}
+	if e.p.errorHandler != nil {
+		e.p.errorHandler(data, serr)
+	}
+	if e.p.deadLetter != nil {
+		e.p.deadLetter.Write(data)
+	}
+	return serr
+}
+
+// Close implements Closer.
+func (e *errSubscription) Close() {
+	closeSubscription(e.sub)
+}
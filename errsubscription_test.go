@@ -0,0 +1,120 @@
+package pubsub_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+func TestPubSubErrSubscription(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	errFailed := errors.New("failed")
+
+	// failing returns an ErrSubscription that fails the given number of
+	// times before it succeeds and a function that returns its attempts.
+	failing := func(failures int) (pubsub.ErrSubscription, func() int) {
+		var (
+			mu       sync.Mutex
+			attempts int
+		)
+		sub := pubsub.ErrSubscriptionFunc(func(data interface{}) error {
+			mu.Lock()
+			defer mu.Unlock()
+			attempts++
+			if attempts <= failures {
+				return errFailed
+			}
+			return nil
+		})
+
+		return sub, func() int {
+			mu.Lock()
+			defer mu.Unlock()
+			return attempts
+		}
+	}
+
+	o.Spec("it returns the errors in the PublishResult", func(t *testing.T) {
+		p := pubsub.New()
+		sub, _ := failing(1)
+		p.Subscribe(sub, pubsub.WithPath([]string{"a"}))
+		p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"a"}))
+
+		r, err := p.PublishCtx(context.Background(), "data", pubsub.LinearTreeTraverser([]string{"a"}))
+		Expect(t, err).To(BeNil())
+		Expect(t, r.Delivered).To(Equal(2))
+		Expect(t, r.Errors).To(HaveLen(1))
+
+		var serr *pubsub.SubscriptionError
+		Expect(t, errors.As(r.Errors[0], &serr)).To(BeTrue())
+		Expect(t, serr.Path).To(Equal([]string{"a"}))
+		Expect(t, errors.Is(serr, errFailed)).To(BeTrue())
+	})
+
+	o.Spec("it returns the errors of concurrent writes", func(t *testing.T) {
+		p := pubsub.New(pubsub.WithFanoutConcurrency(2))
+		for i := 0; i < 3; i++ {
+			sub, _ := failing(1)
+			p.Subscribe(sub)
+		}
+
+		r, _ := p.PublishCtx(context.Background(), "data", pubsub.LinearTreeTraverser(nil))
+		Expect(t, r.Errors).To(HaveLen(3))
+	})
+
+	o.Spec("it retries failed writes", func(t *testing.T) {
+		p := pubsub.New()
+		sub, attempts := failing(2)
+		p.Subscribe(sub, pubsub.WithRetries(2))
+
+		r, _ := p.PublishCtx(context.Background(), "data", pubsub.LinearTreeTraverser(nil))
+		Expect(t, r.Errors).To(HaveLen(0))
+		Expect(t, attempts()).To(Equal(3))
+	})
+
+	o.Spec("it reports failures to the error handler and dead letter subscription", func(t *testing.T) {
+		deadLetter := newSpySubscrption()
+		var handled []error
+		p := pubsub.New(
+			pubsub.WithDeadLetterSubscription(deadLetter),
+			pubsub.WithErrorHandler(func(data interface{}, err *pubsub.SubscriptionError) {
+				handled = append(handled, err)
+			}),
+		)
+		sub, attempts := failing(10)
+		p.Subscribe(sub, pubsub.WithRetries(1))
+
+		r, _ := p.PublishCtx(context.Background(), "data", pubsub.LinearTreeTraverser(nil))
+		Expect(t, attempts()).To(Equal(2))
+		Expect(t, handled).To(Equal(r.Errors))
+		Expect(t, deadLetter.Data()).To(Equal([]interface{}{"data"}))
+	})
+
+	o.Spec("it returns the errors of queued writes from PublishSync", func(t *testing.T) {
+		p := pubsub.New(pubsub.WithAsyncDelivery(10, pubsub.OverflowBlock))
+		sub, _ := failing(1)
+		p.Subscribe(sub)
+
+		r, err := p.PublishSync(context.Background(), "data", pubsub.LinearTreeTraverser(nil))
+		Expect(t, r.Errors).To(HaveLen(0))
+		Expect(t, errors.Is(err, errFailed)).To(BeTrue())
+	})
+
+	o.Spec("it returns the errors of synchronous writes from PublishSync", func(t *testing.T) {
+		p := pubsub.New()
+		sub, _ := failing(1)
+		p.Subscribe(sub)
+
+		_, err := p.PublishSync(context.Background(), "data", pubsub.LinearTreeTraverser(nil))
+		Expect(t, errors.Is(err, errFailed)).To(BeTrue())
+	})
+}
@@ -0,0 +1,323 @@
+package pubsub
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/apoydence/pubsub/internal/expr"
+	"github.com/apoydence/pubsub/internal/field"
+)
+
+// ErrInvalidExpr is returned when an expression can not be compiled.
+var ErrInvalidExpr = errors.New("invalid expression")
+
+// Expr is a compiled subscription expression (see CompileExpr). It is safe
+// for concurrent use.
+type Expr struct {
+	root expr.Node
+	path []string
+
+	// residual is the part of the expression that the path does not
+	// express. It is nil if the path expresses all of it.
+	residual expr.Node
+}
+
+// CompileExpr compiles a textual predicate about the published data, such
+// as:
+//
+//	msg.Status >= 500 && msg.Region == "us"
+//
+// A field of the data is referred to with "msg." followed by its name (with
+// nested fields joined by a '.'). Fields are compared to string, number or
+// boolean literals with ==, !=, <, <=, > and >=, and comparisons are
+// combined with &&, || and ! (and parentheses). The data may be a struct
+// (or a pointer to one) or decoded JSON.
+//
+// Strings and booleans are compared with the field as it is formatted in a
+// path (see ReflectTraverser), so msg.Code == "404" is true for an int Code
+// of 404. Numbers are compared with fields that are numbers or strings that
+// hold one. A comparison with a field that does not exist is false, while a
+// field that is not a number is only != to a number.
+//
+// The fields are the ones, in order, that the published data is traversed
+// by (e.g., the fields given to ReflectTraverser, or "order.kind" for the
+// JSON pointer "/order/kind"). They are used to compile the expression to
+// a path (see Path) that is as specific as the expression allows and a
+// residual filter for the rest of it (see WithExpr).
+func CompileExpr(s string, fields ...string) (*Expr, error) {
+	root, err := expr.Parse(s, expr.Go)
+	if err == nil {
+		root, err = expr.Map(root, newCmpExpr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w %q: %s", ErrInvalidExpr, s, err)
+	}
+
+	e := &Expr{root: root}
+	e.compilePath(fields)
+	return e, nil
+}
+
+// Path returns the subscription path for the expression. Fields that the
+// expression does not constrain are Any and trailing ones are left off.
+func (e *Expr) Path() []string {
+	return append([]string(nil), e.path...)
+}
+
+// Match reports whether the data satisfies the expression.
+func (e *Expr) Match(data interface{}) bool {
+	return expr.Eval(e.root, data)
+}
+
+// WithExpr configures a subscription to reside at the expression's path
+// (replacing any given with WithPath) and to only have data written to it
+// that satisfies the rest of the expression (see WithFilter).
+func WithExpr(e *Expr) SubscribeOption {
+	return subscribeConfigFunc(func(c *subscribeConfig) {
+		c.path = e.Path()
+		if r := e.residual; r != nil {
+			c.filters = append(c.filters, func(data interface{}) bool {
+				return expr.Eval(r, data)
+			})
+		}
+	})
+}
+
+// compilePath builds the path from the comparisons of the top level
+// conjunction and sets the residual to the comparisons it does not use.
+func (e *Expr) compilePath(fields []string) {
+	conjuncts := []expr.Node{e.root}
+	if and, ok := e.root.(expr.And); ok {
+		conjuncts = and
+	}
+
+	used := make([]bool, len(conjuncts))
+	for _, f := range fields {
+		e.path = append(e.path, pathSegment(f, conjuncts, used))
+	}
+
+	for len(e.path) > 0 && e.path[len(e.path)-1] == Any {
+		e.path = e.path[:len(e.path)-1]
+	}
+
+	var residual expr.And
+	for i, c := range conjuncts {
+		if !used[i] {
+			residual = append(residual, c)
+		}
+	}
+
+	switch len(residual) {
+	case 0:
+	case 1:
+		e.residual = residual[0]
+	default:
+		e.residual = residual
+	}
+}
+
+// pathSegment returns the most specific segment for the field that the
+// comparisons allow and marks the ones it uses. An equality is preferred,
+// then the numeric interval and then an inequality.
+func pathSegment(field string, conjuncts []expr.Node, used []bool) string {
+	var (
+		cmps    []int
+		lo, hi  = math.Inf(-1), math.Inf(1)
+		numeric []int
+	)
+	for i, c := range conjuncts {
+		if c, ok := c.(cmpExpr); ok && c.name == field {
+			cmps = append(cmps, i)
+		}
+	}
+
+	for _, i := range cmps {
+		c := conjuncts[i].(cmpExpr)
+		if c.op != "==" {
+			continue
+		}
+
+		used[i] = true
+		switch lit := c.lit.(type) {
+		case float64:
+			return Range(lit, math.Nextafter(lit, math.Inf(1)))
+		case bool:
+			return strconv.FormatBool(lit)
+		default:
+			return lit.(string)
+		}
+	}
+
+	for _, i := range cmps {
+		c := conjuncts[i].(cmpExpr)
+		x, ok := c.lit.(float64)
+		if !ok {
+			continue
+		}
+
+		switch c.op {
+		case ">=":
+			lo = math.Max(lo, x)
+		case ">":
+			lo = math.Max(lo, math.Nextafter(x, math.Inf(1)))
+		case "<":
+			hi = math.Min(hi, x)
+		case "<=":
+			hi = math.Min(hi, math.Nextafter(x, math.Inf(1)))
+		default:
+			continue
+		}
+		numeric = append(numeric, i)
+	}
+	if len(numeric) > 0 {
+		for _, i := range numeric {
+			used[i] = true
+		}
+		return Range(lo, hi)
+	}
+
+	for _, i := range cmps {
+		c := conjuncts[i].(cmpExpr)
+		if c.op != "!=" {
+			continue
+		}
+
+		used[i] = true
+		switch lit := c.lit.(type) {
+		case float64:
+			return Not(Range(lit, math.Nextafter(lit, math.Inf(1))))
+		case bool:
+			return Not(strconv.FormatBool(lit))
+		default:
+			return Not(lit.(string))
+		}
+	}
+
+	return Any
+}
+
+// cmpExpr compares the field with the literal (a string, float64 or bool).
+type cmpExpr struct {
+	name  string
+	field []string
+	op    string
+	lit   interface{}
+}
+
+// newCmpExpr resolves the comparison (see expr.Map).
+func newCmpExpr(c expr.Cmp) (expr.Node, error) {
+	name, ok := strings.CutPrefix(c.Field, "msg.")
+	if !ok || name == "" {
+		return nil, fmt.Errorf("field %q must start with \"msg.\"", c.Field)
+	}
+
+	e := cmpExpr{
+		name:  name,
+		field: strings.Split(name, "."),
+		op:    c.Op,
+	}
+	switch c.Lit.Kind {
+	case expr.Number:
+		// The lexer has already checked that the number can be parsed.
+		e.lit, _ = strconv.ParseFloat(c.Lit.Text, 64)
+	case expr.Bool:
+		e.lit = c.Lit.Text == "true"
+	default:
+		e.lit = c.Lit.Text
+	}
+	return e, nil
+}
+
+// Eval implements expr.Predicate.
+func (e cmpExpr) Eval(data interface{}) bool {
+	v := reflect.ValueOf(data)
+	for _, name := range e.field {
+		var ok bool
+		if v, ok = exprField(v, name); !ok {
+			return false
+		}
+	}
+
+	v, ok := field.Indirect(v)
+	if !ok {
+		return false
+	}
+
+	var c int
+	switch lit := e.lit.(type) {
+	case float64:
+		x, ok := exprNumber(v)
+		if !ok {
+			return e.op == "!="
+		}
+		c = compare(x, lit)
+	case bool:
+		c = strings.Compare(formatValue(v), strconv.FormatBool(lit))
+	default:
+		c = strings.Compare(formatValue(v), lit.(string))
+	}
+
+	switch e.op {
+	case "==":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	default:
+		return c >= 0
+	}
+}
+
+func compare(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// exprField returns the named field of the struct or the named entry of the
+// map that v holds (or points to).
+func exprField(v reflect.Value, name string) (reflect.Value, bool) {
+	v, ok := field.Indirect(v)
+	if !ok {
+		return reflect.Value{}, false
+	}
+
+	if v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String {
+		v = v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+		return v, v.IsValid()
+	}
+
+	return field.Lookup(v, name)
+}
+
+func exprNumber(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	case reflect.String:
+		// As with Range, a string (e.g., a json.Number) that holds a
+		// number is compared as one.
+		x, err := strconv.ParseFloat(v.String(), 64)
+		return x, err == nil
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,131 @@
+package pubsub_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+type exprEvent struct {
+	Region string
+	Status int
+	Kind   reflectKind
+	Meta   *reflectMeta
+}
+
+func TestExpr(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) TPS {
+		return TPS{
+			T:            t,
+			p:            pubsub.New(),
+			subscription: newSpySubscrption(),
+		}
+	})
+
+	compile := func(t *testing.T, expr string, fields ...string) *pubsub.Expr {
+		e, err := pubsub.CompileExpr(expr, fields...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return e
+	}
+
+	o.Spec("it compiles to the most specific path", func(t TPS) {
+		e := compile(t.T, `msg.Status >= 500 && msg.Region == "us"`, "Region", "Status", "Kind")
+		Expect(t, e.Path()).To(Equal([]string{"us", pubsub.Range(500, math.Inf(1))}))
+
+		e = compile(t.T, `msg.Status > 400 && msg.Status < 500`, "Region", "Status")
+		Expect(t, e.Path()).To(Equal([]string{pubsub.Any, pubsub.Range(math.Nextafter(400, math.Inf(1)), 500)}))
+
+		e = compile(t.T, `msg.Region != "eu"`, "Region")
+		Expect(t, e.Path()).To(Equal([]string{pubsub.Not("eu")}))
+
+		e = compile(t.T, `msg.Region == "us" || msg.Region == "eu"`, "Region")
+		Expect(t, e.Path()).To(HaveLen(0))
+	})
+
+	o.Spec("it subscribes with the path and the residual filter", func(t TPS) {
+		fields := []string{"Region", "Status"}
+		e := compile(t.T, `msg.Status >= 500 && msg.Region == "us" && (msg.Kind == "deleted" || msg.Meta.Host == "a")`, fields...)
+		t.p.Subscribe(t.subscription, pubsub.WithExpr(e))
+
+		traverser := pubsub.ReflectTraverser(fields...)
+		events := []exprEvent{
+			{Region: "us", Status: 503, Kind: 1},
+			{Region: "us", Status: 200, Kind: 1},
+			{Region: "eu", Status: 503, Kind: 1},
+			{Region: "us", Status: 500, Meta: &reflectMeta{Host: "a"}},
+			{Region: "us", Status: 500},
+		}
+		var matched []interface{}
+		for _, ev := range events {
+			t.p.Publish(ev, traverser)
+			if e.Match(ev) {
+				matched = append(matched, ev)
+			}
+		}
+
+		Expect(t, t.subscription.Data()).To(Equal([]interface{}{events[0], events[3]}))
+		Expect(t, matched).To(Equal(t.subscription.Data()))
+	})
+
+	o.Spec("it matches decoded JSON", func(t TPS) {
+		e := compile(t.T, `!(msg.order.total < 100) && msg.order.express == true && msg.region != "eu"`, "region")
+		t.p.Subscribe(t.subscription, pubsub.WithExpr(e))
+
+		traverser, err := pubsub.JSONPointerTraverser("/region")
+		Expect(t, err).To(BeNil())
+
+		order := func(region string, total float64, express bool) map[string]interface{} {
+			return map[string]interface{}{
+				"region": region,
+				"order":  map[string]interface{}{"total": total, "express": express},
+			}
+		}
+		t.p.Publish(order("us", 150, true), traverser)
+		t.p.Publish(order("eu", 150, true), traverser)
+		t.p.Publish(order("us", 50, true), traverser)
+		t.p.Publish(order("us", 150, false), traverser)
+
+		Expect(t, t.subscription.Data()).To(Equal([]interface{}{order("us", 150, true)}))
+	})
+
+	o.Spec("it compares literals with the field as it is in a path", func(t TPS) {
+		e := compile(t.T, `msg.Status == "404" && msg.Kind == "created" && 500 > msg.Status`)
+		Expect(t, e.Match(exprEvent{Status: 404})).To(BeTrue())
+		Expect(t, e.Match(exprEvent{Status: 404, Kind: 1})).To(BeFalse())
+
+		e = compile(t.T, `msg.Region != 5 && msg.Missing != 5`)
+		Expect(t, e.Match(exprEvent{Region: "us"})).To(BeFalse())
+
+		e = compile(t.T, `msg.Region != 5`)
+		Expect(t, e.Match(exprEvent{Region: "us"})).To(BeTrue())
+		Expect(t, e.Match(exprEvent{Region: "5"})).To(BeFalse())
+	})
+
+	o.Spec("it returns an error for an invalid expression", func(t TPS) {
+		for _, expr := range []string{
+			``,
+			`msg.Status >=`,
+			`Status == 5`,
+			`msg.Status == msg.Code`,
+			`msg.Status = 5`,
+			`(msg.Status == 5`,
+			`msg.Region == "us`,
+			`msg.Ok < true`,
+			`msg.Status == 5 msg.Region == "us"`,
+		} {
+			_, err := pubsub.CompileExpr(expr)
+			Expect(t, errors.Is(err, pubsub.ErrInvalidExpr)).To(BeTrue())
+		}
+	})
+}
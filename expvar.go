@@ -0,0 +1,62 @@
+package pubsub
+
+import (
+	"expvar"
+	"sync/atomic"
+
+	"github.com/apoydence/pubsub/internal/node"
+)
+
+// WithExpvar configures a PubSub to publish its statistics as an expvar
+// variable with the given name (see expvar.Publish), so that they are
+// served by expvar's handler at /debug/vars. The variable is a JSON object
+// with:
+//
+//   - "nodes": the number of nodes in the subscription tree
+//   - "subscriptions": the number of subscriptions
+//   - "segments": the number of distinct path segments in the tree (see
+//     PubSub.SegmentStats)
+//   - "publishes": the number of publishes
+//   - "delivered": the number of times data was written (or enqueued) to
+//     a subscription
+//   - "dropped": the number of times data was dropped because a
+//     subscription could not keep up
+//
+// The totals are counted from when the PubSub is created. Like
+// expvar.Publish, it panics if a variable with the name already exists.
+func WithExpvar(name string) PubSubOption {
+	return pubsubConfigFunc(func(p *PubSub) {
+		p.vars = &vars{}
+		expvar.Publish(name, expvar.Func(p.expvars))
+	})
+}
+
+// vars holds the totals that WithExpvar publishes.
+type vars struct {
+	publishes atomic.Int64
+	delivered atomic.Int64
+	dropped   atomic.Int64
+}
+
+func (v *vars) record(r PublishResult) {
+	v.publishes.Add(1)
+	v.delivered.Add(int64(r.Delivered))
+	v.dropped.Add(int64(r.Dropped))
+}
+
+func (s *PubSub) expvars() interface{} {
+	var nodes, subscriptions int
+	walk(s.tree.Load().root, nil, func(path []string, n *node.Node) {
+		nodes++
+		subscriptions += n.SubscriptionLen()
+	})
+
+	return map[string]int64{
+		"nodes":         int64(nodes),
+		"subscriptions": int64(subscriptions),
+		"segments":      int64(s.segments.Stats().Strings),
+		"publishes":     s.vars.publishes.Load(),
+		"delivered":     s.vars.delivered.Load(),
+		"dropped":       s.vars.dropped.Load(),
+	}
+}
@@ -0,0 +1,52 @@
+package pubsub_test
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+// expvarNames makes the names of the variables unique across test runs
+// (e.g., with -count), as expvar does not allow them to be published again.
+var expvarNames atomic.Int64
+
+func TestPubSubExpvar(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.Spec("it publishes the statistics of the PubSub", func(t *testing.T) {
+		name := fmt.Sprintf("pubsub_test_%d", expvarNames.Add(1))
+		p := pubsub.New(pubsub.WithExpvar(name))
+		p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"a", "b"}))
+		p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"a"}))
+		p.Subscribe(newSpySubscrption(),
+			pubsub.WithPath([]string{"c"}),
+			pubsub.WithBufferSize(1),
+			pubsub.WithOverflowStrategy(pubsub.OverflowDrop),
+			pubsub.WithFilter(func(data interface{}) bool { return data == "x" }),
+		)
+
+		p.Publish("v", pubsub.LinearTreeTraverser([]string{"a", "b"}))
+		p.Publish("v", pubsub.LinearTreeTraverser([]string{"a"}))
+
+		var stats map[string]int64
+		err := json.Unmarshal([]byte(expvar.Get(name).String()), &stats)
+		Expect(t, err).To(BeNil())
+		Expect(t, stats).To(Equal(map[string]int64{
+			"nodes":         4,
+			"subscriptions": 3,
+			"segments":      3,
+			"publishes":     2,
+			"delivered":     3,
+			"dropped":       0,
+		}))
+	})
+}
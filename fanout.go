@@ -0,0 +1,84 @@
+package pubsub
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/apoydence/pubsub/internal/node"
+)
+
+// WithFanoutConcurrency configures a PubSub to write to the subscriptions
+// of a single Publish with up to n goroutines (including the publishing
+// one) instead of serially. Publish still returns once every subscription
+// has been written to. Subscriptions may therefore be written to
+// concurrently with each other, though each is written to at most once per
+// Publish. Shard groups are still written to serially. It defaults to 1.
+func WithFanoutConcurrency(n int) PubSubOption {
+	return pubsubConfigFunc(func(p *PubSub) {
+		p.fanout = n
+	})
+}
+
+// fanoutWrite is a write that is deferred until the traversal is done.
+type fanoutWrite struct {
+	sub   Subscription
+	path  []string
+	stats *node.Stats
+
+	delivered bool
+	dropped   int
+	err       error
+}
+
+// deferWrite queues the write to the subscription. The path is copied as
+// the traversal reuses it.
+func (p *publish) deferWrite(sub Subscription, l []string, st *node.Stats) {
+	p.pending = append(p.pending, fanoutWrite{
+		sub:   sub,
+		path:  append(l[:0:0], l...),
+		stats: st,
+	})
+}
+
+// fanout writes the queued writes with up to n goroutines and then records
+// their results.
+func (p *publish) fanout(n int) {
+	ws := p.pending
+	if len(ws) == 0 {
+		return
+	}
+
+	var (
+		next int64
+		wg   sync.WaitGroup
+	)
+	work := func() {
+		defer wg.Done()
+		for {
+			i := int(atomic.AddInt64(&next, 1) - 1)
+			if i >= len(ws) || p.ctx.Err() != nil {
+				return
+			}
+			ws[i].delivered, ws[i].dropped, ws[i].err = p.deliver(ws[i].sub, ws[i].path)
+		}
+	}
+
+	workers := max(1, min(n, len(ws)))
+	wg.Add(workers)
+	for i := 1; i < workers; i++ {
+		go work()
+	}
+	work()
+	wg.Wait()
+
+	for _, w := range ws {
+		p.result.Dropped += w.dropped
+		if w.err != nil {
+			p.result.Errors = append(p.result.Errors, w.err)
+		}
+		if w.delivered {
+			p.wrote(w.path)
+		}
+		countWrite(w.stats, w.delivered, w.dropped)
+	}
+}
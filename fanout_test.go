@@ -0,0 +1,115 @@
+package pubsub_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+func TestPubSubFanout(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.Spec("it writes to subscriptions concurrently", func(t *testing.T) {
+		p := pubsub.New(pubsub.WithFanoutConcurrency(3))
+
+		// Each write waits for the others, so they can only finish if
+		// they are concurrent.
+		var wg sync.WaitGroup
+		wg.Add(3)
+		subs := make([]*spySubscription, 3)
+		for i := range subs {
+			subs[i] = newSpySubscrption()
+			sub := subs[i]
+			p.Subscribe(pubsub.SubscriptionFunc(func(data interface{}) {
+				wg.Done()
+				wg.Wait()
+				sub.Write(data)
+			}), pubsub.WithPath([]string{"a"}))
+		}
+
+		done := make(chan pubsub.PublishResult, 1)
+		go func() {
+			r, _ := p.PublishCtx(context.Background(), "data", pubsub.LinearTreeTraverser([]string{"a"}))
+			done <- r
+		}()
+
+		select {
+		case r := <-done:
+			Expect(t, r.Delivered).To(Equal(3))
+		case <-time.After(time.Second):
+			t.Fatal("publish did not write concurrently")
+		}
+		for _, sub := range subs {
+			Expect(t, sub.Data()).To(Equal([]interface{}{"data"}))
+		}
+	})
+
+	o.Spec("it bounds the number of concurrent writes", func(t *testing.T) {
+		p := pubsub.New(pubsub.WithFanoutConcurrency(2))
+
+		// The writes are held until two of them are concurrent, so that
+		// the bound is reached.
+		var current, max int64
+		started := make(chan struct{}, 10)
+		release := make(chan struct{})
+		for i := 0; i < 10; i++ {
+			p.Subscribe(pubsub.SubscriptionFunc(func(data interface{}) {
+				n := atomic.AddInt64(&current, 1)
+				defer atomic.AddInt64(&current, -1)
+				for {
+					m := atomic.LoadInt64(&max)
+					if n <= m || atomic.CompareAndSwapInt64(&max, m, n) {
+						break
+					}
+				}
+				started <- struct{}{}
+				<-release
+			}))
+		}
+
+		var (
+			r    pubsub.PublishResult
+			err  error
+			done = make(chan struct{})
+		)
+		go func() {
+			defer close(done)
+			r, err = p.PublishCtx(context.Background(), "data", pubsub.LinearTreeTraverser(nil))
+		}()
+		<-started
+		<-started
+		close(release)
+
+		<-done
+		Expect(t, err).To(BeNil())
+		Expect(t, r.Delivered).To(Equal(10))
+		Expect(t, atomic.LoadInt64(&max)).To(Equal(int64(2)))
+	})
+
+	o.Spec("it gives each subscription the path it was reached by", func(t *testing.T) {
+		p := pubsub.New(pubsub.WithFanoutConcurrency(4))
+		sub1 := newSpyPathSubscription()
+		sub2 := newSpyPathSubscription()
+		p.Subscribe(sub1, pubsub.WithPath([]string{"a"}))
+		p.Subscribe(sub2, pubsub.WithPath([]string{"b"}))
+
+		p.Publish("data", pubsub.TreeTraverserFunc(func(data interface{}, currentPath []string) pubsub.Paths {
+			if len(currentPath) > 0 {
+				return pubsub.FlatPaths(nil)
+			}
+			return pubsub.FlatPaths([]string{"a", "b"})
+		}))
+
+		Expect(t, sub1.paths()).To(Equal([][]string{{"a"}}))
+		Expect(t, sub2.paths()).To(Equal([][]string{{"b"}}))
+	})
+}
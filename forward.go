@@ -0,0 +1,63 @@
+package pubsub
+
+import "context"
+
+// forwardingSubscription is implemented by subscriptions that publish the
+// data they are written to another PubSub. They are given the context of
+// the publish and the PubSub that the data came from.
+type forwardingSubscription interface {
+	Subscription
+	forward(ctx context.Context, src *PubSub, data interface{})
+}
+
+// NewForwarder returns a Subscription that publishes everything written to
+// it to the dst PubSub with the given TreeTraverser. This can be used to
+// compose PubSubs into a pipeline.
+//
+// Data that has already been published to dst (e.g., because of a cycle of
+// forwarders) is not forwarded again. Loops can only be detected while the
+// data is written synchronously, so a forwarder that uses async delivery
+// (see WithAsyncDelivery) must not be part of a cycle.
+func NewForwarder(dst *PubSub, t TreeTraverser) Subscription {
+	return forwarder{
+		dst: dst,
+		t:   t,
+	}
+}
+
+type forwarder struct {
+	dst *PubSub
+	t   TreeTraverser
+}
+
+// forwardedKey is the context key for the PubSubs that a publish has
+// already visited.
+type forwardedKey struct{}
+
+// Write implements Subscription.
+func (f forwarder) Write(data interface{}) {
+	f.forward(context.Background(), nil, data)
+}
+
+func (f forwarder) forward(ctx context.Context, src *PubSub, data interface{}) {
+	visited, _ := ctx.Value(forwardedKey{}).([]*PubSub)
+	if src != nil && !containsPubSub(visited, src) {
+		visited = append(visited[:len(visited):len(visited)], src)
+	}
+
+	if containsPubSub(visited, f.dst) {
+		return
+	}
+
+	ctx = context.WithValue(ctx, forwardedKey{}, append(visited[:len(visited):len(visited)], f.dst))
+	f.dst.PublishCtx(ctx, data, f.t)
+}
+
+func containsPubSub(ps []*PubSub, p *PubSub) bool {
+	for _, x := range ps {
+		if x == p {
+			return true
+		}
+	}
+	return false
+}
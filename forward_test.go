@@ -0,0 +1,68 @@
+package pubsub_test
+
+import (
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+func TestForwarder(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+	o.BeforeEach(func(t *testing.T) TPS {
+		return TPS{
+			T:            t,
+			p:            pubsub.New(),
+			subscription: newSpySubscrption(),
+		}
+	})
+
+	o.Spec("it publishes to the destination", func(t TPS) {
+		dst := pubsub.New()
+		dst.Subscribe(t.subscription, pubsub.WithPath([]string{"b"}))
+		t.p.Subscribe(
+			pubsub.NewForwarder(dst, pubsub.LinearTreeTraverser([]string{"b"})),
+			pubsub.WithPath([]string{"a"}),
+		)
+
+		t.p.Publish(1, pubsub.LinearTreeTraverser([]string{"a"}))
+
+		Expect(t, t.subscription.Data()).To(Equal([]interface{}{1}))
+	})
+
+	o.Spec("it does not forward in a loop", func(t TPS) {
+		a := t.p
+		b := pubsub.New()
+		c := pubsub.New()
+		a.Subscribe(pubsub.NewForwarder(b, pubsub.LinearTreeTraverser(nil)))
+		b.Subscribe(pubsub.NewForwarder(c, pubsub.LinearTreeTraverser(nil)))
+		c.Subscribe(pubsub.NewForwarder(a, pubsub.LinearTreeTraverser(nil)))
+
+		aSub := newSpySubscrption()
+		cSub := newSpySubscrption()
+		a.Subscribe(aSub)
+		c.Subscribe(cSub)
+
+		a.Publish(1, pubsub.LinearTreeTraverser(nil))
+		c.Publish(2, pubsub.LinearTreeTraverser(nil))
+
+		Expect(t, aSub.Data()).To(Equal([]interface{}{1, 2}))
+		Expect(t, cSub.Data()).To(Equal([]interface{}{1, 2}))
+	})
+
+	o.Spec("it detects loops through metered subscriptions", func(t TPS) {
+		a := pubsub.New(pubsub.WithMetrics(newSpyMetrics()))
+		b := pubsub.New(pubsub.WithMetrics(newSpyMetrics()))
+		a.Subscribe(pubsub.NewForwarder(b, pubsub.LinearTreeTraverser(nil)))
+		b.Subscribe(pubsub.NewForwarder(a, pubsub.LinearTreeTraverser(nil)))
+		a.Subscribe(t.subscription)
+
+		a.Publish(1, pubsub.LinearTreeTraverser(nil))
+
+		Expect(t, t.subscription.Data()).To(Equal([]interface{}{1}))
+	})
+}
@@ -0,0 +1,75 @@
+package pubsub
+
+import "sync"
+
+// SubscriptionGroup registers subscriptions with a PubSub under a single
+// handle so that they can all be removed together. All of
+// SubscriptionGroup's methods are safe to access concurrently. It should be
+// constructed with NewSubscriptionGroup().
+type SubscriptionGroup struct {
+	p *PubSub
+
+	mu           sync.Mutex
+	nextID       int
+	unsubscribes map[int]Unsubscriber
+}
+
+// NewSubscriptionGroup constructs a new SubscriptionGroup for the given
+// PubSub.
+func NewSubscriptionGroup(p *PubSub) *SubscriptionGroup {
+	return &SubscriptionGroup{
+		p:            p,
+		unsubscribes: make(map[int]Unsubscriber),
+	}
+}
+
+// Subscribe adds a subscription to the PubSub (see PubSub.Subscribe) and
+// to the group. The returned Unsubscriber removes it from both.
+func (g *SubscriptionGroup) Subscribe(sub Subscription, opts ...SubscribeOption) Unsubscriber {
+	return g.add(g.p.Subscribe(sub, opts...))
+}
+
+// SubscribeChan adds a channel based subscription to the PubSub (see
+// PubSub.SubscribeChan) and to the group. The returned Unsubscriber removes
+// it from both.
+func (g *SubscriptionGroup) SubscribeChan(opts ...SubscribeOption) (<-chan interface{}, Unsubscriber) {
+	c, unsubscribe := g.p.SubscribeChan(opts...)
+	return c, g.add(unsubscribe)
+}
+
+// UnsubscribeAll removes every subscription in the group from the PubSub.
+// The group can still be used afterwards.
+func (g *SubscriptionGroup) UnsubscribeAll() {
+	g.mu.Lock()
+	unsubscribes := g.unsubscribes
+	g.unsubscribes = make(map[int]Unsubscriber)
+	g.mu.Unlock()
+
+	for _, unsubscribe := range unsubscribes {
+		unsubscribe()
+	}
+}
+
+// Len returns the number of subscriptions in the group.
+func (g *SubscriptionGroup) Len() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.unsubscribes)
+}
+
+func (g *SubscriptionGroup) add(unsubscribe Unsubscriber) Unsubscriber {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	id := g.nextID
+	g.nextID++
+	g.unsubscribes[id] = unsubscribe
+
+	return func() {
+		g.mu.Lock()
+		delete(g.unsubscribes, id)
+		g.mu.Unlock()
+
+		unsubscribe()
+	}
+}
@@ -0,0 +1,62 @@
+package pubsub_test
+
+import (
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+type TG struct {
+	*testing.T
+	p *pubsub.PubSub
+	g *pubsub.SubscriptionGroup
+}
+
+func TestSubscriptionGroup(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+	o.BeforeEach(func(t *testing.T) TG {
+		p := pubsub.New()
+		return TG{
+			T: t,
+			p: p,
+			g: pubsub.NewSubscriptionGroup(p),
+		}
+	})
+
+	o.Spec("it removes every subscription", func(t TG) {
+		sub1 := newSpySubscrption()
+		sub2 := newSpySubscrption()
+		sub3 := newSpySubscrption()
+		t.g.Subscribe(sub1, pubsub.WithPath([]string{"a"}))
+		t.g.Subscribe(sub2, pubsub.WithPath([]string{"a", "b"}))
+		c, _ := t.g.SubscribeChan()
+		t.p.Subscribe(sub3)
+		Expect(t, t.g.Len()).To(Equal(3))
+
+		t.g.UnsubscribeAll()
+		Expect(t, t.g.Len()).To(Equal(0))
+
+		t.p.Publish("some-data", pubsub.LinearTreeTraverser([]string{"a", "b"}))
+		Expect(t, sub1.data).To(HaveLen(0))
+		Expect(t, sub2.data).To(HaveLen(0))
+		Expect(t, sub3.data).To(HaveLen(1))
+
+		_, ok := <-c
+		Expect(t, ok).To(BeFalse())
+	})
+
+	o.Spec("it removes a single subscription from the group", func(t TG) {
+		sub := newSpySubscrption()
+		unsubscribe := t.g.Subscribe(sub)
+		unsubscribe()
+		Expect(t, t.g.Len()).To(Equal(0))
+
+		t.p.Publish("some-data", pubsub.LinearTreeTraverser(nil))
+		Expect(t, sub.data).To(HaveLen(0))
+	})
+}
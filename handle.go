@@ -0,0 +1,267 @@
+package pubsub
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// WithPauseBuffer configures how many messages a subscription holds onto
+// while it is paused (see SubscriptionHandle.Pause). Once the buffer is full
+// the oldest messages are dropped. The buffered messages are written when
+// the subscription is resumed. By default, messages are dropped while a
+// subscription is paused.
+func WithPauseBuffer(size int) SubscribeOption {
+	return subscribeConfigFunc(func(c *subscribeConfig) {
+		c.pauseBufferSize = size
+	})
+}
+
+// SubscriptionHandle is used to control a subscription. It is returned by
+// SubscribeHandle. All of its methods are safe to access concurrently.
+type SubscriptionHandle struct {
+	sr *subscriber
+}
+
+// SubscribeHandle adds a subscription to the PubSub (see Subscribe). The
+// returned SubscriptionHandle can be used to pause, resume, move and remove
+// the subscription.
+func (s *PubSub) SubscribeHandle(sub Subscription, opts ...SubscribeOption) *SubscriptionHandle {
+	c := newSubscribeConfig(opts)
+	c.pausable = true
+
+	sr, _, _ := s.subscribe(sub, c)
+	return &SubscriptionHandle{
+		sr: sr,
+	}
+}
+
+// Pause stops data from being written to the subscription until Resume is
+// invoked. Data published in the meantime is dropped unless the
+// subscription was configured with WithPauseBuffer.
+func (h *SubscriptionHandle) Pause() {
+	if h.sr == nil {
+		return
+	}
+	h.sr.pauser.pause()
+}
+
+// Resume writes any buffered data to the subscription and then continues to
+// write published data to it.
+func (h *SubscriptionHandle) Resume() {
+	if h.sr == nil {
+		return
+	}
+	h.sr.pauser.resume()
+}
+
+// Paused returns if the subscription is paused.
+func (h *SubscriptionHandle) Paused() bool {
+	if h.sr == nil {
+		return false
+	}
+	return h.sr.pauser.isPaused()
+}
+
+// Move re-homes the subscription to the given path. It happens atomically
+// with respect to Publish, so the subscription is written data that is
+// published to either the old or new path (depending on when it was
+// published) without any being missed or written twice. Retained data for
+// the new path is not written. Moving a subscription that has been removed
+// does nothing. If the subscription was added to a mounted PubSub (see
+// Mount), the path is relative to the mount.
+//
+// The subscription is left where it is and an error is returned if the path
+// would exceed the PubSub's limits (ErrLimitExceeded, see LimitOption), is
+// at or beneath a mounted PubSub (ErrPathInUse) or if the PubSub has been
+// closed (ErrClosed). Subscriptions can not be moved between PubSubs.
+func (h *SubscriptionHandle) Move(path []string) error {
+	if h.sr == nil {
+		return nil
+	}
+	return h.sr.p.move(h.sr, path)
+}
+
+// ID returns the subscription's ID. IDs are unique (even across PubSubs),
+// assigned in increasing order and do not change when the subscription is
+// moved. It returns 0 if the subscription was not added (e.g., the PubSub
+// was closed or the Batch has not been committed).
+func (h *SubscriptionHandle) ID() int64 {
+	if h.sr == nil {
+		return 0
+	}
+	return h.sr.id
+}
+
+// Unsubscribe removes the subscription from the PubSub.
+func (h *SubscriptionHandle) Unsubscribe() {
+	if h.sr == nil {
+		return
+	}
+	h.sr.p.unsubscribe(h.sr)
+}
+
+// UnsubscribeAndDrain removes the subscription from the PubSub and blocks
+// until every delivery to it is done, including the data that is queued for
+// it (see WithAsyncDelivery) and any partial batch. This allows downstream
+// resources (e.g., a channel or socket) to be closed once it returns. It
+// must not be invoked from the subscription's Write.
+func (h *SubscriptionHandle) UnsubscribeAndDrain() {
+	if h.sr == nil {
+		return
+	}
+	h.sr.p.unsubscribeAndDrain(h.sr)
+}
+
+func (s *PubSub) move(sr *subscriber, path []string) error {
+	if s.exceedsDepth(path) {
+		return ErrLimitExceeded
+	}
+
+	var t *treeTxn
+	if s.limited() {
+		t = s.lockTree()
+	} else {
+		t = s.lockSubscriber(sr, path)
+	}
+	defer func() {
+		s.unlockTree(t)
+		s.hooks.dispatch()
+	}()
+
+	switch {
+	case t.closed:
+		return ErrClosed
+	case sr.removed.Load():
+		return nil
+	case s.exceedsChildren(t, path):
+		return ErrLimitExceeded
+	}
+
+	if _, _, ok := t.mountFor(path); ok {
+		return ErrPathInUse
+	}
+
+	// Moving to the same path does not count as gaining or losing a
+	// subscription.
+	same := slices.Equal(sr.path, path)
+
+	t.removeSubscription(sr.id, sr.path)
+	if !same {
+		s.hooks.record(sr.path, t.fetch(sr.path).SubscriptionLen(), false)
+	}
+
+	n := t.node(path)
+	sr.path = append([]string(nil), path...)
+	n.AddSubscriptionWithID(sr, sr.shardID, sr.id)
+	if sr.priority != 0 {
+		n.SetPriority(sr.id, sr.priority)
+	}
+	sr.subtree.Store(int32(s.subtreeOfPath(path)))
+	if !same {
+		s.hooks.record(sr.path, n.SubscriptionLen(), true)
+	}
+
+	return nil
+}
+
+// pauser holds back data while a subscription is paused. The data is
+// written without holding mu, so the subscription may pause or resume from
+// within its Write.
+type pauser struct {
+	size int
+	next func(ctx context.Context, data interface{}, path []string) (bool, int, error)
+
+	mu      sync.Mutex
+	paused  bool
+	stopped bool
+	buf     []pausedData
+
+	// flushing is set while resume writes the buffer. Data that is
+	// published meanwhile is still buffered so that it is written after.
+	flushing bool
+}
+
+func newPauser(size int, next func(ctx context.Context, data interface{}, path []string) (bool, int, error)) *pauser {
+	return &pauser{
+		size: size,
+		next: next,
+	}
+}
+
+func (p *pauser) write(ctx context.Context, data interface{}, path []string) (bool, int, error) {
+	p.mu.Lock()
+	if !p.paused && !p.flushing {
+		p.mu.Unlock()
+		return p.next(ctx, data, path)
+	}
+	defer p.mu.Unlock()
+
+	if p.paused && p.size <= 0 {
+		return false, 1, nil
+	}
+
+	var dropped int
+	if p.paused && len(p.buf) >= p.size {
+		p.buf = p.buf[1:]
+		dropped++
+	}
+	// The path is reused by the publish.
+	if path != nil {
+		path = append([]string{}, path...)
+	}
+	p.buf = append(p.buf, pausedData{data: data, path: path})
+
+	return true, dropped, nil
+}
+
+func (p *pauser) pause() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.paused = true
+}
+
+func (p *pauser) resume() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.paused {
+		return
+	}
+	p.paused = false
+
+	// A resume from within a Write leaves the buffer to the resume that is
+	// already writing it.
+	if p.flushing {
+		return
+	}
+
+	p.flushing = true
+	for !p.paused && !p.stopped && len(p.buf) > 0 {
+		d := p.buf[0]
+		p.buf = p.buf[1:]
+
+		p.mu.Unlock()
+		p.next(context.Background(), d.data, d.path)
+		p.mu.Lock()
+	}
+	p.flushing = false
+}
+
+func (p *pauser) isPaused() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.paused
+}
+
+func (p *pauser) stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stopped = true
+	p.buf = nil
+}
+
+type pausedData struct {
+	data interface{}
+	path []string
+}
@@ -0,0 +1,170 @@
+package pubsub_test
+
+import (
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+func TestSubscriptionHandle(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+	o.BeforeEach(func(t *testing.T) TPS {
+		return TPS{
+			T:            t,
+			p:            pubsub.New(),
+			subscription: newSpySubscrption(),
+		}
+	})
+
+	o.Spec("it drops data while paused", func(t TPS) {
+		h := t.p.SubscribeHandle(t.subscription)
+
+		t.p.Publish(1, pubsub.LinearTreeTraverser(nil))
+		h.Pause()
+		Expect(t, h.Paused()).To(BeTrue())
+		t.p.Publish(2, pubsub.LinearTreeTraverser(nil))
+		h.Resume()
+		Expect(t, h.Paused()).To(BeFalse())
+		t.p.Publish(3, pubsub.LinearTreeTraverser(nil))
+
+		Expect(t, t.subscription.Data()).To(Equal([]interface{}{1, 3}))
+	})
+
+	o.Spec("it buffers data while paused", func(t TPS) {
+		h := t.p.SubscribeHandle(t.subscription, pubsub.WithPauseBuffer(2))
+
+		h.Pause()
+		for i := 0; i < 4; i++ {
+			t.p.Publish(i, pubsub.LinearTreeTraverser(nil))
+		}
+		Expect(t, t.subscription.Len()).To(Equal(0))
+
+		h.Resume()
+		t.p.Publish(4, pubsub.LinearTreeTraverser(nil))
+
+		Expect(t, t.subscription.Data()).To(Equal([]interface{}{2, 3, 4}))
+	})
+
+	o.Spec("it pauses and resumes from within a Write", func(t TPS) {
+		var h *pubsub.SubscriptionHandle
+		h = t.p.SubscribeHandle(pubsub.SubscriptionFunc(func(data interface{}) {
+			t.subscription.Write(data)
+			switch data {
+			case 1:
+				h.Pause()
+			case 2:
+				h.Pause()
+				h.Resume()
+			}
+		}), pubsub.WithPauseBuffer(5))
+
+		for i := 1; i <= 3; i++ {
+			t.p.Publish(i, pubsub.LinearTreeTraverser(nil))
+		}
+		Expect(t, h.Paused()).To(BeTrue())
+
+		h.Resume()
+		Expect(t, h.Paused()).To(BeFalse())
+		Expect(t, t.subscription.Data()).To(Equal([]interface{}{1, 2, 3}))
+	})
+
+	o.Spec("it unsubscribes", func(t TPS) {
+		h := t.p.SubscribeHandle(t.subscription, pubsub.WithPauseBuffer(2))
+		h.Pause()
+		t.p.Publish(1, pubsub.LinearTreeTraverser(nil))
+		h.Unsubscribe()
+		h.Resume()
+		t.p.Publish(2, pubsub.LinearTreeTraverser(nil))
+
+		Expect(t, t.subscription.Len()).To(Equal(0))
+	})
+
+	o.Spec("it unsubscribes and drains", func(t TPS) {
+		p := pubsub.New(pubsub.WithAsyncDelivery(5, pubsub.OverflowBlock))
+		block := make(chan struct{})
+		h := p.SubscribeHandle(pubsub.SubscriptionFunc(func(data interface{}) {
+			<-block
+			t.subscription.Write(data)
+		}))
+		for i := 0; i < 3; i++ {
+			p.Publish(i, pubsub.LinearTreeTraverser(nil))
+		}
+
+		// The queued data is only written once UnsubscribeAndDrain has
+		// been invoked.
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			h.UnsubscribeAndDrain()
+		}()
+		close(block)
+		<-done
+		Expect(t, t.subscription.Data()).To(Equal([]interface{}{0, 1, 2}))
+
+		h.UnsubscribeAndDrain()
+	})
+
+	o.Spec("it moves the subscription to a new path", func(t TPS) {
+		h := t.p.SubscribeHandle(t.subscription, pubsub.WithPath([]string{"a"}))
+
+		t.p.Publish(1, pubsub.LinearTreeTraverser([]string{"a"}))
+		h.Move([]string{"b", "c"})
+		t.p.Publish(2, pubsub.LinearTreeTraverser([]string{"a"}))
+		t.p.Publish(3, pubsub.LinearTreeTraverser([]string{"b", "c"}))
+
+		Expect(t, t.subscription.Data()).To(Equal([]interface{}{1, 3}))
+		Expect(t, t.p.Paths()).To(Equal([][]string{{"b", "c"}}))
+
+		h.Unsubscribe()
+		Expect(t, t.p.Paths()).To(HaveLen(0))
+	})
+
+	o.Spec("it does not write data twice while moving", func(t TPS) {
+		h := t.p.SubscribeHandle(t.subscription, pubsub.WithPath([]string{"a"}))
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for i := 0; i < 100; i++ {
+				t.p.Publish(i, pubsub.LinearTreeTraverser([]string{"a", "b"}))
+			}
+		}()
+		h.Move([]string{"a", "b"})
+		<-done
+
+		Expect(t, t.subscription.Len()).To(Equal(100))
+	})
+
+	o.Spec("it exposes a unique ID that survives moves", func(t TPS) {
+		h1 := t.p.SubscribeHandle(t.subscription, pubsub.WithPath([]string{"a"}))
+		h2 := t.p.SubscribeHandle(newSpySubscrption(), pubsub.WithPath([]string{"a"}))
+		Expect(t, h2.ID()).To(BeAbove(float64(h1.ID())))
+
+		id := h1.ID()
+		h1.Move([]string{"b"})
+		Expect(t, h1.ID()).To(Equal(id))
+
+		h2.Unsubscribe()
+		h1.Unsubscribe()
+		Expect(t, t.p.Paths()).To(HaveLen(0))
+	})
+
+	o.Spec("it has a zero ID when the PubSub is closed", func(t TPS) {
+		t.p.Close()
+		h := t.p.SubscribeHandle(t.subscription)
+		Expect(t, h.ID()).To(Equal(int64(0)))
+	})
+
+	o.Spec("it does not move a removed subscription", func(t TPS) {
+		h := t.p.SubscribeHandle(t.subscription)
+		h.Unsubscribe()
+		h.Move([]string{"a"})
+
+		Expect(t, t.p.Paths()).To(HaveLen(0))
+	})
+}
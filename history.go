@@ -0,0 +1,107 @@
+package pubsub
+
+// historyNode is a tree keyed by the path segments that data was published
+// to. It holds the retained data and replay buffers. It is separate from the
+// subscription tree as data can be published to paths without any
+// subscriptions.
+type historyNode struct {
+	children map[string]*historyNode
+
+	retained interface{}
+	replay   *replayBuffer
+}
+
+// fetch returns the node for the path. The node (and its parents) are
+// created if they do not exist.
+func (n *historyNode) fetch(path []string) *historyNode {
+	for _, p := range path {
+		child, ok := n.children[p]
+		if !ok {
+			if n.children == nil {
+				n.children = make(map[string]*historyNode)
+			}
+			child = &historyNode{}
+			n.children[p] = child
+		}
+		n = child
+	}
+
+	return n
+}
+
+// lookup returns the node for the path, or nil if there is none. Unlike
+// fetch, it does not create any nodes.
+func (n *historyNode) lookup(path []string) *historyNode {
+	for _, p := range path {
+		if n = n.children[p]; n == nil {
+			return nil
+		}
+	}
+
+	return n
+}
+
+// prune removes any nodes along the path that are left empty.
+func (n *historyNode) prune(path []string) {
+	if n == nil || len(path) == 0 {
+		return
+	}
+
+	child, ok := n.children[path[0]]
+	if !ok {
+		return
+	}
+	child.prune(path[1:])
+
+	if child.empty() {
+		delete(n.children, path[0])
+	}
+}
+
+func (n *historyNode) empty() bool {
+	return n.retained == nil && n.replay == nil && len(n.children) == 0
+}
+
+// forEachMatch invokes f with each node that matches the subscription path.
+// As a subscription receives anything published beneath its path, this
+// includes each descendant.
+func (n *historyNode) forEachMatch(path []string, f func(n *historyNode)) {
+	if n == nil {
+		return
+	}
+
+	if len(path) == 0 {
+		n.forEach(f)
+		return
+	}
+
+	switch path[0] {
+	case Any:
+		for _, child := range n.children {
+			child.forEachMatch(path[1:], f)
+		}
+	case Rest:
+		for _, child := range n.children {
+			child.forEach(f)
+		}
+	default:
+		if m := newSegmentMatcher(path[0]); m != nil {
+			for segment, child := range n.children {
+				if m.match(segment) {
+					child.forEachMatch(path[1:], f)
+				}
+			}
+			return
+		}
+
+		n.children[path[0]].forEachMatch(path[1:], f)
+	}
+}
+
+func (n *historyNode) forEach(f func(n *historyNode)) {
+	f(n)
+
+	for _, child := range n.children {
+		child.forEach(f)
+	}
+}
@@ -0,0 +1,128 @@
+package pubsub
+
+import "sync"
+
+// WithFirstSubscriberFunc configures a PubSub to invoke the given function
+// when a path gains its first subscription. This can be used to start
+// producing data for a path only when there is something to consume it.
+// The functions configured by WithFirstSubscriberFunc and
+// WithLastSubscriberFunc are invoked in the order the events occurred, from
+// whichever goroutine is adding or removing a subscription. They may add or
+// remove subscriptions themselves.
+func WithFirstSubscriberFunc(f func(path []string)) PubSubOption {
+	return pubsubConfigFunc(func(p *PubSub) {
+		p.hooks.first = f
+	})
+}
+
+// WithLastSubscriberFunc configures a PubSub to invoke the given function
+// when a path loses its last subscription. It is not invoked when the
+// PubSub is closed. See WithFirstSubscriberFunc.
+func WithLastSubscriberFunc(f func(path []string)) PubSubOption {
+	return pubsubConfigFunc(func(p *PubSub) {
+		p.hooks.last = f
+	})
+}
+
+// pathHooks queues and dispatches first and last subscriber events and
+// system events (see WithSystemEvents).
+type pathHooks struct {
+	first func(path []string)
+	last  func(path []string)
+	sys   func(segment string, e SystemEvent)
+
+	mu          sync.Mutex
+	events      []pathEvent
+	dispatching bool
+}
+
+// pathEvent is either a first or last subscriber event or, if segment is
+// set, a system event that is published to it.
+type pathEvent struct {
+	path  []string
+	first bool
+
+	segment string
+	sys     SystemEvent
+}
+
+// record must be invoked while holding the PubSub's write lock. n is the
+// number of subscriptions the path now has.
+func (h *pathHooks) record(path []string, n int, added bool) {
+	if h.sys != nil && !isSystemPath(path) {
+		h.mu.Lock()
+		if added {
+			h.events = append(h.events, pathEvent{segment: SystemSubscriptions, sys: SystemEvent{Type: SubscriptionAdded, Path: path}})
+			if n == 1 {
+				h.events = append(h.events, pathEvent{segment: SystemPaths, sys: SystemEvent{Type: PathCreated, Path: path}})
+			}
+		} else {
+			h.events = append(h.events, pathEvent{segment: SystemSubscriptions, sys: SystemEvent{Type: SubscriptionRemoved, Path: path}})
+			if n == 0 {
+				h.events = append(h.events, pathEvent{segment: SystemPaths, sys: SystemEvent{Type: PathPruned, Path: path}})
+			}
+		}
+		h.mu.Unlock()
+	}
+
+	switch {
+	case added && (n != 1 || h.first == nil):
+		return
+	case !added && (n != 0 || h.last == nil):
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.events = append(h.events, pathEvent{path: path, first: added})
+}
+
+// recordDrop records that data was dropped for the subscription at the
+// path. The event is dispatched once the publish is done.
+func (h *pathHooks) recordDrop(path []string) {
+	if h.sys == nil || isSystemPath(path) {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.events = append(h.events, pathEvent{segment: SystemDrops, sys: SystemEvent{Type: DataDropped, Path: path}})
+}
+
+// dispatch must be invoked after the PubSub's write lock has been released.
+// If another goroutine is already dispatching, it will invoke any queued
+// events instead.
+func (h *pathHooks) dispatch() {
+	if h.first == nil && h.last == nil && h.sys == nil {
+		return
+	}
+
+	h.mu.Lock()
+	if h.dispatching {
+		h.mu.Unlock()
+		return
+	}
+	h.dispatching = true
+
+	for len(h.events) > 0 {
+		events := h.events
+		h.events = nil
+		h.mu.Unlock()
+
+		for _, e := range events {
+			switch {
+			case e.segment != "":
+				h.sys(e.segment, e.sys)
+			case e.first:
+				h.first(e.path)
+			default:
+				h.last(e.path)
+			}
+		}
+
+		h.mu.Lock()
+	}
+
+	h.dispatching = false
+	h.mu.Unlock()
+}
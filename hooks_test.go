@@ -0,0 +1,96 @@
+package pubsub_test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+type TH struct {
+	*testing.T
+	p      *pubsub.PubSub
+	events *spyPathEvents
+}
+
+func TestPubSubPathHooks(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+	o.BeforeEach(func(t *testing.T) TH {
+		e := &spyPathEvents{}
+		return TH{
+			T:      t,
+			events: e,
+			p: pubsub.New(
+				pubsub.WithFirstSubscriberFunc(e.record("first")),
+				pubsub.WithLastSubscriberFunc(e.record("last")),
+			),
+		}
+	})
+
+	o.Spec("it notifies when a path gains its first and loses its last subscription", func(t TH) {
+		u1 := t.p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"a", "b"}))
+		u2 := t.p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"a", "b"}))
+		u3 := t.p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"a"}))
+
+		u1()
+		u3()
+		u2()
+
+		Expect(t, t.events.all()).To(Equal([]string{
+			"first a.b",
+			"first a",
+			"last a",
+			"last a.b",
+		}))
+	})
+
+	o.Spec("it notifies when a subscription is moved", func(t TH) {
+		h := t.p.SubscribeHandle(newSpySubscrption(), pubsub.WithPath([]string{"a"}))
+		h.Move([]string{"a"})
+		h.Move([]string{"b"})
+
+		Expect(t, t.events.all()).To(Equal([]string{
+			"first a",
+			"last a",
+			"first b",
+		}))
+	})
+
+	o.Spec("it allows the hooks to subscribe", func(t TH) {
+		var p *pubsub.PubSub
+		p = pubsub.New(pubsub.WithFirstSubscriberFunc(func(path []string) {
+			if len(path) == 1 {
+				p.Subscribe(newSpySubscrption(), pubsub.WithPath(append(path, "child")))
+			}
+		}))
+		p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"a"}))
+
+		Expect(t, p.Subscriptions("a", "child")).To(Equal(1))
+	})
+}
+
+type spyPathEvents struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (s *spyPathEvents) record(kind string) func(path []string) {
+	return func(path []string) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.events = append(s.events, kind+" "+strings.Join(path, "."))
+	}
+}
+
+func (s *spyPathEvents) all() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.events...)
+}
@@ -0,0 +1,79 @@
+package pubsub
+
+import "time"
+
+// WithIdleEviction configures the PubSub to evict subscriptions that have
+// not been written to for the given duration (or since they were added).
+// This allows consumers that stopped matching anything to be reaped.
+// Evicted subscriptions are removed and their Subscriptions are closed (see
+// Closer and WithEvictionFunc). The idle time is measured with the PubSub's
+// Clock. Subscriptions held by mounted PubSubs are not evicted.
+func WithIdleEviction(d time.Duration) PubSubOption {
+	return pubsubConfigFunc(func(p *PubSub) {
+		p.idleTimeout = d
+	})
+}
+
+// WithEvictionFunc configures a function to be invoked with each
+// subscription that is evicted (see WithEvictionPolicy and
+// WithIdleEviction) once its Subscription has been closed. It is not
+// invoked for subscriptions that are unsubscribed.
+func WithEvictionFunc(f func(info SubscriptionInfo)) PubSubOption {
+	return pubsubConfigFunc(func(p *PubSub) {
+		p.onEvict = f
+	})
+}
+
+// armIdle schedules evictIdle unless the PubSub has been closed.
+func (s *PubSub) armIdle(d time.Duration) {
+	s.idleMu.Lock()
+	defer s.idleMu.Unlock()
+
+	if s.idleStopped {
+		return
+	}
+	s.idleTimer = s.clock.AfterFunc(d, s.evictIdle)
+}
+
+// stopIdle prevents evictIdle from being scheduled again.
+func (s *PubSub) stopIdle() {
+	s.idleMu.Lock()
+	defer s.idleMu.Unlock()
+
+	s.idleStopped = true
+	if s.idleTimer != nil {
+		s.idleTimer.Stop()
+	}
+}
+
+// evictIdle evicts the subscriptions that have been idle for too long. It
+// is then scheduled again for when the next subscription would become idle
+// (or the idle timeout if there are none). A subscription that is added in
+// the meantime can not become idle before then.
+func (s *PubSub) evictIdle() {
+	t := s.lockTree()
+	if t.closed {
+		s.unlockTree(t)
+		return
+	}
+
+	now := s.clock.Now()
+	next := s.idleTimeout
+	srs, candidates := s.evictionCandidates(t)
+	for i, c := range candidates {
+		idle := now.Sub(c.lastUsed())
+		if idle >= s.idleTimeout {
+			s.evictLocked(t, srs[i], c.SubscriptionInfo)
+			continue
+		}
+
+		if remaining := s.idleTimeout - idle; remaining < next {
+			next = remaining
+		}
+	}
+
+	s.unlockTree(t)
+	s.hooks.dispatch()
+	s.evict(t)
+	s.armIdle(next)
+}
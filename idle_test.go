@@ -0,0 +1,110 @@
+package pubsub_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+type TI struct {
+	*testing.T
+	p       *pubsub.PubSub
+	clock   *spyClock
+	evicted *spyEvictions
+}
+
+func TestPubSubIdleEviction(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) TI {
+		clock := newSpyClock()
+		evicted := &spyEvictions{}
+		return TI{
+			T: t,
+			p: pubsub.New(
+				pubsub.WithClock(clock),
+				pubsub.WithIdleEviction(time.Minute),
+				pubsub.WithEvictionFunc(evicted.add),
+			),
+			clock:   clock,
+			evicted: evicted,
+		}
+	})
+
+	o.Spec("it evicts subscriptions that are not written to", func(t TI) {
+		idle := newSpyCloser()
+		active := newSpySubscrption()
+		t.p.Subscribe(idle, pubsub.WithPath([]string{"a"}), pubsub.WithMetadata(map[string]string{"client": "x"}))
+		t.p.Subscribe(active, pubsub.WithPath([]string{"b"}))
+
+		t.clock.advance(30 * time.Second)
+		t.p.Publish(1, pubsub.LinearTreeTraverser([]string{"b"}))
+		t.clock.advance(30 * time.Second)
+
+		Expect(t, idle.closed).To(Equal(1))
+		Expect(t, t.p.Paths()).To(Equal([][]string{{"b"}}))
+		Expect(t, t.evicted.paths()).To(Equal([][]string{{"a"}}))
+		Expect(t, t.evicted.infos()[0].Metadata).To(Equal(map[string]string{"client": "x"}))
+
+		t.clock.advance(30 * time.Second)
+		Expect(t, t.p.Paths()).To(HaveLen(0))
+		Expect(t, active.Data()).To(Equal([]interface{}{1}))
+	})
+
+	o.Spec("it evicts subscriptions that are added later", func(t TI) {
+		t.clock.advance(45 * time.Second)
+		t.p.Subscribe(newSpySubscrption())
+
+		t.clock.advance(15 * time.Second)
+		Expect(t, t.p.Subscriptions()).To(Equal(1))
+
+		t.clock.advance(45 * time.Second)
+		Expect(t, t.p.Subscriptions()).To(Equal(0))
+	})
+
+	o.Spec("it does not evict subscriptions that are unsubscribed", func(t TI) {
+		unsubscribe := t.p.Subscribe(newSpySubscrption())
+		unsubscribe()
+
+		t.clock.advance(time.Hour)
+		Expect(t, t.evicted.infos()).To(HaveLen(0))
+	})
+
+	o.Spec("it stops once the PubSub is closed", func(t TI) {
+		t.p.Close()
+		Expect(t, t.clock.pending()).To(Equal(0))
+	})
+}
+
+// spyEvictions records the subscriptions given to an eviction func.
+type spyEvictions struct {
+	mu sync.Mutex
+	is []pubsub.SubscriptionInfo
+}
+
+func (s *spyEvictions) add(info pubsub.SubscriptionInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.is = append(s.is, info)
+}
+
+func (s *spyEvictions) infos() []pubsub.SubscriptionInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]pubsub.SubscriptionInfo(nil), s.is...)
+}
+
+func (s *spyEvictions) paths() [][]string {
+	var paths [][]string
+	for _, info := range s.infos() {
+		paths = append(paths, info.Path)
+	}
+	return paths
+}
@@ -0,0 +1,81 @@
+package pubsub
+
+import "context"
+
+// PublishFunc publishes data with a TreeTraverser (see PubSub.PublishCtx).
+type PublishFunc func(ctx context.Context, d interface{}, a TreeTraverser) (PublishResult, error)
+
+// PublishInterceptor wraps each Publish. It is given the next PublishFunc in
+// the chain and returns a PublishFunc that should (but is not required to)
+// invoke it. This allows the data, TreeTraverser and context to be inspected
+// or altered.
+type PublishInterceptor func(next PublishFunc) PublishFunc
+
+// SubscribeInterceptor wraps the Subscription of each subscription. It is
+// given the subscription's path and Subscription and returns the
+// Subscription that the PubSub writes to. This allows each delivery to be
+// inspected or altered.
+type SubscribeInterceptor func(path []string, next Subscription) Subscription
+
+// WithPublishInterceptor adds a PublishInterceptor to the PubSub.
+// Interceptors are invoked in the order they are added.
+func WithPublishInterceptor(i PublishInterceptor) PubSubOption {
+	return pubsubConfigFunc(func(p *PubSub) {
+		p.publishInterceptors = append(p.publishInterceptors, i)
+	})
+}
+
+// WithBeforePublish adds a function that is invoked with the data of each
+// Publish before it is traversed. The data it returns is published instead
+// (e.g., to add a timestamp). The functions are invoked in the order they
+// are added, after any PublishInterceptors. Unlike a PublishInterceptor,
+// it does not require the publish to allocate a chain of closures.
+func WithBeforePublish(f func(data interface{}) interface{}) PubSubOption {
+	return pubsubConfigFunc(func(p *PubSub) {
+		p.beforePublish = append(p.beforePublish, f)
+	})
+}
+
+// WithAfterPublish adds a function that is invoked with the data and
+// PublishResult of each Publish once the data has been written to the
+// subscriptions. It is not invoked for data that is dropped because the
+// PubSub is closed. The functions are invoked in the order they are added.
+func WithAfterPublish(f func(data interface{}, result PublishResult)) PubSubOption {
+	return pubsubConfigFunc(func(p *PubSub) {
+		p.afterPublish = append(p.afterPublish, f)
+	})
+}
+
+// WithSubscribeInterceptor adds a SubscribeInterceptor to the PubSub.
+// Interceptors are invoked in the order they are added.
+func WithSubscribeInterceptor(i SubscribeInterceptor) PubSubOption {
+	return pubsubConfigFunc(func(p *PubSub) {
+		p.subscribeInterceptors = append(p.subscribeInterceptors, i)
+	})
+}
+
+// intercept wraps the Subscription with the SubscribeInterceptors.
+func (s *PubSub) intercept(sub Subscription, path []string) Subscription {
+	if len(s.subscribeInterceptors) == 0 {
+		return sub
+	}
+
+	wrapped := sub
+	for i := len(s.subscribeInterceptors) - 1; i >= 0; i-- {
+		wrapped = s.subscribeInterceptors[i](path, wrapped)
+	}
+
+	return interceptedSubscription{Subscription: wrapped, orig: sub}
+}
+
+// interceptedSubscription ensures the original Subscription is closed even
+// if an interceptor did not implement Closer.
+type interceptedSubscription struct {
+	Subscription
+	orig Subscription
+}
+
+// Close implements Closer.
+func (s interceptedSubscription) Close() {
+	closeSubscription(s.orig)
+}
@@ -0,0 +1,124 @@
+package pubsub_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+func TestPubSubInterceptors(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.Spec("it wraps each publish in order", func(t *testing.T) {
+		appender := func(suffix string) pubsub.PublishInterceptor {
+			return func(next pubsub.PublishFunc) pubsub.PublishFunc {
+				return func(ctx context.Context, d interface{}, a pubsub.TreeTraverser) (pubsub.PublishResult, error) {
+					return next(ctx, d.(string)+suffix, a)
+				}
+			}
+		}
+		p := pubsub.New(
+			pubsub.WithPublishInterceptor(appender("-1")),
+			pubsub.WithPublishInterceptor(appender("-2")),
+		)
+		sub := newSpySubscrption()
+		p.Subscribe(sub)
+
+		p.Publish("some-data", pubsub.LinearTreeTraverser(nil))
+		Expect(t, sub.data).To(Equal([]interface{}{"some-data-1-2"}))
+	})
+
+	o.Spec("it can drop a publish", func(t *testing.T) {
+		p := pubsub.New(pubsub.WithPublishInterceptor(func(next pubsub.PublishFunc) pubsub.PublishFunc {
+			return func(ctx context.Context, d interface{}, a pubsub.TreeTraverser) (pubsub.PublishResult, error) {
+				return pubsub.PublishResult{}, nil
+			}
+		}))
+		sub := newSpySubscrption()
+		p.Subscribe(sub)
+
+		p.Publish("some-data", pubsub.LinearTreeTraverser(nil))
+		Expect(t, sub.data).To(HaveLen(0))
+	})
+
+	o.Spec("it invokes the before publish funcs in order", func(t *testing.T) {
+		appender := func(suffix string) func(interface{}) interface{} {
+			return func(d interface{}) interface{} {
+				return d.(string) + suffix
+			}
+		}
+		p := pubsub.New(
+			pubsub.WithBeforePublish(appender("-1")),
+			pubsub.WithBeforePublish(appender("-2")),
+			pubsub.WithPublishInterceptor(func(next pubsub.PublishFunc) pubsub.PublishFunc {
+				return func(ctx context.Context, d interface{}, a pubsub.TreeTraverser) (pubsub.PublishResult, error) {
+					return next(ctx, d.(string)+"-i", a)
+				}
+			}),
+		)
+		sub := newSpySubscrption()
+		p.Subscribe(sub)
+
+		p.Publish("some-data", pubsub.LinearTreeTraverser(nil))
+		Expect(t, sub.data).To(Equal([]interface{}{"some-data-i-1-2"}))
+	})
+
+	o.Spec("it invokes the after publish funcs with the result", func(t *testing.T) {
+		var (
+			data    []interface{}
+			results []pubsub.PublishResult
+		)
+		p := pubsub.New(
+			pubsub.WithBeforePublish(func(d interface{}) interface{} {
+				return d.(string) + "-1"
+			}),
+			pubsub.WithAfterPublish(func(d interface{}, r pubsub.PublishResult) {
+				data = append(data, d)
+				results = append(results, r)
+			}),
+		)
+		p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"a"}))
+		p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"a"}), pubsub.WithFilter(func(interface{}) bool {
+			return false
+		}))
+
+		p.Publish("a", pubsub.LinearTreeTraverser([]string{"a"}))
+		p.Publish("b", pubsub.LinearTreeTraverser([]string{"b"}))
+		p.Close()
+		p.Publish("c", pubsub.LinearTreeTraverser([]string{"a"}))
+
+		Expect(t, data).To(Equal([]interface{}{"a-1", "b-1"}))
+		Expect(t, results).To(Equal([]pubsub.PublishResult{{Matched: 2, Delivered: 1}, {}}))
+	})
+
+	o.Spec("it wraps each delivery in order", func(t *testing.T) {
+		var paths [][]string
+		appender := func(suffix string) pubsub.SubscribeInterceptor {
+			return func(path []string, next pubsub.Subscription) pubsub.Subscription {
+				paths = append(paths, path)
+				return pubsub.SubscriptionFunc(func(data interface{}) {
+					next.Write(data.(string) + suffix)
+				})
+			}
+		}
+		p := pubsub.New(
+			pubsub.WithSubscribeInterceptor(appender("-1")),
+			pubsub.WithSubscribeInterceptor(appender("-2")),
+		)
+		sub := newSpyCloser()
+		p.Subscribe(sub, pubsub.WithPath([]string{"a"}))
+
+		p.Publish("some-data", pubsub.LinearTreeTraverser([]string{"a"}))
+		Expect(t, sub.data).To(Equal([]interface{}{"some-data-1-2"}))
+		Expect(t, paths).To(Equal([][]string{{"a"}, {"a"}}))
+
+		p.Close()
+		Expect(t, sub.closed).To(Equal(1))
+	})
+}
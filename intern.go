@@ -0,0 +1,26 @@
+package pubsub
+
+// SegmentStats describes the table that the PubSub stores the segments of
+// its subscription tree's paths in. Each distinct segment is only stored
+// once, however many nodes (and therefore subscriptions) share it.
+type SegmentStats struct {
+	// Segments is the number of distinct segments and Bytes is their total
+	// length.
+	Segments int
+	Bytes    int
+
+	// References is the number of nodes that use the segments. Without the
+	// table, each of them would store its own copy.
+	References int
+}
+
+// SegmentStats returns the current size of the PubSub's segment table.
+// Segments of mounted PubSubs are not included.
+func (s *PubSub) SegmentStats() SegmentStats {
+	st := s.segments.Stats()
+	return SegmentStats{
+		Segments:   st.Strings,
+		Bytes:      st.Bytes,
+		References: st.References,
+	}
+}
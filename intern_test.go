@@ -0,0 +1,56 @@
+package pubsub_test
+
+import (
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+func TestPubSubSegmentStats(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.Spec("it stores each segment once", func(t *testing.T) {
+		for _, opts := range [][]pubsub.PubSubOption{
+			nil,
+			{pubsub.WithSubtreeLocking()},
+		} {
+			p := pubsub.New(opts...)
+			unsubscribe := p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"a", "b", "a"}))
+			p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"b", "a"}))
+			p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"a", "b"}))
+			Expect(t, p.SegmentStats()).To(Equal(pubsub.SegmentStats{
+				Segments:   2,
+				Bytes:      2,
+				References: 5,
+			}))
+
+			unsubscribe()
+			Expect(t, p.SegmentStats()).To(Equal(pubsub.SegmentStats{
+				Segments:   2,
+				Bytes:      2,
+				References: 4,
+			}))
+		}
+	})
+
+	o.Spec("it removes segments that are no longer used", func(t *testing.T) {
+		p := pubsub.New()
+		unsubscribe := p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"abc", "d"}))
+		p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"e"}))
+
+		unsubscribe()
+		Expect(t, p.SegmentStats()).To(Equal(pubsub.SegmentStats{
+			Segments:   1,
+			Bytes:      1,
+			References: 1,
+		}))
+
+		p.Close()
+		Expect(t, p.SegmentStats()).To(Equal(pubsub.SegmentStats{}))
+	})
+}
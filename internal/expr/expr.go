@@ -0,0 +1,435 @@
+// Package expr parses the boolean expressions that subscriptions can be
+// given as text (see pubsub.CompileExpr and the query package). The
+// expressions are comparisons of fields with literals that are combined
+// with and, or and not. Each caller resolves the comparisons (see Map) for
+// the data it evaluates them with.
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Syntax is the spelling of an expression's operators and literals.
+type Syntax struct {
+	and, or, not string
+
+	// equal is the equality operator. It is parsed as "==".
+	equal string
+
+	// symbols are the operators that are not words, with the longer ones
+	// first.
+	symbols []string
+
+	// fold is set if words (e.g., AND and TRUE) are not case-sensitive.
+	fold bool
+
+	// quote starts and ends strings. Strings quoted with '"' are Go
+	// strings (see strconv.Unquote), while a '\'' is escaped by doubling
+	// it, as in SQL.
+	quote byte
+}
+
+var (
+	// Go is the syntax of Go: &&, ||, !, == and "strings".
+	Go = &Syntax{
+		and:     "&&",
+		or:      "||",
+		not:     "!",
+		equal:   "==",
+		symbols: []string{"&&", "||", "==", "!=", "<=", ">=", "(", ")", "!", "<", ">"},
+		quote:   '"',
+	}
+
+	// SQL is the syntax of a SQL WHERE clause: AND, OR, NOT, =, <> (or !=)
+	// and 'strings'. Words are not case-sensitive.
+	SQL = &Syntax{
+		and:     "AND",
+		or:      "OR",
+		not:     "NOT",
+		equal:   "=",
+		symbols: []string{"<=", ">=", "!=", "<>", "(", ")", "=", "<", ">"},
+		fold:    true,
+		quote:   '\'',
+	}
+)
+
+// Node is an And, Or, Not, Cmp or a node that a Cmp was resolved to (see
+// Map).
+type Node interface{}
+
+// And is a conjunction. Nested conjunctions are flattened, so that each
+// comparison of the top level one can be inspected.
+type And []Node
+
+// Or is a disjunction.
+type Or []Node
+
+// Not negates X.
+type Not struct {
+	X Node
+}
+
+// Cmp compares a field with a literal. The field is always on the left, so
+// the operator of a comparison that was written the other way around is
+// flipped. The operator is one of ==, !=, <, <=, > and >=.
+type Cmp struct {
+	Field string
+	Op    string
+	Lit   Literal
+}
+
+// Literal is a string, number or boolean literal. The Text of a number is
+// kept so it can be parsed as the type of the field it is compared with.
+// The Text of a string is unquoted and that of a boolean is "true" or
+// "false".
+type Literal struct {
+	Kind LiteralKind
+	Text string
+}
+
+// LiteralKind is the kind of a Literal.
+type LiteralKind int
+
+const (
+	String LiteralKind = iota
+	Number
+	Bool
+)
+
+func (k LiteralKind) String() string {
+	return [...]string{"string", "number", "boolean"}[k]
+}
+
+// Predicate is what a Cmp is resolved to (see Map).
+type Predicate interface {
+	Eval(data interface{}) bool
+}
+
+// Parse parses the expression. The errors do not include the expression,
+// so that the caller can wrap them with it.
+func Parse(s string, syntax *Syntax) (Node, error) {
+	p := &parser{syntax: syntax}
+	if err := p.lex(s); err != nil {
+		return nil, err
+	}
+
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q", p.tokens[p.pos].text)
+	}
+	return n, nil
+}
+
+// Map replaces each Cmp of the node with what f returns for it. It stops
+// at the first error.
+func Map(n Node, f func(c Cmp) (Node, error)) (Node, error) {
+	switch n := n.(type) {
+	case And:
+		return mapAll(n, f)
+	case Or:
+		xs, err := mapAll(n, f)
+		return Or(xs), err
+	case Not:
+		x, err := Map(n.X, f)
+		return Not{X: x}, err
+	default:
+		return f(n.(Cmp))
+	}
+}
+
+func mapAll(xs []Node, f func(c Cmp) (Node, error)) (And, error) {
+	mapped := make(And, len(xs))
+	for i, x := range xs {
+		var err error
+		if mapped[i], err = Map(x, f); err != nil {
+			return nil, err
+		}
+	}
+	return mapped, nil
+}
+
+// Eval reports whether the data satisfies the node. The comparisons must
+// have been resolved to Predicates (see Map).
+func Eval(n Node, data interface{}) bool {
+	switch n := n.(type) {
+	case And:
+		for _, x := range n {
+			if !Eval(x, data) {
+				return false
+			}
+		}
+		return true
+	case Or:
+		for _, x := range n {
+			if Eval(x, data) {
+				return true
+			}
+		}
+		return false
+	case Not:
+		return !Eval(n.X, data)
+	default:
+		return n.(Predicate).Eval(data)
+	}
+}
+
+type token struct {
+	text string
+
+	// lit is set for literals.
+	lit *Literal
+}
+
+type parser struct {
+	syntax *Syntax
+	tokens []token
+	pos    int
+}
+
+func (p *parser) lex(s string) error {
+	for i := 0; i < len(s); {
+		c := s[i]
+		if unicode.IsSpace(rune(c)) {
+			i++
+			continue
+		}
+
+		if op, ok := p.symbol(s[i:]); ok {
+			i += len(op)
+			if op == "<>" {
+				op = "!="
+			}
+			p.tokens = append(p.tokens, token{text: op})
+			continue
+		}
+
+		switch {
+		case c == p.syntax.quote:
+			text, n, err := p.unquote(s[i:])
+			if err != nil {
+				return err
+			}
+			p.tokens = append(p.tokens, token{text: s[i : i+n], lit: &Literal{Kind: String, Text: text}})
+			i += n
+		case c == '-' || c == '.' || ('0' <= c && c <= '9'):
+			j := i + 1
+			for ; j < len(s) && (isIdentByte(s[j]) || ((s[j] == '+' || s[j] == '-') && (s[j-1] == 'e' || s[j-1] == 'E'))); j++ {
+			}
+
+			if _, err := strconv.ParseFloat(s[i:j], 64); err != nil {
+				return fmt.Errorf("invalid number %s", s[i:j])
+			}
+			p.tokens = append(p.tokens, token{text: s[i:j], lit: &Literal{Kind: Number, Text: s[i:j]}})
+			i = j
+		case isIdentByte(c):
+			j := i + 1
+			for ; j < len(s) && isIdentByte(s[j]); j++ {
+			}
+			p.tokens = append(p.tokens, p.word(s[i:j]))
+			i = j
+		default:
+			return fmt.Errorf("unexpected %q", c)
+		}
+	}
+	return nil
+}
+
+func (p *parser) symbol(s string) (string, bool) {
+	for _, op := range p.syntax.symbols {
+		if strings.HasPrefix(s, op) {
+			return op, true
+		}
+	}
+	return "", false
+}
+
+// unquote returns the string that s starts with and how many bytes it
+// spans.
+func (p *parser) unquote(s string) (string, int, error) {
+	q := p.syntax.quote
+	if q == '"' {
+		j := 1
+		for ; j < len(s) && s[j] != q; j++ {
+			if s[j] == '\\' {
+				j++
+			}
+		}
+		if j >= len(s) {
+			return "", 0, fmt.Errorf("unterminated string")
+		}
+
+		text, err := strconv.Unquote(s[:j+1])
+		if err != nil {
+			return "", 0, fmt.Errorf("invalid string %s", s[:j+1])
+		}
+		return text, j + 1, nil
+	}
+
+	var b strings.Builder
+	for j := 1; j < len(s); j++ {
+		if s[j] == q {
+			if j+1 >= len(s) || s[j+1] != q {
+				return b.String(), j + 1, nil
+			}
+			j++
+		}
+		b.WriteByte(s[j])
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+// word returns the token for an identifier, which may be a boolean literal
+// or an operator (e.g., AND).
+func (p *parser) word(text string) token {
+	w := text
+	if p.syntax.fold {
+		w = strings.ToLower(text)
+	}
+
+	switch w {
+	case "true", "false":
+		return token{text: text, lit: &Literal{Kind: Bool, Text: w}}
+	}
+
+	if p.syntax.fold {
+		for _, op := range []string{p.syntax.and, p.syntax.or, p.syntax.not} {
+			if strings.EqualFold(text, op) {
+				return token{text: op}
+			}
+		}
+	}
+	return token{text: text}
+}
+
+func isIdentByte(b byte) bool {
+	return b == '_' || b == '.' || ('a' <= b && b <= 'z') || ('A' <= b && b <= 'Z') || ('0' <= b && b <= '9')
+}
+
+func (p *parser) peek() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	return p.tokens[p.pos].text
+}
+
+func (p *parser) parseOr() (Node, error) {
+	var or Or
+	for {
+		x, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		or = append(or, x)
+
+		if p.peek() != p.syntax.or {
+			break
+		}
+		p.pos++
+	}
+
+	if len(or) == 1 {
+		return or[0], nil
+	}
+	return or, nil
+}
+
+func (p *parser) parseAnd() (Node, error) {
+	var and And
+	for {
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		if a, ok := x.(And); ok {
+			and = append(and, a...)
+		} else {
+			and = append(and, x)
+		}
+
+		if p.peek() != p.syntax.and {
+			break
+		}
+		p.pos++
+	}
+
+	if len(and) == 1 {
+		return and[0], nil
+	}
+	return and, nil
+}
+
+func (p *parser) parseUnary() (Node, error) {
+	switch p.peek() {
+	case p.syntax.not:
+		p.pos++
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not{X: x}, nil
+	case "(":
+		p.pos++
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("missing ')'")
+		}
+		p.pos++
+		return x, nil
+	default:
+		return p.parseCmp()
+	}
+}
+
+// flipped is the operator for a comparison with its operands swapped.
+var flipped = map[string]string{
+	"==": "==",
+	"!=": "!=",
+	"<":  ">",
+	"<=": ">=",
+	">":  "<",
+	">=": "<=",
+}
+
+func (p *parser) parseCmp() (Node, error) {
+	if p.pos+3 > len(p.tokens) {
+		return nil, fmt.Errorf("incomplete comparison")
+	}
+
+	left, op, right := p.tokens[p.pos], p.tokens[p.pos+1].text, p.tokens[p.pos+2]
+	if op == p.syntax.equal {
+		op = "=="
+	}
+	if _, ok := flipped[op]; !ok {
+		return nil, fmt.Errorf("expected a comparison operator, got %q", op)
+	}
+	p.pos += 3
+
+	if left.lit != nil {
+		left, right = right, left
+		op = flipped[op]
+	}
+
+	if left.lit != nil || right.lit == nil {
+		return nil, fmt.Errorf("%s %s %s must compare a field with a literal", left.text, op, right.text)
+	}
+
+	if right.lit.Kind == Bool && op != "==" && op != "!=" {
+		return nil, fmt.Errorf("booleans can only be compared with %s and !=", p.syntax.equal)
+	}
+
+	return Cmp{
+		Field: left.text,
+		Op:    op,
+		Lit:   *right.lit,
+	}, nil
+}
@@ -0,0 +1,74 @@
+package expr_test
+
+import (
+	"testing"
+
+	"github.com/apoydence/pubsub/internal/expr"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.Spec("it parses the Go syntax", func(t *testing.T) {
+		n, err := expr.Parse(`a == "x\"y" && (b < 1.5 || !(c != true)) && 2 <= d`, expr.Go)
+		Expect(t, err).To(BeNil())
+		Expect(t, n).To(Equal(expr.And{
+			expr.Cmp{Field: "a", Op: "==", Lit: expr.Literal{Kind: expr.String, Text: `x"y`}},
+			expr.Or{
+				expr.Cmp{Field: "b", Op: "<", Lit: expr.Literal{Kind: expr.Number, Text: "1.5"}},
+				expr.Not{X: expr.Cmp{Field: "c", Op: "!=", Lit: expr.Literal{Kind: expr.Bool, Text: "true"}}},
+			},
+			expr.Cmp{Field: "d", Op: ">=", Lit: expr.Literal{Kind: expr.Number, Text: "2"}},
+		}))
+	})
+
+	o.Spec("it parses the SQL syntax", func(t *testing.T) {
+		n, err := expr.Parse(`a = 'x''y' and (b <> -1e3 OR NOT c = False)`, expr.SQL)
+		Expect(t, err).To(BeNil())
+		Expect(t, n).To(Equal(expr.And{
+			expr.Cmp{Field: "a", Op: "==", Lit: expr.Literal{Kind: expr.String, Text: "x'y"}},
+			expr.Or{
+				expr.Cmp{Field: "b", Op: "!=", Lit: expr.Literal{Kind: expr.Number, Text: "-1e3"}},
+				expr.Not{X: expr.Cmp{Field: "c", Op: "==", Lit: expr.Literal{Kind: expr.Bool, Text: "false"}}},
+			},
+		}))
+	})
+
+	o.Spec("it returns an error for an invalid expression", func(t *testing.T) {
+		for _, s := range []string{``, `a ==`, `a = 1`, `a == b`, `(a == 1`, `a == "x`, `a < true`, `a == 1 b == 2`, `a AND b`} {
+			_, err := expr.Parse(s, expr.Go)
+			Expect(t, err == nil).To(BeFalse())
+		}
+
+		for _, s := range []string{`a == 1`, `a && b`, `a = 'x`, `a < TRUE`, `a = 1 OR`} {
+			_, err := expr.Parse(s, expr.SQL)
+			Expect(t, err == nil).To(BeFalse())
+		}
+	})
+
+	o.Spec("it evaluates the resolved comparisons", func(t *testing.T) {
+		n, err := expr.Parse(`a == 1 && !(b == 2 || c == 3)`, expr.Go)
+		Expect(t, err).To(BeNil())
+
+		n, err = expr.Map(n, func(c expr.Cmp) (expr.Node, error) {
+			return has(c.Field), nil
+		})
+		Expect(t, err).To(BeNil())
+
+		Expect(t, expr.Eval(n, map[string]bool{"a": true})).To(BeTrue())
+		Expect(t, expr.Eval(n, map[string]bool{"a": true, "c": true})).To(BeFalse())
+		Expect(t, expr.Eval(n, map[string]bool{"b": true})).To(BeFalse())
+	})
+}
+
+// has is a Predicate that is true if its field is set in the data.
+type has string
+
+func (h has) Eval(data interface{}) bool {
+	return data.(map[string]bool)[string(h)]
+}
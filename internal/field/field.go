@@ -0,0 +1,58 @@
+// Package field looks up the fields of published data with reflection (see
+// pubsub.ReflectTraverser, pubsub.CompileExpr and the query package).
+package field
+
+import (
+	"reflect"
+	"sync"
+)
+
+// key identifies a field of a struct type in indexes.
+type key struct {
+	t    reflect.Type
+	name string
+}
+
+// indexes caches the index (see reflect.Value.FieldByIndex) of each field
+// that has been looked up. An index of nil means the type does not have
+// the field.
+var indexes sync.Map
+
+// Lookup returns the named field of the struct that v holds (or points
+// to). The fields of each type are looked up once and cached.
+func Lookup(v reflect.Value, name string) (reflect.Value, bool) {
+	v, ok := Indirect(v)
+	if !ok || v.Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+
+	k := key{t: v.Type(), name: name}
+	idx, ok := indexes.Load(k)
+	if !ok {
+		var index []int
+		if f, ok := k.t.FieldByName(name); ok {
+			index = f.Index
+		}
+		idx, _ = indexes.LoadOrStore(k, index)
+	}
+
+	index := idx.([]int)
+	if index == nil {
+		return reflect.Value{}, false
+	}
+
+	// An embedded struct may be a nil pointer.
+	v, err := v.FieldByIndexErr(index)
+	return v, err == nil
+}
+
+// Indirect follows the pointers and interfaces of v to its value.
+func Indirect(v reflect.Value) (reflect.Value, bool) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}, false
+		}
+		v = v.Elem()
+	}
+	return v, v.IsValid()
+}
@@ -0,0 +1,100 @@
+// Package intern deduplicates the strings that many nodes of a
+// subscription tree share (e.g., path segments), so that each value is only
+// stored once.
+package intern
+
+import (
+	"strings"
+	"sync"
+)
+
+// Table holds a single copy of each string that is in use. Strings are
+// reference counted and removed once they are no longer in use. It is safe
+// to use concurrently. The zero value is an empty Table.
+type Table struct {
+	mu      sync.Mutex
+	entries map[string]*entry
+	bytes   int
+	refs    int
+}
+
+type entry struct {
+	s    string
+	refs int
+}
+
+// Stats describes the size of a Table.
+type Stats struct {
+	// Strings is the number of distinct strings in the table and Bytes is
+	// their total length.
+	Strings int
+	Bytes   int
+
+	// References is the number of times the strings are in use. The
+	// difference with Strings is the number of copies the table saved.
+	References int
+}
+
+// Intern returns the table's copy of s and counts it as in use. The copy
+// never refers to the memory of s, so s may be a slice of a larger string.
+// Each Intern must be followed by a Release once the string is no longer
+// in use.
+func (t *Table) Intern(s string) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	e, ok := t.entries[s]
+	if !ok {
+		if t.entries == nil {
+			t.entries = make(map[string]*entry)
+		}
+		e = &entry{s: strings.Clone(s)}
+		t.entries[e.s] = e
+		t.bytes += len(s)
+	}
+	e.refs++
+	t.refs++
+
+	return e.s
+}
+
+// Release counts s as no longer in use. It is removed from the table once
+// every Intern of it has been released.
+func (t *Table) Release(s string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	e, ok := t.entries[s]
+	if !ok {
+		return
+	}
+
+	e.refs--
+	t.refs--
+	if e.refs == 0 {
+		delete(t.entries, s)
+		t.bytes -= len(s)
+	}
+}
+
+// Reset removes every string from the table.
+func (t *Table) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.entries = nil
+	t.bytes = 0
+	t.refs = 0
+}
+
+// Stats returns the current size of the table.
+func (t *Table) Stats() Stats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return Stats{
+		Strings:    len(t.entries),
+		Bytes:      t.bytes,
+		References: t.refs,
+	}
+}
@@ -0,0 +1,52 @@
+package intern_test
+
+import (
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/apoydence/pubsub/internal/intern"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+func TestTable(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.Spec("it returns a single copy of equal strings", func(t *testing.T) {
+		var table intern.Table
+		buf := "a.b.a"
+		a1 := table.Intern(buf[:1])
+		a2 := table.Intern(strings.Clone(buf[4:]))
+
+		Expect(t, a1).To(Equal("a"))
+		Expect(t, unsafe.StringData(a1) == unsafe.StringData(a2)).To(BeTrue())
+		Expect(t, unsafe.StringData(a1) == unsafe.StringData(buf)).To(BeFalse())
+	})
+
+	o.Spec("it removes strings once they are released", func(t *testing.T) {
+		var table intern.Table
+		table.Intern("a")
+		table.Intern("a")
+		table.Intern("bc")
+		Expect(t, table.Stats()).To(Equal(intern.Stats{Strings: 2, Bytes: 3, References: 3}))
+
+		table.Release("a")
+		table.Release("bc")
+		table.Release("unknown")
+		Expect(t, table.Stats()).To(Equal(intern.Stats{Strings: 1, Bytes: 1, References: 1}))
+
+		table.Release("a")
+		Expect(t, table.Stats()).To(Equal(intern.Stats{}))
+	})
+
+	o.Spec("it removes every string with Reset", func(t *testing.T) {
+		var table intern.Table
+		table.Intern("a")
+		table.Reset()
+		Expect(t, table.Stats()).To(Equal(intern.Stats{}))
+	})
+}
@@ -0,0 +1,191 @@
+package node
+
+import (
+	"slices"
+	"sort"
+)
+
+// chunkSize is the most elements that a chunk of a list holds.
+const chunkSize = 64
+
+// list is an ordered list that is split into chunks. Chunks are shared by
+// the clones of a list and never modified, so changing a clone only copies
+// the chunk that is changed and the slice of chunks. This keeps the cost of
+// changing a node (which is cloned first, see Clone) from growing with the
+// number of its children or subscriptions. Most lists are short, so the
+// first chunk is held directly until there are more.
+type list[T any] struct {
+	one    []T
+	chunks [][]T
+	n      int
+
+	// whole keeps every element in a single chunk (e.g., for the
+	// subscriptions of a shard group, which are written as one).
+	whole bool
+}
+
+// pos is the position of an element in a list.
+type pos struct {
+	chunk, i int
+}
+
+func (l *list[T]) clone() list[T] {
+	return list[T]{
+		one:    l.one,
+		chunks: slices.Clone(l.chunks),
+		n:      l.n,
+		whole:  l.whole,
+	}
+}
+
+func (l *list[T]) len() int {
+	return l.n
+}
+
+func (l *list[T]) chunkLen() int {
+	if l.chunks == nil {
+		return 1
+	}
+	return len(l.chunks)
+}
+
+func (l *list[T]) chunk(c int) []T {
+	if l.chunks == nil {
+		return l.one
+	}
+	return l.chunks[c]
+}
+
+func (l *list[T]) setChunk(c int, chunk []T) {
+	if l.chunks == nil {
+		l.one = chunk
+		return
+	}
+	l.chunks[c] = chunk
+}
+
+// forEachChunk passes each non-empty chunk to f in order.
+func (l *list[T]) forEachChunk(f func(chunk []T)) {
+	if l.chunks == nil {
+		if len(l.one) > 0 {
+			f(l.one)
+		}
+		return
+	}
+
+	for _, chunk := range l.chunks {
+		f(chunk)
+	}
+}
+
+// end returns the position after the last element.
+func (l *list[T]) end() pos {
+	last := l.chunkLen() - 1
+	return pos{chunk: last, i: len(l.chunk(last))}
+}
+
+// search returns the position of the first element for which f returns
+// true (or end). Like sort.Search, f must be false for some (possibly
+// empty) prefix of the list and true for the rest.
+func (l *list[T]) search(f func(T) bool) pos {
+	c := 0
+	if l.chunks != nil {
+		c = sort.Search(len(l.chunks), func(c int) bool {
+			chunk := l.chunks[c]
+			return f(chunk[len(chunk)-1])
+		})
+		if c == len(l.chunks) {
+			return l.end()
+		}
+	}
+
+	chunk := l.chunk(c)
+	return pos{chunk: c, i: sort.Search(len(chunk), func(i int) bool {
+		return f(chunk[i])
+	})}
+}
+
+// find returns the position of the first element for which f returns
+// true.
+func (l *list[T]) find(f func(T) bool) (pos, bool) {
+	for c := 0; c < l.chunkLen(); c++ {
+		for i, x := range l.chunk(c) {
+			if f(x) {
+				return pos{chunk: c, i: i}, true
+			}
+		}
+	}
+	return pos{}, false
+}
+
+// get returns the element at the position. It returns false if the
+// position is the end of the list.
+func (l *list[T]) get(p pos) (T, bool) {
+	if p.chunk >= l.chunkLen() || p.i >= len(l.chunk(p.chunk)) {
+		var zero T
+		return zero, false
+	}
+	return l.chunk(p.chunk)[p.i], true
+}
+
+func (l *list[T]) set(p pos, x T) {
+	chunk := slices.Clone(l.chunk(p.chunk))
+	chunk[p.i] = x
+	l.setChunk(p.chunk, chunk)
+}
+
+// insert adds the element before the position. A full chunk is split in
+// two, unless the element is appended, in which case it starts a new
+// chunk.
+func (l *list[T]) insert(p pos, x T) {
+	l.n++
+	old := l.chunk(p.chunk)
+	full := !l.whole && len(old) >= chunkSize
+
+	if full && l.chunks == nil {
+		l.chunks = [][]T{old}
+	}
+
+	if full && p.chunk == len(l.chunks)-1 && p.i == len(old) {
+		l.chunks = append(l.chunks, []T{x})
+		return
+	}
+
+	chunk := make([]T, 0, len(old)+1)
+	chunk = append(chunk, old[:p.i]...)
+	chunk = append(chunk, x)
+	chunk = append(chunk, old[p.i:]...)
+
+	if !full {
+		l.setChunk(p.chunk, chunk)
+		return
+	}
+
+	half := len(chunk) / 2
+	l.chunks[p.chunk] = chunk[:half:half]
+	l.chunks = slices.Insert(l.chunks, p.chunk+1, chunk[half:])
+}
+
+// delete removes the element at the position. Empty chunks are removed
+// (other than the last).
+func (l *list[T]) delete(p pos) {
+	l.n--
+	old := l.chunk(p.chunk)
+	if len(old) == 1 && l.chunks != nil {
+		l.chunks = slices.Delete(l.chunks, p.chunk, p.chunk+1)
+		if len(l.chunks) == 1 {
+			l.one, l.chunks = l.chunks[0], nil
+		}
+		return
+	}
+
+	if len(old) == 1 {
+		l.one = nil
+		return
+	}
+
+	chunk := make([]T, 0, len(old)-1)
+	chunk = append(chunk, old[:p.i]...)
+	chunk = append(chunk, old[p.i+1:]...)
+	l.setChunk(p.chunk, chunk)
+}
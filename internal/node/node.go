@@ -1,9 +1,16 @@
 package node
 
 import (
-	"math/rand"
+	"slices"
+	"sort"
+	"strings"
+	"sync/atomic"
 )
 
+// lastID is the last subscription ID that was handed out. IDs are never
+// reused, so a stale ID can't remove another subscription.
+var lastID int64
+
 type Subscription interface {
 	Write(data interface{})
 }
@@ -12,46 +19,257 @@ type ShardingAlgorithm interface {
 	Write(data interface{}, subscriptions []Subscription)
 }
 
+// compactLimit is how many shard groups a node keeps in a slice. Most
+// nodes have only a few, so they are cheaper to store and search in a
+// slice. Above the limit, a map is used instead.
+const compactLimit = 8
+
 type Node struct {
-	children      map[string]*Node
-	subscriptions map[string][]SubscriptionEnvelope
-	shards        map[int64]string
+	// children are sorted by key.
+	children list[childEntry]
+
+	// groups holds the subscriptions by shardID, sorted by shardID. Once
+	// there are more than compactLimit, groupMap holds them instead.
+	groups          []shardGroup
+	groupMap        map[string]*shardGroup
+	subscriptionLen int
+
+	data interface{}
+
+	// prioritized is the number of subscriptions with a non-zero priority.
+	prioritized int
+
+	// patterns holds the keys of the children that are patterns (see
+	// IsPattern), so they can be found without iterating every child.
+	patterns []string
+
+	// index is derived from the children. It is cleared when they change.
+	index interface{}
+
+	// stats is shared with the node's clones (see EnableStats).
+	stats *Stats
+}
+
+type childEntry struct {
+	key  string
+	node *Node
+}
+
+// shardGroup holds the subscriptions with the same shardID. The
+// subscriptions without a shardID are chunked (see list), while those of a
+// shard group are kept whole so that they can be written as one.
+type shardGroup struct {
+	shardID string
+	s       list[SubscriptionEnvelope]
+}
+
+func newShardGroup(shardID string) shardGroup {
+	return shardGroup{
+		shardID: shardID,
+		s:       list[SubscriptionEnvelope]{whole: shardID != ""},
+	}
+}
+
+// forEach passes each chunk of the group's subscriptions to f.
+func (g *shardGroup) forEach(f func(shardID string, s []SubscriptionEnvelope)) {
+	if g.s.chunks == nil {
+		if len(g.s.one) > 0 {
+			f(g.shardID, g.s.one)
+		}
+		return
+	}
+
+	for _, chunk := range g.s.chunks {
+		f(g.shardID, chunk)
+	}
+}
+
+// Stats counts how a node is used by publishes. It is shared by a node and
+// its clones, so the counts survive changes to the tree.
+type Stats struct {
+	Traversed atomic.Int64
+	Delivered atomic.Int64
+	Dropped   atomic.Int64
+}
+
+// IsPattern reports whether the key is a pattern rather than a literal
+// segment. Patterns start with a NUL byte.
+func IsPattern(key string) bool {
+	return len(key) > 0 && key[0] == 0
 }
 
 type SubscriptionEnvelope struct {
 	Subscription
-	id int64
+	id       int64
+	priority int
+}
+
+func (e SubscriptionEnvelope) ID() int64 {
+	return e.id
+}
+
+// Priority returns the priority that was set with SetPriority.
+func (e SubscriptionEnvelope) Priority() int {
+	return e.priority
 }
 
 func New() *Node {
-	return &Node{
-		children:      make(map[string]*Node),
-		subscriptions: make(map[string][]SubscriptionEnvelope),
-		shards:        make(map[int64]string),
+	return &Node{}
+}
+
+// Clone returns a copy of the node that can be modified without affecting
+// the original. The children are shared with the original. Changing the
+// copy only copies the parts of it that are changed (see list).
+func (n *Node) Clone() *Node {
+	c := &Node{
+		children:        n.children.clone(),
+		subscriptionLen: n.subscriptionLen,
+		data:            n.data,
+		prioritized:     n.prioritized,
+		patterns:        append([]string(nil), n.patterns...),
+		stats:           n.stats,
+	}
+
+	if len(n.groupMap) > compactLimit {
+		c.groupMap = make(map[string]*shardGroup, len(n.groupMap))
+		for shardID, g := range n.groupMap {
+			c.groupMap[shardID] = &shardGroup{shardID: shardID, s: g.s.clone()}
+		}
+		return c
+	}
+
+	for _, g := range n.groupMap {
+		c.groups = append(c.groups, shardGroup{shardID: g.shardID, s: g.s.clone()})
+	}
+	for _, g := range n.groups {
+		c.groups = append(c.groups, shardGroup{shardID: g.shardID, s: g.s.clone()})
+	}
+	if n.groupMap != nil {
+		slices.SortFunc(c.groups, func(a, b shardGroup) int {
+			return strings.Compare(a.shardID, b.shardID)
+		})
+	}
+
+	return c
+}
+
+// Data returns the value that was stored with SetData.
+func (n *Node) Data() interface{} {
+	if n == nil {
+		return nil
 	}
+
+	return n.data
+}
+
+// SetData stores an arbitrary value with the node.
+func (n *Node) SetData(d interface{}) {
+	n.data = d
 }
 
+// EnableStats gives the node Stats if it does not have them yet.
+func (n *Node) EnableStats() {
+	if n.stats == nil {
+		n.stats = &Stats{}
+	}
+}
+
+// Stats returns the node's Stats. It returns nil if they are not enabled.
+func (n *Node) Stats() *Stats {
+	if n == nil {
+		return nil
+	}
+
+	return n.stats
+}
+
+// Index returns the value that was stored with SetIndex, unless the
+// children have changed since.
+func (n *Node) Index() interface{} {
+	if n == nil {
+		return nil
+	}
+
+	return n.index
+}
+
+// SetIndex stores a value that is derived from the node's children (e.g.,
+// a structure to look them up with). It is cleared when a child is added or
+// removed and is not copied by Clone.
+func (n *Node) SetIndex(index interface{}) {
+	n.index = index
+}
 func (n *Node) AddChild(key string) *Node {
 	if n == nil {
 		return nil
 	}
 
-	if child, ok := n.children[key]; ok {
+	if child := n.FetchChild(key); child != nil {
 		return child
 	}
 
 	child := New()
-	n.children[key] = child
+	n.SetChild(key, child)
 	return child
 }
 
+// SetChild adds or replaces the child. A replaced child keeps the key it
+// was added with (which might be interned).
+func (n *Node) SetChild(key string, child *Node) {
+	p := n.searchChild(key)
+	if c, ok := n.children.get(p); ok && c.key == key {
+		n.children.set(p, childEntry{key: c.key, node: child})
+	} else {
+		if IsPattern(key) {
+			n.patterns = append(n.patterns, key)
+		}
+		n.children.insert(p, childEntry{key: key, node: child})
+	}
+	n.index = nil
+}
+
+// searchChild returns the position of the child, or where it would be
+// inserted if there is no such child.
+// It is on the path of every publish, so it does not use list.search.
+func (n *Node) searchChild(key string) pos {
+	c := 0
+	if chunks := n.children.chunks; chunks != nil {
+		lo, hi := 0, len(chunks)
+		for lo < hi {
+			m := int(uint(lo+hi) >> 1)
+			if chunk := chunks[m]; chunk[len(chunk)-1].key < key {
+				lo = m + 1
+			} else {
+				hi = m
+			}
+		}
+		if lo == len(chunks) {
+			return n.children.end()
+		}
+		c = lo
+	}
+
+	chunk := n.children.chunk(c)
+	lo, hi := 0, len(chunk)
+	for lo < hi {
+		m := int(uint(lo+hi) >> 1)
+		if chunk[m].key < key {
+			lo = m + 1
+		} else {
+			hi = m
+		}
+	}
+	return pos{chunk: c, i: lo}
+}
+
 func (n *Node) FetchChild(key string) *Node {
 	if n == nil {
 		return nil
 	}
 
-	if child, ok := n.children[key]; ok {
-		return child
+	p := n.searchChild(key)
+	if chunk := n.children.chunk(p.chunk); p.i < len(chunk) && chunk[p.i].key == key {
+		return chunk[p.i].node
 	}
 
 	return nil
@@ -62,25 +280,87 @@ func (n *Node) DeleteChild(key string) {
 		return
 	}
 
-	delete(n.children, key)
+	p := n.searchChild(key)
+	if c, ok := n.children.get(p); !ok || c.key != key {
+		return
+	}
+
+	if IsPattern(key) {
+		for i, x := range n.patterns {
+			if x == key {
+				n.patterns = append(n.patterns[:i:i], n.patterns[i+1:]...)
+				break
+			}
+		}
+	}
+
+	n.children.delete(p)
+	n.index = nil
+}
+
+// PatternLen returns the number of children that are patterns.
+func (n *Node) PatternLen() int {
+	if n == nil {
+		return 0
+	}
+
+	return len(n.patterns)
+}
+
+// Pattern returns the ith child that is a pattern, in the order they were
+// added.
+func (n *Node) Pattern(i int) (string, *Node) {
+	key := n.patterns[i]
+	return key, n.FetchChild(key)
+}
+
+// ForEachChild passes each child to f in order of their keys.
+func (n *Node) ForEachChild(f func(key string, child *Node)) {
+	if n == nil {
+		return
+	}
+
+	n.children.forEachChunk(func(chunk []childEntry) {
+		for _, c := range chunk {
+			f(c.key, c.node)
+		}
+	})
 }
 
 func (n *Node) ChildLen() int {
-	return len(n.children)
+	if n == nil {
+		return 0
+	}
+
+	return n.children.len()
 }
 
+// AddSubscription adds the subscription with a new ID. IDs are assigned in
+// increasing order.
 func (n *Node) AddSubscription(s Subscription, shardID string) int64 {
 	if n == nil {
 		return 0
 	}
 
-	id := rand.Int63()
-	n.shards[id] = shardID
-	n.subscriptions[shardID] = append(n.subscriptions[shardID], SubscriptionEnvelope{
+	id := atomic.AddInt64(&lastID, 1)
+	n.AddSubscriptionWithID(s, shardID, id)
+	return id
+}
+
+// AddSubscriptionWithID adds the subscription with an ID that was
+// previously returned by AddSubscription (e.g., when moving a subscription
+// between nodes).
+func (n *Node) AddSubscriptionWithID(s Subscription, shardID string, id int64) {
+	if n == nil {
+		return
+	}
+
+	g := n.group(shardID)
+	g.s.insert(g.s.end(), SubscriptionEnvelope{
 		Subscription: s,
 		id:           id,
 	})
-	return id
+	n.subscriptionLen++
 }
 
 func (n *Node) DeleteSubscription(id int64) {
@@ -88,37 +368,220 @@ func (n *Node) DeleteSubscription(id int64) {
 		return
 	}
 
-	shardID, ok := n.shards[id]
+	g, p, ok := n.findSubscription(id)
 	if !ok {
 		return
 	}
 
-	delete(n.shards, id)
+	if ss, _ := g.s.get(p); ss.priority != 0 {
+		n.prioritized--
+	}
+	g.s.delete(p)
+	n.subscriptionLen--
+
+	if g.s.len() == 0 {
+		n.deleteGroup(g.shardID)
+	}
+}
+
+// findSubscription returns the group of the subscription with the ID and
+// its position within it.
+func (n *Node) findSubscription(id int64) (*shardGroup, pos, bool) {
+	match := func(ss SubscriptionEnvelope) bool {
+		return ss.id == id
+	}
 
-	s := n.subscriptions[shardID]
-	for i, ss := range s {
-		if ss.id != id {
-			continue
+	for _, g := range n.groupMap {
+		if p, ok := g.s.find(match); ok {
+			return g, p, true
 		}
+	}
+
+	for i := range n.groups {
+		if p, ok := n.groups[i].s.find(match); ok {
+			return &n.groups[i], p, true
+		}
+	}
+
+	return nil, pos{}, false
+}
+
+// group returns the group with the shardID. It is added if it is missing.
+// It switches to a map once the slice would exceed compactLimit.
+func (n *Node) group(shardID string) *shardGroup {
+	if n.groupMap != nil {
+		g, ok := n.groupMap[shardID]
+		if !ok {
+			ng := newShardGroup(shardID)
+			g = &ng
+			n.groupMap[shardID] = g
+		}
+		return g
+	}
+
+	i, ok := n.searchGroup(shardID)
+	if ok {
+		return &n.groups[i]
+	}
+
+	if len(n.groups) < compactLimit {
+		n.groups = slices.Insert(n.groups, i, newShardGroup(shardID))
+		return &n.groups[i]
+	}
+
+	n.groupMap = make(map[string]*shardGroup, 2*compactLimit)
+	for _, g := range n.groups {
+		n.groupMap[g.shardID] = &g
+	}
+	n.groups = nil
+	return n.group(shardID)
+}
+
+// deleteGroup removes the group with the shardID.
+func (n *Node) deleteGroup(shardID string) {
+	if n.groupMap != nil {
+		delete(n.groupMap, shardID)
+		return
+	}
+
+	if i, ok := n.searchGroup(shardID); ok {
+		n.groups = slices.Delete(n.groups, i, i+1)
+	}
+}
+
+// searchGroup returns the index of the group in the sorted slice, or where
+// it would be inserted if there is no such group.
+func (n *Node) searchGroup(shardID string) (int, bool) {
+	return slices.BinarySearchFunc(n.groups, shardID, func(g shardGroup, shardID string) int {
+		return strings.Compare(g.shardID, shardID)
+	})
+}
 
-		n.subscriptions[shardID] = append(s[:i], s[i+1:]...)
+// SetPriority sets the priority of the subscription. The subscriptions with
+// the same shardID are kept in order of their priority (highest first) and
+// then of when their priority was set.
+func (n *Node) SetPriority(id int64, priority int) {
+	if n == nil {
+		return
+	}
+
+	g, p, ok := n.findSubscription(id)
+	if !ok {
+		return
 	}
 
-	if len(n.subscriptions[shardID]) == 0 {
-		delete(n.subscriptions, shardID)
+	ss, _ := g.s.get(p)
+	if ss.priority != 0 {
+		n.prioritized--
 	}
+	if priority != 0 {
+		n.prioritized++
+	}
+
+	ss.priority = priority
+	g.s.delete(p)
+	g.s.insert(g.s.search(func(x SubscriptionEnvelope) bool {
+		return x.priority < priority
+	}), ss)
 }
 
 func (n *Node) SubscriptionLen() int {
-	return len(n.shards)
+	if n == nil {
+		return 0
+	}
+
+	return n.subscriptionLen
 }
 
+// ForEachSubscription passes the subscriptions to f by shardID. The
+// subscriptions without a shardID might be passed in several calls.
 func (n *Node) ForEachSubscription(f func(shardID string, s []SubscriptionEnvelope)) {
 	if n == nil {
 		return
 	}
 
-	for shardID, s := range n.subscriptions {
-		f(shardID, s)
+	for _, g := range n.groupMap {
+		g.forEach(f)
+	}
+
+	for i := range n.groups {
+		n.groups[i].forEach(f)
+	}
+}
+
+// ForEachSubscriptionSorted is like ForEachSubscriptionByPriority, but the
+// order never depends on map iteration. Without priorities, the
+// subscriptions without a shardID are passed to f first and then the shard
+// groups in order of their shardIDs.
+func (n *Node) ForEachSubscriptionSorted(f func(shardID string, s []SubscriptionEnvelope)) {
+	if n == nil {
+		return
+	}
+
+	// With priorities, the groups are sorted anyway. Without a map, they
+	// are already in order (and "" sorts first).
+	if n.prioritized > 0 || n.groupMap == nil {
+		n.ForEachSubscriptionByPriority(f)
+		return
+	}
+
+	shardIDs := make([]string, 0, len(n.groupMap))
+	for shardID := range n.groupMap {
+		shardIDs = append(shardIDs, shardID)
+	}
+	sort.Strings(shardIDs)
+
+	for _, shardID := range shardIDs {
+		n.groupMap[shardID].forEach(f)
+	}
+}
+
+// ForEachSubscriptionByPriority is like ForEachSubscription, but in order
+// of priority (highest first). So that they can be interleaved with the
+// shard groups, the subscriptions without a shardID are passed to f one at
+// a time. A shard group is ordered by its highest priority.
+func (n *Node) ForEachSubscriptionByPriority(f func(shardID string, s []SubscriptionEnvelope)) {
+	if n == nil {
+		return
+	}
+
+	if n.prioritized == 0 {
+		n.ForEachSubscription(f)
+		return
+	}
+
+	type group struct {
+		shardID string
+		s       []SubscriptionEnvelope
+	}
+
+	var groups []group
+	n.ForEachSubscription(func(shardID string, s []SubscriptionEnvelope) {
+		if shardID != "" {
+			groups = append(groups, group{shardID: shardID, s: s})
+			return
+		}
+
+		for i := range s {
+			groups = append(groups, group{s: s[i : i+1]})
+		}
+	})
+
+	// Each shardID's subscriptions are ordered by priority, so the first
+	// has the highest. Ties keep the order that the subscriptions without
+	// a shardID are in and otherwise go by shardID.
+	sort.SliceStable(groups, func(i, j int) bool {
+		a, b := groups[i].s[0].priority, groups[j].s[0].priority
+		if a != b {
+			return a > b
+		}
+		if groups[i].shardID == "" || groups[j].shardID == "" {
+			return groups[i].shardID == "" && groups[j].shardID != ""
+		}
+		return groups[i].shardID < groups[j].shardID
+	})
+
+	for _, g := range groups {
+		f(g.shardID, g.s)
 	}
 }
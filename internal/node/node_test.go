@@ -1,12 +1,14 @@
 package node_test
 
 import (
+	"fmt"
+	"math/rand"
 	"testing"
 
-	"github.com/apoydence/onpar"
-	. "github.com/apoydence/onpar/expect"
-	. "github.com/apoydence/onpar/matchers"
 	"github.com/apoydence/pubsub/internal/node"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
 )
 
 type TN struct {
@@ -68,6 +70,250 @@ func TestNode(t *testing.T) {
 		Expect(t, ss).To(Contain(s3))
 		Expect(t, t.n.SubscriptionLen()).To(Equal(2))
 	})
+
+	o.Spec("assigns increasing IDs", func(t TN) {
+		id1 := t.n.AddSubscription(spySubscription{id: "a"}, "")
+		id2 := t.n.AddSubscription(spySubscription{id: "b"}, "")
+		id3 := node.New().AddSubscription(spySubscription{id: "c"}, "")
+
+		Expect(t, id2).To(BeAbove(float64(id1)))
+		Expect(t, id3).To(BeAbove(float64(id2)))
+	})
+
+	o.Spec("adds a subscription with an existing ID", func(t TN) {
+		id := t.n.AddSubscription(spySubscription{id: "a"}, "")
+		t.n.DeleteSubscription(id)
+
+		c := t.n.AddChild("a")
+		c.AddSubscriptionWithID(spySubscription{id: "a"}, "", id)
+		Expect(t, c.SubscriptionLen()).To(Equal(1))
+
+		c.DeleteSubscription(id)
+		Expect(t, c.SubscriptionLen()).To(Equal(0))
+	})
+
+	o.Spec("tracks the children that are patterns", func(t TN) {
+		t.n.AddChild("a")
+		p1 := t.n.AddChild("\x00p1")
+		p2 := node.New()
+		t.n.SetChild("\x00p2", p2)
+		t.n.SetChild("\x00p2", p2)
+		Expect(t, t.n.PatternLen()).To(Equal(2))
+
+		key, c := t.n.Pattern(0)
+		Expect(t, key).To(Equal("\x00p1"))
+		Expect(t, c).To(Equal(p1))
+
+		c2 := t.n.Clone()
+		t.n.DeleteChild("\x00p1")
+		Expect(t, t.n.PatternLen()).To(Equal(1))
+		Expect(t, c2.PatternLen()).To(Equal(2))
+
+		key, _ = t.n.Pattern(0)
+		Expect(t, key).To(Equal("\x00p2"))
+	})
+
+	o.Spec("clones without affecting the original", func(t TN) {
+		a := t.n.AddChild("a")
+		id := t.n.AddSubscription(spySubscription{id: "a"}, "")
+		t.n.AddSubscription(spySubscription{id: "b"}, "")
+		t.n.SetData("some-data")
+
+		c := t.n.Clone()
+		c.DeleteSubscription(id)
+		c.AddSubscription(spySubscription{id: "c"}, "")
+		c.SetChild("b", node.New())
+
+		Expect(t, c.FetchChild("a")).To(Equal(a))
+		Expect(t, c.Data()).To(Equal("some-data"))
+		Expect(t, c.ChildLen()).To(Equal(2))
+		Expect(t, t.n.ChildLen()).To(Equal(1))
+
+		var ss []node.Subscription
+		t.n.ForEachSubscription(func(id string, s []node.SubscriptionEnvelope) {
+			for _, x := range s {
+				ss = append(ss, x.Subscription)
+			}
+		})
+		Expect(t, ss).To(Equal([]node.Subscription{
+			spySubscription{id: "a"},
+			spySubscription{id: "b"},
+		}))
+	})
+
+	o.Spec("orders subscriptions by priority", func(t TN) {
+		t.n.AddSubscription(spySubscription{id: "a"}, "")
+		b := t.n.AddSubscription(spySubscription{id: "b"}, "")
+		c := t.n.AddSubscription(spySubscription{id: "c"}, "")
+		d := t.n.AddSubscription(spySubscription{id: "d"}, "x")
+		t.n.SetPriority(c, 2)
+		t.n.SetPriority(b, 2)
+		t.n.SetPriority(d, 5)
+
+		var (
+			shardIDs []string
+			ss       []node.Subscription
+		)
+		t.n.ForEachSubscriptionByPriority(func(id string, s []node.SubscriptionEnvelope) {
+			shardIDs = append(shardIDs, id)
+			for _, x := range s {
+				ss = append(ss, x.Subscription)
+			}
+		})
+		Expect(t, shardIDs).To(Equal([]string{"x", "", "", ""}))
+		Expect(t, ss).To(Equal([]node.Subscription{
+			spySubscription{id: "d"},
+			spySubscription{id: "c"},
+			spySubscription{id: "b"},
+			spySubscription{id: "a"},
+		}))
+
+		t.n.DeleteSubscription(d)
+		t.n.SetPriority(c, 0)
+		t.n.SetPriority(b, 0)
+
+		ss = nil
+		t.n.ForEachSubscriptionByPriority(func(id string, s []node.SubscriptionEnvelope) {
+			for _, x := range s {
+				ss = append(ss, x.Subscription)
+			}
+		})
+		Expect(t, ss).To(Equal([]node.Subscription{
+			spySubscription{id: "a"},
+			spySubscription{id: "c"},
+			spySubscription{id: "b"},
+		}))
+	})
+
+	o.Spec("orders shard groups by shardID", func(t TN) {
+		t.n.AddSubscription(spySubscription{id: "a"}, "z")
+		t.n.AddSubscription(spySubscription{id: "b"}, "")
+		t.n.AddSubscription(spySubscription{id: "c"}, "y")
+		t.n.AddSubscription(spySubscription{id: "d"}, "")
+
+		var (
+			shardIDs []string
+			ss       []node.Subscription
+		)
+		t.n.ForEachSubscriptionSorted(func(id string, s []node.SubscriptionEnvelope) {
+			shardIDs = append(shardIDs, id)
+			for _, x := range s {
+				ss = append(ss, x.Subscription)
+			}
+		})
+		Expect(t, shardIDs).To(Equal([]string{"", "y", "z"}))
+		Expect(t, ss).To(Equal([]node.Subscription{
+			spySubscription{id: "b"},
+			spySubscription{id: "d"},
+			spySubscription{id: "c"},
+			spySubscription{id: "a"},
+		}))
+	})
+
+	o.Spec("keeps many children in order", func(t TN) {
+		var keys []string
+		for i := 0; i < 300; i++ {
+			keys = append(keys, fmt.Sprintf("%03d", i))
+		}
+		for _, i := range rand.Perm(len(keys)) {
+			t.n.AddChild(keys[i])
+		}
+
+		c := t.n.Clone()
+		for _, key := range keys[100:200] {
+			c.DeleteChild(key)
+		}
+		Expect(t, c.ChildLen()).To(Equal(200))
+		Expect(t, t.n.ChildLen()).To(Equal(300))
+		Expect(t, c.FetchChild("250")).To(Equal(t.n.FetchChild("250")))
+		Expect(t, c.FetchChild("150") == nil).To(BeTrue())
+
+		var got []string
+		c.ForEachChild(func(key string, _ *node.Node) {
+			got = append(got, key)
+		})
+		Expect(t, got).To(Equal(append(keys[:100:100], keys[200:]...)))
+	})
+
+	o.Spec("keeps many subscriptions in order", func(t TN) {
+		var ids []int64
+		for i := 0; i < 300; i++ {
+			ids = append(ids, t.n.AddSubscription(spySubscription{id: fmt.Sprint(i)}, ""))
+		}
+
+		c := t.n.Clone()
+		c.SetPriority(ids[250], 1)
+		for _, id := range ids[:200] {
+			c.DeleteSubscription(id)
+		}
+		Expect(t, c.SubscriptionLen()).To(Equal(100))
+		Expect(t, t.n.SubscriptionLen()).To(Equal(300))
+
+		var ss []string
+		c.ForEachSubscriptionSorted(func(id string, s []node.SubscriptionEnvelope) {
+			for _, x := range s {
+				ss = append(ss, x.Subscription.(spySubscription).id)
+			}
+		})
+		Expect(t, ss).To(HaveLen(100))
+		Expect(t, ss[0]).To(Equal("250"))
+		Expect(t, ss[1]).To(Equal("200"))
+		Expect(t, ss[99]).To(Equal("299"))
+	})
+
+	o.Spec("keeps many children", func(t TN) {
+		var keys []string
+		for i := 0; i < 20; i++ {
+			key := fmt.Sprintf("%02d", i)
+			keys = append(keys, key)
+			t.n.AddChild(key)
+		}
+		Expect(t, t.n.ChildLen()).To(Equal(20))
+
+		for _, key := range keys[2:] {
+			t.n.DeleteChild(key)
+		}
+		c := t.n.Clone()
+		Expect(t, c.ChildLen()).To(Equal(2))
+		Expect(t, c.FetchChild("01")).To(Equal(t.n.FetchChild("01")))
+
+		var got []string
+		c.ForEachChild(func(key string, _ *node.Node) {
+			got = append(got, key)
+		})
+		Expect(t, got).To(Equal(keys[:2]))
+	})
+
+	o.Spec("keeps many subscriptions", func(t TN) {
+		var ids []int64
+		for i := 0; i < 20; i++ {
+			ids = append(ids, t.n.AddSubscription(spySubscription{id: fmt.Sprint(i)}, fmt.Sprint(i%10)))
+		}
+		t.n.AddSubscription(spySubscription{id: "a"}, "")
+		Expect(t, t.n.SubscriptionLen()).To(Equal(21))
+
+		for _, id := range ids[1:] {
+			t.n.DeleteSubscription(id)
+		}
+		c := t.n.Clone()
+		c.SetPriority(ids[0], 1)
+		Expect(t, c.SubscriptionLen()).To(Equal(2))
+
+		var ss []node.Subscription
+		c.ForEachSubscriptionSorted(func(id string, s []node.SubscriptionEnvelope) {
+			for _, x := range s {
+				ss = append(ss, x.Subscription)
+			}
+		})
+		Expect(t, ss).To(Equal([]node.Subscription{
+			spySubscription{id: "0"},
+			spySubscription{id: "a"},
+		}))
+
+		c.DeleteSubscription(ids[0])
+		Expect(t, c.SubscriptionLen()).To(Equal(1))
+		Expect(t, t.n.SubscriptionLen()).To(Equal(2))
+	})
 }
 
 type spySubscription struct {
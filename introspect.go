@@ -0,0 +1,79 @@
+package pubsub
+
+import (
+	"sort"
+
+	"github.com/apoydence/pubsub/internal/node"
+)
+
+// Subscriptions returns the number of subscriptions that subscribed with
+// exactly the given path.
+func (s *PubSub) Subscriptions(path ...string) int {
+	n := s.tree.Load().root
+	for _, p := range path {
+		n = n.FetchChild(p)
+	}
+
+	return n.SubscriptionLen()
+}
+
+// ShardGroups returns the number of subscriptions for each shardID that
+// subscribed with exactly the given path. Subscriptions without a shardID
+// are counted with the empty shardID.
+func (s *PubSub) ShardGroups(path ...string) map[string]int {
+	n := s.tree.Load().root
+	for _, p := range path {
+		n = n.FetchChild(p)
+	}
+
+	m := make(map[string]int)
+	n.ForEachSubscription(func(shardID string, ss []node.SubscriptionEnvelope) {
+		m[shardID] += len(ss)
+	})
+
+	return m
+}
+
+// Paths returns each path that has at least one subscription.
+func (s *PubSub) Paths() [][]string {
+	var paths [][]string
+	s.Walk(func(path []string, subCount int) {
+		if subCount > 0 {
+			paths = append(paths, path)
+		}
+	})
+
+	return paths
+}
+
+// Walk invokes f for each node in the subscription tree with the node's path
+// and how many subscriptions it has. Parents are visited before their
+// children and siblings are visited in sorted order. The given path is not
+// reused by subsequent invocations.
+//
+// Walk visits the version of the subscription tree from when it was
+// invoked, so f may subscribe to or unsubscribe from the PubSub.
+func (s *PubSub) Walk(f func(path []string, subCount int)) {
+	walk(s.tree.Load().root, nil, func(path []string, n *node.Node) {
+		f(path, n.SubscriptionLen())
+	})
+}
+
+// walk visits n and its descendants. Each path is a new slice.
+func walk(n *node.Node, path []string, f func(path []string, n *node.Node)) {
+	f(path, n)
+
+	var keys []string
+	n.ForEachChild(func(key string, child *node.Node) {
+		keys = append(keys, key)
+	})
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		childPath := make([]string, len(path)+1)
+		copy(childPath, path)
+		childPath[len(path)] = key
+
+		walk(n.FetchChild(key), childPath, f)
+	}
+}
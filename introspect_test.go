@@ -0,0 +1,81 @@
+package pubsub_test
+
+import (
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+func TestPubSubIntrospection(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+	o.BeforeEach(func(t *testing.T) TPS {
+		p := pubsub.New()
+		p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"a", "b"}))
+		p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"a", "b"}), pubsub.WithShardID("1"))
+		p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"a", "c", "d"}))
+		p.Subscribe(newSpySubscrption())
+
+		return TPS{
+			T: t,
+			p: p,
+		}
+	})
+
+	o.Spec("it counts the subscriptions at a path", func(t TPS) {
+		Expect(t, t.p.Subscriptions()).To(Equal(1))
+		Expect(t, t.p.Subscriptions("a")).To(Equal(0))
+		Expect(t, t.p.Subscriptions("a", "b")).To(Equal(2))
+		Expect(t, t.p.Subscriptions("x", "y")).To(Equal(0))
+	})
+
+	o.Spec("it counts the subscriptions of each shard group", func(t TPS) {
+		Expect(t, t.p.ShardGroups("a", "b")).To(Equal(map[string]int{"": 1, "1": 1}))
+		Expect(t, t.p.ShardGroups("x")).To(HaveLen(0))
+	})
+
+	o.Spec("it describes the subscriptions at a path", func(t TPS) {
+		sub := newSpySubscrption()
+		t.p.Subscribe(sub, pubsub.WithPath([]string{"a", "b"}), pubsub.WithMetadata(map[string]string{"client": "x"}), pubsub.WithPriority(1))
+
+		infos := t.p.SubscriptionInfos("a", "b")
+		Expect(t, infos).To(HaveLen(3))
+		Expect(t, infos[0]).To(Equal(pubsub.SubscriptionInfo{
+			Subscription: sub,
+			Path:         []string{"a", "b"},
+			Metadata:     map[string]string{"client": "x"},
+		}))
+		Expect(t, infos[2].ShardID).To(Equal("1"))
+		Expect(t, t.p.SubscriptionInfos("x")).To(HaveLen(0))
+	})
+
+	o.Spec("it returns each path with subscriptions", func(t TPS) {
+		Expect(t, t.p.Paths()).To(Equal([][]string{
+			nil,
+			{"a", "b"},
+			{"a", "c", "d"},
+		}))
+	})
+
+	o.Spec("it walks the tree", func(t TPS) {
+		var paths [][]string
+		var counts []int
+		t.p.Walk(func(path []string, subCount int) {
+			paths = append(paths, path)
+			counts = append(counts, subCount)
+		})
+
+		Expect(t, paths).To(Equal([][]string{
+			nil,
+			{"a"},
+			{"a", "b"},
+			{"a", "c"},
+			{"a", "c", "d"},
+		}))
+		Expect(t, counts).To(Equal([]int{1, 0, 2, 0, 1}))
+	})
+}
@@ -0,0 +1,122 @@
+package pubsub
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidJSONPointer is returned when a JSON pointer can not be parsed.
+var ErrInvalidJSONPointer = errors.New("invalid JSON pointer")
+
+// JSONPointerTraverser returns a TreeTraverser for decoded JSON (e.g., a
+// map[string]interface{} from json.Unmarshal). It builds the path from the
+// values that the given JSON pointers (RFC 6901) refer to. Each pointer is
+// a segment of the path, in the given order. For example, with
+// JSONPointerTraverser("/region", "/order/kind"), the data
+// {"region": "us", "order": {"kind": "created"}} is published to
+// []string{"us", "created"}.
+//
+// Strings are used as they are, numbers are formatted with Number (so they
+// can be matched by Range) and booleans as "true" or "false". The traversal
+// ends at a pointer that does not refer to one of those (e.g., a missing
+// member, null or an object).
+func JSONPointerTraverser(pointers ...string) (TreeTraverser, error) {
+	j := &jsonTraverser{
+		pointers: make([][]string, len(pointers)),
+		next:     make([]TreeTraverser, len(pointers)+1),
+	}
+	for i, p := range pointers {
+		tokens, err := parseJSONPointer(p)
+		if err != nil {
+			return nil, err
+		}
+		j.pointers[i] = tokens
+	}
+
+	j.next[len(pointers)] = TreeTraverserFunc(func(interface{}, []string) Paths {
+		return EmptyPaths
+	})
+	for i := len(pointers) - 1; i >= 0; i-- {
+		j.next[i] = TreeTraverserFunc(func(data interface{}, currentPath []string) Paths {
+			return j.traverse(data, i)
+		})
+	}
+
+	return j.next[0], nil
+}
+
+type jsonTraverser struct {
+	pointers [][]string
+
+	// next[i] is the TreeTraverser for pointers[i].
+	next []TreeTraverser
+}
+
+func (j *jsonTraverser) traverse(data interface{}, i int) Paths {
+	v := data
+	for _, token := range j.pointers[i] {
+		switch x := v.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if v, ok = x[token]; !ok {
+				return EmptyPaths
+			}
+		case []interface{}:
+			idx, err := strconv.Atoi(token)
+			if err != nil || idx < 0 || idx >= len(x) {
+				return EmptyPaths
+			}
+			v = x[idx]
+		default:
+			return EmptyPaths
+		}
+	}
+
+	var segment string
+	switch x := v.(type) {
+	case string:
+		segment = x
+	case float64:
+		segment = formatNumber(x)
+	case json.Number:
+		f, err := x.Float64()
+		if err != nil {
+			return EmptyPaths
+		}
+		segment = formatNumber(f)
+	case bool:
+		segment = strconv.FormatBool(x)
+	default:
+		return EmptyPaths
+	}
+
+	return NewPathsWithTraverser(FlatPaths{segment}, j.next[i+1])
+}
+
+// parseJSONPointer splits the JSON pointer into its reference tokens.
+func parseJSONPointer(p string) ([]string, error) {
+	if p == "" {
+		return nil, nil
+	}
+
+	if p[0] != '/' {
+		return nil, fmt.Errorf("%w %q: must start with '/'", ErrInvalidJSONPointer, p)
+	}
+
+	tokens := strings.Split(p[1:], "/")
+	for i, t := range tokens {
+		for j := 0; j < len(t); j++ {
+			if t[j] == '~' && (j+1 == len(t) || (t[j+1] != '0' && t[j+1] != '1')) {
+				return nil, fmt.Errorf("%w %q: '~' must be followed by '0' or '1'", ErrInvalidJSONPointer, p)
+			}
+		}
+
+		// ~1 is replaced before ~0 so that "~01" is "~1".
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+
+	return tokens, nil
+}
@@ -0,0 +1,96 @@
+package pubsub_test
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+func TestJSONPointerTraverser(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) TPS {
+		return TPS{
+			T:            t,
+			p:            pubsub.New(),
+			subscription: newSpySubscrption(),
+		}
+	})
+
+	decode := func(t *testing.T, s string) interface{} {
+		var v interface{}
+		if err := json.Unmarshal([]byte(s), &v); err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	o.Spec("it builds the path from the pointers", func(t TPS) {
+		t.p.Subscribe(t.subscription, pubsub.WithPath([]string{"us", "created", "b", "true"}))
+
+		traverser, err := pubsub.JSONPointerTraverser("/region", "/order/kind", "/tags/1", "/ok")
+		Expect(t, err).To(BeNil())
+
+		data := decode(t.T, `{"region": "us", "order": {"kind": "created"}, "tags": ["a", "b"], "ok": true}`)
+		t.p.Publish(data, traverser)
+		t.p.Publish(decode(t.T, `{"region": "eu", "order": {"kind": "created"}, "tags": ["a", "b"], "ok": true}`), traverser)
+
+		Expect(t, t.subscription.Data()).To(Equal([]interface{}{data}))
+	})
+
+	o.Spec("it formats numbers for Range", func(t TPS) {
+		t.p.Subscribe(t.subscription, pubsub.WithPath([]string{pubsub.Range(500, math.Inf(1))}))
+
+		traverser, err := pubsub.JSONPointerTraverser("/status")
+		Expect(t, err).To(BeNil())
+
+		t.p.Publish(decode(t.T, `{"status": 200}`), traverser)
+		t.p.Publish(decode(t.T, `{"status": 503}`), traverser)
+		t.p.Publish(map[string]interface{}{"status": json.Number("500")}, traverser)
+
+		Expect(t, t.subscription.Len()).To(Equal(2))
+	})
+
+	o.Spec("it unescapes the reference tokens", func(t TPS) {
+		t.p.Subscribe(t.subscription, pubsub.WithPath([]string{"x"}))
+
+		traverser, err := pubsub.JSONPointerTraverser("/a~1b/c~0d")
+		Expect(t, err).To(BeNil())
+
+		t.p.Publish(decode(t.T, `{"a/b": {"c~d": "x"}}`), traverser)
+
+		Expect(t, t.subscription.Len()).To(Equal(1))
+	})
+
+	o.Spec("it ends the traversal at a pointer without a value", func(t TPS) {
+		shallow := newSpySubscrption()
+		t.p.Subscribe(shallow, pubsub.WithPath([]string{"us"}))
+		t.p.Subscribe(t.subscription, pubsub.WithPath([]string{"us", pubsub.Any}))
+
+		traverser, err := pubsub.JSONPointerTraverser("/region", "/kind")
+		Expect(t, err).To(BeNil())
+
+		t.p.Publish(decode(t.T, `{"region": "us"}`), traverser)
+		t.p.Publish(decode(t.T, `{"region": "us", "kind": null}`), traverser)
+		t.p.Publish(decode(t.T, `{"region": "us", "kind": {"a": "b"}}`), traverser)
+
+		Expect(t, shallow.Len()).To(Equal(3))
+		Expect(t, t.subscription.Len()).To(Equal(0))
+	})
+
+	o.Spec("it returns an error for an invalid pointer", func(t TPS) {
+		_, err := pubsub.JSONPointerTraverser("region")
+		Expect(t, errors.Is(err, pubsub.ErrInvalidJSONPointer)).To(BeTrue())
+
+		_, err = pubsub.JSONPointerTraverser("/a~2")
+		Expect(t, errors.Is(err, pubsub.ErrInvalidJSONPointer)).To(BeTrue())
+	})
+}
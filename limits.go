@@ -0,0 +1,397 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/apoydence/pubsub/internal/node"
+)
+
+// ErrLimitExceeded is returned when a subscription would exceed one of the
+// PubSub's limits (see LimitOption).
+var ErrLimitExceeded = errors.New("subscription limit exceeded")
+
+// LimitOption bounds the size of the subscription tree, which protects
+// against untrusted clients exhausting memory. It can be given to New, or
+// to PubSub.Namespace to bound the Namespace's subtree. Limits lock the
+// whole tree while subscribing (see WithSubtreeLocking).
+type LimitOption interface {
+	PubSubOption
+	NamespaceOption
+}
+
+type limitConfigFunc func(*PubSub)
+
+func (f limitConfigFunc) configure(p *PubSub) {
+	f(p)
+}
+
+func (f limitConfigFunc) configureNamespace(ns *Namespace) {
+	ns.limits = append(ns.limits, f)
+}
+
+// WithMaxSubscriptions configures how many subscriptions the PubSub may
+// have. Subscriptions held by mounted PubSubs are not counted. Once it is
+// reached, a subscription is evicted (see WithEvictionPolicy) or, by
+// default, new subscriptions are rejected with ErrLimitExceeded (or
+// ErrQuotaExceeded for a Namespace).
+func WithMaxSubscriptions(n int) LimitOption {
+	return limitConfigFunc(func(p *PubSub) {
+		p.maxSubscriptions = n
+	})
+}
+
+// WithMaxDepth configures how many segments a subscription's path may
+// have. Subscriptions with longer paths are rejected with
+// ErrLimitExceeded.
+func WithMaxDepth(n int) LimitOption {
+	return limitConfigFunc(func(p *PubSub) {
+		p.maxDepth = n
+	})
+}
+
+// WithMaxChildrenPerNode configures how many children each node of the
+// subscription tree may have. Subscriptions that would add another child
+// to a full node are rejected with ErrLimitExceeded.
+func WithMaxChildrenPerNode(n int) LimitOption {
+	return limitConfigFunc(func(p *PubSub) {
+		p.maxChildren = n
+	})
+}
+
+// WithEvictionPolicy configures the PubSub to evict a subscription instead
+// of rejecting a new one once it has its maximum number of subscriptions
+// (see WithMaxSubscriptions). Evicted subscriptions are removed and their
+// Subscriptions are closed (see Closer and WithEvictionFunc).
+func WithEvictionPolicy(e EvictionPolicy) LimitOption {
+	return limitConfigFunc(func(p *PubSub) {
+		p.evictionPolicy = e
+	})
+}
+
+// EvictionPolicy chooses which subscription is evicted (see
+// WithEvictionPolicy). Other than for EvictOldest and
+// EvictLeastRecentlyUsed, which keep the subscriptions in order as they
+// are added, removed and written to, every subscription is made a
+// candidate each time, which requires walking the whole tree.
+type EvictionPolicy interface {
+	// Evict returns the index of the candidate to evict. If the index is
+	// out of range, the new subscription is rejected instead.
+	Evict(candidates []EvictionCandidate) int
+}
+
+// EvictionPolicyFunc is an adapter to allow ordinary functions to be an
+// EvictionPolicy.
+type EvictionPolicyFunc func(candidates []EvictionCandidate) int
+
+// Evict implements EvictionPolicy.
+func (f EvictionPolicyFunc) Evict(candidates []EvictionCandidate) int {
+	return f(candidates)
+}
+
+// EvictionCandidate describes a subscription that could be evicted.
+type EvictionCandidate struct {
+	SubscriptionInfo
+
+	// Subscribed is when the subscription was added.
+	Subscribed time.Time
+
+	// LastDelivered is when data was last written to the subscription. It
+	// is the zero time if nothing has been written to it.
+	LastDelivered time.Time
+}
+
+// EvictOldest returns an EvictionPolicy that evicts the subscription that
+// was added first.
+func EvictOldest() EvictionPolicy {
+	return evictOldest{}
+}
+
+type evictOldest struct{}
+
+// Evict implements EvictionPolicy.
+func (evictOldest) Evict(candidates []EvictionCandidate) int {
+	return oldest(candidates, func(c EvictionCandidate) time.Time {
+		return c.Subscribed
+	})
+}
+
+func (evictOldest) leastRecentlyUsed() bool {
+	return false
+}
+
+// EvictLeastRecentlyUsed returns an EvictionPolicy that evicts the
+// subscription that was written to the longest time ago. Subscriptions that
+// have never been written to are considered used when they were added.
+func EvictLeastRecentlyUsed() EvictionPolicy {
+	return evictLeastRecentlyUsed{}
+}
+
+type evictLeastRecentlyUsed struct{}
+
+// Evict implements EvictionPolicy.
+func (evictLeastRecentlyUsed) Evict(candidates []EvictionCandidate) int {
+	return oldest(candidates, EvictionCandidate.lastUsed)
+}
+
+func (evictLeastRecentlyUsed) leastRecentlyUsed() bool {
+	return true
+}
+
+// indexedEvictionPolicy is implemented by the EvictionPolicies that always
+// evict the first subscriber of an evictionIndex. They are not given the
+// candidates, so that evicting does not require walking the whole tree.
+type indexedEvictionPolicy interface {
+	EvictionPolicy
+	leastRecentlyUsed() bool
+}
+
+// lastUsed returns when the subscription was last written to or, if it
+// never was, when it was added.
+func (c EvictionCandidate) lastUsed() time.Time {
+	if c.LastDelivered.IsZero() {
+		return c.Subscribed
+	}
+	return c.LastDelivered
+}
+
+// oldest returns the index of the candidate with the earliest time (or -1
+// if there are none).
+func oldest(candidates []EvictionCandidate, at func(EvictionCandidate) time.Time) int {
+	idx := -1
+	for i, c := range candidates {
+		if idx < 0 || at(c).Before(at(candidates[idx])) {
+			idx = i
+		}
+	}
+	return idx
+}
+
+// limited reports if subscribing requires the whole tree to be locked to
+// enforce the limits.
+func (s *PubSub) limited() bool {
+	return s.maxSubscriptions > 0 || s.maxChildren > 0
+}
+
+// exceedsDepth reports if the path is longer than WithMaxDepth allows.
+func (s *PubSub) exceedsDepth(path []string) bool {
+	return s.maxDepth > 0 && len(path) > s.maxDepth
+}
+
+// exceedsChildren reports if adding the path to the tree would add a child
+// to a node that already has its maximum number of children.
+func (s *PubSub) exceedsChildren(t *treeTxn, path []string) bool {
+	if s.maxChildren <= 0 {
+		return false
+	}
+
+	n := t.root
+	for _, p := range path {
+		child := n.FetchChild(p)
+		if child == nil {
+			return n.ChildLen() >= s.maxChildren
+		}
+		n = child
+	}
+	return false
+}
+
+// limitLocked returns ErrLimitExceeded if the subscription at the path
+// would exceed a limit. If the PubSub has its maximum number of
+// subscriptions, it evicts one instead (if it has an EvictionPolicy). It
+// must be invoked while holding the write lock for the whole tree.
+func (s *PubSub) limitLocked(t *treeTxn, path []string) error {
+	if s.exceedsChildren(t, path) {
+		return ErrLimitExceeded
+	}
+
+	if s.maxSubscriptions <= 0 || s.subscriptions.Load() < int64(s.maxSubscriptions) {
+		return nil
+	}
+
+	if s.evictionPolicy == nil {
+		return ErrLimitExceeded
+	}
+
+	if s.evictionIndex != nil {
+		sr := s.evictionIndex.first()
+		if sr == nil {
+			return ErrLimitExceeded
+		}
+
+		s.evictLocked(t, sr, newSubscriptionInfo(node.SubscriptionEnvelope{Subscription: sr}, sr.shardID, sr.path))
+		return nil
+	}
+
+	srs, candidates := s.evictionCandidates(t)
+	i := s.evictionPolicy.Evict(candidates)
+	if i < 0 || i >= len(srs) {
+		return ErrLimitExceeded
+	}
+
+	s.evictLocked(t, srs[i], candidates[i].SubscriptionInfo)
+	return nil
+}
+
+// evictionCandidates returns every subscriber in the transaction's tree
+// along with its description.
+func (s *PubSub) evictionCandidates(t *treeTxn) ([]*subscriber, []EvictionCandidate) {
+	var (
+		srs        []*subscriber
+		candidates []EvictionCandidate
+	)
+	walk(t.root, nil, func(path []string, n *node.Node) {
+		s.forEachSubscription(n, func(shardID string, ss []node.SubscriptionEnvelope) {
+			for _, x := range ss {
+				sr := x.Subscription.(*subscriber)
+				if sr.primary != nil {
+					// The subscriber it writes to is a candidate instead.
+					continue
+				}
+				srs = append(srs, sr)
+				candidates = append(candidates, EvictionCandidate{
+					SubscriptionInfo: newSubscriptionInfo(x, shardID, path),
+					Subscribed:       sr.subscribed,
+					LastDelivered:    sr.lastDeliveredAt(),
+				})
+			}
+		})
+	})
+	return srs, candidates
+}
+
+// eviction is a subscriber that was removed to make room for others or
+// because it was idle.
+type eviction struct {
+	sr   *subscriber
+	info SubscriptionInfo
+}
+
+// evictLocked removes the subscriber. It is stopped and closed once the
+// transaction is done (see evict).
+func (s *PubSub) evictLocked(t *treeTxn, sr *subscriber, info SubscriptionInfo) {
+	if s.removeLocked(t, sr) {
+		t.evicted = append(t.evicted, eviction{sr: sr, info: info})
+	}
+}
+
+// evict stops and closes the subscribers that the transaction evicted once
+// the writes to them are done (see afterWrites). The eviction func (see
+// WithEvictionFunc) is then invoked for each of them.
+func (s *PubSub) evict(t *treeTxn) {
+	for _, e := range t.evicted {
+		e.sr.afterWrites(func() {
+			e.sr.evict()
+			if s.onEvict != nil {
+				s.onEvict(e.info)
+			}
+			s.debug(context.Background(), "evicted", pathAttr(e.info.Path))
+		})
+	}
+}
+
+// evictionIndex holds the subscribers that can be evicted (see
+// indexedEvictionPolicy) in the order they were added or, if lru is set,
+// last written to. The subscribers are linked through their evictPrev and
+// evictNext fields, which are guarded by mu.
+type evictionIndex struct {
+	lru bool
+
+	mu         sync.Mutex
+	head, tail *subscriber
+}
+
+// newEvictionIndex returns an evictionIndex if the PubSub evicts its
+// subscriptions with an indexedEvictionPolicy.
+func newEvictionIndex(p *PubSub) *evictionIndex {
+	e, ok := p.evictionPolicy.(indexedEvictionPolicy)
+	if !ok || p.maxSubscriptions <= 0 {
+		return nil
+	}
+	return &evictionIndex{lru: e.leastRecentlyUsed()}
+}
+
+// add appends the subscriber to the index.
+func (x *evictionIndex) add(sr *subscriber) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.pushLocked(sr)
+}
+
+// remove removes the subscriber from the index (if it is in it).
+func (x *evictionIndex) remove(sr *subscriber) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.removeLocked(sr)
+}
+
+// touch moves the subscriber to the end of the index if it is ordered by
+// when the subscribers were last written to.
+func (x *evictionIndex) touch(sr *subscriber) {
+	if !x.lru {
+		return
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if !sr.evictIndexed || x.tail == sr {
+		return
+	}
+	x.removeLocked(sr)
+	x.pushLocked(sr)
+}
+
+// first returns the subscriber that would be evicted (or nil if there are
+// none).
+func (x *evictionIndex) first() *subscriber {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	return x.head
+}
+
+// clear empties the index.
+func (x *evictionIndex) clear() {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	for sr := x.head; sr != nil; {
+		next := sr.evictNext
+		sr.evictPrev, sr.evictNext, sr.evictIndexed = nil, nil, false
+		sr = next
+	}
+	x.head, x.tail = nil, nil
+}
+
+func (x *evictionIndex) pushLocked(sr *subscriber) {
+	sr.evictPrev, sr.evictNext, sr.evictIndexed = x.tail, nil, true
+	if x.tail != nil {
+		x.tail.evictNext = sr
+	} else {
+		x.head = sr
+	}
+	x.tail = sr
+}
+
+func (x *evictionIndex) removeLocked(sr *subscriber) {
+	if !sr.evictIndexed {
+		return
+	}
+
+	if sr.evictPrev != nil {
+		sr.evictPrev.evictNext = sr.evictNext
+	} else {
+		x.head = sr.evictNext
+	}
+	if sr.evictNext != nil {
+		sr.evictNext.evictPrev = sr.evictPrev
+	} else {
+		x.tail = sr.evictPrev
+	}
+	sr.evictPrev, sr.evictNext, sr.evictIndexed = nil, nil, false
+}
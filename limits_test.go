@@ -0,0 +1,167 @@
+package pubsub_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+func TestPubSubLimits(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) TC {
+		return TC{
+			T:     t,
+			clock: newSpyClock(),
+		}
+	})
+
+	o.Spec("it rejects subscriptions beyond the maximum", func(t TC) {
+		t.p = pubsub.New(pubsub.WithMaxSubscriptions(2))
+
+		unsubscribe, err := t.p.SubscribeErr(newSpySubscrption(), pubsub.WithPath([]string{"a"}))
+		Expect(t, err).To(BeNil())
+		_, err = t.p.SubscribeErr(newSpySubscrption(), pubsub.WithPath([]string{"b"}))
+		Expect(t, err).To(BeNil())
+
+		sub := newSpyCloser()
+		_, err = t.p.SubscribeErr(sub, pubsub.WithPath([]string{"c"}))
+		Expect(t, err).To(Equal(pubsub.ErrLimitExceeded))
+		Expect(t, sub.closed).To(Equal(1))
+
+		unsubscribe()
+		_, err = t.p.SubscribeErr(newSpySubscrption(), pubsub.WithPath([]string{"c"}))
+		Expect(t, err).To(BeNil())
+		Expect(t, t.p.Paths()).To(Equal([][]string{{"b"}, {"c"}}))
+	})
+
+	o.Spec("it rejects paths that are too deep", func(t TC) {
+		t.p = pubsub.New(pubsub.WithMaxDepth(2))
+
+		_, err := t.p.SubscribeErr(newSpySubscrption(), pubsub.WithPath([]string{"a", "b"}))
+		Expect(t, err).To(BeNil())
+		_, err = t.p.SubscribeErr(newSpySubscrption(), pubsub.WithPath([]string{"a", "b", "c"}))
+		Expect(t, err).To(Equal(pubsub.ErrLimitExceeded))
+	})
+
+	o.Spec("it rejects paths that add too many children to a node", func(t TC) {
+		t.p = pubsub.New(pubsub.WithMaxChildrenPerNode(2))
+
+		_, err := t.p.SubscribeErr(newSpySubscrption(), pubsub.WithPath([]string{"a", "x"}))
+		Expect(t, err).To(BeNil())
+		_, err = t.p.SubscribeErr(newSpySubscrption(), pubsub.WithPath([]string{"a", "y"}))
+		Expect(t, err).To(BeNil())
+		_, err = t.p.SubscribeErr(newSpySubscrption(), pubsub.WithPath([]string{"a", "y", "z"}))
+		Expect(t, err).To(BeNil())
+		_, err = t.p.SubscribeErr(newSpySubscrption(), pubsub.WithPath([]string{"a", "z"}))
+		Expect(t, err).To(Equal(pubsub.ErrLimitExceeded))
+		_, err = t.p.SubscribeErr(newSpySubscrption(), pubsub.WithPath([]string{"b"}))
+		Expect(t, err).To(BeNil())
+	})
+
+	o.Spec("it does not move subscriptions beyond the limits", func(t TC) {
+		t.p = pubsub.New(pubsub.WithMaxDepth(1))
+
+		h := t.p.SubscribeHandle(newSpySubscrption(), pubsub.WithPath([]string{"a"}))
+		Expect(t, h.Move([]string{"a", "b"})).To(Equal(pubsub.ErrLimitExceeded))
+		Expect(t, t.p.Paths()).To(Equal([][]string{{"a"}}))
+
+		Expect(t, h.Move([]string{"b"})).To(BeNil())
+		Expect(t, t.p.Paths()).To(Equal([][]string{{"b"}}))
+	})
+
+	o.Spec("it evicts the oldest subscription", func(t TC) {
+		t.p = pubsub.New(
+			pubsub.WithClock(t.clock),
+			pubsub.WithMaxSubscriptions(2),
+			pubsub.WithEvictionPolicy(pubsub.EvictOldest()),
+		)
+
+		oldest := newSpyCloser()
+		t.p.Subscribe(oldest, pubsub.WithPath([]string{"a"}))
+		t.clock.advance(time.Second)
+		t.p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"b"}))
+		t.clock.advance(time.Second)
+
+		_, err := t.p.SubscribeErr(newSpySubscrption(), pubsub.WithPath([]string{"c"}))
+		Expect(t, err).To(BeNil())
+		Expect(t, oldest.closed).To(Equal(1))
+		Expect(t, t.p.Paths()).To(Equal([][]string{{"b"}, {"c"}}))
+
+		t.p.Publish(1, pubsub.LinearTreeTraverser([]string{"a"}))
+		Expect(t, oldest.Len()).To(Equal(0))
+	})
+
+	o.Spec("it evicts the least recently used subscription", func(t TC) {
+		t.p = pubsub.New(
+			pubsub.WithClock(t.clock),
+			pubsub.WithMaxSubscriptions(2),
+			pubsub.WithEvictionPolicy(pubsub.EvictLeastRecentlyUsed()),
+		)
+
+		t.p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"a"}))
+		t.clock.advance(time.Second)
+		t.p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"b"}))
+		t.clock.advance(time.Second)
+		t.p.Publish(1, pubsub.LinearTreeTraverser([]string{"a"}))
+		t.clock.advance(time.Second)
+
+		t.p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"c"}))
+		Expect(t, t.p.Paths()).To(Equal([][]string{{"a"}, {"c"}}))
+	})
+
+	o.Spec("it keeps evicting in order as subscriptions come and go", func(t TC) {
+		t.p = pubsub.New(
+			pubsub.WithClock(t.clock),
+			pubsub.WithMaxSubscriptions(3),
+			pubsub.WithEvictionPolicy(pubsub.EvictOldest()),
+		)
+
+		t.p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"a"}))
+		unsubscribe := t.p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"b"}))
+		t.p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"c"}))
+		unsubscribe()
+
+		t.p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"d"}))
+		Expect(t, t.p.Paths()).To(Equal([][]string{{"a"}, {"c"}, {"d"}}))
+
+		t.p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"e"}))
+		t.p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"f"}))
+		Expect(t, t.p.Paths()).To(Equal([][]string{{"d"}, {"e"}, {"f"}}))
+	})
+
+	o.Spec("it rejects the subscription if the policy does not choose one", func(t TC) {
+		var candidates []pubsub.EvictionCandidate
+		t.p = pubsub.New(
+			pubsub.WithMaxSubscriptions(1),
+			pubsub.WithEvictionPolicy(pubsub.EvictionPolicyFunc(func(cs []pubsub.EvictionCandidate) int {
+				candidates = cs
+				return -1
+			})),
+		)
+
+		t.p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"a"}), pubsub.WithMetadata(map[string]string{"client": "x"}))
+		_, err := t.p.SubscribeErr(newSpySubscrption(), pubsub.WithPath([]string{"b"}))
+		Expect(t, err).To(Equal(pubsub.ErrLimitExceeded))
+
+		Expect(t, candidates).To(HaveLen(1))
+		Expect(t, candidates[0].Path).To(Equal([]string{"a"}))
+		Expect(t, candidates[0].Metadata).To(Equal(map[string]string{"client": "x"}))
+	})
+
+	o.Spec("it limits a Namespace", func(t TC) {
+		t.p = pubsub.New()
+		ns, err := t.p.Namespace("a", pubsub.WithMaxDepth(1))
+		Expect(t, err).To(BeNil())
+
+		_, err = ns.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"x", "y"}))
+		Expect(t, err).To(Equal(pubsub.ErrQuotaExceeded))
+		Expect(t, ns.Stats().RejectedSubscriptions).To(Equal(1))
+	})
+}
@@ -0,0 +1,50 @@
+package pubsub
+
+import (
+	"context"
+	"log/slog"
+)
+
+// WithLogger configures a PubSub to log debug-level events to the given
+// Logger:
+//
+//   - "subscribed" and "unsubscribed" when a subscription is added or
+//     removed
+//   - "evicted" when a subscription is evicted (see WithEvictionPolicy and
+//     WithIdleEviction)
+//   - "dropped" when data is dropped because a subscription's queue is full
+//     (see OverflowStrategy)
+//   - "no match" when published data did not match any subscription
+//   - "panicked" when a Subscription (or anything else invoked while
+//     publishing) panics. The panic is then resumed.
+//
+// The events of subscriptions have a "path" attribute with the path the
+// subscription subscribed with (or the one being traversed for a panic while
+// publishing), formatted with FormatTopic and '.' as the delimiter. By
+// default, nothing is logged.
+func WithLogger(l *slog.Logger) PubSubOption {
+	return pubsubConfigFunc(func(p *PubSub) {
+		p.logger = l
+	})
+}
+
+// pathAttr returns the "path" attribute of an event.
+func pathAttr(path []string) slog.Attr {
+	return slog.Any("path", logPath(path))
+}
+
+// logPath formats a path when it is logged.
+type logPath []string
+
+// LogValue implements slog.LogValuer.
+func (p logPath) LogValue() slog.Value {
+	return slog.StringValue(FormatTopic(p, '.'))
+}
+
+// debug logs the event if the PubSub has a logger.
+func (s *PubSub) debug(ctx context.Context, msg string, args ...interface{}) {
+	if s.logger == nil {
+		return
+	}
+	s.logger.DebugContext(ctx, msg, args...)
+}
@@ -0,0 +1,150 @@
+package pubsub_test
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+type TL struct {
+	*testing.T
+	h *spyHandler
+	p *pubsub.PubSub
+}
+
+func TestPubSubLogger(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) TL {
+		h := &spyHandler{}
+		return TL{
+			T: t,
+			h: h,
+			p: pubsub.New(pubsub.WithLogger(slog.New(h))),
+		}
+	})
+
+	o.Spec("it logs subscribes and unsubscribes", func(t TL) {
+		unsubscribe := t.p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"a", pubsub.Any}))
+		unsubscribe()
+
+		Expect(t, t.h.events()).To(Equal([]string{
+			"subscribed a.*",
+			"unsubscribed a.*",
+		}))
+	})
+
+	o.Spec("it logs publishes that do not match", func(t TL) {
+		t.p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"a"}))
+		t.p.Publish("x", pubsub.LinearTreeTraverser([]string{"a"}))
+		t.p.Publish("x", pubsub.LinearTreeTraverser([]string{"b"}))
+
+		Expect(t, t.h.events()).To(Equal([]string{
+			"subscribed a",
+			"no match ",
+		}))
+	})
+
+	o.Spec("it logs drops", func(t TL) {
+		block := make(chan struct{})
+		defer close(block)
+
+		t.p.Subscribe(pubsub.SubscriptionFunc(func(interface{}) {
+			<-block
+		}),
+			pubsub.WithPath([]string{"a"}),
+			pubsub.WithBufferSize(1),
+			pubsub.WithOverflowStrategy(pubsub.OverflowDrop),
+		)
+
+		// The first publish might be dequeued (and block) before the
+		// second is enqueued.
+		for i := 0; i < 3; i++ {
+			t.p.Publish(i, pubsub.LinearTreeTraverser([]string{"a"}))
+		}
+
+		Expect(t, t.h.events()).To(Contain("dropped a"))
+	})
+
+	o.Spec("it logs evictions", func(t TL) {
+		p := pubsub.New(
+			pubsub.WithLogger(slog.New(t.h)),
+			pubsub.WithMaxSubscriptions(1),
+			pubsub.WithEvictionPolicy(pubsub.EvictOldest()),
+		)
+		p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"a"}))
+		p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"b"}))
+
+		Expect(t, t.h.events()).To(Contain("evicted a"))
+	})
+
+	o.Spec("it logs panics and resumes them", func(t TL) {
+		t.p.Subscribe(pubsub.SubscriptionFunc(func(interface{}) {
+			panic("some-panic")
+		}), pubsub.WithPath([]string{"a", "b"}))
+
+		var r interface{}
+		func() {
+			defer func() { r = recover() }()
+			t.p.Publish("x", pubsub.LinearTreeTraverser([]string{"a", "b"}))
+		}()
+
+		Expect(t, r).To(Equal("some-panic"))
+		Expect(t, t.h.events()).To(Contain("panicked a.b"))
+	})
+
+	o.Spec("it does not log without a logger", func(t TL) {
+		p := pubsub.New()
+		p.Subscribe(newSpySubscrption())
+		p.Publish("x", pubsub.LinearTreeTraverser([]string{"a"}))
+
+		Expect(t, t.h.events()).To(HaveLen(0))
+	})
+}
+
+// spyHandler records each event as its message followed by its path.
+type spyHandler struct {
+	mu      sync.Mutex
+	records []string
+}
+
+func (h *spyHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+func (h *spyHandler) Handle(ctx context.Context, r slog.Record) error {
+	var path string
+	r.Attrs(func(a slog.Attr) bool {
+		if a.Key == "path" {
+			path = a.Value.Resolve().String()
+		}
+		return true
+	})
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records = append(h.records, r.Message+" "+path)
+	return nil
+}
+
+func (h *spyHandler) WithAttrs([]slog.Attr) slog.Handler {
+	return h
+}
+
+func (h *spyHandler) WithGroup(string) slog.Handler {
+	return h
+}
+
+func (h *spyHandler) events() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.records...)
+}
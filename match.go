@@ -0,0 +1,70 @@
+package pubsub
+
+import (
+	"context"
+
+	"github.com/apoydence/pubsub/internal/node"
+)
+
+// SubscriptionInfo describes a subscription (see Match and
+// SubscriptionInfos).
+type SubscriptionInfo struct {
+	// Subscription is the Subscription that was given to Subscribe.
+	Subscription Subscription
+
+	// Path is the path that was traversed to reach the subscription. It
+	// does not include Any or Rest. For SubscriptionInfos, it is the path
+	// the subscription subscribed with.
+	Path []string
+
+	// ShardID is the shardID the subscription was given (if any). Only one
+	// subscription for each shardID at a path would be written to.
+	ShardID string
+
+	// Metadata is the metadata the subscription was given (see
+	// WithMetadata). It is a copy.
+	Metadata map[string]string
+
+	// Name is the name the subscription was given (see WithName).
+	Name string
+}
+
+// Match traverses the subscription tree as if the data was being published
+// with the given TreeTraverser, but does not write to any subscriptions.
+// Instead it returns the subscriptions that would have been written to (or
+// considered for sharding) along with the path that reached them. Filters,
+// sampling and the other per-subscription options are not applied. It is
+// intended for debugging TreeTraversers.
+func (s *PubSub) Match(d interface{}, a TreeTraverser) []SubscriptionInfo {
+	t := s.acquire()
+	defer t.release()
+
+	if t.closed {
+		return nil
+	}
+
+	p := newPublish(context.Background(), d)
+	defer p.release()
+	p.dryRun = true
+	s.traversePublish(p, a, t.root)
+
+	return append([]SubscriptionInfo(nil), p.matches...)
+}
+
+// matchNode records the subscriptions at the node for a dry run.
+func (s *PubSub) matchNode(p *publish, n *node.Node, l []string) {
+	s.forEachSubscription(n, func(shardID string, ss []node.SubscriptionEnvelope) {
+		for _, x := range ss {
+			p.matches = append(p.matches, newSubscriptionInfo(x, shardID, l))
+		}
+	})
+}
+
+// matchMount adds the matches from the mounted PubSub, which are relative
+// to the mount at l.
+func (p *publish) matchMount(m *PubSub, a TreeTraverser, l []string) {
+	for _, info := range m.Match(p.data, a) {
+		info.Path = append(append([]string(nil), l...), info.Path...)
+		p.matches = append(p.matches, info)
+	}
+}
@@ -0,0 +1,75 @@
+package pubsub_test
+
+import (
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+func TestPubSubMatch(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+	o.BeforeEach(func(t *testing.T) TPS {
+		return TPS{
+			T:            t,
+			p:            pubsub.New(),
+			subscription: newSpySubscrption(),
+		}
+	})
+
+	o.Spec("it returns the subscriptions that would be written to", func(t TPS) {
+		a := newSpySubscrption()
+		b := newSpySubscrption()
+		t.p.Subscribe(a, pubsub.WithPath([]string{"a"}))
+		t.p.Subscribe(b, pubsub.WithPath([]string{"a", pubsub.Any}), pubsub.WithShardID("1"))
+		t.p.Subscribe(t.subscription, pubsub.WithPath([]string{"x"}))
+
+		infos := t.p.Match(1, pubsub.LinearTreeTraverser([]string{"a", "b"}))
+
+		Expect(t, infos).To(Equal([]pubsub.SubscriptionInfo{
+			{Subscription: a, Path: []string{"a"}},
+			{Subscription: b, Path: []string{"a", "b"}, ShardID: "1"},
+		}))
+		Expect(t, a.Len()).To(Equal(0))
+		Expect(t, b.Len()).To(Equal(0))
+	})
+
+	o.Spec("it returns the metadata of the subscriptions", func(t TPS) {
+		md := map[string]string{"client": "a"}
+		t.p.Subscribe(t.subscription, pubsub.WithMetadata(md))
+		md["client"] = "b"
+
+		infos := t.p.Match(1, pubsub.LinearTreeTraverser(nil))
+		Expect(t, infos).To(HaveLen(1))
+		Expect(t, infos[0].Metadata).To(Equal(map[string]string{"client": "a"}))
+	})
+
+	o.Spec("it returns the original subscription when intercepted", func(t TPS) {
+		p := pubsub.New(
+			pubsub.WithSubscribeInterceptor(func(path []string, next pubsub.Subscription) pubsub.Subscription {
+				return pubsub.SubscriptionFunc(next.Write)
+			}),
+		)
+		p.Subscribe(t.subscription)
+
+		infos := p.Match(1, pubsub.LinearTreeTraverser(nil))
+		Expect(t, infos).To(HaveLen(1))
+		Expect(t, infos[0].Subscription).To(Equal(t.subscription))
+	})
+
+	o.Spec("it includes subscriptions from mounted PubSubs", func(t TPS) {
+		child := pubsub.New()
+		Expect(t, t.p.Mount([]string{"a"}, child)).To(BeNil())
+		t.p.Subscribe(t.subscription, pubsub.WithPath([]string{"a", "b"}))
+
+		infos := t.p.Match(1, pubsub.LinearTreeTraverser([]string{"a", "b"}))
+
+		Expect(t, infos).To(Equal([]pubsub.SubscriptionInfo{
+			{Subscription: t.subscription, Path: []string{"a", "b"}},
+		}))
+	})
+}
@@ -0,0 +1,54 @@
+package pubsub
+
+import (
+	"maps"
+
+	"github.com/apoydence/pubsub/internal/node"
+)
+
+// WithMetadata attaches metadata to a subscription (e.g., which client owns
+// it). It is not used by the PubSub, but is returned with the subscription
+// by Match and SubscriptionInfos. The map is copied, so it may be changed
+// afterwards.
+func WithMetadata(md map[string]string) SubscribeOption {
+	md = maps.Clone(md)
+	return subscribeConfigFunc(func(c *subscribeConfig) {
+		c.metadata = md
+	})
+}
+
+// SubscriptionInfos returns the subscriptions that subscribed with exactly
+// the given path, in the order they are written to. This can be used with
+// Walk to dump the subscription tree.
+func (s *PubSub) SubscriptionInfos(path ...string) []SubscriptionInfo {
+	n := s.tree.Load().root
+	for _, p := range path {
+		n = n.FetchChild(p)
+	}
+
+	var infos []SubscriptionInfo
+	s.forEachSubscription(n, func(shardID string, ss []node.SubscriptionEnvelope) {
+		for _, x := range ss {
+			infos = append(infos, newSubscriptionInfo(x, shardID, path))
+		}
+	})
+
+	return infos
+}
+
+// newSubscriptionInfo describes the subscription. The path is copied.
+func newSubscriptionInfo(x node.SubscriptionEnvelope, shardID string, path []string) SubscriptionInfo {
+	info := SubscriptionInfo{
+		Subscription: x.Subscription,
+		Path:         append([]string(nil), path...),
+		ShardID:      shardID,
+	}
+
+	if sr, ok := x.Subscription.(*subscriber); ok {
+		info.Subscription = sr.orig
+		info.Metadata = maps.Clone(sr.metadata)
+		info.Name = sr.name
+	}
+
+	return info
+}
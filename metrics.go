@@ -0,0 +1,96 @@
+package pubsub
+
+import (
+	"context"
+	"time"
+)
+
+// Metrics is used to observe a PubSub. Its methods are invoked while the
+// PubSub is publishing or subscribing and must therefore be quick and must
+// not use the PubSub. They must be safe to access concurrently. The given
+// paths must not be modified.
+type Metrics interface {
+	// Published is invoked after each Publish with the number of
+	// subscriptions the data was written to (or enqueued for) and how long
+	// the Publish took.
+	Published(deliveries int, d time.Duration)
+
+	// Delivered is invoked each time data is written to a subscription with
+	// the path the subscription subscribed with.
+	Delivered(path []string)
+
+	// Dropped is invoked each time data is dropped instead of being written
+	// to a subscription (see OverflowStrategy) with the path the
+	// subscription subscribed with.
+	Dropped(path []string)
+
+	// Subscribed is invoked each time a subscription is added with its
+	// path.
+	Subscribed(path []string)
+
+	// Unsubscribed is invoked each time a subscription is removed with its
+	// path.
+	Unsubscribed(path []string)
+}
+
+// QueueMetrics may be implemented by a Metrics to observe the queues of
+// buffered subscriptions (see WithAsyncDelivery and WithOverflowStrategy),
+// e.g., to track how deep they are. Its methods are invoked with the path
+// the subscription subscribed with.
+type QueueMetrics interface {
+	// Enqueued is invoked each time data is added to a queue.
+	Enqueued(path []string)
+
+	// Dequeued is invoked each time data is removed from a queue, either
+	// to be written to the subscription or because it was dropped (see
+	// OverflowDropOldest).
+	Dequeued(path []string)
+}
+
+// WithMetrics configures a PubSub to report to the given Metrics.
+func WithMetrics(m Metrics) PubSubOption {
+	return pubsubConfigFunc(func(p *PubSub) {
+		p.metrics = m
+	})
+}
+
+// meteredSubscription reports each write to the Metrics.
+type meteredSubscription struct {
+	Subscription
+	path []string
+	m    Metrics
+}
+
+// Write implements Subscription.
+func (s meteredSubscription) Write(data interface{}) {
+	s.m.Delivered(s.path)
+	s.Subscription.Write(data)
+}
+
+// WritePath implements PathAwareSubscription. It is only used when the
+// wrapped Subscription implements it.
+func (s meteredSubscription) WritePath(data interface{}, path []string) {
+	s.m.Delivered(s.path)
+	s.Subscription.(PathAwareSubscription).WritePath(data, path)
+}
+
+// writeErr implements errWriter. It is only used when the wrapped
+// Subscription implements it.
+func (s meteredSubscription) writeErr(data interface{}) error {
+	s.m.Delivered(s.path)
+	return s.Subscription.(errWriter).writeErr(data)
+}
+
+func (s meteredSubscription) forward(ctx context.Context, src *PubSub, data interface{}) {
+	s.m.Delivered(s.path)
+	if f, ok := s.Subscription.(forwardingSubscription); ok {
+		f.forward(ctx, src, data)
+		return
+	}
+	s.Subscription.Write(data)
+}
+
+// Close implements Closer.
+func (s meteredSubscription) Close() {
+	closeSubscription(s.Subscription)
+}
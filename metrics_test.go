@@ -0,0 +1,136 @@
+package pubsub_test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+func TestPubSubMetrics(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.Spec("it reports subscribes and unsubscribes", func(t *testing.T) {
+		m := newSpyMetrics()
+		p := pubsub.New(pubsub.WithMetrics(m))
+		unsubscribe := p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"a", "b"}))
+		p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"c"}))
+		unsubscribe()
+		unsubscribe()
+		p.Close()
+
+		Expect(t, m.get("subscribed")).To(Equal([]string{"a.b", "c"}))
+		Expect(t, m.get("unsubscribed")).To(Equal([]string{"a.b", "c"}))
+	})
+
+	o.Spec("it reports publishes and deliveries", func(t *testing.T) {
+		m := newSpyMetrics()
+		p := pubsub.New(pubsub.WithMetrics(m))
+		p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"a", "b"}))
+		p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"a"}))
+
+		p.Publish("some-data", pubsub.LinearTreeTraverser([]string{"a", "b"}))
+
+		Expect(t, m.get("delivered")).To(Equal([]string{"a", "a.b"}))
+		Expect(t, m.deliveries).To(Equal([]int{2}))
+	})
+
+	o.Spec("it reports drops", func(t *testing.T) {
+		m := newSpyMetrics()
+		p := pubsub.New(pubsub.WithMetrics(m))
+		block := make(chan struct{})
+		defer close(block)
+		p.Subscribe(pubsub.SubscriptionFunc(func(interface{}) {
+			<-block
+		}),
+			pubsub.WithPath([]string{"a"}),
+			pubsub.WithBufferSize(1),
+			pubsub.WithOverflowStrategy(pubsub.OverflowDrop),
+		)
+
+		for i := 0; i < 5; i++ {
+			p.Publish(i, pubsub.LinearTreeTraverser([]string{"a"}))
+		}
+
+		Expect(t, func() int { return len(m.get("dropped")) }).To(ViaPolling(BeAbove(2)))
+		Expect(t, m.get("dropped")[0]).To(Equal("a"))
+	})
+
+	o.Spec("it reports queue depths", func(t *testing.T) {
+		m := newSpyMetrics()
+		p := pubsub.New(pubsub.WithMetrics(m))
+		block := make(chan struct{})
+		p.Subscribe(pubsub.SubscriptionFunc(func(interface{}) {
+			<-block
+		}),
+			pubsub.WithPath([]string{"a"}),
+			pubsub.WithBufferSize(2),
+			pubsub.WithOverflowStrategy(pubsub.OverflowDropOldest),
+		)
+
+		depth := func() int { return len(m.get("enqueued")) - len(m.get("dequeued")) }
+		p.Publish(0, pubsub.LinearTreeTraverser([]string{"a"}))
+		Expect(t, depth).To(ViaPolling(Equal(0)))
+
+		for i := 1; i < 5; i++ {
+			p.Publish(i, pubsub.LinearTreeTraverser([]string{"a"}))
+		}
+		Expect(t, depth()).To(Equal(2))
+		Expect(t, m.get("enqueued")[0]).To(Equal("a"))
+
+		close(block)
+		Expect(t, depth).To(ViaPolling(Equal(0)))
+	})
+}
+
+type spyMetrics struct {
+	mu         sync.Mutex
+	paths      map[string][]string
+	deliveries []int
+	times      []time.Duration
+}
+
+func newSpyMetrics() *spyMetrics {
+	return &spyMetrics{
+		paths: make(map[string][]string),
+	}
+}
+
+func (m *spyMetrics) Published(deliveries int, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deliveries = append(m.deliveries, deliveries)
+	m.times = append(m.times, d)
+}
+
+func (m *spyMetrics) durations() []time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]time.Duration(nil), m.times...)
+}
+
+func (m *spyMetrics) Delivered(path []string)    { m.add("delivered", path) }
+func (m *spyMetrics) Dropped(path []string)      { m.add("dropped", path) }
+func (m *spyMetrics) Subscribed(path []string)   { m.add("subscribed", path) }
+func (m *spyMetrics) Unsubscribed(path []string) { m.add("unsubscribed", path) }
+func (m *spyMetrics) Enqueued(path []string)     { m.add("enqueued", path) }
+func (m *spyMetrics) Dequeued(path []string)     { m.add("dequeued", path) }
+
+func (m *spyMetrics) add(name string, path []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.paths[name] = append(m.paths[name], strings.Join(path, "."))
+}
+
+func (m *spyMetrics) get(name string) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.paths[name]...)
+}
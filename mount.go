@@ -0,0 +1,112 @@
+package pubsub
+
+import (
+	"errors"
+
+	"github.com/apoydence/pubsub/internal/node"
+)
+
+// ErrPathInUse is returned when mounting a PubSub at a path that already
+// has subscriptions (or mounts) at or beneath it, or when moving a
+// subscription to a path that is at or beneath a mount.
+var ErrPathInUse = errors.New("path is already in use")
+
+// Mount delegates the subtree at the given path to the child PubSub. Any
+// subscriptions with a path at or beneath it are added to the child (with
+// the mounted path removed) and any data published beneath it is published
+// to the child. The child has its own lock, sharding algorithm and other
+// configuration, so it can be used to isolate a namespace from the rest of
+// the PubSub. Subscriptions above the path (including Any and Rest ones)
+// are unaffected.
+//
+// The path must not contain Any, Rest or other patterns (e.g., Regexp) and
+// must not already be in use.
+// Closing the PubSub does not close the child. TreeTraversers that are
+// handed to the child are given paths relative to the mount.
+func (s *PubSub) Mount(path []string, child *PubSub) error {
+	if len(path) == 0 {
+		return ErrPathInUse
+	}
+
+	for _, p := range path {
+		if node.IsPattern(p) {
+			return errors.New("mount path must not contain Any, Rest or other patterns")
+		}
+	}
+
+	t := s.lockTree(path)
+	defer s.unlockTree(t)
+
+	if t.closed {
+		return ErrClosed
+	}
+
+	n := t.root
+	for _, p := range path {
+		n = n.FetchChild(p)
+		if n == nil {
+			break
+		}
+
+		if n.Data() != nil {
+			return ErrPathInUse
+		}
+	}
+
+	if n != nil {
+		return ErrPathInUse
+	}
+
+	t.node(path).SetData(child)
+
+	return nil
+}
+
+// mountFor returns the mounted PubSub (if any) that the given path belongs
+// to, along with the path relative to it. The transaction must hold the
+// write lock for the path.
+func (t *treeTxn) mountFor(path []string) (*PubSub, []string, bool) {
+	n := t.root
+	for i, p := range path {
+		n = n.FetchChild(p)
+		if n == nil {
+			return nil, nil, false
+		}
+
+		if m, ok := n.Data().(*PubSub); ok {
+			return m, path[i+1:], true
+		}
+	}
+
+	return nil, nil, false
+}
+
+// pendingMount is a publish to a mounted PubSub that was deferred until
+// the traversal is done (see publish.deferWrites).
+type pendingMount struct {
+	m *PubSub
+	a TreeTraverser
+}
+
+// publishMounts publishes to the mounted PubSubs that were deferred.
+func (s *PubSub) publishMounts(p *publish) {
+	for _, pm := range p.mounts {
+		s.publishMount(p, pm.m, pm.a)
+	}
+}
+
+// publishMount publishes to the mounted PubSub and adds its results to the
+// publish. An error from the mounted PubSub (e.g., ErrClosed) is added to
+// the Errors, unless it is the publish's context that was done (which is
+// already returned by the publish).
+func (s *PubSub) publishMount(p *publish, m *PubSub, a TreeTraverser) {
+	r, err := m.publish(p.ctx, p.data, a, publishConfig{retain: p.retain, mounted: true})
+
+	p.result.Matched += r.Matched
+	p.result.Delivered += r.Delivered
+	p.result.Dropped += r.Dropped
+	p.result.Errors = append(p.result.Errors, r.Errors...)
+	if err != nil && p.ctx.Err() == nil {
+		p.result.Errors = append(p.result.Errors, err)
+	}
+}
@@ -0,0 +1,110 @@
+package pubsub_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+type TM struct {
+	*testing.T
+	p     *pubsub.PubSub
+	child *pubsub.PubSub
+}
+
+func TestPubSubMount(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+	o.BeforeEach(func(t *testing.T) TM {
+		p := pubsub.New()
+		child := pubsub.New()
+		Expect(t, p.Mount([]string{"a", "b"}, child)).To(BeNil())
+
+		return TM{
+			T:     t,
+			p:     p,
+			child: child,
+		}
+	})
+
+	o.Spec("it delegates subscriptions beneath the mount", func(t TM) {
+		sub := newSpySubscrption()
+		t.p.Subscribe(sub, pubsub.WithPath([]string{"a", "b", "c"}))
+
+		Expect(t, t.child.Subscriptions("c")).To(Equal(1))
+
+		r, err := t.p.PublishCtx(context.Background(), 1, pubsub.LinearTreeTraverser([]string{"a", "b", "c"}))
+		Expect(t, err).To(BeNil())
+		Expect(t, r.Delivered).To(Equal(1))
+		t.child.Publish(2, pubsub.LinearTreeTraverser([]string{"c"}))
+		t.p.Publish(3, pubsub.LinearTreeTraverser([]string{"a", "x"}))
+
+		Expect(t, sub.Data()).To(Equal([]interface{}{1, 2}))
+	})
+
+	o.Spec("it still writes to subscriptions above the mount", func(t TM) {
+		sub := newSpySubscrption()
+		t.p.Subscribe(sub, pubsub.WithPath([]string{"a"}))
+		t.p.Subscribe(sub, pubsub.WithPath([]string{"a", pubsub.Any, "c"}))
+
+		t.p.Publish(1, pubsub.LinearTreeTraverser([]string{"a", "b", "c"}))
+
+		Expect(t, sub.Data()).To(Equal([]interface{}{1, 1}))
+	})
+
+	o.Spec("it removes delegated subscriptions", func(t TM) {
+		sub := newSpySubscrption()
+		unsubscribe := t.p.Subscribe(sub, pubsub.WithPath([]string{"a", "b"}))
+		unsubscribe()
+
+		t.p.Publish(1, pubsub.LinearTreeTraverser([]string{"a", "b"}))
+
+		Expect(t, sub.Len()).To(Equal(0))
+		Expect(t, t.child.Paths()).To(HaveLen(0))
+	})
+
+	o.Spec("it keeps the mount when the subscriptions beneath it are removed", func(t TM) {
+		t.p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"a"}))()
+
+		sub := newSpySubscrption()
+		t.p.Subscribe(sub, pubsub.WithPath([]string{"a", "b"}))
+		Expect(t, t.child.Subscriptions()).To(Equal(1))
+	})
+
+	o.Spec("it returns an error if the path is in use", func(t TM) {
+		Expect(t, t.p.Mount([]string{"a", "b"}, pubsub.New())).To(Equal(pubsub.ErrPathInUse))
+		Expect(t, t.p.Mount([]string{"a", "b", "c"}, pubsub.New())).To(Equal(pubsub.ErrPathInUse))
+
+		t.p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"x"}))
+		Expect(t, t.p.Mount([]string{"x"}, pubsub.New())).To(Equal(pubsub.ErrPathInUse))
+		Expect(t, t.p.Mount([]string{"y", pubsub.Any}, pubsub.New())).To(Not(BeNil()))
+	})
+
+	o.Spec("it does not move subscriptions into the mount", func(t TM) {
+		sub := newSpySubscrption()
+		h := t.p.SubscribeHandle(sub, pubsub.WithPath([]string{"x"}))
+
+		Expect(t, h.Move([]string{"a", "b", "y"})).To(Equal(pubsub.ErrPathInUse))
+		Expect(t, h.Move([]string{"a", "b"})).To(Equal(pubsub.ErrPathInUse))
+		Expect(t, t.child.Subscriptions()).To(Equal(0))
+
+		t.p.Publish(1, pubsub.LinearTreeTraverser([]string{"x"}))
+		Expect(t, sub.Data()).To(Equal([]interface{}{1}))
+
+		Expect(t, h.Move([]string{"a", "c"})).To(BeNil())
+		Expect(t, t.p.Paths()).To(Contain([]string{"a", "c"}))
+	})
+
+	o.Spec("it returns the errors of the mount", func(t TM) {
+		t.child.Close()
+
+		r, err := t.p.PublishCtx(context.Background(), 1, pubsub.LinearTreeTraverser([]string{"a", "b", "c"}))
+		Expect(t, err).To(BeNil())
+		Expect(t, r.Errors).To(Equal([]error{pubsub.ErrClosed}))
+	})
+}
@@ -0,0 +1,118 @@
+package pubsub
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WithMQTTMatching configures a PubSub to match paths the way MQTT matches
+// topic filters:
+//
+//   - A subscription only receives data published to exactly its path
+//     instead of anything beneath it.
+//   - Any (MQTT's "+") matches a single segment.
+//   - Rest (MQTT's "#") matches any number of segments, including none, so
+//     []string{"sport", pubsub.Rest} receives data published to
+//     []string{"sport"} as well as beneath it.
+//   - Any and Rest do not match a first segment that starts with '$' (e.g.,
+//     "$SYS"), so such segments must be subscribed to explicitly.
+//
+// Retained and replayed data is matched the same way. Topic strings (see
+// SubscribeTopic and PublishTopic) are parsed with ParseMQTTTopic.
+func WithMQTTMatching() PubSubOption {
+	return pubsubConfigFunc(func(p *PubSub) {
+		p.mqtt = true
+	})
+}
+
+// ParseMQTTTopic splits an MQTT topic name or filter into a path at each
+// '/'. A segment of "+" is Any and a last segment of "#" is Rest. Unlike
+// ParseTopic, segments may be empty and there is no escaping, as with MQTT.
+func ParseMQTTTopic(topic string) ([]string, error) {
+	return parseMQTTTopic(topic, true)
+}
+
+func parseMQTTTopic(topic string, wildcards bool) ([]string, error) {
+	if topic == "" {
+		return nil, fmt.Errorf("%w %q: empty topic", ErrInvalidTopic, topic)
+	}
+
+	path := strings.Split(topic, "/")
+	for i, s := range path {
+		switch {
+		case s == "+" || s == "#":
+			if !wildcards {
+				return nil, fmt.Errorf("%w %q: wildcards are not allowed", ErrInvalidTopic, topic)
+			}
+
+			if s == "+" {
+				path[i] = Any
+				continue
+			}
+
+			if i != len(path)-1 {
+				return nil, fmt.Errorf("%w %q: '#' must be the last segment", ErrInvalidTopic, topic)
+			}
+			path[i] = Rest
+		case strings.ContainsAny(s, "+#"):
+			return nil, fmt.Errorf("%w %q: wildcards must be an entire segment", ErrInvalidTopic, topic)
+		}
+	}
+
+	return path, nil
+}
+
+// hidden reports whether the segment is excluded from Any and Rest matches
+// with WithMQTTMatching. depth is the number of segments before it.
+func (s *PubSub) hidden(depth int, segment string) bool {
+	return s.mqtt && depth == 0 && strings.HasPrefix(segment, "$")
+}
+
+// forEachHistoryMatch invokes f with each history node that matches the
+// subscription path.
+func (s *PubSub) forEachHistoryMatch(path []string, f func(n *historyNode)) {
+	if !s.mqtt {
+		s.history.forEachMatch(path, f)
+		return
+	}
+
+	s.history.forEachMQTTMatch(path, 0, s, f)
+}
+
+func (n *historyNode) forEachMQTTMatch(path []string, depth int, s *PubSub, f func(n *historyNode)) {
+	if n == nil {
+		return
+	}
+
+	if len(path) == 0 {
+		f(n)
+		return
+	}
+
+	switch path[0] {
+	case Any:
+		for segment, child := range n.children {
+			if !s.hidden(depth, segment) {
+				child.forEachMQTTMatch(path[1:], depth+1, s, f)
+			}
+		}
+	case Rest:
+		f(n)
+		for segment, child := range n.children {
+			if !s.hidden(depth, segment) {
+				child.forEach(f)
+			}
+		}
+	default:
+		if m := newSegmentMatcher(path[0]); m != nil {
+			for segment, child := range n.children {
+				if m.match(segment) {
+					child.forEachMQTTMatch(path[1:], depth+1, s, f)
+				}
+			}
+			return
+		}
+
+		n.children[path[0]].forEachMQTTMatch(path[1:], depth+1, s, f)
+	}
+}
@@ -0,0 +1,114 @@
+package pubsub_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+func TestPubSubMQTTMatching(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) TPS {
+		return TPS{
+			T:            t,
+			p:            pubsub.New(pubsub.WithMQTTMatching()),
+			subscription: newSpySubscrption(),
+		}
+	})
+
+	o.Spec("it only writes to exact matches", func(t TPS) {
+		t.p.SubscribeTopic(t.subscription, "sport/tennis")
+
+		t.p.PublishTopic(1, "sport")
+		t.p.PublishTopic(2, "sport/tennis")
+		t.p.PublishTopic(3, "sport/tennis/player1")
+
+		Expect(t, t.subscription.Data()).To(Equal([]interface{}{2}))
+	})
+
+	o.Spec("it matches a single level with +", func(t TPS) {
+		t.p.SubscribeTopic(t.subscription, "sport/+/player1")
+
+		t.p.PublishTopic(1, "sport/tennis/player1")
+		t.p.PublishTopic(2, "sport/tennis/player2")
+		t.p.PublishTopic(3, "sport/tennis")
+		t.p.PublishTopic(4, "sport/golf/player1/score")
+
+		Expect(t, t.subscription.Data()).To(Equal([]interface{}{1}))
+	})
+
+	o.Spec("it matches the parent and any levels beneath with #", func(t TPS) {
+		t.p.SubscribeTopic(t.subscription, "sport/#")
+
+		t.p.PublishTopic(1, "sport")
+		t.p.PublishTopic(2, "sport/tennis")
+		t.p.PublishTopic(3, "sport/tennis/player1")
+		t.p.PublishTopic(4, "music")
+
+		Expect(t, t.subscription.Data()).To(Equal([]interface{}{1, 2, 3}))
+	})
+
+	o.Spec("it does not match $ topics with leading wildcards", func(t TPS) {
+		all := newSpySubscrption()
+		any := newSpySubscrption()
+		sys := newSpySubscrption()
+		t.p.SubscribeTopic(all, "#")
+		t.p.SubscribeTopic(any, "+/broker")
+		t.p.SubscribeTopic(sys, "$SYS/#")
+
+		t.p.PublishTopic(1, "$SYS/broker")
+		t.p.PublishTopic(2, "home/broker")
+
+		Expect(t, all.Data()).To(Equal([]interface{}{2}))
+		Expect(t, any.Data()).To(Equal([]interface{}{2}))
+		Expect(t, sys.Data()).To(Equal([]interface{}{1}))
+	})
+
+	o.Spec("it writes retained data with MQTT matching", func(t TPS) {
+		t.p.PublishTopic(1, "sport", pubsub.WithRetain())
+		t.p.PublishTopic(2, "sport/tennis", pubsub.WithRetain())
+		t.p.PublishTopic(3, "$SYS/broker", pubsub.WithRetain())
+
+		exact := newSpySubscrption()
+		t.p.SubscribeTopic(exact, "sport")
+		Expect(t, exact.Data()).To(Equal([]interface{}{1}))
+
+		all := newSpySubscrption()
+		t.p.SubscribeTopic(all, "#")
+		Expect(t, all.Data()).To(HaveLen(2))
+		Expect(t, all.Data()).To(Not(Contain(3)))
+	})
+
+	o.Spec("it allows empty levels", func(t TPS) {
+		t.p.SubscribeTopic(t.subscription, "/a//b")
+		t.p.PublishTopic(1, "/a//b")
+
+		Expect(t, t.subscription.Data()).To(Equal([]interface{}{1}))
+	})
+}
+
+func TestParseMQTTTopic(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.Spec("it parses wildcards", func(t *testing.T) {
+		path, err := pubsub.ParseMQTTTopic("a/+/b/#")
+		Expect(t, err).To(BeNil())
+		Expect(t, path).To(Equal([]string{"a", pubsub.Any, "b", pubsub.Rest}))
+	})
+
+	o.Spec("it returns an error for invalid topics", func(t *testing.T) {
+		for _, topic := range []string{"", "a/#/b", "a+/b", "a/b#"} {
+			_, err := pubsub.ParseMQTTTopic(topic)
+			Expect(t, errors.Is(err, pubsub.ErrInvalidTopic)).To(BeTrue())
+		}
+	})
+}
@@ -0,0 +1,68 @@
+package pubsub
+
+// WithPaths configures a subscription to reside at several paths (see
+// WithPath) so that it can cover several branches of the tree. It is still
+// a single subscription: it has one Unsubscriber (or SubscriptionHandle) and
+// is written published data at most once per publish, even if several of
+// its paths match. It overrides any earlier WithPath and is overridden by a
+// later one. It does nothing if no paths are given.
+//
+// Each path counts towards the PubSub's limits (see LimitOption) and
+// towards the Matched of a PublishResult. Shard groups (see WithShardID)
+// are formed per path, so the at-most-once guarantee does not extend to
+// them. Moving the subscription (see SubscriptionHandle.Move) only moves
+// its first path. None of the paths may be within a mounted PubSub (see
+// Mount).
+func WithPaths(paths ...[]string) SubscribeOption {
+	return subscribeConfigFunc(func(c *subscribeConfig) {
+		if len(paths) == 0 {
+			return
+		}
+		c.path = paths[0]
+		c.aliases = paths[1:]
+	})
+}
+
+// addAliasLocked adds a subscriber at the path that writes to sr (see
+// WithPaths). It must be invoked while holding the write lock for the path
+// (see lockTree).
+func (s *PubSub) addAliasLocked(t *treeTxn, sr *subscriber, path []string) error {
+	if err := s.limitLocked(t, path); err != nil {
+		return err
+	}
+
+	n := t.node(path)
+	a := &subscriber{
+		orig:       sr.orig,
+		primary:    sr,
+		p:          s,
+		subscribed: sr.subscribed,
+		path:       path,
+		shardID:    sr.shardID,
+		priority:   sr.priority,
+		metadata:   sr.metadata,
+		once:       sr.once,
+	}
+	a.id = n.AddSubscription(a, a.shardID)
+	if a.priority != 0 {
+		n.SetPriority(a.id, a.priority)
+	}
+	a.subtree.Store(int32(s.subtreeOfPath(path)))
+	s.hooks.record(path, n.SubscriptionLen(), true)
+	s.subscriptions.Add(1)
+	if s.metrics != nil {
+		s.metrics.Subscribed(path)
+	}
+
+	sr.aliases = append(sr.aliases, a)
+	return nil
+}
+
+// aliasPaths returns the paths of the subscriber's aliases.
+func (s *subscriber) aliasPaths() [][]string {
+	paths := make([][]string, 0, len(s.aliases))
+	for _, a := range s.aliases {
+		paths = append(paths, a.path)
+	}
+	return paths
+}
@@ -0,0 +1,88 @@
+package pubsub_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+func TestPubSubWithPaths(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.Spec("it writes data published to any of the paths", func(t *testing.T) {
+		p := pubsub.New()
+		sub := newSpySubscrption()
+		p.Subscribe(sub, pubsub.WithPaths([]string{"a"}, []string{"b"}))
+
+		p.Publish(1, pubsub.LinearTreeTraverser([]string{"a"}))
+		p.Publish(2, pubsub.LinearTreeTraverser([]string{"b"}))
+		p.Publish(3, pubsub.LinearTreeTraverser([]string{"c"}))
+
+		Expect(t, sub.Data()).To(Equal([]interface{}{1, 2}))
+	})
+
+	o.Spec("it writes data at most once per publish", func(t *testing.T) {
+		for _, opts := range [][]pubsub.PubSubOption{
+			nil,
+			{pubsub.WithFanoutConcurrency(2)},
+			{pubsub.WithSubtreeLocking()},
+		} {
+			p := pubsub.New(opts...)
+			sub := newSpySubscrption()
+			p.Subscribe(sub, pubsub.WithPaths([]string{"a"}, []string{"a", "b"}, []string{pubsub.Any, "b"}))
+
+			r, _ := p.PublishCtx(context.Background(), 1, pubsub.LinearTreeTraverser([]string{"a", "b"}))
+			Expect(t, r.Matched).To(Equal(3))
+			Expect(t, r.Delivered).To(Equal(1))
+			Expect(t, sub.Data()).To(Equal([]interface{}{1}))
+		}
+	})
+
+	o.Spec("it writes the path that was traversed", func(t *testing.T) {
+		p := pubsub.New()
+		sub := newSpyPathSubscription()
+		p.Subscribe(sub, pubsub.WithPaths([]string{"a"}, []string{"b"}))
+
+		p.Publish(1, pubsub.LinearTreeTraverser([]string{"b", "c"}))
+		Expect(t, sub.paths()).To(Equal([][]string{{"b"}}))
+	})
+
+	o.Spec("it removes every path with a single unsubscribe", func(t *testing.T) {
+		p := pubsub.New(pubsub.WithSubtreeLocking())
+		unsubscribe := p.Subscribe(newSpySubscrption(), pubsub.WithPaths([]string{"a"}, []string{"b"}))
+		Expect(t, p.Paths()).To(HaveLen(2))
+
+		unsubscribe()
+		Expect(t, p.Paths()).To(HaveLen(0))
+	})
+
+	o.Spec("it removes every path once it is removed", func(t *testing.T) {
+		p := pubsub.New()
+		sub := newSpySubscrption()
+		p.Subscribe(sub, pubsub.WithPaths([]string{"a"}, []string{"b"}), pubsub.WithMaxDeliveries(1))
+
+		p.Publish(1, pubsub.LinearTreeTraverser([]string{"b"}))
+		Expect(t, p.Paths).To(ViaPolling(HaveLen(0)))
+	})
+
+	o.Spec("it does not add any path if one exceeds a limit", func(t *testing.T) {
+		p := pubsub.New(pubsub.WithMaxSubscriptions(2))
+		_, err := p.SubscribeErr(newSpySubscrption(), pubsub.WithPaths([]string{"a"}, []string{"b"}, []string{"c"}))
+		Expect(t, err).To(Equal(pubsub.ErrLimitExceeded))
+		Expect(t, p.Paths()).To(HaveLen(0))
+	})
+
+	o.Spec("it rejects paths within a mounted PubSub", func(t *testing.T) {
+		p := pubsub.New()
+		Expect(t, p.Mount([]string{"m"}, pubsub.New())).To(BeNil())
+
+		_, err := p.SubscribeErr(newSpySubscrption(), pubsub.WithPaths([]string{"a"}, []string{"m", "x"}))
+		Expect(t, err).To(Equal(pubsub.ErrPathInUse))
+	})
+}
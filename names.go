@@ -0,0 +1,117 @@
+package pubsub
+
+import (
+	"maps"
+	"sync"
+)
+
+// WithName names a subscription. Subscribing again with the same name
+// replaces the subscription: the old one is removed in the same version of
+// the subscription tree that the new one is added in, so each publish is
+// written to exactly one of them. This allows a durable consumer to
+// resubscribe (e.g., after reconnecting) without unsubscribing first. The
+// named subscription can be found with LookupName. Names are scoped to the
+// PubSub that Subscribe was invoked on.
+func WithName(name string) SubscribeOption {
+	return subscribeConfigFunc(func(c *subscribeConfig) {
+		c.name = name
+	})
+}
+
+// LookupName returns the subscription with the given name (see WithName).
+// If it was added to a mounted PubSub, its path is relative to the mount.
+func (s *PubSub) LookupName(name string) (SubscriptionInfo, bool) {
+	sr := s.names.get(name)
+	if sr == nil {
+		return SubscriptionInfo{}, false
+	}
+
+	// The path is guarded by the lock.
+	t := sr.p.lockSubscriber(sr)
+	info := SubscriptionInfo{
+		Subscription: sr.orig,
+		Path:         append([]string(nil), sr.path...),
+		ShardID:      sr.shardID,
+		Metadata:     maps.Clone(sr.metadata),
+		Name:         sr.name,
+	}
+	removed := sr.removed.Load()
+	sr.p.unlockTree(t)
+
+	return info, !removed
+}
+
+// UnsubscribeName removes the subscription with the given name (see
+// WithName). It returns false if there is not one.
+func (s *PubSub) UnsubscribeName(name string) bool {
+	sr := s.names.get(name)
+	if sr == nil {
+		return false
+	}
+	return sr.p.unsubscribe(sr)
+}
+
+// nameRegistry holds the named subscriptions of a PubSub.
+type nameRegistry struct {
+	mu sync.Mutex
+	m  map[string]*subscriber
+}
+
+func (r *nameRegistry) get(name string) *subscriber {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.m[name]
+}
+
+func (r *nameRegistry) set(sr *subscriber) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.m == nil {
+		r.m = make(map[string]*subscriber)
+	}
+	r.m[sr.name] = sr
+}
+
+// remove forgets the subscriber if it still has its name.
+func (r *nameRegistry) remove(sr *subscriber) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.m[sr.name] == sr {
+		delete(r.m, sr.name)
+	}
+}
+
+func (r *nameRegistry) clear() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	clear(r.m)
+}
+
+// replaceLocked removes the subscription with the given name (if any). It
+// must be invoked while holding the write lock for the entire tree. If the
+// subscription belongs to a mounted PubSub, it is removed from it
+// separately. The removed subscriber must be stopped once the writes to it
+// are done (see afterWrites).
+func (s *PubSub) replaceLocked(t *treeTxn, name string) *subscriber {
+	old := s.names.get(name)
+	if old == nil {
+		return nil
+	}
+
+	if old.p == s {
+		if !s.removeLocked(t, old) {
+			return nil
+		}
+		return old
+	}
+
+	mt := old.p.lockSubscriber(old)
+	removed := old.p.removeLocked(mt, old)
+	old.p.unlockTree(mt)
+	if !removed {
+		return nil
+	}
+	return old
+}
@@ -0,0 +1,99 @@
+package pubsub_test
+
+import (
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+func TestPubSubNames(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) TPS {
+		return TPS{
+			T:            t,
+			p:            pubsub.New(),
+			subscription: newSpySubscrption(),
+		}
+	})
+
+	o.Spec("it replaces a subscription with the same name", func(t TPS) {
+		old := newSpyCloser()
+		t.p.Subscribe(old, pubsub.WithName("consumer"), pubsub.WithPath([]string{"a"}))
+		t.p.Subscribe(t.subscription, pubsub.WithName("consumer"), pubsub.WithPath([]string{"b"}))
+
+		t.p.Publish(1, pubsub.LinearTreeTraverser([]string{"a"}))
+		t.p.Publish(2, pubsub.LinearTreeTraverser([]string{"b"}))
+
+		Expect(t, old.data).To(HaveLen(0))
+		Expect(t, t.subscription.Data()).To(Equal([]interface{}{2}))
+		Expect(t, t.p.Subscriptions("a")).To(Equal(0))
+		Expect(t, old.closed).To(Equal(0))
+	})
+
+	o.Spec("it looks up a subscription by name", func(t TPS) {
+		t.p.Subscribe(t.subscription,
+			pubsub.WithName("consumer"),
+			pubsub.WithPath([]string{"a"}),
+			pubsub.WithMetadata(map[string]string{"client": "x"}),
+		)
+
+		info, ok := t.p.LookupName("consumer")
+		Expect(t, ok).To(BeTrue())
+		Expect(t, info).To(Equal(pubsub.SubscriptionInfo{
+			Subscription: t.subscription,
+			Path:         []string{"a"},
+			Metadata:     map[string]string{"client": "x"},
+			Name:         "consumer",
+		}))
+
+		infos := t.p.Match(1, pubsub.LinearTreeTraverser([]string{"a"}))
+		Expect(t, infos).To(HaveLen(1))
+		Expect(t, infos[0].Name).To(Equal("consumer"))
+
+		_, ok = t.p.LookupName("other")
+		Expect(t, ok).To(BeFalse())
+	})
+
+	o.Spec("it forgets the name once unsubscribed", func(t TPS) {
+		unsubscribe := t.p.Subscribe(t.subscription, pubsub.WithName("consumer"))
+		unsubscribe()
+
+		_, ok := t.p.LookupName("consumer")
+		Expect(t, ok).To(BeFalse())
+		Expect(t, t.p.UnsubscribeName("consumer")).To(BeFalse())
+
+		t.p.Subscribe(t.subscription, pubsub.WithName("consumer"))
+		Expect(t, t.p.UnsubscribeName("consumer")).To(BeTrue())
+		Expect(t, t.p.Subscriptions()).To(Equal(0))
+	})
+
+	o.Spec("it replaces subscriptions in mounted PubSubs", func(t TPS) {
+		child := pubsub.New()
+		Expect(t, t.p.Mount([]string{"m"}, child)).To(BeNil())
+
+		t.p.Subscribe(newSpySubscrption(), pubsub.WithName("consumer"), pubsub.WithPath([]string{"m", "a"}))
+		t.p.Subscribe(t.subscription, pubsub.WithName("consumer"), pubsub.WithPath([]string{"b"}))
+
+		Expect(t, child.Subscriptions("a")).To(Equal(0))
+		info, ok := t.p.LookupName("consumer")
+		Expect(t, ok).To(BeTrue())
+		Expect(t, info.Path).To(Equal([]string{"b"}))
+	})
+
+	o.Spec("it replaces subscriptions in a Batch", func(t TPS) {
+		t.p.Subscribe(newSpySubscrption(), pubsub.WithName("consumer"), pubsub.WithPath([]string{"a"}))
+
+		b := t.p.Batch()
+		b.Subscribe(t.subscription, pubsub.WithName("consumer"), pubsub.WithPath([]string{"b"}))
+		b.Commit()
+
+		Expect(t, t.p.Subscriptions("a")).To(Equal(0))
+		Expect(t, t.p.Subscriptions("b")).To(Equal(1))
+	})
+}
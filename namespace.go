@@ -0,0 +1,280 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrQuotaExceeded is returned when a Namespace's quota does not allow a
+// subscription or publish.
+var ErrQuotaExceeded = errors.New("namespace quota exceeded")
+
+// NamespaceOption is used to configure a Namespace. Any LimitOption (e.g.,
+// WithMaxSubscriptions) is also a NamespaceOption.
+type NamespaceOption interface {
+	configureNamespace(*Namespace)
+}
+
+type namespaceConfigFunc func(*Namespace)
+
+func (f namespaceConfigFunc) configureNamespace(ns *Namespace) {
+	f(ns)
+}
+
+// WithPublishQuota configures how many publishes a Namespace may make
+// within each period (as measured by the PubSub's Clock). Publishing beyond
+// it returns ErrQuotaExceeded until the next period starts.
+func WithPublishQuota(n int, period time.Duration) NamespaceOption {
+	return namespaceConfigFunc(func(ns *Namespace) {
+		ns.publishQuota = n
+		ns.publishPeriod = period
+	})
+}
+
+// WithNamespaceMetrics configures a Namespace to report to the given
+// Metrics. The paths are relative to the Namespace.
+func WithNamespaceMetrics(m Metrics) NamespaceOption {
+	return namespaceConfigFunc(func(ns *Namespace) {
+		ns.metrics.next = m
+	})
+}
+
+// Namespace is an isolated subtree of a PubSub for a tenant. Its
+// subscriptions are only written data that is published to the Namespace
+// (or to the PubSub beneath the Namespace's name) and its quotas limit how
+// much the tenant may subscribe and publish. Paths given to a Namespace are
+// relative to it. It is returned by PubSub.Namespace and is safe to access
+// concurrently.
+type Namespace struct {
+	name string
+	p    *PubSub
+
+	metrics namespaceMetrics
+
+	// limits are given to the Namespace's PubSub.
+	limits []PubSubOption
+
+	publishMu     sync.Mutex
+	publishQuota  int
+	publishPeriod time.Duration
+	periodStart   time.Time
+	periodCount   int
+
+	published             atomic.Int64
+	rejectedPublishes     atomic.Int64
+	rejectedSubscriptions atomic.Int64
+}
+
+// Namespace returns the Namespace with the given name, creating it if it
+// does not exist yet. It is mounted (see Mount) at the path of the name, so
+// it returns ErrPathInUse if the path is already used by something else.
+// The options are only used when the Namespace is created.
+//
+// The Namespace's PubSub is configured like the PubSub at the time it is
+// created: its Clock, asynchronous delivery, Authorizer, interceptors,
+// sharding, ordered and deterministic delivery, fan-out, replay size,
+// Logger, Tracer, error handler, traversal limits and matching (e.g.,
+// WithMQTTMatching) are the same. The Authorizer and interceptors are given
+// paths relative to the Namespace. The rest is not inherited: the Metrics
+// are the Namespace's (see WithNamespaceMetrics), the limits are its
+// LimitOptions and the dead letter Subscription, expvars, idle timeout,
+// eviction, locking and the functions given to WithBeforePublish and
+// WithAfterPublish are the PubSub's alone, as publishes to the PubSub
+// already use them for the Namespace's subscriptions.
+func (s *PubSub) Namespace(name string, opts ...NamespaceOption) (*Namespace, error) {
+	s.namespacesMu.Lock()
+	defer s.namespacesMu.Unlock()
+
+	if ns, ok := s.namespaces[name]; ok {
+		return ns, nil
+	}
+
+	ns := &Namespace{name: name}
+	for _, o := range opts {
+		o.configureNamespace(ns)
+	}
+
+	ns.p = New(append([]PubSubOption{s.inherited(), WithMetrics(&ns.metrics)}, ns.limits...)...)
+	if err := s.Mount([]string{name}, ns.p); err != nil {
+		return nil, err
+	}
+
+	if s.namespaces == nil {
+		s.namespaces = make(map[string]*Namespace)
+	}
+	s.namespaces[name] = ns
+
+	return ns, nil
+}
+
+// inherited returns the option that configures a Namespace's PubSub like
+// the PubSub (see Namespace).
+func (s *PubSub) inherited() PubSubOption {
+	return pubsubConfigFunc(func(p *PubSub) {
+		p.clock = s.clock
+		p.asyncBufferSize = s.asyncBufferSize
+		p.overflow = s.overflow
+		p.authorizer = s.authorizer
+		p.publishInterceptors = slices.Clone(s.publishInterceptors)
+		p.subscribeInterceptors = slices.Clone(s.subscribeInterceptors)
+		p.sa = s.sa
+		p.crossNodeSharding = s.crossNodeSharding
+		p.ordered = s.ordered
+		p.deterministic = s.deterministic
+		p.rand = s.rand
+		p.fanout = s.fanout
+		p.replaySize = s.replaySize
+		p.logger = s.logger
+		p.tracer = s.tracer
+		p.errorHandler = s.errorHandler
+		p.maxTraversalDepth = s.maxTraversalDepth
+		p.traversalErr = s.traversalErr
+		p.mqtt = s.mqtt
+		p.topicDelim = s.topicDelim
+		p.dedupeSubscriptions = s.dedupeSubscriptions
+		p.profilerLabels = s.profilerLabels
+		p.nodeStats = s.nodeStats
+	})
+}
+
+// Name returns the Namespace's name.
+func (ns *Namespace) Name() string {
+	return ns.name
+}
+
+// Subscribe adds a subscription to the Namespace (see PubSub.SubscribeErr).
+// It returns ErrQuotaExceeded if the subscription would exceed one of the
+// Namespace's limits (see LimitOption).
+func (ns *Namespace) Subscribe(sub Subscription, opts ...SubscribeOption) (Unsubscriber, error) {
+	unsubscribe, err := ns.p.SubscribeErr(sub, opts...)
+	if err == ErrLimitExceeded {
+		ns.rejectedSubscriptions.Add(1)
+		return unsubscribe, ErrQuotaExceeded
+	}
+	return unsubscribe, err
+}
+
+// Publish publishes the data to the Namespace's subscriptions (see
+// PubSub.Publish). It returns ErrQuotaExceeded if the Namespace has used
+// its publish quota (see WithPublishQuota).
+func (ns *Namespace) Publish(d interface{}, a TreeTraverser, opts ...PublishOption) error {
+	_, err := ns.PublishCtx(context.Background(), d, a, opts...)
+	return err
+}
+
+// PublishCtx publishes the data to the Namespace's subscriptions (see
+// PubSub.PublishCtx). It returns ErrQuotaExceeded if the Namespace has used
+// its publish quota (see WithPublishQuota).
+func (ns *Namespace) PublishCtx(ctx context.Context, d interface{}, a TreeTraverser, opts ...PublishOption) (PublishResult, error) {
+	if !ns.allowPublish() {
+		ns.rejectedPublishes.Add(1)
+		return PublishResult{}, ErrQuotaExceeded
+	}
+
+	ns.published.Add(1)
+	return ns.p.PublishCtx(ctx, d, a, opts...)
+}
+
+// allowPublish reports if the publish quota allows another publish in the
+// current period.
+func (ns *Namespace) allowPublish() bool {
+	if ns.publishQuota <= 0 {
+		return true
+	}
+	now := ns.p.clock.Now()
+
+	ns.publishMu.Lock()
+	defer ns.publishMu.Unlock()
+
+	if ns.periodStart.IsZero() || now.Sub(ns.periodStart) >= ns.publishPeriod {
+		ns.periodStart = now
+		ns.periodCount = 0
+	}
+
+	if ns.periodCount >= ns.publishQuota {
+		return false
+	}
+	ns.periodCount++
+	return true
+}
+
+// NamespaceStats describes the usage of a Namespace.
+type NamespaceStats struct {
+	// Subscriptions is the number of subscriptions the Namespace has.
+	Subscriptions int
+
+	// Published is the number of publishes that were made with the
+	// Namespace.
+	Published int
+
+	// RejectedSubscriptions and RejectedPublishes are the number of
+	// subscribes and publishes that exceeded the Namespace's limits and
+	// quotas.
+	RejectedSubscriptions int
+	RejectedPublishes     int
+}
+
+// Stats returns the usage of the Namespace.
+func (ns *Namespace) Stats() NamespaceStats {
+	return NamespaceStats{
+		Subscriptions:         int(ns.metrics.subscriptions.Load()),
+		Published:             int(ns.published.Load()),
+		RejectedSubscriptions: int(ns.rejectedSubscriptions.Load()),
+		RejectedPublishes:     int(ns.rejectedPublishes.Load()),
+	}
+}
+
+// namespaceMetrics counts the subscriptions of a Namespace and reports to
+// the Metrics given to WithNamespaceMetrics (if any).
+type namespaceMetrics struct {
+	subscriptions atomic.Int64
+	next          Metrics
+}
+
+func (m *namespaceMetrics) Published(deliveries int, d time.Duration) {
+	if m.next != nil {
+		m.next.Published(deliveries, d)
+	}
+}
+
+func (m *namespaceMetrics) Delivered(path []string) {
+	if m.next != nil {
+		m.next.Delivered(path)
+	}
+}
+
+func (m *namespaceMetrics) Dropped(path []string) {
+	if m.next != nil {
+		m.next.Dropped(path)
+	}
+}
+
+func (m *namespaceMetrics) Subscribed(path []string) {
+	m.subscriptions.Add(1)
+	if m.next != nil {
+		m.next.Subscribed(path)
+	}
+}
+
+func (m *namespaceMetrics) Unsubscribed(path []string) {
+	m.subscriptions.Add(-1)
+	if m.next != nil {
+		m.next.Unsubscribed(path)
+	}
+}
+
+func (m *namespaceMetrics) Enqueued(path []string) {
+	if qm, ok := m.next.(QueueMetrics); ok {
+		qm.Enqueued(path)
+	}
+}
+
+func (m *namespaceMetrics) Dequeued(path []string) {
+	if qm, ok := m.next.(QueueMetrics); ok {
+		qm.Dequeued(path)
+	}
+}
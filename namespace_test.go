@@ -0,0 +1,144 @@
+package pubsub_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+func TestPubSubNamespace(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) TC {
+		clock := newSpyClock()
+		return TC{
+			T:            t,
+			p:            pubsub.New(pubsub.WithClock(clock)),
+			clock:        clock,
+			subscription: newSpySubscrption(),
+		}
+	})
+
+	namespace := func(t TC, name string, opts ...pubsub.NamespaceOption) *pubsub.Namespace {
+		ns, err := t.p.Namespace(name, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ns
+	}
+
+	o.Spec("it isolates the subscriptions of each namespace", func(t TC) {
+		a := namespace(t, "a")
+		b := namespace(t, "b")
+		Expect(t, namespace(t, "a")).To(Equal(a))
+
+		other := newSpySubscrption()
+		_, err := a.Subscribe(t.subscription, pubsub.WithPath([]string{"x"}))
+		Expect(t, err).To(BeNil())
+		_, err = b.Subscribe(other, pubsub.WithPath([]string{"x"}))
+		Expect(t, err).To(BeNil())
+
+		Expect(t, a.Publish(1, pubsub.LinearTreeTraverser([]string{"x"}))).To(BeNil())
+		t.p.Publish(2, pubsub.LinearTreeTraverser([]string{"b", "x"}))
+
+		Expect(t, t.subscription.Data()).To(Equal([]interface{}{1}))
+		Expect(t, other.Data()).To(Equal([]interface{}{2}))
+		Expect(t, t.p.Subscriptions("a", "x")).To(Equal(0))
+	})
+
+	o.Spec("it enforces the subscription quota", func(t TC) {
+		ns := namespace(t, "a", pubsub.WithMaxSubscriptions(2))
+
+		unsubscribe, err := ns.Subscribe(newSpySubscrption())
+		Expect(t, err).To(BeNil())
+		_, err = ns.Subscribe(newSpySubscrption())
+		Expect(t, err).To(BeNil())
+		_, err = ns.Subscribe(newSpySubscrption())
+		Expect(t, err).To(Equal(pubsub.ErrQuotaExceeded))
+
+		unsubscribe()
+		_, err = ns.Subscribe(newSpySubscrption())
+		Expect(t, err).To(BeNil())
+
+		Expect(t, ns.Stats()).To(Equal(pubsub.NamespaceStats{
+			Subscriptions:         2,
+			RejectedSubscriptions: 1,
+		}))
+	})
+
+	o.Spec("it enforces the publish quota", func(t TC) {
+		ns := namespace(t, "a", pubsub.WithPublishQuota(2, time.Second))
+		ns.Subscribe(t.subscription)
+
+		for i := 0; i < 3; i++ {
+			ns.Publish(i, pubsub.LinearTreeTraverser(nil))
+		}
+		Expect(t, ns.Publish(3, pubsub.LinearTreeTraverser(nil))).To(Equal(pubsub.ErrQuotaExceeded))
+
+		t.clock.advance(time.Second)
+		Expect(t, ns.Publish(4, pubsub.LinearTreeTraverser(nil))).To(BeNil())
+
+		Expect(t, t.subscription.Data()).To(Equal([]interface{}{0, 1, 4}))
+		Expect(t, ns.Stats()).To(Equal(pubsub.NamespaceStats{
+			Subscriptions:     1,
+			Published:         3,
+			RejectedPublishes: 2,
+		}))
+	})
+
+	o.Spec("it reports to the namespace's metrics", func(t TC) {
+		m := newSpyMetrics()
+		ns := namespace(t, "a", pubsub.WithNamespaceMetrics(m))
+		ns.Subscribe(t.subscription, pubsub.WithPath([]string{"x"}))
+		ns.Publish(1, pubsub.LinearTreeTraverser([]string{"x"}))
+
+		Expect(t, m.get("subscribed")).To(Equal([]string{"x"}))
+		Expect(t, m.get("delivered")).To(Equal([]string{"x"}))
+	})
+
+	o.Spec("it configures the namespace like the PubSub", func(t TC) {
+		deadLetter := newSpySubscrption()
+		p := pubsub.New(
+			pubsub.WithClock(t.clock),
+			pubsub.WithAuthorizer(newSpyAuthorizer()),
+			pubsub.WithAsyncDelivery(5, pubsub.OverflowBlock),
+			pubsub.WithDeadLetterSubscription(deadLetter),
+		)
+		ns, err := p.Namespace("a")
+		Expect(t, err).To(BeNil())
+
+		_, err = ns.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"private"}))
+		Expect(t, err).To(Equal(errUnauthorized))
+
+		block := make(chan struct{})
+		_, err = ns.Subscribe(pubsub.SubscriptionFunc(func(data interface{}) {
+			<-block
+			t.subscription.Write(data)
+		}), pubsub.WithPath([]string{"public"}))
+		Expect(t, err).To(BeNil())
+
+		// The publish does not wait for the blocked subscription.
+		Expect(t, ns.Publish(1, pubsub.LinearTreeTraverser([]string{"public"}))).To(BeNil())
+		Expect(t, ns.Publish("secret", pubsub.LinearTreeTraverser([]string{"public"}))).To(Equal(errUnauthorized))
+		close(block)
+		Expect(t, t.subscription.Len).To(ViaPolling(Equal(1)))
+
+		// The dead letter Subscription is only the PubSub's.
+		ns.Publish(2, pubsub.LinearTreeTraverser([]string{"other"}))
+		p.Publish(3, pubsub.LinearTreeTraverser([]string{"a", "other"}))
+		Expect(t, deadLetter.Data()).To(Equal([]interface{}{3}))
+	})
+
+	o.Spec("it returns an error if the path is in use", func(t TC) {
+		t.p.Subscribe(t.subscription, pubsub.WithPath([]string{"a", "x"}))
+
+		_, err := t.p.Namespace("a")
+		Expect(t, err).To(Equal(pubsub.ErrPathInUse))
+	})
+}
@@ -0,0 +1,103 @@
+package pubsub
+
+import (
+	"slices"
+
+	"github.com/apoydence/pubsub/internal/node"
+)
+
+// WithNodeStats configures the PubSub to count how often each node of the
+// subscription tree is traversed, written to and dropped at (see
+// PubSub.NodeStats). The counters are atomic, but they are still only kept
+// when this option is used. Building with the pubsub_nonodestats tag
+// removes them entirely, in which case this option does nothing.
+func WithNodeStats() PubSubOption {
+	return pubsubConfigFunc(func(p *PubSub) {
+		p.nodeStats = nodeStatsCompiled
+	})
+}
+
+// NodeStats describes how a node of the subscription tree has been used by
+// publishes since it was created (see WithNodeStats). Nodes are removed
+// once they no longer have subscriptions or children, so their counts
+// start over if they are needed again.
+type NodeStats struct {
+	// Path is the node's path.
+	Path []string
+
+	// Subscriptions is the number of subscriptions at the node.
+	Subscriptions int
+
+	// Traversed is the number of publishes that reached the node. Nodes
+	// that are never traversed are dead subtrees.
+	Traversed int64
+
+	// Delivered is the number of times data was written (or enqueued) to a
+	// subscription at the node. Dropped is the number of times data was
+	// dropped because a subscription at the node could not keep up.
+	Delivered int64
+	Dropped   int64
+}
+
+// NodeStats returns the stats of each node in the subscription tree in the
+// same order as Walk. It returns nil if the PubSub was not configured with
+// WithNodeStats. Nodes of mounted PubSubs are not included.
+func (s *PubSub) NodeStats() []NodeStats {
+	if !s.nodeStats {
+		return nil
+	}
+
+	var stats []NodeStats
+	walk(s.tree.Load().root, nil, func(path []string, n *node.Node) {
+		st := n.Stats()
+		if st == nil {
+			return
+		}
+
+		stats = append(stats, NodeStats{
+			Path:          path,
+			Subscriptions: n.SubscriptionLen(),
+			Traversed:     st.Traversed.Load(),
+			Delivered:     st.Delivered.Load(),
+			Dropped:       st.Dropped.Load(),
+		})
+	})
+
+	return stats
+}
+
+// HotPaths returns the stats (see NodeStats) of the n nodes that have been
+// traversed the most, the most traversed first.
+func (s *PubSub) HotPaths(n int) []NodeStats {
+	stats := s.NodeStats()
+	slices.SortStableFunc(stats, func(a, b NodeStats) int {
+		switch {
+		case a.Traversed > b.Traversed:
+			return -1
+		case a.Traversed < b.Traversed:
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	if len(stats) > n {
+		stats = stats[:n]
+	}
+	return stats
+}
+
+// countWrite records a write to a subscription in the Stats of its node. It
+// does nothing if st is nil.
+func countWrite(st *node.Stats, delivered bool, dropped int) {
+	if st == nil {
+		return
+	}
+
+	if delivered {
+		st.Delivered.Add(1)
+	}
+	if dropped > 0 {
+		st.Dropped.Add(int64(dropped))
+	}
+}
@@ -0,0 +1,7 @@
+//go:build pubsub_nonodestats
+
+package pubsub
+
+// nodeStatsCompiled is false when building with the pubsub_nonodestats tag
+// (see WithNodeStats).
+const nodeStatsCompiled = false
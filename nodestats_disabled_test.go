@@ -0,0 +1,26 @@
+//go:build pubsub_nonodestats
+
+package pubsub_test
+
+import (
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+func TestPubSubNodeStatsDisabled(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.Spec("it does not count anything", func(t *testing.T) {
+		p := pubsub.New(pubsub.WithNodeStats())
+		p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"a"}))
+		p.Publish(1, pubsub.LinearTreeTraverser([]string{"a"}))
+
+		Expect(t, p.NodeStats()).To(BeNil())
+	})
+}
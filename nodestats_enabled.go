@@ -0,0 +1,7 @@
+//go:build !pubsub_nonodestats
+
+package pubsub
+
+// nodeStatsCompiled is true as node stats are compiled in unless the
+// pubsub_nonodestats tag is given (see WithNodeStats).
+const nodeStatsCompiled = true
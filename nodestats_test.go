@@ -0,0 +1,95 @@
+//go:build !pubsub_nonodestats
+
+package pubsub_test
+
+import (
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+func TestPubSubNodeStats(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) TPS {
+		return TPS{
+			T:            t,
+			p:            pubsub.New(pubsub.WithNodeStats()),
+			subscription: newSpySubscrption(),
+		}
+	})
+
+	o.Spec("it counts traversals and deliveries per node", func(t TPS) {
+		t.p.Subscribe(t.subscription, pubsub.WithPath([]string{"a", "b"}))
+		t.p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"c"}))
+
+		t.p.Publish(1, pubsub.LinearTreeTraverser([]string{"a", "b"}))
+		t.p.Publish(2, pubsub.LinearTreeTraverser([]string{"a", "b"}))
+		t.p.Publish(3, pubsub.LinearTreeTraverser([]string{"a", "x"}))
+
+		Expect(t, t.p.NodeStats()).To(Equal([]pubsub.NodeStats{
+			{Path: nil, Traversed: 3},
+			{Path: []string{"a"}, Traversed: 3},
+			{Path: []string{"a", "b"}, Subscriptions: 1, Traversed: 2, Delivered: 2},
+			{Path: []string{"c"}, Subscriptions: 1},
+		}))
+	})
+
+	o.Spec("it keeps the counts when the tree changes", func(t TPS) {
+		t.p.Subscribe(t.subscription, pubsub.WithPath([]string{"a"}))
+		t.p.Publish(1, pubsub.LinearTreeTraverser([]string{"a"}))
+		t.p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"a"}))
+		t.p.Publish(2, pubsub.LinearTreeTraverser([]string{"a"}))
+
+		stats := t.p.NodeStats()
+		Expect(t, stats[1].Traversed).To(Equal(int64(2)))
+		Expect(t, stats[1].Delivered).To(Equal(int64(3)))
+	})
+
+	o.Spec("it counts dropped data", func(t TPS) {
+		blocked := make(chan struct{})
+		defer close(blocked)
+		t.p.Subscribe(pubsub.SubscriptionFunc(func(interface{}) { <-blocked }),
+			pubsub.WithPath([]string{"a"}),
+			pubsub.WithBufferSize(1),
+			pubsub.WithOverflowStrategy(pubsub.OverflowDrop),
+		)
+
+		for i := 0; i < 10; i++ {
+			t.p.Publish(i, pubsub.LinearTreeTraverser([]string{"a"}))
+		}
+		Expect(t, t.p.NodeStats()[1].Dropped).To(BeAbove(0))
+	})
+
+	o.Spec("it counts concurrent writes", func(t TPS) {
+		p := pubsub.New(pubsub.WithNodeStats(), pubsub.WithFanoutConcurrency(2))
+		p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"a"}))
+		p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"a"}))
+
+		p.Publish(1, pubsub.LinearTreeTraverser([]string{"a"}))
+		Expect(t, p.NodeStats()[1].Delivered).To(Equal(int64(2)))
+	})
+
+	o.Spec("it returns the hottest paths", func(t TPS) {
+		t.p.Subscribe(t.subscription, pubsub.WithPath([]string{"a"}))
+		t.p.Subscribe(t.subscription, pubsub.WithPath([]string{"b"}))
+		t.p.Publish(1, pubsub.LinearTreeTraverser([]string{"b"}))
+
+		hot := t.p.HotPaths(2)
+		Expect(t, hot).To(HaveLen(2))
+		Expect(t, hot[0].Path).To(HaveLen(0))
+		Expect(t, hot[1].Path).To(Equal([]string{"b"}))
+	})
+
+	o.Spec("it does not count without WithNodeStats", func(t TPS) {
+		p := pubsub.New()
+		p.Subscribe(t.subscription)
+		p.Publish(1, pubsub.LinearTreeTraverser(nil))
+		Expect(t, p.NodeStats()).To(BeNil())
+	})
+}
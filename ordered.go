@@ -0,0 +1,80 @@
+package pubsub
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// WithOrderedDelivery configures a PubSub to deliver data to every
+// subscription in publish order, even when publishes are concurrent. Each
+// subscription then receives the data of any two publishes in the same
+// order as every other subscription that both publishes match.
+//
+// Without it, only the data of publishes that happen one after the other
+// (e.g., from a single goroutine) are ordered. Concurrent publishes are
+// ordered differently for different subscriptions, as each is written to
+// (or enqueued for) separately and, with WithFanoutConcurrency,
+// concurrently.
+//
+// Publishes are ordered by serializing their traversals, which hold the
+// data for each subscription that they reach in publish order. Each
+// subscription's data is then written (or, with WithAsyncDelivery,
+// enqueued) by a single publish at a time, outside of the serialized part.
+// A publish that reaches a subscription that another publish is writing to
+// leaves its data to that publish and does not wait for it. This is also
+// the case for a publish from within a Write (e.g., RequestMessage.Reply),
+// so it does not deadlock.
+//
+// The data of a publish is counted as delivered to a subscription once it
+// has been held for it. The errors of an ErrSubscription are therefore not
+// returned by PublishCtx.
+func WithOrderedDelivery() PubSubOption {
+	return pubsubConfigFunc(func(p *PubSub) {
+		p.ordered = true
+	})
+}
+
+// order holds the data for the subscriber (see subscriber.order). It
+// returns false if the data was not admitted.
+func (p *publish) order(sr *subscriber, data interface{}, path []string) bool {
+	ok, claimed := sr.order(p.ctx, data, path)
+	if claimed {
+		// A ShardingAlgorithm might write concurrently.
+		p.claimMu.Lock()
+		p.claimed = append(p.claimed, sr)
+		p.claimMu.Unlock()
+	}
+	return ok
+}
+
+// flush writes the data held for the claimed subscribers with up to n
+// goroutines. It must be invoked once orderMu is released.
+func (p *publish) flush(n int) {
+	subs := p.claimed
+	if len(subs) == 0 {
+		return
+	}
+
+	var (
+		next int64
+		wg   sync.WaitGroup
+	)
+	work := func() {
+		defer wg.Done()
+		for {
+			i := int(atomic.AddInt64(&next, 1) - 1)
+			if i >= len(subs) {
+				return
+			}
+			subs[i].flush()
+		}
+	}
+
+	workers := max(1, min(n, len(subs)))
+	wg.Add(workers)
+	for i := 1; i < workers; i++ {
+		go work()
+	}
+	work()
+	wg.Wait()
+}
@@ -0,0 +1,117 @@
+package pubsub_test
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+func TestPubSubOrderedDelivery(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.Spec("it writes concurrent publishes in the same order to each subscription", func(t *testing.T) {
+		p := pubsub.New(pubsub.WithOrderedDelivery())
+
+		// Yielding in each write gives concurrent publishes the chance to
+		// overtake each other.
+		subs := make([]*spySubscription, 3)
+		for i := range subs {
+			sub := newSpySubscrption()
+			subs[i] = sub
+			p.Subscribe(pubsub.SubscriptionFunc(func(data interface{}) {
+				runtime.Gosched()
+				sub.Write(data)
+			}), pubsub.WithPath([]string{"a"}))
+		}
+
+		publishConcurrently(t, p, subs)
+	})
+
+	o.Spec("it enqueues concurrent publishes in the same order for each subscription", func(t *testing.T) {
+		p := pubsub.New(
+			pubsub.WithOrderedDelivery(),
+			pubsub.WithAsyncDelivery(10, pubsub.OverflowBlock),
+			pubsub.WithFanoutConcurrency(3),
+		)
+
+		subs := make([]*spySubscription, 3)
+		for i := range subs {
+			subs[i] = newSpySubscrption()
+			p.Subscribe(subs[i], pubsub.WithPath([]string{"a"}))
+		}
+
+		publishConcurrently(t, p, subs)
+	})
+
+	o.Spec("it writes publishes from within a Write after the current one", func(t *testing.T) {
+		p := pubsub.New(pubsub.WithOrderedDelivery())
+		sub1 := newSpySubscrption()
+		sub2 := newSpySubscrption()
+		p.Subscribe(pubsub.SubscriptionFunc(func(data interface{}) {
+			sub1.Write(data)
+			if data == 1 {
+				p.Publish(2, pubsub.LinearTreeTraverser(nil))
+			}
+		}))
+		p.Subscribe(sub2)
+
+		p.Publish(1, pubsub.LinearTreeTraverser(nil))
+
+		Expect(t, sub1.Data()).To(Equal([]interface{}{1, 2}))
+		Expect(t, sub2.Data()).To(Equal([]interface{}{1, 2}))
+	})
+
+	o.Spec("it replies to requests from within a Write", func(t *testing.T) {
+		p := pubsub.New(pubsub.WithOrderedDelivery())
+		p.Subscribe(pubsub.SubscriptionFunc(func(data interface{}) {
+			m := data.(pubsub.RequestMessage)
+			m.Reply(m.Data)
+		}), pubsub.WithPath([]string{"echo"}))
+
+		reply, err := pubsub.Request(p, "x", pubsub.LinearTreeTraverser([]string{"echo"}), time.Second)
+		Expect(t, err).To(BeNil())
+		Expect(t, reply).To(Equal("x"))
+	})
+}
+
+// publishConcurrently publishes to "a" from several goroutines and asserts
+// that each subscription received the data in the same order.
+func publishConcurrently(t *testing.T, p *pubsub.PubSub, subs []*spySubscription) {
+	const publishers, publishes = 4, 100
+	var wg sync.WaitGroup
+	for i := 0; i < publishers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < publishes; j++ {
+				p.Publish([2]int{i, j}, pubsub.LinearTreeTraverser([]string{"a"}))
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for _, sub := range subs {
+		Expect(t, sub.Len).To(ViaPolling(Equal(publishers * publishes)))
+	}
+
+	data := subs[0].Data()
+	for _, sub := range subs[1:] {
+		Expect(t, sub.Data()).To(Equal(data))
+	}
+
+	// Each publisher's data is in the order it was published.
+	next := make([]int, publishers)
+	for _, d := range data {
+		v := d.([2]int)
+		Expect(t, v[1]).To(Equal(next[v[0]]))
+		next[v[0]]++
+	}
+}
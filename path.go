@@ -0,0 +1,21 @@
+package pubsub
+
+// PathAwareSubscription is a Subscription that wants to know which path
+// was traversed to reach it. When a Subscription implements it, WritePath
+// is used instead of Write for data that is written by Publish. Any is
+// replaced by the segment it matched, while the path for a subscription
+// with Rest ends before the Rest. The path is a copy that the Subscription
+// may keep. Data that is written by a ShardingAlgorithm is given the path
+// of the subscription it picked. Data that is written without a known path
+// (e.g., retained data, replayed data and batches) is still written with
+// Write.
+type PathAwareSubscription interface {
+	Subscription
+	WritePath(data interface{}, path []string)
+}
+
+// pathData is used to enqueue data along with its path.
+type pathData struct {
+	data interface{}
+	path []string
+}
@@ -0,0 +1,85 @@
+package pubsub_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+func TestPathAwareSubscription(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+	o.BeforeEach(func(t *testing.T) TPS {
+		return TPS{
+			T: t,
+			p: pubsub.New(),
+		}
+	})
+
+	o.Spec("it writes the path that was traversed", func(t TPS) {
+		sub := newSpyPathSubscription()
+		t.p.Subscribe(sub, pubsub.WithPath([]string{"a", pubsub.Any}))
+		t.p.Subscribe(sub, pubsub.WithPath([]string{"a", pubsub.Rest}))
+
+		t.p.Publish(1, pubsub.LinearTreeTraverser([]string{"a", "b", "c"}))
+
+		Expect(t, sub.paths()).To(Equal([][]string{
+			{"a"},
+			{"a", "b"},
+		}))
+	})
+
+	o.Spec("it writes an empty path for the root", func(t TPS) {
+		sub := newSpyPathSubscription()
+		t.p.Subscribe(sub)
+
+		t.p.Publish(1, pubsub.LinearTreeTraverser(nil))
+
+		Expect(t, sub.paths()).To(Equal([][]string{{}}))
+	})
+
+	o.Spec("it writes the path with async delivery", func(t TPS) {
+		p := pubsub.New(
+			pubsub.WithAsyncDelivery(10, pubsub.OverflowBlock),
+			pubsub.WithMetrics(newSpyMetrics()),
+		)
+		sub := newSpyPathSubscription()
+		p.Subscribe(sub, pubsub.WithPath([]string{pubsub.Any}))
+
+		p.Publish(1, pubsub.LinearTreeTraverser([]string{"x"}))
+		p.Publish(2, pubsub.LinearTreeTraverser([]string{"y"}))
+
+		Expect(t, func() int { return len(sub.paths()) }).To(ViaPolling(Equal(2)))
+		Expect(t, sub.paths()).To(Equal([][]string{{"x"}, {"y"}}))
+	})
+}
+
+type spyPathSubscription struct {
+	mu sync.Mutex
+	ps [][]string
+}
+
+func newSpyPathSubscription() *spyPathSubscription {
+	return &spyPathSubscription{}
+}
+
+func (s *spyPathSubscription) Write(data interface{}) {
+	panic("Write should not be used")
+}
+
+func (s *spyPathSubscription) WritePath(data interface{}, path []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ps = append(s.ps, path)
+}
+
+func (s *spyPathSubscription) paths() [][]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([][]string(nil), s.ps...)
+}
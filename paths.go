@@ -0,0 +1,103 @@
+package pubsub
+
+import "iter"
+
+// EmptyPaths is a Paths without any paths. A TreeTraverser returns it to
+// end the traversal.
+var EmptyPaths Paths = FlatPaths(nil)
+
+// ConcatPaths returns a Paths with the paths of each of the given Paths in
+// order. Each path keeps its nextTraverser (including nil, meaning the
+// previous TreeTraverser is used).
+func ConcatPaths(ps ...Paths) Paths {
+	var all PathAndTraversers
+	for _, p := range ps {
+		all = appendPaths(all, p, nil)
+	}
+
+	if len(all) == 0 {
+		return EmptyPaths
+	}
+	return all
+}
+
+// UnionPaths returns a Paths with the paths of a followed by those of b
+// that a does not have. If both have a path, a's nextTraverser is used.
+func UnionPaths(a, b Paths) Paths {
+	seen := make(map[string]bool)
+	all := appendPaths(nil, a, seen)
+	all = appendPaths(all, b, seen)
+
+	if len(all) == 0 {
+		return EmptyPaths
+	}
+	return all
+}
+
+// appendPaths appends each path of p to all. If seen is not nil, paths that
+// are already in it are skipped.
+func appendPaths(all PathAndTraversers, p Paths, seen map[string]bool) PathAndTraversers {
+	for path, next := range AllPaths(p) {
+		if seen != nil {
+			if seen[path] {
+				continue
+			}
+			seen[path] = true
+		}
+
+		all = append(all, PathAndTraverser{Path: path, Traverser: next})
+	}
+	return all
+}
+
+// PathsSeq is an alternative form of Paths that yields each path along with
+// its nextTraverser (which may be nil, meaning the previous TreeTraverser is
+// used). It allows a TreeTraverser to be written as a generator:
+//
+//	func(data interface{}, currentPath []string) pubsub.Paths {
+//		return pubsub.PathsSeq(func(yield func(string, pubsub.TreeTraverser) bool) {
+//			for _, tag := range data.(*Event).Tags {
+//				if !yield(tag, nil) {
+//					return
+//				}
+//			}
+//		})
+//	}
+//
+// PubSub ranges over the sequence directly. The sequence must yield the
+// same paths each time it is ranged over.
+type PathsSeq iter.Seq2[string, TreeTraverser]
+
+// At implements Paths. It ranges over the sequence until the idx'th path,
+// so ranging over the sequence (see AllPaths) should be preferred.
+func (s PathsSeq) At(idx int) (string, TreeTraverser, bool) {
+	i := 0
+	for path, next := range s {
+		if i == idx {
+			return path, next, true
+		}
+		i++
+	}
+	return "", nil, false
+}
+
+// AllPaths returns a sequence of the paths (and their nextTraversers) of
+// the given Paths.
+func AllPaths(p Paths) iter.Seq2[string, TreeTraverser] {
+	if s, ok := p.(PathsSeq); ok {
+		return iter.Seq2[string, TreeTraverser](s)
+	}
+
+	return func(yield func(string, TreeTraverser) bool) {
+		if p == nil {
+			return
+		}
+
+		for i := 0; ; i++ {
+			path, next, ok := p.At(i)
+			if !ok || !yield(path, next) {
+				return
+			}
+		}
+	}
+}
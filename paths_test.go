@@ -0,0 +1,168 @@
+package pubsub_test
+
+import (
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+func TestPathsCombinators(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	// collect returns each path and whether it has a nextTraverser.
+	collect := func(p pubsub.Paths) ([]string, []bool) {
+		var (
+			paths []string
+			next  []bool
+		)
+		for i := 0; ; i++ {
+			path, a, ok := p.At(i)
+			if !ok {
+				return paths, next
+			}
+			paths = append(paths, path)
+			next = append(next, a != nil)
+		}
+	}
+
+	o.Spec("EmptyPaths does not have any paths", func(t *testing.T) {
+		paths, _ := collect(pubsub.EmptyPaths)
+		Expect(t, paths).To(HaveLen(0))
+	})
+
+	o.Spec("ConcatPaths has the paths of each in order", func(t *testing.T) {
+		a := pubsub.LinearTreeTraverser(nil)
+		paths, next := collect(pubsub.ConcatPaths(
+			pubsub.FlatPaths{"a", "b"},
+			pubsub.EmptyPaths,
+			nil,
+			pubsub.NewPathsWithTraverser([]string{"b", "c"}, a),
+		))
+
+		Expect(t, paths).To(Equal([]string{"a", "b", "b", "c"}))
+		Expect(t, next).To(Equal([]bool{false, false, true, true}))
+	})
+
+	o.Spec("ConcatPaths without any paths is empty", func(t *testing.T) {
+		paths, _ := collect(pubsub.ConcatPaths())
+		Expect(t, paths).To(HaveLen(0))
+	})
+
+	o.Spec("UnionPaths has each path once", func(t *testing.T) {
+		a := pubsub.LinearTreeTraverser(nil)
+		paths, next := collect(pubsub.UnionPaths(
+			pubsub.FlatPaths{"a", "b", "a"},
+			pubsub.NewPathsWithTraverser([]string{"b", "c"}, a),
+		))
+
+		Expect(t, paths).To(Equal([]string{"a", "b", "c"}))
+		Expect(t, next).To(Equal([]bool{false, false, true}))
+	})
+
+	o.Spec("it publishes with combined Paths", func(t *testing.T) {
+		p := pubsub.New()
+		sub1 := newSpySubscrption()
+		sub2 := newSpySubscrption()
+		p.Subscribe(sub1, pubsub.WithPath([]string{"a"}))
+		p.Subscribe(sub2, pubsub.WithPath([]string{"b"}))
+
+		var traverser pubsub.TreeTraverserFunc
+		traverser = func(data interface{}, currentPath []string) pubsub.Paths {
+			if len(currentPath) > 0 {
+				return pubsub.EmptyPaths
+			}
+			return pubsub.UnionPaths(pubsub.FlatPaths{"a"}, pubsub.FlatPaths{"a", "b"})
+		}
+		p.Publish(1, traverser)
+
+		Expect(t, sub1.Data()).To(Equal([]interface{}{1}))
+		Expect(t, sub2.Data()).To(Equal([]interface{}{1}))
+	})
+}
+
+func TestPathsSeq(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) TPS {
+		return TPS{
+			T:            t,
+			p:            pubsub.New(),
+			subscription: newSpySubscrption(),
+		}
+	})
+
+	// tags yields each tag of the data as a path.
+	tags := pubsub.TreeTraverserFunc(func(data interface{}, currentPath []string) pubsub.Paths {
+		if len(currentPath) > 0 {
+			return pubsub.EmptyPaths
+		}
+
+		return pubsub.PathsSeq(func(yield func(string, pubsub.TreeTraverser) bool) {
+			for _, tag := range data.([]string) {
+				if !yield(tag, nil) {
+					return
+				}
+			}
+		})
+	})
+
+	o.Spec("it publishes with a generator TreeTraverser", func(t TPS) {
+		other := newSpySubscrption()
+		t.p.Subscribe(t.subscription, pubsub.WithPath([]string{"b"}))
+		t.p.Subscribe(other, pubsub.WithPath([]string{pubsub.Any}))
+
+		data := []string{"a", "b", "c"}
+		t.p.Publish(data, tags)
+
+		Expect(t, t.subscription.Data()).To(Equal([]interface{}{data}))
+		Expect(t, other.Data()).To(Equal([]interface{}{data}))
+	})
+
+	o.Spec("it implements At", func(t TPS) {
+		seq := tags([]string{"a", "b"}, nil)
+
+		path, _, ok := seq.At(1)
+		Expect(t, ok).To(BeTrue())
+		Expect(t, path).To(Equal("b"))
+
+		_, _, ok = seq.At(2)
+		Expect(t, ok).To(BeFalse())
+	})
+
+	o.Spec("AllPaths adapts At based Paths", func(t TPS) {
+		a := pubsub.LinearTreeTraverser(nil)
+		var (
+			paths []string
+			next  []bool
+		)
+		for path, n := range pubsub.AllPaths(pubsub.PathAndTraversers{
+			{Path: "a"},
+			{Path: "b", Traverser: a},
+		}) {
+			paths = append(paths, path)
+			next = append(next, n != nil)
+		}
+
+		Expect(t, paths).To(Equal([]string{"a", "b"}))
+		Expect(t, next).To(Equal([]bool{false, true}))
+	})
+
+	o.Spec("AllPaths stops when the loop breaks", func(t TPS) {
+		var paths []string
+		for path := range pubsub.AllPaths(pubsub.FlatPaths([]string{"a", "b", "c"})) {
+			paths = append(paths, path)
+			if path == "b" {
+				break
+			}
+		}
+
+		Expect(t, paths).To(Equal([]string{"a", "b"}))
+	})
+}
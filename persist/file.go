@@ -0,0 +1,191 @@
+package persist
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"hash/crc32"
+	"io"
+	"os"
+	"sync"
+)
+
+// recordHeaderSize is the size of the header that precedes each record:
+// the length of the record and its CRC-32 (IEEE), both little-endian.
+const recordHeaderSize = 8
+
+// FileLogOption is used to configure a FileLog.
+type FileLogOption interface {
+	configure(*FileLog)
+}
+
+type fileLogConfigFunc func(*FileLog)
+
+func (f fileLogConfigFunc) configure(l *FileLog) {
+	f(l)
+}
+
+// WithSync configures a FileLog to sync the file after each Append. Without
+// it, Entries that were appended shortly before the machine (rather than
+// the process) crashed may be lost.
+func WithSync() FileLogOption {
+	return fileLogConfigFunc(func(l *FileLog) {
+		l.sync = true
+	})
+}
+
+// FileLog is a Log that appends Entries to a file. Each Entry is stored as
+// a checksummed record, so a record that was only partially written when
+// the process crashed is detected and discarded when the file is opened.
+// It should be constructed with OpenFileLog.
+type FileLog struct {
+	sync bool
+
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+}
+
+// OpenFileLog opens (or creates) the file at the given path. Any partially
+// written record at the end of the file is removed.
+func OpenFileLog(name string, opts ...FileLogOption) (*FileLog, error) {
+	f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &FileLog{f: f}
+	for _, o := range opts {
+		o.configure(l)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	size, err := scanRecords(io.NewSectionReader(f, 0, info.Size()), nil)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	if err := f.Truncate(size); err != nil {
+		f.Close()
+		return nil, err
+	}
+	l.size = size
+
+	return l, nil
+}
+
+// Append implements Log.
+func (l *FileLog) Append(e Entry) error {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	record := make([]byte, recordHeaderSize+len(payload))
+	binary.LittleEndian.PutUint32(record[0:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(record[4:8], crc32.ChecksumIEEE(payload))
+	copy(record[recordHeaderSize:], payload)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, err := l.f.WriteAt(record, l.size); err != nil {
+		return err
+	}
+
+	if l.sync {
+		if err := l.f.Sync(); err != nil {
+			return err
+		}
+	}
+
+	l.size += int64(len(record))
+	return nil
+}
+
+// Replay implements Log. Entries that are appended while replaying are not
+// replayed.
+func (l *FileLog) Replay(f func(e Entry) error) error {
+	l.mu.Lock()
+	size := l.size
+	l.mu.Unlock()
+
+	_, err := scanRecords(io.NewSectionReader(l.f, 0, size), f)
+	return err
+}
+
+// Close closes the file.
+func (l *FileLog) Close() error {
+	return l.f.Close()
+}
+
+// errCorrupt is returned by readRecord when a record is incomplete or does
+// not match its checksum.
+var errCorrupt = errors.New("corrupt record")
+
+// scanRecords invokes f (if not nil) with each valid record and returns
+// the size of the records up to the first one that is not. It only returns
+// an error if reading fails or f returns one.
+func scanRecords(r *io.SectionReader, f func(e Entry) error) (int64, error) {
+	br := bufio.NewReader(r)
+
+	var size int64
+	for {
+		payload, err := readRecord(br, r.Size()-size)
+		if err == io.EOF || err == errCorrupt {
+			return size, nil
+		}
+		if err != nil {
+			return size, err
+		}
+
+		var e Entry
+		if err := json.Unmarshal(payload, &e); err != nil {
+			return size, nil
+		}
+
+		if f != nil {
+			if err := f(e); err != nil {
+				return size, err
+			}
+		}
+		size += int64(recordHeaderSize + len(payload))
+	}
+}
+
+// readRecord reads the next record, which must fit within the remaining
+// bytes.
+func readRecord(r io.Reader, remaining int64) ([]byte, error) {
+	var header [recordHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, errCorrupt
+		}
+		return nil, err
+	}
+
+	n := int64(binary.LittleEndian.Uint32(header[0:4]))
+	if n > remaining-recordHeaderSize {
+		return nil, errCorrupt
+	}
+
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, errCorrupt
+		}
+		return nil, err
+	}
+
+	if crc32.ChecksumIEEE(payload) != binary.LittleEndian.Uint32(header[4:8]) {
+		return nil, errCorrupt
+	}
+	return payload, nil
+}
@@ -0,0 +1,114 @@
+// Package persist journals publishes to a Log so that they can be replayed
+// into a PubSub on startup. This allows retained data and replay buffers
+// (see pubsub.WithRetain and pubsub.WithReplayBuffer) to survive a crash.
+package persist
+
+import (
+	"sync"
+
+	"github.com/apoydence/pubsub"
+	"github.com/apoydence/pubsub/codec"
+)
+
+// Entry is a journaled publish.
+type Entry struct {
+	// Path is the path the data was published to.
+	Path []string `json:"path"`
+
+	// Data is the published data as encoded by the Journal's Codec.
+	Data []byte `json:"data"`
+
+	// Retain is set if the data was published with pubsub.WithRetain.
+	Retain bool `json:"retain,omitempty"`
+}
+
+// Log stores Entries. Implementations must be safe to access concurrently.
+type Log interface {
+	// Append durably stores the Entry after any that were appended before
+	// it.
+	Append(e Entry) error
+
+	// Replay invokes f with each stored Entry in the order they were
+	// appended. It stops at the first error f returns and returns it.
+	Replay(f func(e Entry) error) error
+}
+
+// Codec converts published data to and from the bytes that are stored in a
+// Log. See the codec package for implementations.
+type Codec = codec.Codec
+
+// Journal publishes to a PubSub after appending each publish to a Log. It
+// should be constructed with NewJournal. It is safe to access
+// concurrently, though its publishes are serialized so that they are
+// replayed in the order they were published.
+type Journal struct {
+	p     *pubsub.PubSub
+	log   Log
+	codec Codec
+
+	mu sync.Mutex
+}
+
+// NewJournal constructs a new Journal.
+func NewJournal(p *pubsub.PubSub, log Log, codec Codec) *Journal {
+	return &Journal{
+		p:     p,
+		log:   log,
+		codec: codec,
+	}
+}
+
+// Publish appends the data to the Log and then publishes it to the path
+// with a LinearTreeTraverser. Nothing is published if the data can not be
+// encoded or appended.
+func (j *Journal) Publish(d interface{}, path []string) error {
+	return j.publish(d, path, false)
+}
+
+// PublishRetained is like Publish, but the data is retained (see
+// pubsub.WithRetain).
+func (j *Journal) PublishRetained(d interface{}, path []string) error {
+	return j.publish(d, path, true)
+}
+
+func (j *Journal) publish(d interface{}, path []string, retain bool) error {
+	b, err := j.codec.Marshal(d)
+	if err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if err := j.log.Append(Entry{Path: path, Data: b, Retain: retain}); err != nil {
+		return err
+	}
+
+	j.p.Publish(d, pubsub.LinearTreeTraverser(path), publishOptions(retain)...)
+	return nil
+}
+
+// Recover publishes every Entry in the Log to the PubSub (without
+// appending them again). It should be invoked on startup, before anything
+// subscribes, as the data is written to any matching subscriptions.
+func (j *Journal) Recover() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.log.Replay(func(e Entry) error {
+		d, err := j.codec.Unmarshal(e.Data)
+		if err != nil {
+			return err
+		}
+
+		j.p.Publish(d, pubsub.LinearTreeTraverser(e.Path), publishOptions(e.Retain)...)
+		return nil
+	})
+}
+
+func publishOptions(retain bool) []pubsub.PublishOption {
+	if retain {
+		return []pubsub.PublishOption{pubsub.WithRetain()}
+	}
+	return nil
+}
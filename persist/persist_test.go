@@ -0,0 +1,143 @@
+package persist_test
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/apoydence/pubsub/codec"
+	"github.com/apoydence/pubsub/persist"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+type TJ struct {
+	*testing.T
+	name string
+	log  *persist.FileLog
+}
+
+func TestJournal(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) TJ {
+		name := filepath.Join(t.TempDir(), "journal")
+		log, err := persist.OpenFileLog(name, persist.WithSync())
+		Expect(t, err).To(BeNil())
+		t.Cleanup(func() { log.Close() })
+
+		return TJ{
+			T:    t,
+			name: name,
+			log:  log,
+		}
+	})
+
+	o.Spec("it publishes and recovers retained data", func(t TJ) {
+		p := pubsub.New()
+		sub := newSpySubscription()
+		p.Subscribe(sub, pubsub.WithPath([]string{"a"}))
+
+		j := persist.NewJournal(p, t.log, codec.JSON[string]())
+		Expect(t, j.PublishRetained("x", []string{"a"})).To(BeNil())
+		Expect(t, j.Publish("y", []string{"a"})).To(BeNil())
+		Expect(t, sub.Data()).To(Equal([]interface{}{"x", "y"}))
+		t.log.Close()
+
+		log, err := persist.OpenFileLog(t.name)
+		Expect(t, err).To(BeNil())
+		defer log.Close()
+
+		p = pubsub.New()
+		Expect(t, persist.NewJournal(p, log, codec.JSON[string]()).Recover()).To(BeNil())
+
+		sub = newSpySubscription()
+		p.Subscribe(sub, pubsub.WithPath([]string{"a"}))
+		Expect(t, sub.Data()).To(Equal([]interface{}{"x"}))
+	})
+
+	o.Spec("it recovers replay buffers", func(t TJ) {
+		j := persist.NewJournal(pubsub.New(), t.log, codec.JSON[int]())
+		for i := 0; i < 3; i++ {
+			Expect(t, j.Publish(i, []string{"a", "b"})).To(BeNil())
+		}
+
+		p := pubsub.New(pubsub.WithReplayBuffer(2))
+		Expect(t, persist.NewJournal(p, t.log, codec.JSON[int]()).Recover()).To(BeNil())
+
+		sub := newSpySubscription()
+		p.Subscribe(sub, pubsub.WithPath([]string{"a"}), pubsub.WithReplay(2))
+		Expect(t, sub.Data()).To(Equal([]interface{}{1, 2}))
+	})
+
+	o.Spec("it discards a partially written record", func(t TJ) {
+		j := persist.NewJournal(pubsub.New(), t.log, codec.JSON[string]())
+		Expect(t, j.Publish("x", []string{"a"})).To(BeNil())
+		Expect(t, j.Publish("y", []string{"a"})).To(BeNil())
+		t.log.Close()
+
+		info, err := os.Stat(t.name)
+		Expect(t, err).To(BeNil())
+		Expect(t, os.Truncate(t.name, info.Size()-3)).To(BeNil())
+
+		log, err := persist.OpenFileLog(t.name)
+		Expect(t, err).To(BeNil())
+		defer log.Close()
+
+		var entries []persist.Entry
+		Expect(t, log.Append(persist.Entry{Path: []string{"b"}, Data: []byte(`"z"`)})).To(BeNil())
+		Expect(t, log.Replay(func(e persist.Entry) error {
+			entries = append(entries, e)
+			return nil
+		})).To(BeNil())
+
+		Expect(t, entries).To(Equal([]persist.Entry{
+			{Path: []string{"a"}, Data: []byte(`"x"`)},
+			{Path: []string{"b"}, Data: []byte(`"z"`)},
+		}))
+	})
+
+	o.Spec("it does not publish data that can not be appended", func(t TJ) {
+		p := pubsub.New()
+		sub := newSpySubscription()
+		p.Subscribe(sub)
+		t.log.Close()
+
+		err := persist.NewJournal(p, t.log, codec.JSON[string]()).Publish("x", nil)
+		Expect(t, err).To(Not(BeNil()))
+		Expect(t, sub.Data()).To(HaveLen(0))
+	})
+
+	o.Spec("it stops recovering at the first error", func(t TJ) {
+		Expect(t, t.log.Append(persist.Entry{Data: []byte("not-json")})).To(BeNil())
+
+		err := persist.NewJournal(pubsub.New(), t.log, codec.JSON[string]()).Recover()
+		Expect(t, err).To(Not(BeNil()))
+	})
+}
+
+type spySubscription struct {
+	mu   sync.Mutex
+	data []interface{}
+}
+
+func newSpySubscription() *spySubscription {
+	return &spySubscription{}
+}
+
+func (s *spySubscription) Write(data interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data = append(s.data, data)
+}
+
+func (s *spySubscription) Data() []interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]interface{}(nil), s.data...)
+}
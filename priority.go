@@ -0,0 +1,20 @@
+package pubsub
+
+// WithPriority configures the priority of a subscription. Within a single
+// Publish, the subscriptions at a node are written to in order of their
+// priority (highest first). Subscriptions with the same priority are
+// written to in the order they subscribed. A shard group is written to in
+// order of its highest priority (unless WithCrossNodeSharding is used, in
+// which case shard groups are written to after the traversal). It defaults
+// to 0, and negative priorities are written to after the default.
+//
+// With WithAsyncDelivery, each subscription has its own queue, so the data
+// is enqueued for higher-priority subscriptions first and their queues are
+// drained independently of lower-priority ones. With
+// WithFanoutConcurrency, subscriptions are written to concurrently, so the
+// priority only determines which writes are started first.
+func WithPriority(n int) SubscribeOption {
+	return subscribeConfigFunc(func(c *subscribeConfig) {
+		c.priority = n
+	})
+}
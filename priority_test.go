@@ -0,0 +1,85 @@
+package pubsub_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+func TestPubSubPriority(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) TPS {
+		return TPS{
+			T: t,
+			p: pubsub.New(),
+		}
+	})
+
+	// recorder returns a Subscription that records its name in order.
+	type recorder struct {
+		mu    sync.Mutex
+		names []string
+	}
+	record := func(r *recorder, name string) pubsub.Subscription {
+		return pubsub.SubscriptionFunc(func(interface{}) {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.names = append(r.names, name)
+		})
+	}
+
+	o.Spec("it writes to higher priority subscriptions first", func(t TPS) {
+		r := &recorder{}
+		t.p.Subscribe(record(r, "default"))
+		t.p.Subscribe(record(r, "best-effort"), pubsub.WithPriority(-1))
+		t.p.Subscribe(record(r, "audit"), pubsub.WithPriority(10))
+		t.p.Subscribe(record(r, "critical"), pubsub.WithPriority(5))
+		t.p.Subscribe(record(r, "critical-2"), pubsub.WithPriority(5))
+		t.p.Subscribe(record(r, "sharded"), pubsub.WithPriority(7), pubsub.WithShardID("x"))
+
+		t.p.Publish("data", pubsub.LinearTreeTraverser(nil))
+
+		Expect(t, r.names).To(Equal([]string{"audit", "sharded", "critical", "critical-2", "default", "best-effort"}))
+	})
+
+	o.Spec("it keeps the priority when the subscription moves", func(t TPS) {
+		r := &recorder{}
+		t.p.Subscribe(record(r, "default"), pubsub.WithPath([]string{"b"}))
+		h := t.p.SubscribeHandle(record(r, "audit"), pubsub.WithPath([]string{"a"}), pubsub.WithPriority(1))
+		h.Move([]string{"b"})
+
+		t.p.Publish("data", pubsub.LinearTreeTraverser([]string{"b"}))
+
+		Expect(t, r.names).To(Equal([]string{"audit", "default"}))
+	})
+
+	o.Spec("it enqueues for higher priority subscriptions first", func(t TPS) {
+		p := pubsub.New(pubsub.WithAsyncDelivery(1, pubsub.OverflowBlock))
+		block := make(chan struct{})
+		defer close(block)
+
+		audit := newSpySubscrption()
+		p.Subscribe(pubsub.SubscriptionFunc(func(interface{}) {
+			<-block
+		}))
+		p.Subscribe(audit, pubsub.WithPriority(1))
+
+		// The low priority subscription blocks the publisher once its
+		// queue is full, but only after the audit subscription has the
+		// data.
+		go func() {
+			for i := 0; i < 3; i++ {
+				p.Publish(i, pubsub.LinearTreeTraverser(nil))
+			}
+		}()
+
+		Expect(t, audit.Len).To(ViaPolling(Equal(3)))
+	})
+}
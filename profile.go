@@ -0,0 +1,38 @@
+package pubsub
+
+import "runtime/pprof"
+
+// ProfilerLabel is the pprof label that WithProfilerLabels sets.
+const ProfilerLabel = "pubsub_path"
+
+// WithProfilerLabels configures a PubSub to label the publishing goroutine
+// with the first segment of the path it is traversing (see
+// runtime/pprof.SetGoroutineLabels). CPU profiles then attribute the cost
+// of traversing the subscription tree and of writing to subscriptions
+// synchronously to each top-level path. The label is added to those of the
+// publish's context, which are restored once the traversal is done.
+// Writes that happen on other goroutines (e.g., with WithAsyncDelivery)
+// are not labeled.
+func WithProfilerLabels() PubSubOption {
+	return pubsubConfigFunc(func(p *PubSub) {
+		p.profilerLabels = true
+	})
+}
+
+// labelGoroutine labels the goroutine with the first segment of the
+// publish's current path if it differs from the given label, which it
+// updates. It must only be invoked with a non-empty path.
+func (p *publish) labelGoroutine(label *string) {
+	if p.path[0] == *label {
+		return
+	}
+
+	*label = p.path[0]
+	pprof.SetGoroutineLabels(pprof.WithLabels(p.ctx, pprof.Labels(ProfilerLabel, *label)))
+}
+
+// restoreGoroutineLabels sets the goroutine's labels back to those of the
+// publish's context.
+func (p *publish) restoreGoroutineLabels() {
+	pprof.SetGoroutineLabels(p.ctx)
+}
@@ -0,0 +1,31 @@
+package pubsub_test
+
+import (
+	"bytes"
+	"runtime/pprof"
+	"strings"
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+func TestPubSubProfilerLabels(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.Spec("it labels the publishing goroutine with the top-level path", func(t *testing.T) {
+		p := pubsub.New(pubsub.WithProfilerLabels())
+
+		var profile bytes.Buffer
+		p.Subscribe(pubsub.SubscriptionFunc(func(interface{}) {
+			pprof.Lookup("goroutine").WriteTo(&profile, 1)
+		}), pubsub.WithPath([]string{"a", "b"}))
+
+		p.Publish("v", pubsub.LinearTreeTraverser([]string{"a", "b"}))
+		Expect(t, strings.Contains(profile.String(), `"pubsub_path":"a"`)).To(BeTrue())
+	})
+}
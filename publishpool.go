@@ -0,0 +1,93 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+
+	"github.com/apoydence/pubsub/internal/node"
+)
+
+// maxVisited is how many nodes a publish tracks with a slice before it
+// switches to a map.
+const maxVisited = 32
+
+var publishPool = sync.Pool{
+	New: func() interface{} {
+		return &publish{}
+	},
+}
+
+// newPublish returns a publish from the pool. It must be released once the
+// publish is done.
+func newPublish(ctx context.Context, d interface{}) *publish {
+	p := publishPool.Get().(*publish)
+	p.ctx = ctx
+	p.data = d
+	return p
+}
+
+// release resets the publish and returns it to the pool. Its buffers are
+// kept to be reused.
+func (p *publish) release() {
+	clear(p.visited)
+	clear(p.stack)
+	clear(p.path)
+	clear(p.matches)
+	clear(p.shardGroups)
+	clear(p.pending)
+	clear(p.mounts)
+	clear(p.written)
+	clear(p.claimed)
+	if p.useMap {
+		clear(p.visitedMap)
+	}
+	clear(p.writtenMap)
+
+	*p = publish{
+		visited:     p.visited[:0],
+		visitedMap:  p.visitedMap,
+		stack:       p.stack[:0],
+		path:        p.path[:0],
+		matches:     p.matches[:0],
+		shardGroups: p.shardGroups,
+		pending:     p.pending[:0],
+		mounts:      p.mounts[:0],
+		written:     p.written[:0],
+		writtenMap:  p.writtenMap,
+		claimed:     p.claimed[:0],
+	}
+	publishPool.Put(p)
+}
+
+// visit returns false if the node has already been visited.
+func (p *publish) visit(n *node.Node) bool {
+	if p.useMap {
+		if _, ok := p.visitedMap[n]; ok {
+			return false
+		}
+		p.visitedMap[n] = struct{}{}
+		return true
+	}
+
+	for _, x := range p.visited {
+		if x == n {
+			return false
+		}
+	}
+
+	if len(p.visited) < maxVisited {
+		p.visited = append(p.visited, n)
+		return true
+	}
+
+	if p.visitedMap == nil {
+		p.visitedMap = make(map[*node.Node]struct{}, 2*maxVisited)
+	}
+	for _, x := range p.visited {
+		p.visitedMap[x] = struct{}{}
+	}
+	p.visitedMap[n] = struct{}{}
+	p.useMap = true
+
+	return true
+}
@@ -0,0 +1,91 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrDropped is returned by PublishSync when data was dropped because a
+// subscription could not keep up (see OverflowStrategy).
+var ErrDropped = errors.New("data was dropped")
+
+// PublishSync is like PublishCtx, but it also waits for every subscription
+// that the data was delivered to to finish writing it, including the ones
+// whose data is queued (see WithAsyncDelivery) and the ones of PubSubs it
+// is forwarded to (see Mount and NewForwarder). The returned error joins
+// (see errors.Join) the error of the publish, the errors of transformers
+// and ErrSubscriptions that failed (see WithTransformer and
+// SubscriptionError) and ErrDropped for each subscription that dropped the
+// data. If the context is done before every subscription has finished, its
+// error is included and PublishSync returns without waiting for the rest.
+//
+// Data that a subscription buffers (see WithBatching, WithDebounce,
+// WithThrottle, Pause and SubscribePull) is considered finished once it is
+// buffered, and an AckedSubscription once WriteDelivery returns rather
+// than when the data is acknowledged.
+func (s *PubSub) PublishSync(ctx context.Context, d interface{}, a TreeTraverser, opts ...PublishOption) (PublishResult, error) {
+	c := &completion{}
+	result, err := s.PublishCtx(context.WithValue(ctx, completionKey{}, c), d, a, opts...)
+
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		// The publish already returned the context's error if it was
+		// aborted.
+		if err == nil {
+			err = ctx.Err()
+		}
+	}
+
+	errs := append([]error{err}, result.Errors...)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return result, errors.Join(append(errs, c.errs...)...)
+}
+
+// completionKey is the context key of the completion of a PublishSync.
+type completionKey struct{}
+
+// completion tracks the writes of a PublishSync.
+type completion struct {
+	wg sync.WaitGroup
+
+	mu   sync.Mutex
+	errs []error
+}
+
+// completionFrom returns the completion of the PublishSync that the context
+// belongs to. It returns nil for other publishes.
+func completionFrom(ctx context.Context) *completion {
+	c, _ := ctx.Value(completionKey{}).(*completion)
+	return c
+}
+
+func (c *completion) fail(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.errs = append(c.errs, err)
+}
+
+// completionData is queued (see WithAsyncDelivery) instead of the data of a
+// PublishSync so that the completion is notified once it is written.
+type completionData struct {
+	data interface{}
+	c    *completion
+}
+
+// done records that the data was written or, if there is an error, that
+// it failed or was dropped.
+func (d completionData) done(err error) {
+	if err != nil {
+		d.c.fail(err)
+	}
+	d.c.wg.Done()
+}
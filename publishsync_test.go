@@ -0,0 +1,130 @@
+package pubsub_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+func TestPubSubPublishSync(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	// blockingSubscription signals each write on started and then waits
+	// for release.
+	blockingSubscription := func(sub *spySubscription, started chan<- struct{}, release <-chan struct{}) pubsub.Subscription {
+		return pubsub.SubscriptionFunc(func(data interface{}) {
+			started <- struct{}{}
+			<-release
+			sub.Write(data)
+		})
+	}
+
+	o.Spec("it waits for queued data to be written", func(t *testing.T) {
+		p := pubsub.New(pubsub.WithAsyncDelivery(10, pubsub.OverflowBlock))
+		sub := newSpySubscrption()
+		started, release := make(chan struct{}, 1), make(chan struct{})
+		p.Subscribe(blockingSubscription(sub, started, release), pubsub.WithPath([]string{"a"}))
+
+		done := make(chan error, 1)
+		go func() {
+			_, err := p.PublishSync(context.Background(), "data", pubsub.LinearTreeTraverser([]string{"a"}))
+			done <- err
+		}()
+
+		<-started
+		select {
+		case <-done:
+			t.Fatal("PublishSync returned before the data was written")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		close(release)
+		Expect(t, <-done).To(BeNil())
+		Expect(t, sub.Data()).To(Equal([]interface{}{"data"}))
+	})
+
+	o.Spec("it waits for the PubSubs the data is forwarded to", func(t *testing.T) {
+		dst := pubsub.New(pubsub.WithAsyncDelivery(10, pubsub.OverflowBlock))
+		sub := newSpySubscrption()
+		started, release := make(chan struct{}, 1), make(chan struct{})
+		dst.Subscribe(blockingSubscription(sub, started, release))
+
+		p := pubsub.New()
+		p.Subscribe(pubsub.NewForwarder(dst, pubsub.LinearTreeTraverser(nil)))
+
+		done := make(chan error, 1)
+		go func() {
+			_, err := p.PublishSync(context.Background(), "data", pubsub.LinearTreeTraverser(nil))
+			done <- err
+		}()
+
+		<-started
+		close(release)
+		Expect(t, <-done).To(BeNil())
+		Expect(t, sub.Data()).To(Equal([]interface{}{"data"}))
+	})
+
+	o.Spec("it returns the errors of the transformers", func(t *testing.T) {
+		p := pubsub.New(pubsub.WithAsyncDelivery(10, pubsub.OverflowBlock))
+		errA, errB := errors.New("a"), errors.New("b")
+		for _, err := range []error{errA, errB} {
+			err := err
+			p.Subscribe(newSpySubscrption(), pubsub.WithTransformer(func(ctx context.Context, data interface{}, path []string) (interface{}, error) {
+				return nil, err
+			}))
+		}
+		sub := newSpySubscrption()
+		p.Subscribe(sub)
+
+		r, err := p.PublishSync(context.Background(), "data", pubsub.LinearTreeTraverser(nil))
+		Expect(t, errors.Is(err, errA)).To(BeTrue())
+		Expect(t, errors.Is(err, errB)).To(BeTrue())
+		Expect(t, r.Delivered).To(Equal(1))
+		Expect(t, sub.Data()).To(Equal([]interface{}{"data"}))
+	})
+
+	o.Spec("it returns ErrDropped when the data is dropped", func(t *testing.T) {
+		p := pubsub.New(pubsub.WithAsyncDelivery(1, pubsub.OverflowDropOldest))
+		started, release := make(chan struct{}, 3), make(chan struct{})
+		p.Subscribe(blockingSubscription(newSpySubscrption(), started, release))
+
+		// The first data is being written and the second fills the queue.
+		p.Publish("first", pubsub.LinearTreeTraverser(nil))
+		<-started
+
+		done := make(chan error, 1)
+		go func() {
+			_, err := p.PublishSync(context.Background(), "second", pubsub.LinearTreeTraverser(nil))
+			done <- err
+		}()
+		dropped := func() bool {
+			r, _ := p.PublishCtx(context.Background(), "third", pubsub.LinearTreeTraverser(nil))
+			return r.Dropped > 0
+		}
+		Expect(t, dropped).To(ViaPolling(BeTrue()))
+
+		Expect(t, errors.Is(<-done, pubsub.ErrDropped)).To(BeTrue())
+		close(release)
+	})
+
+	o.Spec("it returns the context's error if it is done first", func(t *testing.T) {
+		p := pubsub.New(pubsub.WithAsyncDelivery(10, pubsub.OverflowBlock))
+		started, release := make(chan struct{}, 1), make(chan struct{})
+		defer close(release)
+		p.Subscribe(blockingSubscription(newSpySubscrption(), started, release))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		r, err := p.PublishSync(ctx, "data", pubsub.LinearTreeTraverser(nil))
+		Expect(t, errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+		Expect(t, r.Delivered).To(Equal(1))
+	})
+}
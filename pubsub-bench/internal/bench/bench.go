@@ -0,0 +1,239 @@
+// Package bench builds subscription trees and drives publishes against them
+// to measure the throughput, latency and allocations of a PubSub.
+package bench
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/apoydence/pubsub"
+)
+
+// Config describes a benchmark.
+type Config struct {
+	// Depth and Fanout describe the subscription tree: each node above
+	// Depth has Fanout children. Subscribers is the number of
+	// subscriptions at each leaf.
+	Depth       int
+	Fanout      int
+	Subscribers int
+
+	// Publishers is the number of goroutines that publish. Each publish
+	// goes to a random leaf.
+	Publishers int
+
+	// Rate is the target number of publishes per second across all the
+	// publishers. Zero means as fast as possible.
+	Rate float64
+
+	// Churn is the number of subscriptions per second that are added to
+	// (and removed from) random leaves while publishing. Zero means none.
+	Churn float64
+
+	// Duration is how long to publish for.
+	Duration time.Duration
+
+	// Options are given to the PubSub.
+	Options []pubsub.PubSubOption
+}
+
+// Result describes the outcome of a benchmark.
+type Result struct {
+	// Publishes and Deliveries are the number of publishes and of
+	// subscriptions written to (or enqueued for) in total.
+	Publishes  int64
+	Deliveries int64
+
+	// Elapsed is how long the publishes took.
+	Elapsed time.Duration
+
+	// Latencies of the publishes. With a Rate, a latency is measured from
+	// when the publish was scheduled so that a slow publish does not hide
+	// the delay it causes the next ones.
+	P50, P90, P99, Max time.Duration
+
+	// AllocsPerPublish and BytesPerPublish are the heap allocations per
+	// publish (see runtime.MemStats).
+	AllocsPerPublish float64
+	BytesPerPublish  float64
+}
+
+// Throughput returns the number of publishes per second.
+func (r Result) Throughput() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Publishes) / r.Elapsed.Seconds()
+}
+
+// Report writes the Result in a human readable form.
+func (r Result) Report(w io.Writer) error {
+	_, err := fmt.Fprintf(w, `publishes:    %d (%.0f/s)
+deliveries:   %d (%.0f/s)
+latency:      p50=%v p90=%v p99=%v max=%v
+allocations:  %.1f allocs/publish, %.0f B/publish
+`,
+		r.Publishes, r.Throughput(),
+		r.Deliveries, float64(r.Deliveries)/r.Elapsed.Seconds(),
+		r.P50, r.P90, r.P99, r.Max,
+		r.AllocsPerPublish, r.BytesPerPublish,
+	)
+	return err
+}
+
+// Leaves returns the paths of the leaves of the tree that the Config
+// describes.
+func (c Config) Leaves() [][]string {
+	leaves := [][]string{nil}
+	for d := 0; d < c.Depth; d++ {
+		next := make([][]string, 0, len(leaves)*c.Fanout)
+		for _, l := range leaves {
+			for i := 0; i < c.Fanout; i++ {
+				next = append(next, append(append([]string(nil), l...), strconv.Itoa(i)))
+			}
+		}
+		leaves = next
+	}
+	return leaves
+}
+
+// Run builds the tree and publishes until the Duration has passed or the
+// context is done.
+func Run(ctx context.Context, c Config) (Result, error) {
+	if c.Depth < 0 || c.Fanout < 1 || c.Subscribers < 0 || c.Publishers < 1 || c.Rate < 0 || c.Churn < 0 {
+		return Result{}, fmt.Errorf("invalid config: %+v", c)
+	}
+
+	p := pubsub.New(c.Options...)
+	defer p.Close()
+
+	leaves := c.Leaves()
+	sub := pubsub.SubscriptionFunc(func(interface{}) {})
+	for _, l := range leaves {
+		for i := 0; i < c.Subscribers; i++ {
+			p.Subscribe(sub, pubsub.WithPath(l))
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.Duration)
+	defer cancel()
+
+	var churnWG sync.WaitGroup
+	if c.Churn > 0 {
+		churnWG.Add(1)
+		go func() {
+			defer churnWG.Done()
+			churn(ctx, p, leaves, c.Churn)
+		}()
+	}
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	start := time.Now()
+	results := make([]publisherResult, c.Publishers)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = publish(ctx, p, leaves, c, int64(i))
+		}(i)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	runtime.ReadMemStats(&after)
+	churnWG.Wait()
+
+	r := Result{Elapsed: elapsed}
+	var h histogram
+	for _, pr := range results {
+		r.Publishes += pr.publishes
+		r.Deliveries += pr.deliveries
+		h.merge(&pr.latencies)
+	}
+	r.P50, r.P90, r.P99, r.Max = h.percentile(0.5), h.percentile(0.9), h.percentile(0.99), h.max
+
+	if r.Publishes > 0 {
+		r.AllocsPerPublish = float64(after.Mallocs-before.Mallocs) / float64(r.Publishes)
+		r.BytesPerPublish = float64(after.TotalAlloc-before.TotalAlloc) / float64(r.Publishes)
+	}
+
+	return r, nil
+}
+
+type publisherResult struct {
+	publishes  int64
+	deliveries int64
+	latencies  histogram
+}
+
+// publish publishes to random leaves until the context is done. Each
+// publisher is given an equal share of the rate.
+func publish(ctx context.Context, p *pubsub.PubSub, leaves [][]string, c Config, seed int64) publisherResult {
+	var (
+		r        publisherResult
+		rnd      = rand.New(rand.NewSource(seed))
+		interval time.Duration
+		next     = time.Now()
+	)
+	if c.Rate > 0 {
+		interval = time.Duration(float64(time.Second) * float64(c.Publishers) / c.Rate)
+	}
+
+	for ctx.Err() == nil {
+		start := time.Now()
+		if interval > 0 {
+			if d := next.Sub(start); d > 0 {
+				select {
+				case <-time.After(d):
+				case <-ctx.Done():
+					return r
+				}
+			}
+			start = next
+			next = next.Add(interval)
+		}
+
+		result, err := p.PublishCtx(ctx, "data", pubsub.LinearTreeTraverser(leaves[rnd.Intn(len(leaves))]))
+		if err != nil && ctx.Err() != nil {
+			// The publish was aborted when the benchmark ended.
+			return r
+		}
+		r.latencies.record(time.Since(start))
+		r.publishes++
+		r.deliveries += int64(result.Delivered)
+	}
+	return r
+}
+
+// churn subscribes to random leaves at the given rate. Each subscription
+// is removed once the next one has been added.
+func churn(ctx context.Context, p *pubsub.PubSub, leaves [][]string, rate float64) {
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / rate))
+	defer ticker.Stop()
+
+	rnd := rand.New(rand.NewSource(-1))
+	unsubscribe := func() {}
+	defer func() { unsubscribe() }()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		next := p.Subscribe(pubsub.SubscriptionFunc(func(interface{}) {}), pubsub.WithPath(leaves[rnd.Intn(len(leaves))]))
+		unsubscribe()
+		unsubscribe = next
+	}
+}
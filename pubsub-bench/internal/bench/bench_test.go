@@ -0,0 +1,82 @@
+package bench_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apoydence/pubsub/pubsub-bench/internal/bench"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+func TestBench(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.Spec("it describes the leaves of the tree", func(t *testing.T) {
+		c := bench.Config{Depth: 2, Fanout: 3}
+		Expect(t, c.Leaves()).To(Equal([][]string{
+			{"0", "0"}, {"0", "1"}, {"0", "2"},
+			{"1", "0"}, {"1", "1"}, {"1", "2"},
+			{"2", "0"}, {"2", "1"}, {"2", "2"},
+		}))
+	})
+
+	o.Spec("it publishes to every subscription of a leaf", func(t *testing.T) {
+		r, err := bench.Run(context.Background(), bench.Config{
+			Depth:       2,
+			Fanout:      3,
+			Subscribers: 2,
+			Publishers:  2,
+			Duration:    50 * time.Millisecond,
+		})
+		Expect(t, err).To(BeNil())
+
+		Expect(t, r.Publishes).To(BeAbove(0))
+		Expect(t, r.Deliveries).To(Equal(2 * r.Publishes))
+		Expect(t, r.Throughput()).To(BeAbove(0.0))
+		Expect(t, r.P50 <= r.P90 && r.P90 <= r.P99 && r.P99 <= r.Max).To(BeTrue())
+		Expect(t, r.Max > 0).To(BeTrue())
+
+		var b bytes.Buffer
+		Expect(t, r.Report(&b)).To(BeNil())
+		Expect(t, strings.Contains(b.String(), "p99=")).To(BeTrue())
+	})
+
+	o.Spec("it publishes while subscriptions are added and removed", func(t *testing.T) {
+		r, err := bench.Run(context.Background(), bench.Config{
+			Depth:       2,
+			Fanout:      3,
+			Subscribers: 1,
+			Publishers:  2,
+			Churn:       1000,
+			Duration:    50 * time.Millisecond,
+		})
+		Expect(t, err).To(BeNil())
+		Expect(t, r.Publishes).To(BeAbove(0))
+	})
+
+	o.Spec("it publishes at the given rate", func(t *testing.T) {
+		r, err := bench.Run(context.Background(), bench.Config{
+			Depth:       1,
+			Fanout:      1,
+			Subscribers: 1,
+			Publishers:  2,
+			Rate:        100,
+			Duration:    200 * time.Millisecond,
+		})
+		Expect(t, err).To(BeNil())
+		Expect(t, r.Publishes).To(BeAbove(5))
+		Expect(t, r.Publishes).To(BeBelow(26))
+	})
+
+	o.Spec("it rejects invalid configs", func(t *testing.T) {
+		_, err := bench.Run(context.Background(), bench.Config{Fanout: 0, Publishers: 1})
+		Expect(t, err).To(Not(BeNil()))
+	})
+}
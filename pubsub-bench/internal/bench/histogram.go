@@ -0,0 +1,90 @@
+package bench
+
+import (
+	"math/bits"
+	"time"
+)
+
+// subBuckets is the number of buckets each power of two is divided into.
+// The percentiles are therefore accurate to within 1/subBuckets.
+const subBuckets = 16
+
+// histogram records durations in logarithmic buckets so that it uses a
+// fixed amount of memory regardless of how many are recorded.
+type histogram struct {
+	counts [64 * subBuckets]int64
+	total  int64
+	max    time.Duration
+}
+
+func (h *histogram) record(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+
+	h.counts[bucket(uint64(d))]++
+	h.total++
+	if d > h.max {
+		h.max = d
+	}
+}
+
+func (h *histogram) merge(o *histogram) {
+	for i, c := range o.counts {
+		h.counts[i] += c
+	}
+	h.total += o.total
+	if o.max > h.max {
+		h.max = o.max
+	}
+}
+
+// percentile returns the upper bound of the bucket that holds the given
+// percentile (in [0, 1]). It never exceeds the maximum.
+func (h *histogram) percentile(q float64) time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+
+	rank := int64(q * float64(h.total))
+	if rank >= h.total {
+		rank = h.total - 1
+	}
+
+	var seen int64
+	for i, c := range h.counts {
+		seen += c
+		if seen > rank {
+			if d := time.Duration(upperBound(i)); d < h.max {
+				return d
+			}
+			return h.max
+		}
+	}
+	return h.max
+}
+
+// bucket returns the index of the bucket that holds v. Values below
+// subBuckets have a bucket each. Above, each power of two is divided into
+// subBuckets buckets.
+func bucket(v uint64) int {
+	if v < subBuckets {
+		return int(v)
+	}
+
+	// v>>exp has the highest bit of v and the bits that follow it, which
+	// is in [subBuckets, 2*subBuckets).
+	exp := bits.Len64(v) - bits.Len64(subBuckets)
+	return (exp+1)*subBuckets + int(v>>uint(exp)) - subBuckets
+}
+
+// upperBound returns the largest value that bucket i holds.
+func upperBound(i int) uint64 {
+	if i < subBuckets {
+		return uint64(i)
+	}
+
+	exp := i/subBuckets - 1
+	mantissa := uint64(i%subBuckets + subBuckets)
+	return (mantissa+1)<<uint(exp) - 1
+}
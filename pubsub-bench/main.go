@@ -0,0 +1,58 @@
+// pubsub-bench builds a subscription tree and publishes to it to measure
+// the throughput, latency and allocations of a PubSub. For example:
+//
+//	pubsub-bench -depth 4 -fanout 8 -subscribers 2 -publishers 4 -duration 30s
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/apoydence/pubsub"
+	"github.com/apoydence/pubsub/pubsub-bench/internal/bench"
+)
+
+func main() {
+	var c bench.Config
+	flag.IntVar(&c.Depth, "depth", 3, "The depth of the subscription tree")
+	flag.IntVar(&c.Fanout, "fanout", 4, "The number of children of each node")
+	flag.IntVar(&c.Subscribers, "subscribers", 1, "The number of subscriptions at each leaf")
+	flag.IntVar(&c.Publishers, "publishers", 1, "The number of goroutines that publish")
+	flag.Float64Var(&c.Rate, "rate", 0, "The target publishes per second (0 publishes as fast as possible)")
+	flag.Float64Var(&c.Churn, "churn", 0, "The subscriptions per second to add and remove while publishing")
+	flag.DurationVar(&c.Duration, "duration", 10*time.Second, "How long to publish for")
+	asyncBuffer := flag.Int("async-buffer", 0, "Deliver asynchronously with a queue of this size for each subscription, dropping data when it is full (see WithAsyncDelivery)")
+	fanoutConcurrency := flag.Int("fanout-concurrency", 0, "Write to this many subscriptions concurrently (see WithFanoutConcurrency)")
+	subtreeLocking := flag.Bool("subtree-locking", false, "Lock subtrees instead of the whole tree (see WithSubtreeLocking)")
+	flag.Parse()
+
+	if *asyncBuffer > 0 {
+		c.Options = append(c.Options, pubsub.WithAsyncDelivery(*asyncBuffer, pubsub.OverflowDrop))
+	}
+	if *fanoutConcurrency > 0 {
+		c.Options = append(c.Options, pubsub.WithFanoutConcurrency(*fanoutConcurrency))
+	}
+	if *subtreeLocking {
+		c.Options = append(c.Options, pubsub.WithSubtreeLocking())
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	fmt.Printf("tree:         depth=%d fanout=%d leaves=%d subscriptions=%d\n",
+		c.Depth, c.Fanout, len(c.Leaves()), len(c.Leaves())*c.Subscribers)
+
+	r, err := bench.Run(ctx, c)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := r.Report(os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -4,12 +4,12 @@ import (
 	"flag"
 	"testing"
 
-	"github.com/apoydence/onpar"
-	. "github.com/apoydence/onpar/expect"
-	. "github.com/apoydence/onpar/matchers"
 	"github.com/apoydence/pubsub"
 	. "github.com/apoydence/pubsub/pubsub-gen/internal/end2end"
 	"github.com/apoydence/pubsub/pubsub-gen/setters"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
 )
 
 func TestEnd2End(t *testing.T) {
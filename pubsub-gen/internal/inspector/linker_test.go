@@ -3,10 +3,10 @@ package inspector_test
 import (
 	"testing"
 
-	"github.com/apoydence/onpar"
-	. "github.com/apoydence/onpar/expect"
-	. "github.com/apoydence/onpar/matchers"
 	"github.com/apoydence/pubsub/pubsub-gen/internal/inspector"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
 )
 
 type TL struct {
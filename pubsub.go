@@ -13,38 +13,235 @@
 package pubsub
 
 import (
+	"context"
+	"errors"
+	"log/slog"
 	"math/rand"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/apoydence/pubsub/internal/intern"
 	"github.com/apoydence/pubsub/internal/node"
 )
 
 // PubSub uses the given SubscriptionEnroller to  create the subscription
 // tree. It also uses the TreeTraverser to then write to the subscriber. All
-// of PubSub's methods safe to access concurrently. PubSub should be
+// of PubSub's methods safe to access concurrently. Publishes read an
+// immutable version of the subscription tree, so they do not wait on
+// subscribes and unsubscribes (nor each other). PubSub should be
 // constructed with New().
 type PubSub struct {
-	mu rlocker
-	n  *node.Node
-	sa ShardingAlgorithm
+	// mu is held while changing the subscription tree, unless subtreeLocks
+	// is set (see WithSubtreeLocking). Publishes do not acquire either, they
+	// use the current version of the tree instead.
+	mu           rlocker
+	subtreeLocks []sync.Mutex
+	sa           ShardingAlgorithm
+
+	// tree is the current version of the subscription tree. retired holds
+	// older versions that publishes might still be using. Both are changed
+	// while holding commitMu.
+	tree     atomic.Pointer[tree]
+	retired  []*tree
+	commitMu sync.Mutex
+
+	// history holds the retained data and replay buffers. It is guarded by
+	// either the historyLock write lock or both its read lock and
+	// historyMu. Publishes that use the history hold historyLock while they
+	// traverse the tree so that they are ordered with subscriptions.
+	history     *historyNode
+	historyLock rlocker
+	historyMu   sync.Mutex
+	replaySize  int
+	seq         uint64
+
+	asyncBufferSize int
+	overflow        OverflowStrategy
+
+	crossNodeSharding bool
+
+	// topicDelim separates the segments of topic strings (see
+	// WithTopicDelimiter).
+	topicDelim rune
+
+	// mqtt is set with WithMQTTMatching.
+	mqtt bool
+
+	// fanout is the number of goroutines a publish may write with (see
+	// WithFanoutConcurrency).
+	fanout int
+
+	metrics Metrics
+	tracer  Tracer
+	clock   Clock
+	rand    *rand.Rand
+
+	// vars is set with WithExpvar.
+	vars *vars
+
+	// profilerLabels is set with WithProfilerLabels.
+	profilerLabels bool
+
+	// logger is set with WithLogger.
+	logger *slog.Logger
+
+	// deterministic is set with WithDeterministic.
+	deterministic bool
+
+	// dedupeSubscriptions is set with WithSubscriptionDeduplication.
+	dedupeSubscriptions bool
+
+	// ordered is set with WithOrderedDelivery. orderMu is then held by each
+	// publish while it holds its data for the subscriptions.
+	ordered bool
+	orderMu sync.Mutex
+
+	publishInterceptors   []PublishInterceptor
+	subscribeInterceptors []SubscribeInterceptor
+
+	beforePublish []func(data interface{}) interface{}
+	afterPublish  []func(data interface{}, result PublishResult)
+
+	deadLetter   Subscription
+	errorHandler func(data interface{}, err *SubscriptionError)
+
+	hooks     pathHooks
+	scheduler scheduler
+	names     nameRegistry
+
+	// segments holds the keys of the tree's nodes (see SegmentStats).
+	segments intern.Table
+
+	namespacesMu sync.Mutex
+	namespaces   map[string]*Namespace
+
+	maxTraversalDepth int
+	traversalErr      func(data interface{}, path []string, err error)
+
+	authorizer Authorizer
+
+	// subscriptions is the number of subscriptions the PubSub holds (not
+	// counting mounted PubSubs). See LimitOption for the others.
+	subscriptions    atomic.Int64
+	maxSubscriptions int
+	maxDepth         int
+	maxChildren      int
+	evictionPolicy   EvictionPolicy
+	evictionIndex    *evictionIndex
+	onEvict          func(info SubscriptionInfo)
+
+	idleTimeout time.Duration
+	idleMu      sync.Mutex
+	idleTimer   Timer
+	idleStopped bool
+
+	// nodeStats is set with WithNodeStats.
+	nodeStats bool
 }
 
 // New constructs a new PubSub.
 func New(opts ...PubSubOption) *PubSub {
 	p := &PubSub{
-		n:  node.New(),
-		sa: NewRandSharding(),
-		mu: &sync.RWMutex{},
+		mu:          &sync.RWMutex{},
+		history:     &historyNode{},
+		historyLock: &sync.RWMutex{},
+		clock:       systemClock{},
+
+		maxTraversalDepth: defaultMaxTraversalDepth,
 	}
+	p.tree.Store(&tree{root: node.New()})
+	p.scheduler.p = p
 
 	for _, o := range opts {
 		o.configure(p)
 	}
 
+	if _, ok := p.mu.(nopLock); ok {
+		p.subtreeLocks = nil
+	}
+
+	if p.deterministic && p.rand == nil {
+		p.rand = rand.New(newLockedSource(rand.NewSource(deterministicSeed)))
+	}
+
+	if p.sa == nil {
+		p.sa = RandSharding{p.rand}
+	}
+
+	p.evictionIndex = newEvictionIndex(p)
+
+	if p.idleTimeout > 0 {
+		p.armIdle(p.idleTimeout)
+	}
+
+	if p.nodeStats {
+		p.tree.Load().root.EnableStats()
+	}
+
 	return p
 }
 
+// ErrClosed is returned when publishing to a PubSub that has been closed.
+var ErrClosed = errors.New("pubsub is closed")
+
+// Closer is an optional interface that a Subscription can implement. If
+// implemented, Close is invoked when the PubSub the Subscription is
+// subscribed to is closed.
+type Closer interface {
+	Close()
+}
+
+// Close removes every subscription from the PubSub and notifies each
+// Subscription that implements Closer. Any data published afterwards is
+// dropped (PublishCtx returns ErrClosed) and any Subscription that
+// subscribes afterwards is closed immediately. It is safe to invoke Close
+// multiple times, including from within a Write. The Subscriptions are
+// notified once the publishes in progress are done. If there are any, Close
+// does not wait for them and the Subscriptions are notified in the
+// background.
+func (s *PubSub) Close() {
+	t := s.lockTree()
+	if t.closed {
+		s.unlockTree(t)
+		return
+	}
+
+	t.closed = true
+	n := t.root
+	t.reset()
+	if s.evictionIndex != nil {
+		s.evictionIndex.clear()
+	}
+	s.scheduler.close()
+	s.names.clear()
+	s.stopIdle()
+
+	s.historyLock.Lock()
+	s.history = &historyNode{}
+	s.historyLock.Unlock()
+
+	afterPublishes(s.unlockTree(t), func() {
+		walk(n, nil, func(path []string, n *node.Node) {
+			s.forEachSubscription(n, func(shardID string, ss []node.SubscriptionEnvelope) {
+				for _, x := range ss {
+					if s.metrics != nil {
+						s.metrics.Unsubscribed(path)
+					}
+					s.debug(context.Background(), "unsubscribed", pathAttr(path))
+					closeSubscription(x.Subscription)
+				}
+			})
+		})
+	})
+}
+
+func closeSubscription(sub Subscription) {
+	if c, ok := sub.(Closer); ok {
+		c.Close()
+	}
+}
+
 // PubSubOption is used to configure a PubSub.
 type PubSubOption interface {
 	configure(*PubSub)
@@ -57,11 +254,43 @@ func (f pubsubConfigFunc) configure(p *PubSub) {
 }
 
 // WithNoMutex configures a PubSub that does not have any internal mutexes.
-// This is useful if more complex or custom locking is required. For example,
-// if a subscription needs to subscribe while being published to.
+// This is useful if more complex or custom locking is required.
 func WithNoMutex() PubSubOption {
 	return pubsubConfigFunc(func(p *PubSub) {
 		p.mu = nopLock{}
+		p.historyLock = nopLock{}
+	})
+}
+
+// WithSubtreeLocking configures a PubSub to lock each top-level path
+// segment separately while subscribing and unsubscribing, instead of
+// locking the entire subscription tree. Subscriptions in disjoint
+// namespaces can then be added and removed without contending with each
+// other. Publishes never wait on either lock. It has no effect when
+// combined with WithNoMutex.
+func WithSubtreeLocking() PubSubOption {
+	return pubsubConfigFunc(func(p *PubSub) {
+		// The first lock is for the root path. The rest are shared by the
+		// top-level segments.
+		p.subtreeLocks = make([]sync.Mutex, 65)
+	})
+}
+
+// WithShardingAlgorithm configures the ShardingAlgorithm that is used to
+// pick which subscription of a shard group data is written to. It defaults
+// to RandSharding.
+func WithShardingAlgorithm(sa ShardingAlgorithm) PubSubOption {
+	return pubsubConfigFunc(func(p *PubSub) {
+		p.sa = sa
+	})
+}
+
+// WithDeadLetterSubscription configures a PubSub to write any published data
+// that did not match any subscriptions to the given Subscription. Data that
+// an ErrSubscription failed to write is also written to it.
+func WithDeadLetterSubscription(sub Subscription) PubSubOption {
+	return pubsubConfigFunc(func(p *PubSub) {
+		p.deadLetter = sub
 	})
 }
 
@@ -84,7 +313,10 @@ func (f SubscriptionFunc) Write(data interface{}) {
 // shardID and path.
 type ShardingAlgorithm interface {
 	// Write is invoked with the given data if publishing traverses to a node
-	// that has multiple subscriptions with the same shardID.
+	// that has multiple subscriptions with the same shardID. The
+	// subscriptions are written with the publish's context and path (see
+	// PathAwareSubscription) and count towards its PublishResult, so they
+	// must be written before Write returns and not be retained.
 	Write(data interface{}, subscriptions []Subscription)
 }
 
@@ -98,24 +330,40 @@ func (f ShardingAlgorithmFunc) Write(data interface{}, subscriptions []Subscript
 }
 
 // RandSharding implements ShardingAlgorithm. It picks a random subscription
-// to write to.
+// to write to. If Rand is set, it must be safe for concurrent use, as
+// publishes can happen concurrently. Otherwise the global source of
+// math/rand is used, which does not serialize publishes.
 type RandSharding struct {
 	*rand.Rand
 }
 
-// NewRandSharding constructs a new RandSharding.
+// NewRandSharding constructs a new RandSharding. It is safe for concurrent
+// use.
 func NewRandSharding() RandSharding {
-	return RandSharding{rand.New(rand.NewSource(time.Now().UnixNano()))}
+	return RandSharding{}
+}
+
+// NewRandShardingWithSource constructs a new RandSharding that uses the
+// given source. The source does not have to be safe for concurrent use.
+func NewRandShardingWithSource(src rand.Source) RandSharding {
+	return RandSharding{rand.New(newLockedSource(src))}
 }
 
 // Write implements ShardingAlgorithm.
 func (r RandSharding) Write(data interface{}, subscriptions []Subscription) {
-	idx := r.Rand.Intn(len(subscriptions))
+	idx := intn(r.Rand, len(subscriptions))
 	subscriptions[idx].Write(data)
 }
 
 // Unsubscriber is returned by Subscribe. It should be invoked to
-// remove a subscription from the PubSub.
+// remove a subscription from the PubSub. It is safe to invoke multiple times
+// and after the PubSub has been closed. Once it returns, publishes no longer
+// write to the subscription. If no Writes to the subscription are in
+// progress, that includes the publishes that already started. Otherwise, it
+// might have been invoked from within one of them (e.g., for a
+// subscription to remove itself, possibly from another goroutine), so it
+// does not wait for them to return. The subscription is then stopped once
+// they do.
 type Unsubscriber func()
 
 // SubscribeOption is used to configure a subscription while subscribing.
@@ -132,9 +380,29 @@ func WithShardID(shardID string) SubscribeOption {
 	})
 }
 
+// Any is a path segment that matches any single segment yielded by the
+// publishing TreeTraverser. For example, a subscription with the path
+// []string{"orders", pubsub.Any, "created"} will receive data published to
+// both []string{"orders", "us", "created"} and
+// []string{"orders", "eu", "created"}.
+const Any = "\x00any"
+
+// Rest is a path segment that matches one or more segments yielded by the
+// publishing TreeTraverser. It must be the last segment of a path. For
+// example, a subscription with the path []string{"orders", pubsub.Rest} will
+// receive data published to []string{"orders", "us"} and
+// []string{"orders", "us", "created"}, but not to []string{"orders"}.
+//
+// Note that a subscription without Rest already receives data published
+// beneath its path. Rest is useful when the data published to the prefix
+// itself is not wanted, or in combination with Any.
+const Rest = "\x00rest"
+
 // WithPath configures a subscription to reside at a path. The path determines
 // what data the subscription is interested in. This value should be
-// correspond to what the publishing TreeTraverser yields.
+// correspond to what the publishing TreeTraverser yields. A segment of the
+// path may be Any (or another pattern, such as Regexp) and the last segment
+// may be Rest.
 // It defaults to nil (meaning it gets everything).
 func WithPath(path []string) SubscribeOption {
 	return subscribeConfigFunc(func(c *subscribeConfig) {
@@ -142,9 +410,165 @@ func WithPath(path []string) SubscribeOption {
 	})
 }
 
+// WithBufferSize configures how much data can be buffered for a
+// subscription that is buffered (e.g., SubscribeChan, SubscribePull or
+// WithOverflowStrategy).
+// It defaults to the PubSub's async buffer size if WithAsyncDelivery was
+// used and 100 otherwise.
+func WithBufferSize(size int) SubscribeOption {
+	return subscribeConfigFunc(func(c *subscribeConfig) {
+		c.bufferSize = size
+	})
+}
+
+// WithOverflowStrategy configures a subscription to be buffered (see
+// WithBufferSize) and to be written to from its own goroutine. The given
+// OverflowStrategy determines what happens when the subscription can not
+// keep up with the publishers. It overrides any strategy given to
+// WithAsyncDelivery.
+func WithOverflowStrategy(s OverflowStrategy) SubscribeOption {
+	return subscribeConfigFunc(func(c *subscribeConfig) {
+		c.overflow = &s
+	})
+}
+
+// WithContext configures a subscription to be removed once the given
+// context is cancelled or its deadline passes.
+func WithContext(ctx context.Context) SubscribeOption {
+	return subscribeConfigFunc(func(c *subscribeConfig) {
+		c.ctx = ctx
+	})
+}
+
+// WithFilter configures a subscription to only have data written to it that
+// the given function returns true for. The function is invoked by the
+// publisher after the path matched.
+func WithFilter(f func(data interface{}) bool) SubscribeOption {
+	return subscribeConfigFunc(func(c *subscribeConfig) {
+		c.filters = append(c.filters, f)
+	})
+}
+
+// WithMapper configures a subscription to have the result of the given
+// function written to it instead of the published data. This allows each
+// subscription to have its own view of the data. The function is invoked
+// by the publisher after any filters (see WithFilter) and must not modify
+// the published data. Multiple mappers are applied in the order they are
+// given.
+func WithMapper(f func(data interface{}) interface{}) SubscribeOption {
+	return subscribeConfigFunc(func(c *subscribeConfig) {
+		c.mappers = append(c.mappers, f)
+	})
+}
+
+// WithDrainOnUnsubscribe configures the Unsubscriber of a subscription to
+// block until every delivery to the subscription is done, including the
+// data that is queued for it (see WithAsyncDelivery) and any partial batch.
+// This allows downstream resources (e.g., a channel or socket) to be closed
+// once it returns. It must not be invoked from the subscription's Write.
+// See SubscriptionHandle.UnsubscribeAndDrain.
+func WithDrainOnUnsubscribe() SubscribeOption {
+	return subscribeConfigFunc(func(c *subscribeConfig) {
+		c.drain = true
+	})
+}
+
+// WithBatching configures a subscription to have data written to it in
+// batches ([]interface{}) instead of one at a time. A batch is written once
+// it has maxSize entries or maxDelay has passed since its first entry was
+// added (whichever is first). A maxDelay of 0 means batches are only written
+// once full. Any partial batch is written when the subscription is removed.
+func WithBatching(maxSize int, maxDelay time.Duration) SubscribeOption {
+	return subscribeConfigFunc(func(c *subscribeConfig) {
+		c.batchSize = maxSize
+		c.batchDelay = maxDelay
+	})
+}
+
+// WithMaxDeliveries configures a subscription to be removed once n messages
+// have been written to it. WithMaxDeliveries(1) can be used for a
+// subscription that only wants to be written to once. Data that is filtered
+// or sampled out does not count towards n.
+func WithMaxDeliveries(n int) SubscribeOption {
+	return subscribeConfigFunc(func(c *subscribeConfig) {
+		c.maxDeliveries = int64(n)
+	})
+}
+
+// WithTTL configures a subscription to be removed once the given duration
+// has passed. See WithExpiryFunc to be notified when this happens.
+func WithTTL(d time.Duration) SubscribeOption {
+	return subscribeConfigFunc(func(c *subscribeConfig) {
+		c.ttl = d
+	})
+}
+
+// WithExpiryFunc configures a function to be invoked when a subscription is
+// removed because its TTL (see WithTTL) has passed. It is not invoked when
+// the subscription is unsubscribed before then.
+func WithExpiryFunc(f func()) SubscribeOption {
+	return subscribeConfigFunc(func(c *subscribeConfig) {
+		c.onExpire = f
+	})
+}
+
+const defaultBufferSize = 100
+
 type subscribeConfig struct {
-	shardID string
-	path    []string
+	shardID    string
+	path       []string
+	aliases    [][]string
+	bufferSize int
+	overflow   *OverflowStrategy
+	ctx        context.Context
+	replay     int
+	filters    []func(data interface{}) bool
+	mappers    []func(data interface{}) interface{}
+	batchSize  int
+	batchDelay time.Duration
+	coalesce   time.Duration
+	throttle   bool
+
+	transformers []func(ctx context.Context, data interface{}, path []string) (interface{}, error)
+
+	sampleEvery int
+	sampleRate  *float64
+
+	maxDeliveries int64
+
+	ttl      time.Duration
+	onExpire func()
+
+	pausable        bool
+	pull            bool
+	pauseBufferSize int
+
+	priority int
+	drain    bool
+
+	ackTimeout  time.Duration
+	maxAttempts int
+	maxInFlight int
+	retries     int
+
+	dedupeID     func(data interface{}) string
+	dedupeWindow time.Duration
+
+	metadata map[string]string
+
+	// names is the registry of the PubSub that a named subscription was
+	// given to (see WithName), which might not be the one that holds it.
+	name  string
+	names *nameRegistry
+}
+
+func newSubscribeConfig(opts []SubscribeOption) subscribeConfig {
+	c := subscribeConfig{}
+	for _, o := range opts {
+		o.configure(&c)
+	}
+
+	return c
 }
 
 type subscribeConfigFunc func(*subscribeConfig)
@@ -155,42 +579,295 @@ func (f subscribeConfigFunc) configure(c *subscribeConfig) {
 
 // Subscribe will add a subscription  to the PubSub. It returns a function
 // that can be used to unsubscribe.  Options can be provided to configure
-// the subscription and its interactions with published data.
+// the subscription and its interactions with published data. It is safe to
+// invoke from within a Write, in which case the subscription is written the
+// data that is published afterwards.
 func (s *PubSub) Subscribe(sub Subscription, opts ...SubscribeOption) Unsubscriber {
-	c := subscribeConfig{}
-	for _, o := range opts {
-		o.configure(&c)
+	_, unsubscribe, _ := s.subscribe(sub, newSubscribeConfig(opts))
+	return unsubscribe
+}
+
+// SubscribeErr adds a subscription to the PubSub (see Subscribe). Unlike
+// Subscribe, it reports why a subscription could not be added: ErrClosed if
+// the PubSub (or the mounted PubSub that would hold it) is closed,
+// ErrInvalidPath if the path is malformed, ErrLimitExceeded if it would
+// exceed a limit (see LimitOption), or the error of the Authorizer that
+// rejected it (see WithAuthorizer). The Subscription is closed in that
+// case and the returned Unsubscriber does nothing.
+func (s *PubSub) SubscribeErr(sub Subscription, opts ...SubscribeOption) (Unsubscriber, error) {
+	_, unsubscribe, err := s.subscribe(sub, newSubscribeConfig(opts))
+	return unsubscribe, err
+}
+
+// subscribe returns a nil subscriber and the reason if the subscription
+// could not be added.
+func (s *PubSub) subscribe(sub Subscription, c subscribeConfig) (*subscriber, Unsubscriber, error) {
+	if err := s.checkSubscribe(c); err != nil {
+		closeSubscription(sub)
+		return nil, func() {}, err
 	}
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	// A named subscription is registered with the PubSub it was given to,
+	// even if a mounted PubSub holds it.
+	replacing := c.name != "" && c.names == nil
+	paths := append([][]string{c.path}, c.aliases...)
+	if replacing {
+		c.names = &s.names
 
-	n := s.n
-	for _, p := range c.path {
-		n = n.AddChild(p)
+		// The subscription it replaces might be anywhere in the tree.
+		paths = nil
+	}
+	if s.limited() {
+		paths = nil
+	}
+
+	t := s.lockTree(paths...)
+	if t.closed {
+		s.unlockTree(t)
+		closeSubscription(sub)
+		return nil, func() {}, ErrClosed
 	}
-	id := n.AddSubscription(sub, c.shardID)
 
-	return func() {
-		s.mu.Lock()
-		defer s.mu.Unlock()
+	var old *subscriber
+	if replacing {
+		old = s.replaceLocked(t, c.name)
+	}
+
+	sr, err := s.subscribeLocked(t, sub, c)
+	if replacing && sr != nil {
+		s.names.set(sr)
+	}
 
-		s.cleanupSubscriptionTree(s.n, id, c.path)
+	// The history lock is held until the new version of the tree is stored
+	// so that publishes that use the history are either written to the
+	// subscription (after the history, see subscriber.hold) or are in the
+	// history it is written.
+	s.historyLock.Lock()
+	history := s.historyFor(sr, c)
+	s.unlockTree(t)
+	s.historyLock.Unlock()
+	if history != nil {
+		sr.writeHistory(history)
 	}
+	s.hooks.dispatch()
+
+	if old != nil {
+		old.afterWrites(old.stop)
+	}
+	s.evict(t)
+
+	if sr == nil {
+		return nil, func() {}, err
+	}
+
+	if c.drain {
+		return sr, func() {
+			sr.p.unsubscribeAndDrain(sr)
+		}, nil
+	}
+
+	return sr, func() {
+		sr.p.unsubscribe(sr)
+	}, nil
 }
 
-func (s *PubSub) cleanupSubscriptionTree(n *node.Node, id int64, p []string) {
-	if len(p) == 0 {
-		n.DeleteSubscription(id)
-		return
+// checkSubscribe returns an error if the subscription must not be added
+// regardless of the state of the tree.
+func (s *PubSub) checkSubscribe(c subscribeConfig) error {
+	if !validPath(c.path) {
+		return ErrInvalidPath
+	}
+	if s.exceedsDepth(c.path) {
+		return ErrLimitExceeded
+	}
+	if err := s.authorizeSubscribe(c); err != nil {
+		return err
+	}
+
+	for _, path := range c.aliases {
+		c.path, c.aliases = path, nil
+		if err := s.checkSubscribe(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// subscribeLocked must be invoked while holding the write lock for the
+// path (see lockTree). The history must be read (see historyFor) before
+// the new version of the tree is stored. It returns nil and the
+// reason if the subscription was not added (e.g., it would exceed a limit or
+// was delegated to a mounted PubSub that is closed), in which case the
+// Subscription has been closed.
+func (s *PubSub) subscribeLocked(t *treeTxn, sub Subscription, c subscribeConfig) (*subscriber, error) {
+	for _, path := range c.aliases {
+		if _, _, ok := t.mountFor(path); ok {
+			closeSubscription(sub)
+			return nil, ErrPathInUse
+		}
+	}
+
+	if m, path, ok := t.mountFor(c.path); ok {
+		if len(c.aliases) > 0 {
+			closeSubscription(sub)
+			return nil, ErrPathInUse
+		}
+
+		c.path = path
+		sr, _, err := m.subscribe(sub, c)
+		return sr, err
+	}
+
+	if err := s.limitLocked(t, c.path); err != nil {
+		closeSubscription(sub)
+		return nil, err
+	}
+
+	n := t.node(c.path)
+
+	sr := s.newSubscriber(sub, c)
+	sr.p = s
+	sr.subscribed = s.clock.Now()
+	sr.path = c.path
+	sr.shardID = c.shardID
+	sr.priority = c.priority
+	sr.metadata = c.metadata
+	sr.id = n.AddSubscription(sr, c.shardID)
+	if sr.priority != 0 {
+		n.SetPriority(sr.id, sr.priority)
+	}
+	sr.subtree.Store(int32(s.subtreeOfPath(c.path)))
+	s.hooks.record(c.path, n.SubscriptionLen(), true)
+	s.subscriptions.Add(1)
+	if s.evictionIndex != nil {
+		s.evictionIndex.add(sr)
 	}
 
-	child := n.FetchChild(p[0])
-	s.cleanupSubscriptionTree(child, id, p[1:])
+	sr.disconnect = func() {
+		s.unsubscribe(sr)
+	}
+	if sr.q != nil {
+		sr.q.disconnect = sr.disconnect
+	}
+
+	// The functions below wait for the lock before they can remove the
+	// subscription, so it is safe to set stopCtx and stopTTL afterwards.
+	if c.ctx != nil {
+		sr.stopCtx = context.AfterFunc(c.ctx, sr.disconnect)
+	}
+
+	if c.ttl > 0 {
+		sr.stopTTL = s.clock.AfterFunc(c.ttl, func() {
+			if s.unsubscribe(sr) && c.onExpire != nil {
+				c.onExpire()
+			}
+		}).Stop
+	}
 
-	if child.ChildLen() == 0 && child.SubscriptionLen() == 0 {
-		n.DeleteChild(p[0])
+	if len(c.aliases) > 0 && sr.once == nil {
+		sr.once = sr
+	}
+	for _, path := range c.aliases {
+		err := s.addAliasLocked(t, sr, path)
+		if err == nil && sr.removed.Load() {
+			// It was evicted to make room for its own path, and is
+			// closed as such.
+			return nil, ErrLimitExceeded
+		}
+		if err != nil {
+			s.removeLocked(t, sr)
+			sr.stop()
+			closeSubscription(sub)
+			return nil, err
+		}
 	}
+
+	return sr, nil
+}
+
+// historyFor returns the retained and replayed data for the paths of the
+// subscriber. It must be invoked while holding the history write lock and
+// before the new version of the tree is stored. If it returns any data, the
+// subscriber holds the data that is published to it until the history has
+// been written with writeHistory (once the locks are released). It returns
+// nil if the subscription was delegated to a mounted PubSub, which writes
+// its own history.
+func (s *PubSub) historyFor(sr *subscriber, c subscribeConfig) []interface{} {
+	if sr == nil || sr.p != s {
+		return nil
+	}
+
+	data := s.retained(nil, c.path)
+	data = s.replayed(data, c.path, c.replay)
+	for _, path := range c.aliases {
+		data = s.retained(data, path)
+		data = s.replayed(data, path, c.replay)
+	}
+
+	if len(data) == 0 {
+		return nil
+	}
+	sr.busy.Store(true)
+	return data
+}
+
+// unsubscribe returns false if the subscriber was already removed.
+func (s *PubSub) unsubscribe(sr *subscriber) bool {
+	t := s.lockSubscriber(sr)
+	removed := s.removeLocked(t, sr)
+	s.unlockTree(t)
+	s.hooks.dispatch()
+
+	if removed {
+		sr.afterWrites(sr.stop)
+	}
+	return removed
+}
+
+// unsubscribeAndDrain removes the subscriber (see unsubscribe) and then
+// waits for any data that is queued for it to be written.
+func (s *PubSub) unsubscribeAndDrain(sr *subscriber) {
+	s.unsubscribe(sr)
+	sr.drain()
+}
+
+// removeLocked must be invoked while holding the subscriber's write lock
+// (see lockSubscriber). If it returns true, the subscriber must be stopped
+// once the publishes that might be using it are done.
+func (s *PubSub) removeLocked(t *treeTxn, sr *subscriber) bool {
+	if sr.removed.Load() {
+		return false
+	}
+	sr.removed.Store(true)
+
+	if sr.names != nil {
+		sr.names.remove(sr)
+	}
+
+	if sr.stopCtx != nil {
+		sr.stopCtx()
+	}
+	if sr.stopTTL != nil {
+		sr.stopTTL()
+	}
+
+	if !t.closed {
+		t.removeSubscription(sr.id, sr.path)
+		s.hooks.record(sr.path, t.fetch(sr.path).SubscriptionLen(), false)
+		s.subscriptions.Add(-1)
+		if s.evictionIndex != nil {
+			s.evictionIndex.remove(sr)
+		}
+		if s.metrics != nil {
+			s.metrics.Unsubscribed(sr.path)
+		}
+		s.debug(context.Background(), "unsubscribed", pathAttr(sr.path))
+	}
+
+	for _, a := range sr.aliases {
+		s.removeLocked(t, a)
+	}
+	return true
 }
 
 // TreeTraverser publishes data to the correct subscriptions. Each
@@ -201,7 +878,10 @@ func (s *PubSub) cleanupSubscriptionTree(n *node.Node, id int64, p []string) {
 // Traversing a path ends when the return len(paths) == 0. If
 // len(paths) > 1, then each path will be traversed.
 type TreeTraverser interface {
-	// Traverse is used to traverse the subscription tree.
+	// Traverse is used to traverse the subscription tree. The currentPath
+	// is the path that has been traversed so far. It must not be modified
+	// and is only guaranteed to be stable for the duration of the call, so
+	// it must be copied to be kept.
 	Traverse(data interface{}, currentPath []string) Paths
 }
 
@@ -299,52 +979,599 @@ func (t PathAndTraversers) At(idx int) (string, TreeTraverser, bool) {
 }
 
 // Publish writes data using the TreeTraverser to the interested subscriptions.
-func (s *PubSub) Publish(d interface{}, a TreeTraverser) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	s.traversePublish(d, d, a, s.n, nil, make(map[*node.Node]bool))
+// Options can be provided to configure how the data is published.
+func (s *PubSub) Publish(d interface{}, a TreeTraverser, opts ...PublishOption) {
+	s.PublishCtx(context.Background(), d, a, opts...)
 }
 
-func (s *PubSub) traversePublish(d, next interface{}, a TreeTraverser, n *node.Node, l []string, history map[*node.Node]bool) {
-	if n == nil {
-		return
+// PublishCtx writes data using the TreeTraverser to the interested
+// subscriptions. It returns a PublishResult that describes what happened to
+// the data. If the context is cancelled or its deadline passes, the
+// traversal and any remaining writes are aborted and the context's error is
+// returned. If the traversal got too deep (see WithMaxTraversalDepth),
+// ErrMaxTraversalDepth is returned. If the Authorizer rejects the data (see
+// WithAuthorizer), its error is returned.
+func (s *PubSub) PublishCtx(ctx context.Context, d interface{}, a TreeTraverser, opts ...PublishOption) (PublishResult, error) {
+	// Configuring the options makes the config escape to the heap, so it
+	// is avoided when there aren't any.
+	var c publishConfig
+	if len(opts) > 0 {
+		c = newPublishConfig(opts)
 	}
 
-	if _, ok := history[n]; !ok {
-		n.ForEachSubscription(func(shardID string, ss []node.SubscriptionEnvelope) {
-			if shardID == "" {
-				for _, x := range ss {
-					x.Subscription.Write(d)
-				}
-				return
-			}
+	if len(s.publishInterceptors) == 0 {
+		return s.publish(ctx, d, a, c)
+	}
 
-			var subs []Subscription
-			for _, x := range ss {
-				subs = append(subs, x)
+	f := PublishFunc(func(ctx context.Context, d interface{}, a TreeTraverser) (PublishResult, error) {
+		return s.publish(ctx, d, a, c)
+	})
+	for i := len(s.publishInterceptors) - 1; i >= 0; i-- {
+		f = s.publishInterceptors[i](f)
+	}
+
+	return f(ctx, d, a)
+}
+
+// PublishResult describes what happened to published data.
+type PublishResult struct {
+	// Matched is the number of subscriptions whose path matched the data.
+	// This includes every subscription of a shard group.
+	Matched int
+
+	// Delivered is the number of subscriptions the data was written to (or
+	// enqueued for).
+	Delivered int
+
+	// Dropped is the number of times data was dropped because a
+	// subscription could not keep up (see OverflowStrategy).
+	Dropped int
+
+	// Errors are the SubscriptionErrors of the ErrSubscriptions that failed
+	// to write the data, along with the errors of mounted PubSubs that
+	// failed to publish it (see Mount). Writes that are queued (see
+	// WithAsyncDelivery) fail after the publish, so their errors are only
+	// returned by PublishSync.
+	Errors []error
+}
+
+func (s *PubSub) publish(ctx context.Context, d interface{}, a TreeTraverser, c publishConfig) (PublishResult, error) {
+	// The system events of drops are dispatched once the locks below are
+	// released.
+	if s.hooks.sys != nil {
+		defer s.hooks.dispatch()
+	}
+
+	if s.authorizer != nil {
+		if err := s.authorizer.AuthorizePublish(ctx, d); err != nil {
+			return PublishResult{}, err
+		}
+	}
+
+	// Publishes that use the history hold the history lock while they
+	// traverse the tree, so that they are ordered with subscriptions (see
+	// historyFor). It is released before any Subscription is written to.
+	history := c.retain || s.replaySize > 0
+	unlockHistory := s.lockHistory(c.retain)
+	defer func() {
+		unlockHistory()
+	}()
+
+	t := s.acquire()
+	defer t.release()
+
+	if t.closed {
+		return PublishResult{}, ErrClosed
+	}
+
+	for _, f := range s.beforePublish {
+		d = f(d)
+	}
+
+	p := newPublish(ctx, d)
+	defer p.release()
+	p.retain = c.retain
+	p.mounted = c.mounted
+	if m, ok := d.(RequestMessage); ok {
+		p.responders = m.responders
+	}
+
+	if s.logger != nil {
+		defer func() {
+			if r := recover(); r != nil {
+				s.debug(ctx, "panicked", pathAttr(p.path), "panic", r)
+				panic(r)
 			}
+		}()
+	}
 
-			s.sa.Write(d, subs)
-		})
-		history[n] = true
+	if s.metrics != nil {
+		start := s.clock.Now()
+		defer func() {
+			s.metrics.Published(p.result.Delivered, s.clock.Now().Sub(start))
+		}()
+	}
+
+	if s.tracer != nil {
+		p.ctx, p.span = s.tracer.StartPublish(ctx)
+		defer func() {
+			p.span.End(p.result.Delivered)
+		}()
+	}
+
+	p.ordered = s.ordered
+	p.deferWrites = (s.fanout > 1 || history) && !s.ordered
+	s.traverse(p, a, t.root)
+
+	unlockHistory()
+	unlockHistory = func() {}
+
+	if p.ordered {
+		p.flush(s.fanout)
+	} else {
+		p.fanout(s.fanout)
+		s.writeShardGroups(p)
+	}
+	s.publishMounts(p)
+
+	if p.result.Matched == 0 && p.ctx.Err() == nil {
+		if s.deadLetter != nil {
+			s.deadLetter.Write(d)
+		}
+		s.debug(ctx, "no match")
+	}
+
+	if s.vars != nil {
+		s.vars.record(p.result)
+	}
+
+	for _, f := range s.afterPublish {
+		f(d, p.result)
+	}
+
+	if err := p.ctx.Err(); err != nil {
+		return p.result, err
+	}
+	return p.result, p.err
+}
+
+// lockHistory locks the history for a publish (if it uses it) and returns
+// the function that unlocks it. Retaining data alters the history and
+// therefore requires the write lock.
+func (s *PubSub) lockHistory(retain bool) func() {
+	switch {
+	case retain:
+		s.historyLock.Lock()
+		return s.historyLock.Unlock
+	case s.replaySize > 0:
+		s.historyLock.RLock()
+		return s.historyLock.RUnlock
+	}
+	return func() {}
+}
+
+// traverse traverses the tree and writes the data to the subscriptions it
+// reaches, unless the writes are deferred (see publish.deferWrites). With
+// WithOrderedDelivery, it holds orderMu and the data is only held for the
+// subscribers (see publish.order), including the shard groups.
+func (s *PubSub) traverse(p *publish, a TreeTraverser, root *node.Node) {
+	if s.ordered {
+		s.orderMu.Lock()
+		defer s.orderMu.Unlock()
+	}
+
+	if s.replaySize > 0 {
+		p.seq = atomic.AddUint64(&s.seq, 1)
 	}
+	p.crossNodeSharding = s.crossNodeSharding
+	if p.crossNodeSharding && p.shardGroups == nil {
+		p.shardGroups = make(map[string][]shardMember)
+	}
+	s.traversePublish(p, a, root)
+
+	if s.ordered {
+		s.writeShardGroups(p)
+	}
+}
+
+// PublishOption is used to configure a Publish.
+type PublishOption interface {
+	configure(*publishConfig)
+}
+
+type publishConfig struct {
+	retain bool
+
+	// mounted is set for publishes to a mounted PubSub (see Mount).
+	mounted bool
+}
+
+func newPublishConfig(opts []PublishOption) publishConfig {
+	var c publishConfig
+	for _, o := range opts {
+		o.configure(&c)
+	}
+	return c
+}
+
+type publishConfigFunc func(*publishConfig)
+
+func (f publishConfigFunc) configure(c *publishConfig) {
+	f(c)
+}
+
+// publish holds the state of a single Publish.
+type publish struct {
+	ctx    context.Context
+	data   interface{}
+	retain bool
+	seq    uint64
+
+	// mounted is set if the PubSub is mounted, so its root is not the
+	// root of the subscription tree.
+	mounted bool
+
+	// responders is set if the data is a RequestMessage. It counts the
+	// subscriptions that are not at a catch-all path (see
+	// traverseFrame.catchAll).
+	responders *atomic.Int64
+
+	// visited holds the nodes that have been written to. Most publishes
+	// only visit a few nodes, so a slice is used until there are too many
+	// and then visitedMap is used instead.
+	visited    []*node.Node
+	visitedMap map[*node.Node]struct{}
+	useMap     bool
+
+	// stack and path are used by traversePublish.
+	stack []traverseFrame
+	path  []string
+
+	result PublishResult
+
+	// crossNodeSharding is set with WithCrossNodeSharding. The shard
+	// groups are gathered in shardGroups and written once the traversal is
+	// done.
+	crossNodeSharding bool
+	shardGroups       map[string][]shardMember
+
+	// span is only set with WithTracer.
+	span PublishSpan
+
+	// dryRun is set by Match. The matched subscriptions are recorded
+	// instead of being written to.
+	dryRun  bool
+	matches []SubscriptionInfo
+
+	// deferWrites is set with WithFanoutConcurrency or if the publish
+	// holds the history lock while it traverses. The writes are queued in
+	// pending (and the publishes to mounted PubSubs in mounts) and written
+	// once the traversal is done.
+	deferWrites bool
+	pending     []fanoutWrite
+	mounts      []pendingMount
+
+	// written are the keys of the subscribers that have been written to
+	// and must not be written to again (see subscriber.once). Like
+	// visited, they are moved to writtenMap once there are too many.
+	written    []interface{}
+	writtenMap map[interface{}]struct{}
+
+	// err is set if the traversal was stopped (e.g., with
+	// ErrMaxTraversalDepth).
+	err error
+
+	// ordered is set with WithOrderedDelivery. The subscribers that the
+	// publish claimed (see publish.order) are gathered in claimed.
+	ordered bool
+	claimMu sync.Mutex
+	claimed []*subscriber
+}
+
+// write writes the data to the subscription that was reached via the path.
+// The write is counted in the Stats of the node that holds the
+// subscription (if they are enabled).
+func (p *publish) write(sub Subscription, l []string, st *node.Stats) {
+	if sr, ok := sub.(*subscriber); ok && sr.once != nil && !p.writeOnce(sr.once) {
+		return
+	}
+
+	if p.deferWrites {
+		p.deferWrite(sub, l, st)
+		return
+	}
+
+	delivered, dropped, err := p.deliver(sub, l)
+	p.result.Dropped += dropped
+	if err != nil {
+		p.result.Errors = append(p.result.Errors, err)
+	}
+	if delivered {
+		p.wrote(l)
+	}
+	countWrite(st, delivered, dropped)
+}
+
+// deliver writes the data to the subscription. It returns whether the data
+// was delivered, how much data the subscription dropped and the error of
+// an ErrSubscription. It does not change the publish, so it can be invoked
+// concurrently.
+func (p *publish) deliver(sub Subscription, l []string) (bool, int, error) {
+	return p.deliverData(sub, p.data, l)
+}
+
+// deliverData is deliver with the data that a ShardingAlgorithm wrote.
+func (p *publish) deliverData(sub Subscription, data interface{}, l []string) (bool, int, error) {
+	// A nil path means the path is not known.
+	path := l
+	if path == nil {
+		path = []string{}
+	}
+
+	if sr, ok := sub.(*subscriber); ok {
+		if p.ordered {
+			return p.order(sr, data, path), 0, nil
+		}
+		return sr.write(p.ctx, data, path)
+	}
+
+	if ps, ok := sub.(PathAwareSubscription); ok {
+		ps.WritePath(data, append(path[:0:0], path...))
+	} else {
+		sub.Write(data)
+	}
+	return true, 0, nil
+}
+
+// wrote records that the data was written to a subscription that was
+// reached via the path.
+func (p *publish) wrote(l []string) {
+	p.result.Delivered++
+	if p.span != nil {
+		p.span.Wrote(l)
+	}
+}
+
+// traverseFrame is a node that is waiting to be traversed.
+type traverseFrame struct {
+	a TreeTraverser
+	n *node.Node
 
-	paths := a.Traverse(next, l)
+	// depth is the length of the path to n and segment is the last
+	// segment of it.
+	depth   int
+	segment string
 
-	for i := 0; ; i++ {
-		child, nextA, ok := paths.At(i)
-		if !ok {
+	// catchAll is set if n is the root or beneath an Any that is the
+	// first segment. Its subscriptions receive data published to any
+	// path, so they are not counted as the responders of a Request.
+	catchAll bool
+}
+
+// traversePublish walks the subscription tree (depth first) using an
+// explicit stack. A single path buffer is shared by every frame: a frame's
+// ancestors are never overwritten before it is popped, so only its own
+// segment has to be set.
+func (s *PubSub) traversePublish(p *publish, a TreeTraverser, n *node.Node) {
+	p.stack = append(p.stack[:0], traverseFrame{a: a, n: n, catchAll: !p.mounted})
+
+	var label string
+	if s.profilerLabels && !p.dryRun {
+		defer p.restoreGoroutineLabels()
+	}
+
+	for len(p.stack) > 0 && p.ctx.Err() == nil {
+		f := p.stack[len(p.stack)-1]
+		p.stack = p.stack[:len(p.stack)-1]
+
+		p.path = p.path[:f.depth]
+		if f.depth > 0 {
+			p.path[f.depth-1] = f.segment
+		}
+
+		if s.profilerLabels && !p.dryRun && f.depth > 0 {
+			p.labelGoroutine(&label)
+		}
+
+		s.traverseNode(p, f)
+	}
+}
+
+func (s *PubSub) traverseNode(p *publish, f traverseFrame) {
+	l := p.path
+
+	if nodeStatsCompiled && s.nodeStats && !p.dryRun {
+		if st := f.n.Stats(); st != nil {
+			st.Traversed.Add(1)
+		}
+	}
+
+	if m, ok := f.n.Data().(*PubSub); ok {
+		if p.dryRun {
+			p.matchMount(m, f.a, l)
 			return
 		}
+		if p.deferWrites || p.ordered {
+			p.mounts = append(p.mounts, pendingMount{m: m, a: f.a})
+			return
+		}
+		s.publishMount(p, m, f.a)
+		return
+	}
+
+	// With MQTT matching, subscriptions only receive data published to
+	// exactly their path, so they are written once the path ends.
+	if !s.mqtt {
+		s.writeNode(p, f.n, l, f.catchAll)
+	}
 
-		if nextA == nil {
-			nextA = a
+	if s.exceededDepth(p, l) {
+		return
+	}
+
+	paths := f.a.Traverse(p.data, l)
+
+	start := len(p.stack)
+	var n int
+	if seq, ok := paths.(PathsSeq); ok {
+		n = s.traverseSeq(p, f, l, seq)
+	} else {
+		for ; ; n++ {
+			child, nextA, ok := paths.At(n)
+			if !ok {
+				break
+			}
+			s.traverseChild(p, f, l, n, child, nextA)
 		}
+	}
+
+	if n == 0 {
+		if s.mqtt {
+			// MQTT's multi-level wildcard includes the parent.
+			s.writeNode(p, f.n, l, f.catchAll)
+			s.writeNode(p, f.n.FetchChild(Rest), l, f.catchAll)
+		}
+		s.recordHistory(p, l)
+	}
+
+	// The children are popped in reverse, so they are reversed to be
+	// traversed in order.
+	for i, j := start, len(p.stack)-1; i < j; i, j = i+1, j-1 {
+		p.stack[i], p.stack[j] = p.stack[j], p.stack[i]
+	}
+}
 
-		c := n.FetchChild(child)
+// traverseSeq ranges over the PathsSeq (instead of using At) and returns
+// the number of paths.
+func (s *PubSub) traverseSeq(p *publish, f traverseFrame, l []string, seq PathsSeq) int {
+	var n int
+	for child, nextA := range seq {
+		s.traverseChild(p, f, l, n, child, nextA)
+		n++
+	}
+	return n
+}
+
+// traverseChild pushes the nodes beneath f.n that the ith path matches. l
+// is the path to f.n.
+func (s *PubSub) traverseChild(p *publish, f traverseFrame, l []string, i int, child string, nextA TreeTraverser) {
+	if nextA == nil {
+		nextA = f.a
+	}
+
+	hidden := s.hidden(len(l), child)
+
+	// Subscriptions at Rest are interested in anything beneath n.
+	if (i == 0 || s.mqtt) && !hidden {
+		s.writeNode(p, f.n.FetchChild(Rest), l, f.catchAll)
+	}
+
+	// Only the root and the nodes beneath an Any at the first segment are
+	// catch-all.
+	catchAll := f.catchAll && len(l) > 0
+	p.push(nextA, f.n.FetchChild(child), len(l)+1, child, catchAll)
+
+	if node.IsPattern(child) || hidden {
+		return
+	}
 
-		s.traversePublish(d, next, nextA, c, append(l, child), history)
+	if c := f.n.FetchChild(Any); c != nil {
+		p.push(nextA, c, len(l)+1, child, f.catchAll)
 	}
+
+	if f.n.PatternLen() > 0 {
+		p.pushPatterns(nextA, f.n, len(l)+1, child, catchAll)
+	}
+}
+
+// push adds the node to the stack to be traversed.
+func (p *publish) push(a TreeTraverser, n *node.Node, depth int, segment string, catchAll bool) {
+	// Retained data and replay buffers are stored regardless of whether
+	// there are any subscriptions, so the traversal has to continue.
+	if n == nil && !p.retain && p.seq == 0 {
+		return
+	}
+
+	if depth > cap(p.path) {
+		p.path = append(p.path[:cap(p.path)], make([]string, depth-cap(p.path))...)
+	}
+
+	p.stack = append(p.stack, traverseFrame{
+		a:        a,
+		n:        n,
+		depth:    depth,
+		segment:  segment,
+		catchAll: catchAll,
+	})
+}
+
+func (s *PubSub) writeNode(p *publish, n *node.Node, l []string, catchAll bool) {
+	if n == nil {
+		return
+	}
+
+	if !p.visit(n) {
+		return
+	}
+
+	if p.dryRun {
+		s.matchNode(p, n, l)
+		return
+	}
+
+	p.result.Matched += n.SubscriptionLen()
+	if p.responders != nil && !catchAll {
+		p.responders.Add(int64(n.SubscriptionLen()))
+	}
+	if p.span != nil && n.SubscriptionLen() > 0 {
+		p.span.Matched(l, n.SubscriptionLen())
+	}
+
+	var st *node.Stats
+	if nodeStatsCompiled && s.nodeStats {
+		st = n.Stats()
+	}
+
+	s.forEachSubscription(n, func(shardID string, ss []node.SubscriptionEnvelope) {
+		if p.ctx.Err() != nil {
+			return
+		}
+
+		if shardID == "" {
+			for _, x := range ss {
+				if p.ctx.Err() != nil {
+					return
+				}
+				p.write(x.Subscription, l, st)
+			}
+			return
+		}
+
+		if p.crossNodeSharding {
+			// The path is copied as the traversal reuses it.
+			path := append(l[:0:0], l...)
+			for _, x := range ss {
+				p.shardGroups[shardID] = append(p.shardGroups[shardID], shardMember{
+					SubscriptionEnvelope: x,
+					p:                    p,
+					path:                 path,
+					stats:                st,
+				})
+			}
+			return
+		}
+
+		members := make([]shardMember, len(ss))
+		for i, x := range ss {
+			members[i] = shardMember{
+				SubscriptionEnvelope: x,
+				p:                    p,
+				path:                 l,
+				stats:                st,
+			}
+		}
+		s.writeShardGroup(p, members)
+	})
 }
 
 // rlocker is used to hold either a real sync.RWMutex or a nop lock.
@@ -1,16 +1,17 @@
 package pubsub_test
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"strings"
 	"sync"
 	"testing"
 
-	"github.com/apoydence/onpar"
-	. "github.com/apoydence/onpar/expect"
-	. "github.com/apoydence/onpar/matchers"
 	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
 )
 
 type TPS struct {
@@ -133,6 +134,105 @@ func TestPubSub(t *testing.T) {
 		Expect(t, f.ids).To(Contain(2))
 	})
 
+	o.Spec("it writes to subscriptions with an Any segment", func(t TPS) {
+		sub1 := newSpySubscrption()
+		sub2 := newSpySubscrption()
+		sub3 := newSpySubscrption()
+		t.p.Subscribe(sub1, pubsub.WithPath([]string{"a", pubsub.Any, "c"}))
+		t.p.Subscribe(sub2, pubsub.WithPath([]string{"a", "b", "c"}))
+		t.p.Subscribe(sub3, pubsub.WithPath([]string{pubsub.Any, "x"}))
+
+		t.p.Publish("data-1", pubsub.LinearTreeTraverser([]string{"a", "b", "c"}))
+		t.p.Publish("data-2", pubsub.LinearTreeTraverser([]string{"a", "z", "c"}))
+		t.p.Publish("data-3", pubsub.LinearTreeTraverser([]string{"a", "z", "d"}))
+
+		Expect(t, sub1.data).To(Equal([]interface{}{"data-1", "data-2"}))
+		Expect(t, sub2.data).To(Equal([]interface{}{"data-1"}))
+		Expect(t, sub3.data).To(HaveLen(0))
+	})
+
+	o.Spec("it writes to subscriptions with a Rest segment", func(t TPS) {
+		sub1 := newSpySubscrption()
+		sub2 := newSpySubscrption()
+		t.p.Subscribe(sub1, pubsub.WithPath([]string{"a", pubsub.Rest}))
+		t.p.Subscribe(sub2, pubsub.WithPath([]string{pubsub.Any, "b", pubsub.Rest}))
+
+		t.p.Publish("data-1", pubsub.LinearTreeTraverser([]string{"a"}))
+		t.p.Publish("data-2", pubsub.LinearTreeTraverser([]string{"a", "b"}))
+		t.p.Publish("data-3", pubsub.LinearTreeTraverser([]string{"a", "b", "c", "d"}))
+		t.p.Publish("data-4", pubsub.LinearTreeTraverser([]string{"x", "b", "c"}))
+
+		Expect(t, sub1.data).To(Equal([]interface{}{"data-2", "data-3"}))
+		Expect(t, sub2.data).To(Equal([]interface{}{"data-3", "data-4"}))
+	})
+
+	o.Spec("it stops writing when the context is cancelled", func(t TPS) {
+		ctx, cancel := context.WithCancel(context.Background())
+		sub := newSpySubscrption()
+		t.p.Subscribe(pubsub.SubscriptionFunc(func(data interface{}) {
+			sub.Write(data)
+			cancel()
+		}), pubsub.WithPath([]string{"a"}))
+		t.p.Subscribe(sub, pubsub.WithPath([]string{"a"}))
+		t.p.Subscribe(sub, pubsub.WithPath([]string{"a", "b"}))
+
+		_, err := t.p.PublishCtx(ctx, "some-data", pubsub.LinearTreeTraverser([]string{"a", "b"}))
+		Expect(t, err).To(Equal(context.Canceled))
+		Expect(t, sub.data).To(HaveLen(1))
+
+		_, err = t.p.PublishCtx(context.Background(), "some-data", pubsub.LinearTreeTraverser([]string{"a", "b"}))
+		Expect(t, err == nil).To(BeTrue())
+		Expect(t, sub.data).To(HaveLen(4))
+	})
+
+	o.Spec("it removes the subscription when its context is done", func(t TPS) {
+		ctx, cancel := context.WithCancel(context.Background())
+		sub := newSpySubscrption()
+		t.p.Subscribe(sub, pubsub.WithContext(ctx))
+
+		t.p.Publish("some-data", pubsub.LinearTreeTraverser(nil))
+		Expect(t, sub.Len()).To(Equal(1))
+		cancel()
+
+		Expect(t, func() bool {
+			l := sub.Len()
+			t.p.Publish("some-data", pubsub.LinearTreeTraverser(nil))
+			return sub.Len() == l
+		}).To(ViaPolling(BeTrue()))
+	})
+
+	o.Spec("it returns the result of the publish", func(t TPS) {
+		block := make(chan struct{})
+		defer close(block)
+		t.p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"a"}))
+		t.p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"a", "b"}), pubsub.WithShardID("1"))
+		t.p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"a", "b"}), pubsub.WithShardID("1"))
+		t.p.Subscribe(pubsub.SubscriptionFunc(func(interface{}) {
+			<-block
+		}),
+			pubsub.WithPath([]string{"a", "b"}),
+			pubsub.WithBufferSize(1),
+			pubsub.WithOverflowStrategy(pubsub.OverflowDrop),
+		)
+
+		var results []pubsub.PublishResult
+		for i := 0; i < 3; i++ {
+			r, err := t.p.PublishCtx(context.Background(), i, pubsub.LinearTreeTraverser([]string{"a", "b"}))
+			Expect(t, err == nil).To(BeTrue())
+			results = append(results, r)
+		}
+
+		Expect(t, results[0]).To(Equal(pubsub.PublishResult{Matched: 4, Delivered: 3}))
+		Expect(t, results[1].Dropped+results[2].Dropped).To(BeAbove(0))
+		for _, r := range results {
+			Expect(t, r.Matched).To(Equal(4))
+			Expect(t, r.Delivered+r.Dropped).To(Equal(3))
+		}
+
+		r, _ := t.p.PublishCtx(context.Background(), "some-data", pubsub.LinearTreeTraverser([]string{"x"}))
+		Expect(t, r).To(Equal(pubsub.PublishResult{}))
+	})
+
 	o.Spec("it does not write to a subscription after it unsubscribes", func(t TPS) {
 		sub := newSpySubscrption()
 		t.treeTraverser.keys = map[string][]string{
@@ -145,6 +245,109 @@ func TestPubSub(t *testing.T) {
 		t.p.Publish("some-data", t.treeTraverser)
 		Expect(t, sub.data).To(HaveLen(0))
 	})
+
+	o.Spec("it gives each branch its own currentPath", func(t TPS) {
+		t.p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"a", "x", "y", "p"}))
+		t.p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"a", "x", "y", "q"}))
+
+		var paths [][]string
+		t.p.Publish("some-data", pubsub.TreeTraverserFunc(func(data interface{}, currentPath []string) pubsub.Paths {
+			switch len(currentPath) {
+			case 0:
+				return pubsub.FlatPaths{"a"}
+			case 1:
+				return pubsub.FlatPaths{"x"}
+			case 2:
+				return pubsub.FlatPaths{"y"}
+			case 3:
+				return pubsub.FlatPaths{"p", "q"}
+			default:
+				paths = append(paths, append([]string(nil), currentPath...))
+				return pubsub.FlatPaths(nil)
+			}
+		}))
+
+		Expect(t, paths).To(Equal([][]string{
+			{"a", "x", "y", "p"},
+			{"a", "x", "y", "q"},
+		}))
+	})
+}
+
+func TestPubSubWithDeadLetterSubscription(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.Spec("it writes data that did not match any subscriptions", func(t *testing.T) {
+		deadLetter := newSpySubscrption()
+		p := pubsub.New(pubsub.WithDeadLetterSubscription(deadLetter))
+		sub := newSpySubscrption()
+		p.Subscribe(sub, pubsub.WithPath([]string{"a"}))
+
+		p.Publish("data-1", pubsub.LinearTreeTraverser([]string{"a", "b"}))
+		p.Publish("data-2", pubsub.LinearTreeTraverser([]string{"x"}))
+
+		Expect(t, sub.data).To(Equal([]interface{}{"data-1"}))
+		Expect(t, deadLetter.data).To(Equal([]interface{}{"data-2"}))
+	})
+}
+
+func TestPubSubSubscribeErr(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+	o.BeforeEach(func(t *testing.T) TPS {
+		return TPS{
+			T: t,
+			p: pubsub.New(),
+		}
+	})
+
+	o.Spec("it adds the subscription", func(t TPS) {
+		sub := newSpySubscrption()
+		unsubscribe, err := t.p.SubscribeErr(sub, pubsub.WithPath([]string{"a", pubsub.Any, pubsub.Rest}))
+		Expect(t, err).To(BeNil())
+
+		t.p.Publish(1, pubsub.LinearTreeTraverser([]string{"a", "b", "c"}))
+		unsubscribe()
+		t.p.Publish(2, pubsub.LinearTreeTraverser([]string{"a", "b", "c"}))
+		Expect(t, sub.Data()).To(Equal([]interface{}{1}))
+	})
+
+	o.Spec("it returns an error for a closed PubSub", func(t TPS) {
+		t.p.Close()
+
+		sub := newSpyCloser()
+		_, err := t.p.SubscribeErr(sub)
+		Expect(t, err).To(Equal(pubsub.ErrClosed))
+		Expect(t, sub.closed).To(Equal(1))
+	})
+
+	o.Spec("it returns an error for a closed mounted PubSub", func(t TPS) {
+		child := pubsub.New()
+		Expect(t, t.p.Mount([]string{"a"}, child)).To(BeNil())
+		child.Close()
+
+		_, err := t.p.SubscribeErr(newSpySubscrption(), pubsub.WithPath([]string{"a", "b"}))
+		Expect(t, err).To(Equal(pubsub.ErrClosed))
+	})
+
+	o.Spec("it returns an error for an invalid path", func(t TPS) {
+		for _, path := range [][]string{
+			{pubsub.Rest, "a"},
+			{"\x00unknown"},
+			{pubsub.Not("\x00unknown")},
+			{"\x00range:1"},
+			{"\x00regexp:("},
+		} {
+			sub := newSpyCloser()
+			_, err := t.p.SubscribeErr(sub, pubsub.WithPath(path))
+			Expect(t, err).To(Equal(pubsub.ErrInvalidPath))
+			Expect(t, sub.closed).To(Equal(1))
+		}
+		Expect(t, t.p.Paths()).To(HaveLen(0))
+	})
 }
 
 func TestPubSubWithShardID(t *testing.T) {
@@ -269,6 +472,18 @@ func (s *spySubscription) Write(data interface{}) {
 	s.data = append(s.data, data)
 }
 
+func (s *spySubscription) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.data)
+}
+
+func (s *spySubscription) Data() []interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]interface{}(nil), s.data...)
+}
+
 type fakePaths struct {
 	paths []string
 	a     *spyTreeTraverser
@@ -0,0 +1,225 @@
+// Package pubsubdebug serves the live subscription tree of a PubSub over
+// HTTP for debugging, much like net/http/pprof does for profiles.
+package pubsubdebug
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/apoydence/pubsub"
+	"github.com/apoydence/pubsub/debug"
+)
+
+// maxDataLen is the number of bytes of the data of a NoMatch that is
+// shown.
+const maxDataLen = 256
+
+// Option is used to configure a Handler.
+type Option interface {
+	configure(*handler)
+}
+
+type configFunc func(*handler)
+
+func (f configFunc) configure(h *handler) {
+	f(h)
+}
+
+// WithRecorder configures a Handler to show the publishes that did not
+// match any subscription that the Recorder recorded.
+func WithRecorder(r *Recorder) Option {
+	return configFunc(func(h *handler) {
+		h.recorder = r
+	})
+}
+
+// Handler returns an http.Handler that serves the subscription tree of the
+// PubSub. It serves, relative to where it is mounted:
+//
+//   - "json": the tree as JSON (see Snapshot)
+//   - "dot": the tree in the DOT language (see debug.WriteDOT)
+//   - anything else: the tree as an HTML page
+//
+// Each node includes its subscriptions' shardIDs, names and metadata (see
+// pubsub.WithMetadata) and, if the PubSub was configured with
+// pubsub.WithNodeStats, its counters. It should be mounted with a trailing
+// slash:
+//
+//	mux.Handle("/debug/pubsub/", pubsubdebug.Handler(p))
+func Handler(p *pubsub.PubSub, opts ...Option) http.Handler {
+	h := &handler{p: p}
+	for _, o := range opts {
+		o.configure(h)
+	}
+	return h
+}
+
+type handler struct {
+	p        *pubsub.PubSub
+	recorder *Recorder
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	switch path.Base(r.URL.Path) {
+	case "json":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(h.snapshot())
+	case "dot":
+		w.Header().Set("Content-Type", "text/vnd.graphviz; charset=utf-8")
+		debug.WriteDOT(w, h.p)
+	default:
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := page.Execute(w, h.snapshot()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
+
+// Snapshot is what a Handler serves.
+type Snapshot struct {
+	// Nodes are the nodes of the subscription tree in the order of
+	// pubsub.PubSub.Walk.
+	Nodes []Node `json:"nodes"`
+
+	// NoMatches are the publishes that did not match any subscription (see
+	// WithRecorder), the oldest first.
+	NoMatches []NoMatchInfo `json:"noMatches,omitempty"`
+}
+
+// Node describes a node of the subscription tree.
+type Node struct {
+	// Path is the node's path and Topic is the path formatted with
+	// pubsub.FormatTopic and '.' as the delimiter.
+	Path  []string `json:"path"`
+	Topic string   `json:"topic"`
+
+	Subscriptions     int                `json:"subscriptions"`
+	SubscriptionInfos []SubscriptionInfo `json:"subscriptionInfos,omitempty"`
+
+	// Stats are the node's counters (see pubsub.WithNodeStats).
+	Stats *Stats `json:"stats,omitempty"`
+}
+
+// SubscriptionInfo describes a subscription (see pubsub.SubscriptionInfo).
+type SubscriptionInfo struct {
+	ShardID  string            `json:"shardID,omitempty"`
+	Name     string            `json:"name,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// Stats are the counters of a node (see pubsub.NodeStats).
+type Stats struct {
+	Traversed int64 `json:"traversed"`
+	Delivered int64 `json:"delivered"`
+	Dropped   int64 `json:"dropped"`
+}
+
+// NoMatchInfo describes a publish that did not match any subscription. The
+// data is formatted with fmt and truncated.
+type NoMatchInfo struct {
+	Time  time.Time `json:"time"`
+	Path  []string  `json:"path"`
+	Topic string    `json:"topic"`
+	Data  string    `json:"data"`
+}
+
+func (h *handler) snapshot() Snapshot {
+	stats := make(map[string]pubsub.NodeStats)
+	for _, st := range h.p.NodeStats() {
+		stats[key(st.Path)] = st
+	}
+
+	var s Snapshot
+	h.p.Walk(func(path []string, subCount int) {
+		n := Node{
+			Path:          path,
+			Topic:         pubsub.FormatTopic(path, '.'),
+			Subscriptions: subCount,
+		}
+
+		for _, info := range h.p.SubscriptionInfos(path...) {
+			n.SubscriptionInfos = append(n.SubscriptionInfos, SubscriptionInfo{
+				ShardID:  info.ShardID,
+				Name:     info.Name,
+				Metadata: info.Metadata,
+			})
+		}
+
+		if st, ok := stats[key(path)]; ok {
+			n.Stats = &Stats{
+				Traversed: st.Traversed,
+				Delivered: st.Delivered,
+				Dropped:   st.Dropped,
+			}
+		}
+
+		s.Nodes = append(s.Nodes, n)
+	})
+
+	if h.recorder != nil {
+		for _, m := range h.recorder.NoMatches() {
+			data := fmt.Sprint(m.Data)
+			if len(data) > maxDataLen {
+				data = data[:maxDataLen] + "..."
+			}
+
+			s.NoMatches = append(s.NoMatches, NoMatchInfo{
+				Time:  m.Time,
+				Path:  m.Path,
+				Topic: pubsub.FormatTopic(m.Path, '.'),
+				Data:  data,
+			})
+		}
+	}
+
+	return s
+}
+
+// key identifies a path in a map.
+func key(path []string) string {
+	return strings.Join(path, "\x00")
+}
+
+var page = template.Must(template.New("page").Funcs(template.FuncMap{
+	"indent": func(path []string) int { return 2 * len(path) },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<title>pubsub</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 2px 8px; text-align: left; vertical-align: top; }
+</style>
+</head>
+<body>
+<p><a href="json">json</a> <a href="dot">dot</a></p>
+<h2>Subscription tree</h2>
+<table>
+<tr><th>topic</th><th>subscriptions</th><th>shard / name / metadata</th><th>traversed</th><th>delivered</th><th>dropped</th></tr>
+{{range .Nodes}}<tr>
+<td style="padding-left: {{indent .Path}}em">{{if .Path}}{{.Topic}}{{else}}(root){{end}}</td>
+<td>{{.Subscriptions}}</td>
+<td>{{range .SubscriptionInfos}}{{if .ShardID}}shard {{.ShardID}} {{end}}{{if .Name}}{{.Name}} {{end}}{{range $k, $v := .Metadata}}{{$k}}={{$v}} {{end}}<br>{{end}}</td>
+{{with .Stats}}<td>{{.Traversed}}</td><td>{{.Delivered}}</td><td>{{.Dropped}}</td>{{else}}<td></td><td></td><td></td>{{end}}
+</tr>
+{{end}}</table>
+{{with .NoMatches}}<h2>Recent publishes without a match</h2>
+<table>
+<tr><th>time</th><th>topic</th><th>data</th></tr>
+{{range .}}<tr><td>{{.Time.Format "15:04:05.000"}}</td><td>{{.Topic}}</td><td>{{.Data}}</td></tr>
+{{end}}</table>
+{{end}}</body>
+</html>
+`))
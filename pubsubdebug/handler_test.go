@@ -0,0 +1,133 @@
+package pubsubdebug_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/apoydence/pubsub/pubsubdebug"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+type TD struct {
+	*testing.T
+	p        *pubsub.PubSub
+	recorder *pubsubdebug.Recorder
+	mux      *http.ServeMux
+}
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) TD {
+		recorder := pubsubdebug.NewRecorder(2)
+		p := pubsub.New(
+			pubsub.WithNodeStats(),
+			pubsub.WithPublishInterceptor(recorder.Intercept),
+		)
+
+		sub := pubsub.SubscriptionFunc(func(interface{}) {})
+		p.Subscribe(sub, pubsub.WithPath([]string{"a", "b"}), pubsub.WithName("b-sub"))
+		p.Subscribe(sub,
+			pubsub.WithPath([]string{"a", pubsub.Any}),
+			pubsub.WithShardID("1"),
+			pubsub.WithMetadata(map[string]string{"owner": "x"}),
+		)
+
+		mux := http.NewServeMux()
+		mux.Handle("/debug/pubsub/", pubsubdebug.Handler(p, pubsubdebug.WithRecorder(recorder)))
+
+		return TD{
+			T:        t,
+			p:        p,
+			recorder: recorder,
+			mux:      mux,
+		}
+	})
+
+	get := func(t TD, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		t.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	o.Spec("it serves the tree as JSON", func(t TD) {
+		t.p.Publish("x", pubsub.LinearTreeTraverser([]string{"a", "b"}))
+
+		rec := get(t, "/debug/pubsub/json")
+		Expect(t, rec.Code).To(Equal(http.StatusOK))
+		Expect(t, rec.Header().Get("Content-Type")).To(Equal("application/json"))
+
+		var s pubsubdebug.Snapshot
+		Expect(t, json.Unmarshal(rec.Body.Bytes(), &s)).To(BeNil())
+		Expect(t, s.Nodes).To(HaveLen(4))
+
+		var topics []string
+		for _, n := range s.Nodes {
+			topics = append(topics, n.Topic)
+		}
+		Expect(t, topics).To(Equal([]string{"", "a", "a.*", "a.b"}))
+
+		Expect(t, s.Nodes[2].SubscriptionInfos).To(Equal([]pubsubdebug.SubscriptionInfo{
+			{ShardID: "1", Metadata: map[string]string{"owner": "x"}},
+		}))
+		Expect(t, s.Nodes[3].SubscriptionInfos).To(Equal([]pubsubdebug.SubscriptionInfo{
+			{Name: "b-sub"},
+		}))
+
+		// The stats are left out if they are not compiled in (see the
+		// pubsub_nonodestats tag).
+		if t.p.NodeStats() == nil {
+			Expect(t, s.Nodes[3].Stats).To(BeNil())
+			return
+		}
+		Expect(t, s.Nodes[3].Stats).To(Equal(&pubsubdebug.Stats{Traversed: 1, Delivered: 1}))
+	})
+
+	o.Spec("it serves the recent publishes that did not match", func(t TD) {
+		for _, d := range []string{"x", "y", "z"} {
+			t.p.Publish(d, pubsub.LinearTreeTraverser([]string{"c"}))
+		}
+		t.p.Publish("matched", pubsub.LinearTreeTraverser([]string{"a", "b"}))
+
+		var s pubsubdebug.Snapshot
+		Expect(t, json.Unmarshal(get(t, "/debug/pubsub/json").Body.Bytes(), &s)).To(BeNil())
+		Expect(t, s.NoMatches).To(HaveLen(2))
+		Expect(t, s.NoMatches[0].Data).To(Equal("y"))
+		Expect(t, s.NoMatches[0].Topic).To(Equal("c"))
+		Expect(t, s.NoMatches[1].Data).To(Equal("z"))
+	})
+
+	o.Spec("it serves the tree as HTML", func(t TD) {
+		t.p.Publish("<script>", pubsub.LinearTreeTraverser([]string{"c"}))
+
+		rec := get(t, "/debug/pubsub/")
+		Expect(t, rec.Code).To(Equal(http.StatusOK))
+		Expect(t, rec.Header().Get("Content-Type")).To(Equal("text/html; charset=utf-8"))
+
+		body := rec.Body.String()
+		Expect(t, strings.Contains(body, "a.*")).To(BeTrue())
+		Expect(t, strings.Contains(body, "owner=x")).To(BeTrue())
+		Expect(t, strings.Contains(body, "b-sub")).To(BeTrue())
+		Expect(t, strings.Contains(body, "&lt;script&gt;")).To(BeTrue())
+	})
+
+	o.Spec("it serves the tree in the DOT language", func(t TD) {
+		rec := get(t, "/debug/pubsub/dot")
+		Expect(t, rec.Code).To(Equal(http.StatusOK))
+		Expect(t, strings.HasPrefix(rec.Body.String(), "digraph pubsub {")).To(BeTrue())
+	})
+
+	o.Spec("it only allows GET and HEAD", func(t TD) {
+		rec := httptest.NewRecorder()
+		t.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/debug/pubsub/json", nil))
+		Expect(t, rec.Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+}
@@ -0,0 +1,86 @@
+package pubsubdebug
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/apoydence/pubsub"
+)
+
+// NoMatch is a publish that did not match any subscription.
+type NoMatch struct {
+	// Time is when the publish returned.
+	Time time.Time
+
+	// Path is the path that was published to. It is only known for a
+	// pubsub.LinearTreeTraverser and is nil otherwise.
+	Path []string
+
+	// Data is the data that was published.
+	Data interface{}
+}
+
+// Recorder records the most recent publishes that did not match any
+// subscription. It must be given to the PubSub as a PublishInterceptor:
+//
+//	r := pubsubdebug.NewRecorder(100)
+//	p := pubsub.New(pubsub.WithPublishInterceptor(r.Intercept))
+//
+// It should be constructed with NewRecorder().
+type Recorder struct {
+	mu      sync.Mutex
+	entries []NoMatch
+	next    int
+	full    bool
+}
+
+// NewRecorder constructs a new Recorder that keeps the given number of
+// publishes.
+func NewRecorder(size int) *Recorder {
+	return &Recorder{
+		entries: make([]NoMatch, size),
+	}
+}
+
+// Intercept implements pubsub.PublishInterceptor.
+func (r *Recorder) Intercept(next pubsub.PublishFunc) pubsub.PublishFunc {
+	return func(ctx context.Context, d interface{}, a pubsub.TreeTraverser) (pubsub.PublishResult, error) {
+		result, err := next(ctx, d, a)
+		if err == nil && result.Matched == 0 {
+			var path []string
+			if l, ok := a.(pubsub.LinearTreeTraverser); ok {
+				path = append([]string{}, l...)
+			}
+			r.record(NoMatch{Time: time.Now(), Path: path, Data: d})
+		}
+		return result, err
+	}
+}
+
+// NoMatches returns the recorded publishes, the oldest first.
+func (r *Recorder) NoMatches() []NoMatch {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.full {
+		return append([]NoMatch(nil), r.entries[:r.next]...)
+	}
+	return append(append([]NoMatch(nil), r.entries[r.next:]...), r.entries[:r.next]...)
+}
+
+func (r *Recorder) record(m NoMatch) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.entries) == 0 {
+		return
+	}
+
+	r.entries[r.next] = m
+	r.next++
+	if r.next == len(r.entries) {
+		r.next = 0
+		r.full = true
+	}
+}
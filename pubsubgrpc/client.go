@@ -0,0 +1,211 @@
+package pubsubgrpc
+
+import (
+	"context"
+	"time"
+
+	"github.com/apoydence/pubsub"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ClientOption is used to configure a Client.
+type ClientOption interface {
+	configure(*Client)
+}
+
+type clientConfigFunc func(*Client)
+
+func (f clientConfigFunc) configure(c *Client) {
+	f(c)
+}
+
+// WithBackoff configures how long a Client waits before resubscribing after
+// a stream was interrupted. The wait starts at min and doubles after each
+// failed attempt, up to max. It defaults to 100ms and 10s.
+func WithBackoff(min, max time.Duration) ClientOption {
+	return clientConfigFunc(func(c *Client) {
+		c.minBackoff = min
+		c.maxBackoff = max
+	})
+}
+
+// Client is a PubSub that publishes and subscribes to a Server. Each
+// subscription is a Subscribe stream that is reopened (after a backoff,
+// see WithBackoff) whenever it is interrupted, e.g., because the server
+// restarted. Any data that is published while a stream is interrupted is
+// not written to the subscription. It should be constructed with
+// NewClient.
+type Client struct {
+	rpc        PubSubClient
+	codec      Codec
+	minBackoff time.Duration
+	maxBackoff time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewClient constructs a new Client that invokes the RPCs with the given
+// connection (e.g., a *grpc.ClientConn). The Codec must match the one the
+// Server uses.
+func NewClient(conn grpc.ClientConnInterface, codec Codec, opts ...ClientOption) *Client {
+	c := &Client{
+		rpc:        NewPubSubClient(conn),
+		codec:      codec,
+		minBackoff: 100 * time.Millisecond,
+		maxBackoff: 10 * time.Second,
+	}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+
+	for _, o := range opts {
+		o.configure(c)
+	}
+
+	return c
+}
+
+// Publish implements PubSub. It waits for the connection to be ready (or
+// the context to be done) rather than failing while the server is
+// unavailable.
+func (c *Client) Publish(ctx context.Context, d interface{}, path []string) (pubsub.PublishResult, error) {
+	b, err := c.codec.Marshal(d)
+	if err != nil {
+		return pubsub.PublishResult{}, err
+	}
+
+	resp, err := c.rpc.Publish(ctx, &PublishRequest{Path: path, Data: b}, grpc.WaitForReady(true))
+	if err != nil {
+		return pubsub.PublishResult{}, fromStatus(err)
+	}
+
+	return pubsub.PublishResult{
+		Matched:   int(resp.GetMatched()),
+		Delivered: int(resp.GetDelivered()),
+		Dropped:   int(resp.GetDropped()),
+	}, nil
+}
+
+// Subscribe implements PubSub. It only returns an error if the Server
+// rejected the subscription or the Client is closed. If the server is
+// unavailable, the subscription is added once it is available again. The
+// Subscription is closed (if it implements pubsub.Closer) if the Server
+// rejects it when it is resubscribed, if the Server's PubSub is closed or
+// if the Client is closed. Data that is received in the meantime may still
+// be written after the Unsubscriber returns.
+func (c *Client) Subscribe(sub pubsub.Subscription, path []string) (pubsub.Unsubscriber, error) {
+	ctx, cancel := context.WithCancel(c.ctx)
+
+	stream, err := c.open(ctx, path)
+	if c.ctx.Err() != nil || permanent(err) {
+		cancel()
+		closeSubscription(sub)
+		if c.ctx.Err() != nil {
+			return func() {}, pubsub.ErrClosed
+		}
+		return func() {}, fromStatus(err)
+	}
+
+	go c.run(ctx, sub, path, stream)
+
+	return pubsub.Unsubscriber(cancel), nil
+}
+
+// Close removes every subscription and closes their Subscriptions. It does
+// not close the connection.
+func (c *Client) Close() {
+	c.cancel()
+}
+
+// run writes the data of the stream (if any) to the Subscription and
+// reopens it until the subscription is removed.
+func (c *Client) run(ctx context.Context, sub pubsub.Subscription, path []string, stream PubSub_SubscribeClient) {
+	backoff := c.minBackoff
+	for {
+		if stream != nil {
+			if err := c.receive(stream, sub); permanent(err) {
+				closeSubscription(sub)
+				return
+			}
+			backoff = c.minBackoff
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			if c.ctx.Err() != nil {
+				closeSubscription(sub)
+			}
+			return
+		}
+		backoff = min(2*backoff, c.maxBackoff)
+
+		var err error
+		stream, err = c.open(ctx, path)
+		if permanent(err) {
+			closeSubscription(sub)
+			return
+		}
+	}
+}
+
+// open opens a Subscribe stream and waits for the Server to add the
+// subscription.
+func (c *Client) open(ctx context.Context, path []string) (PubSub_SubscribeClient, error) {
+	stream, err := c.rpc.Subscribe(ctx, &SubscribeRequest{Path: path})
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := stream.Header(); err != nil {
+		return nil, err
+	}
+
+	return stream, nil
+}
+
+// receive writes the data of the stream to the Subscription until the
+// stream ends. Data that the Codec can not decode is dropped.
+func (c *Client) receive(stream PubSub_SubscribeClient, sub pubsub.Subscription) error {
+	for {
+		m, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+
+		d, err := c.codec.Unmarshal(m.GetData())
+		if err != nil {
+			continue
+		}
+		sub.Write(d)
+	}
+}
+
+// permanent reports whether the error is a rejection from the Server that
+// resubscribing would not resolve.
+func permanent(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	switch status.Code(err) {
+	case codes.InvalidArgument,
+		codes.ResourceExhausted,
+		codes.FailedPrecondition,
+		codes.PermissionDenied,
+		codes.Unauthenticated,
+		codes.Unimplemented:
+		return true
+	default:
+		return false
+	}
+}
+
+func closeSubscription(sub pubsub.Subscription) {
+	if c, ok := sub.(pubsub.Closer); ok {
+		c.Close()
+	}
+}
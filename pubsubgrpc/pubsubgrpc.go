@@ -0,0 +1,102 @@
+// Package pubsubgrpc exposes a PubSub over gRPC (see pubsub.proto). A
+// Server serves Publish and Subscribe RPCs for a PubSub, while a Client
+// invokes them and resubscribes whenever its streams are interrupted. Both
+// the Client and a local PubSub (see Local) implement PubSub, so code can be
+// written without knowing whether the PubSub is in-process or remote.
+package pubsubgrpc
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative pubsub.proto
+
+import (
+	"context"
+	"errors"
+
+	"github.com/apoydence/pubsub"
+	"github.com/apoydence/pubsub/codec"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// PubSub publishes data to and subscribes to paths.
+type PubSub interface {
+	// Publish publishes the data to the path with a LinearTreeTraverser.
+	Publish(ctx context.Context, d interface{}, path []string) (pubsub.PublishResult, error)
+
+	// Subscribe adds a subscription to the path. If the subscription can
+	// not be added, the Subscription is closed (if it implements
+	// pubsub.Closer) and the reason is returned (see
+	// pubsub.PubSub.SubscribeErr).
+	Subscribe(sub pubsub.Subscription, path []string) (pubsub.Unsubscriber, error)
+}
+
+// Local returns a PubSub that publishes to and subscribes to the given
+// PubSub.
+func Local(p *pubsub.PubSub) PubSub {
+	return local{p: p}
+}
+
+type local struct {
+	p *pubsub.PubSub
+}
+
+func (l local) Publish(ctx context.Context, d interface{}, path []string) (pubsub.PublishResult, error) {
+	return l.p.PublishCtx(ctx, d, pubsub.LinearTreeTraverser(path))
+}
+
+func (l local) Subscribe(sub pubsub.Subscription, path []string) (pubsub.Unsubscriber, error) {
+	return l.p.SubscribeErr(sub, pubsub.WithPath(path))
+}
+
+// Codec converts published data to and from the bytes that are sent over
+// the network. See the codec package for implementations.
+type Codec = codec.Codec
+
+// sentinels are the errors of the pubsub package that are sent as a status
+// and converted back by the Client.
+var sentinels = []struct {
+	code codes.Code
+	err  error
+}{
+	{codes.InvalidArgument, pubsub.ErrInvalidPath},
+	{codes.InvalidArgument, pubsub.ErrMaxTraversalDepth},
+	{codes.ResourceExhausted, pubsub.ErrLimitExceeded},
+	{codes.FailedPrecondition, pubsub.ErrClosed},
+}
+
+// toStatus converts an error returned by a PubSub to a status error. Errors
+// that are not known are assumed to come from an Authorizer.
+func toStatus(err error) error {
+	for _, s := range sentinels {
+		if errors.Is(err, s.err) {
+			return status.Error(s.code, s.err.Error())
+		}
+	}
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	return status.Error(codes.PermissionDenied, err.Error())
+}
+
+// fromStatus converts a status error back to the error of the pubsub
+// package it was converted from (if any).
+func fromStatus(err error) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+
+	for _, s := range sentinels {
+		if st.Code() == s.code && st.Message() == s.err.Error() {
+			return s.err
+		}
+	}
+	return err
+}
@@ -0,0 +1,392 @@
+package pubsubgrpc_test
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/apoydence/pubsub"
+	"github.com/apoydence/pubsub/codec"
+	"github.com/apoydence/pubsub/pubsubgrpc"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/encoding/proto"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type TG struct {
+	*testing.T
+	p      *pubsub.PubSub
+	conn   *fakeConn
+	client *pubsubgrpc.Client
+}
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) TG {
+		p := pubsub.New()
+		conn := newFakeConn()
+		pubsubgrpc.NewServer(pubsubgrpc.Local(p), codec.JSON[string]()).Register(conn)
+
+		client := pubsubgrpc.NewClient(conn, codec.JSON[string](),
+			pubsubgrpc.WithBackoff(time.Millisecond, 10*time.Millisecond),
+		)
+		t.Cleanup(client.Close)
+
+		return TG{
+			T:      t,
+			p:      p,
+			conn:   conn,
+			client: client,
+		}
+	})
+
+	o.Spec("it publishes to the server's PubSub", func(t TG) {
+		sub := newSpySubscription()
+		t.p.Subscribe(sub, pubsub.WithPath([]string{"a"}))
+
+		result, err := t.client.Publish(context.Background(), "x", []string{"a", "b"})
+		Expect(t, err).To(BeNil())
+		Expect(t, result).To(Equal(pubsub.PublishResult{Matched: 1, Delivered: 1}))
+		Expect(t, sub.Data()).To(Equal([]interface{}{"x"}))
+	})
+
+	o.Spec("it subscribes to the server's PubSub", func(t TG) {
+		sub := newSpySubscription()
+		_, err := t.client.Subscribe(sub, []string{"a", pubsub.Any})
+		Expect(t, err).To(BeNil())
+
+		t.p.Publish("x", pubsub.LinearTreeTraverser([]string{"a", "b"}))
+		t.p.Publish("y", pubsub.LinearTreeTraverser([]string{"b"}))
+		Expect(t, sub.Data).To(ViaPolling(Equal([]interface{}{"x"})))
+	})
+
+	o.Spec("it removes the subscription when unsubscribed", func(t TG) {
+		unsubscribe, err := t.client.Subscribe(newSpySubscription(), []string{"a"})
+		Expect(t, err).To(BeNil())
+		Expect(t, t.p.Subscriptions("a")).To(Equal(1))
+
+		unsubscribe()
+		Expect(t, func() int { return t.p.Subscriptions("a") }).To(ViaPolling(Equal(0)))
+	})
+
+	o.Spec("it returns the errors of the server's PubSub", func(t TG) {
+		sub := newSpySubscription()
+		_, err := t.client.Subscribe(sub, []string{pubsub.Rest, "a"})
+		Expect(t, err).To(Equal(pubsub.ErrInvalidPath))
+		Expect(t, sub.Closed()).To(BeTrue())
+
+		t.p.Close()
+		_, err = t.client.Publish(context.Background(), "x", nil)
+		Expect(t, err).To(Equal(pubsub.ErrClosed))
+	})
+
+	o.Spec("it resubscribes after the stream is interrupted", func(t TG) {
+		sub := newSpySubscription()
+		_, err := t.client.Subscribe(sub, []string{"a"})
+		Expect(t, err).To(BeNil())
+
+		t.conn.disconnect()
+		Expect(t, func() int { return t.p.Subscriptions("a") }).To(ViaPolling(Equal(0)))
+		t.conn.reconnect()
+		Expect(t, func() int { return t.p.Subscriptions("a") }).To(ViaPolling(Equal(1)))
+
+		t.p.Publish("x", pubsub.LinearTreeTraverser([]string{"a"}))
+		Expect(t, sub.Data).To(ViaPolling(Equal([]interface{}{"x"})))
+		Expect(t, sub.Closed()).To(BeFalse())
+	})
+
+	o.Spec("it subscribes once the server is available", func(t TG) {
+		t.conn.disconnect()
+
+		sub := newSpySubscription()
+		_, err := t.client.Subscribe(sub, []string{"a"})
+		Expect(t, err).To(BeNil())
+
+		t.conn.reconnect()
+		Expect(t, func() int { return t.p.Subscriptions("a") }).To(ViaPolling(Equal(1)))
+	})
+
+	o.Spec("it closes the subscription when the server's PubSub is closed", func(t TG) {
+		sub := newSpySubscription()
+		_, err := t.client.Subscribe(sub, []string{"a"})
+		Expect(t, err).To(BeNil())
+
+		t.p.Close()
+		Expect(t, sub.Closed).To(ViaPolling(BeTrue()))
+	})
+
+	o.Spec("it closes the subscriptions when the client is closed", func(t TG) {
+		sub := newSpySubscription()
+		_, err := t.client.Subscribe(sub, []string{"a"})
+		Expect(t, err).To(BeNil())
+
+		t.client.Close()
+		Expect(t, sub.Closed).To(ViaPolling(BeTrue()))
+		Expect(t, func() int { return t.p.Subscriptions("a") }).To(ViaPolling(Equal(0)))
+
+		_, err = t.client.Subscribe(newSpySubscription(), []string{"a"})
+		Expect(t, err).To(Equal(pubsub.ErrClosed))
+	})
+}
+
+type spySubscription struct {
+	mu     sync.Mutex
+	data   []interface{}
+	closed bool
+}
+
+func newSpySubscription() *spySubscription {
+	return &spySubscription{}
+}
+
+func (s *spySubscription) Write(data interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data = append(s.data, data)
+}
+
+func (s *spySubscription) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+}
+
+func (s *spySubscription) Data() []interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]interface{}(nil), s.data...)
+}
+
+func (s *spySubscription) Closed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+// fakeConn is an in-memory grpc.ClientConnInterface that invokes the
+// registered service directly. Messages are still encoded with gRPC's
+// protobuf codec.
+type fakeConn struct {
+	codec encoding.Codec
+	desc  *grpc.ServiceDesc
+	srv   interface{}
+
+	mu      sync.Mutex
+	down    bool
+	streams []*fakeStream
+}
+
+func newFakeConn() *fakeConn {
+	return &fakeConn{
+		codec: encoding.GetCodec(proto.Name),
+	}
+}
+
+func (c *fakeConn) RegisterService(desc *grpc.ServiceDesc, srv interface{}) {
+	c.desc = desc
+	c.srv = srv
+}
+
+func (c *fakeConn) Invoke(ctx context.Context, method string, args, reply interface{}, opts ...grpc.CallOption) error {
+	b, err := c.codec.Marshal(args)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range c.desc.Methods {
+		if "/"+c.desc.ServiceName+"/"+m.MethodName != method {
+			continue
+		}
+
+		resp, err := m.Handler(c.srv, ctx, func(v interface{}) error {
+			return c.codec.Unmarshal(b, v)
+		}, nil)
+		if err != nil {
+			return err
+		}
+
+		b, err := c.codec.Marshal(resp)
+		if err != nil {
+			return err
+		}
+		return c.codec.Unmarshal(b, reply)
+	}
+
+	return status.Error(codes.Unimplemented, method)
+}
+
+func (c *fakeConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.down {
+		return nil, status.Error(codes.Unavailable, "disconnected")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	s := &fakeStream{
+		ctx:    ctx,
+		cancel: cancel,
+		codec:  c.codec,
+		req:    make(chan []byte, 1),
+		msgs:   make(chan []byte),
+		header: make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	c.streams = append(c.streams, s)
+
+	go func() {
+		s.err = desc.Handler(c.srv, fakeServerStream{s})
+		cancel()
+		close(s.done)
+	}()
+
+	return fakeClientStream{s}, nil
+}
+
+// disconnect interrupts every stream. New streams fail until reconnect is
+// invoked.
+func (c *fakeConn) disconnect() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.down = true
+	for _, s := range c.streams {
+		s.dropped.Store(true)
+		s.cancel()
+	}
+	c.streams = nil
+}
+
+func (c *fakeConn) reconnect() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.down = false
+}
+
+type fakeStream struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	codec   encoding.Codec
+	req     chan []byte
+	msgs    chan []byte
+	header  chan struct{}
+	once    sync.Once
+	done    chan struct{}
+	err     error
+	dropped atomic.Bool
+}
+
+// status returns the error the client sees once the handler has returned.
+func (s *fakeStream) status() error {
+	switch {
+	case s.dropped.Load():
+		return status.Error(codes.Unavailable, "disconnected")
+	case s.err == nil:
+		return io.EOF
+	default:
+		return s.err
+	}
+}
+
+type fakeServerStream struct {
+	s *fakeStream
+}
+
+func (f fakeServerStream) SetHeader(metadata.MD) error {
+	return nil
+}
+
+func (f fakeServerStream) SendHeader(metadata.MD) error {
+	f.s.once.Do(func() { close(f.s.header) })
+	return nil
+}
+
+func (f fakeServerStream) SetTrailer(metadata.MD) {}
+
+func (f fakeServerStream) Context() context.Context {
+	return f.s.ctx
+}
+
+func (f fakeServerStream) SendMsg(m interface{}) error {
+	b, err := f.s.codec.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case f.s.msgs <- b:
+		return nil
+	case <-f.s.ctx.Done():
+		return f.s.ctx.Err()
+	}
+}
+
+func (f fakeServerStream) RecvMsg(m interface{}) error {
+	select {
+	case b := <-f.s.req:
+		return f.s.codec.Unmarshal(b, m)
+	case <-f.s.ctx.Done():
+		return f.s.ctx.Err()
+	}
+}
+
+type fakeClientStream struct {
+	s *fakeStream
+}
+
+func (f fakeClientStream) Header() (metadata.MD, error) {
+	select {
+	case <-f.s.header:
+		return metadata.MD{}, nil
+	case <-f.s.done:
+		select {
+		case <-f.s.header:
+			return metadata.MD{}, nil
+		default:
+			return nil, f.s.status()
+		}
+	}
+}
+
+func (f fakeClientStream) Trailer() metadata.MD {
+	return nil
+}
+
+func (f fakeClientStream) CloseSend() error {
+	return nil
+}
+
+func (f fakeClientStream) Context() context.Context {
+	return f.s.ctx
+}
+
+func (f fakeClientStream) SendMsg(m interface{}) error {
+	b, err := f.s.codec.Marshal(m)
+	if err != nil {
+		return err
+	}
+	f.s.req <- b
+	return nil
+}
+
+func (f fakeClientStream) RecvMsg(m interface{}) error {
+	select {
+	case b := <-f.s.msgs:
+		return f.s.codec.Unmarshal(b, m)
+	case <-f.s.done:
+		return f.s.status()
+	}
+}
@@ -0,0 +1,165 @@
+package pubsubgrpc
+
+import (
+	"context"
+	"sync"
+
+	"github.com/apoydence/pubsub"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ServerOption is used to configure a Server.
+type ServerOption interface {
+	configure(*Server)
+}
+
+type serverConfigFunc func(*Server)
+
+func (f serverConfigFunc) configure(s *Server) {
+	f(s)
+}
+
+// WithBufferSize configures how much data a Server buffers for each
+// subscription while it is being sent to the client. Data that is written
+// while the buffer is full is dropped. It defaults to 100.
+func WithBufferSize(size int) ServerOption {
+	return serverConfigFunc(func(s *Server) {
+		s.bufferSize = size
+	})
+}
+
+// Server serves the PubSub service of pubsub.proto. It should be
+// constructed with NewServer and registered with a gRPC server with
+// Register.
+//
+// Any error that the PubSub returns is sent as a status: InvalidArgument for
+// pubsub.ErrInvalidPath and pubsub.ErrMaxTraversalDepth, ResourceExhausted
+// for pubsub.ErrLimitExceeded, FailedPrecondition for pubsub.ErrClosed and
+// PermissionDenied for any other error (i.e., one from a pubsub.Authorizer).
+// The gRPC context is given to the PubSub, so an Authorizer can inspect its
+// metadata.
+type Server struct {
+	UnimplementedPubSubServer
+
+	p          PubSub
+	codec      Codec
+	bufferSize int
+}
+
+// NewServer constructs a new Server for the given PubSub (see Local). The
+// Codec must match the one the clients use.
+func NewServer(p PubSub, codec Codec, opts ...ServerOption) *Server {
+	s := &Server{
+		p:          p,
+		codec:      codec,
+		bufferSize: 100,
+	}
+
+	for _, o := range opts {
+		o.configure(s)
+	}
+
+	return s
+}
+
+// Register registers the PubSub service with the gRPC server.
+func (s *Server) Register(r grpc.ServiceRegistrar) {
+	RegisterPubSubServer(r, s)
+}
+
+// Publish implements PubSubServer.
+func (s *Server) Publish(ctx context.Context, req *PublishRequest) (*PublishResponse, error) {
+	d, err := s.codec.Unmarshal(req.GetData())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	result, err := s.p.Publish(ctx, d, req.GetPath())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &PublishResponse{
+		Matched:   int64(result.Matched),
+		Delivered: int64(result.Delivered),
+		Dropped:   int64(result.Dropped),
+	}, nil
+}
+
+// Subscribe implements PubSubServer.
+func (s *Server) Subscribe(req *SubscribeRequest, stream PubSub_SubscribeServer) error {
+	sub := newStreamSubscription(s.bufferSize)
+	unsubscribe, err := s.p.Subscribe(sub, req.GetPath())
+	if err != nil {
+		return toStatus(err)
+	}
+	defer unsubscribe()
+
+	if err := stream.SendHeader(nil); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case d := <-sub.data:
+			if err := s.send(stream, d); err != nil {
+				return err
+			}
+		case <-sub.closed:
+			// The data that was written before the subscription was
+			// closed is still sent.
+			for {
+				select {
+				case d := <-sub.data:
+					if err := s.send(stream, d); err != nil {
+						return err
+					}
+				default:
+					return toStatus(pubsub.ErrClosed)
+				}
+			}
+		case <-stream.Context().Done():
+			return status.FromContextError(stream.Context().Err()).Err()
+		}
+	}
+}
+
+func (s *Server) send(stream PubSub_SubscribeServer, d interface{}) error {
+	b, err := s.codec.Marshal(d)
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	return stream.Send(&Message{Data: b})
+}
+
+// streamSubscription buffers the data that is written to it until it is
+// sent to the client.
+type streamSubscription struct {
+	data   chan interface{}
+	closed chan struct{}
+	once   sync.Once
+}
+
+func newStreamSubscription(size int) *streamSubscription {
+	return &streamSubscription{
+		data:   make(chan interface{}, size),
+		closed: make(chan struct{}),
+	}
+}
+
+// Write implements pubsub.Subscription.
+func (s *streamSubscription) Write(data interface{}) {
+	select {
+	case s.data <- data:
+	default:
+	}
+}
+
+// Close implements pubsub.Closer.
+func (s *streamSubscription) Close() {
+	s.once.Do(func() {
+		close(s.closed)
+	})
+}
@@ -0,0 +1,89 @@
+// Package pubsubhttp streams the data of a PubSub to browsers over HTTP.
+package pubsubhttp
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/apoydence/pubsub"
+	"github.com/apoydence/pubsub/codec"
+)
+
+// Option is used to configure a handler.
+type Option interface {
+	configure(*config)
+}
+
+type configFunc func(*config)
+
+func (f configFunc) configure(c *config) {
+	f(c)
+}
+
+type config struct {
+	bufferSize  int
+	heartbeat   time.Duration
+	checkOrigin func(r *http.Request) bool
+	codec       codec.Codec
+	subOpts     []pubsub.SubscribeOption
+}
+
+func newConfig(opts []Option) config {
+	c := config{
+		bufferSize:  100,
+		heartbeat:   30 * time.Second,
+		checkOrigin: sameOrigin,
+	}
+
+	for _, o := range opts {
+		o.configure(&c)
+	}
+
+	return c
+}
+
+// WithBufferSize configures how much data is buffered for each client
+// while it is being sent. Data that is written while the buffer is full is
+// dropped. It defaults to 100.
+func WithBufferSize(size int) Option {
+	return configFunc(func(c *config) {
+		c.bufferSize = size
+	})
+}
+
+// WithHeartbeat configures how often a message is sent to each client to
+// keep idle connections from being closed by proxies. A value of 0
+// disables heartbeats. It defaults to 30s.
+func WithHeartbeat(d time.Duration) Option {
+	return configFunc(func(c *config) {
+		c.heartbeat = d
+	})
+}
+
+// WithCodec configures a handler to encode data with the given Codec (see
+// the codec package) instead of sending it as a JSON value. As the
+// messages themselves are JSON, the encoded data is sent as a base64
+// string.
+func WithCodec(c codec.Codec) Option {
+	return configFunc(func(cfg *config) {
+		cfg.codec = c
+	})
+}
+
+// WithSubscribeOptions configures the options that a handler adds to the
+// ones it subscribes with on a client's behalf. For example,
+// pubsub.WithTransformer can encrypt or redact data before it is sent to
+// clients.
+func WithSubscribeOptions(opts ...pubsub.SubscribeOption) Option {
+	return configFunc(func(c *config) {
+		c.subOpts = append(c.subOpts, opts...)
+	})
+}
+
+// encode returns the data as it is sent to a client.
+func (c config) encode(data interface{}) (interface{}, error) {
+	if c.codec == nil {
+		return data, nil
+	}
+	return c.codec.Marshal(data)
+}
@@ -0,0 +1,170 @@
+package pubsubhttp
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/apoydence/pubsub"
+)
+
+// SSEHandler returns an http.Handler that subscribes to the PubSub for each
+// request and streams the data that is written to the subscription as
+// Server-Sent Events. The path is determined by pathFromRequest; if it
+// returns an error, the request is answered with 400 Bad Request. Each
+// piece of data is encoded as JSON (see WithCodec) and sent as an event's
+// data (data that can not be encoded is dropped):
+//
+//	data: {"Status":503}
+//
+// If the subscription can not be added, the request is answered with 400
+// Bad Request for pubsub.ErrInvalidPath, 429 Too Many Requests for
+// pubsub.ErrLimitExceeded, 503 Service Unavailable for pubsub.ErrClosed and
+// 403 Forbidden for any other error (i.e., one from an Authorizer). The
+// subscription is added with the request's context, so an Authorizer can
+// inspect it, and is removed once the client disconnects. The response
+// ends when the PubSub is closed. Heartbeats (see WithHeartbeat) are sent
+// as comments.
+func SSEHandler(p *pubsub.PubSub, pathFromRequest func(*http.Request) ([]string, error), opts ...Option) http.Handler {
+	c := newConfig(opts)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, err := pathFromRequest(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		sub := newSSESubscription(c.bufferSize)
+		subOpts := append([]pubsub.SubscribeOption{
+			pubsub.WithPath(path),
+			pubsub.WithContext(r.Context()),
+		}, c.subOpts...)
+		unsubscribe, err := p.SubscribeErr(sub, subOpts...)
+		if err != nil {
+			http.Error(w, err.Error(), subscribeStatus(err))
+			return
+		}
+		defer unsubscribe()
+
+		h := w.Header()
+		h.Set("Content-Type", "text/event-stream")
+		h.Set("Cache-Control", "no-cache")
+		h.Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+
+		rc := http.NewResponseController(w)
+		if err := rc.Flush(); err != nil {
+			return
+		}
+
+		var tick <-chan time.Time
+		if c.heartbeat > 0 {
+			t := time.NewTicker(c.heartbeat)
+			defer t.Stop()
+			tick = t.C
+		}
+
+		for {
+			var err error
+			select {
+			case d := <-sub.data:
+				err = writeEvent(w, c, d)
+			case <-tick:
+				_, err = w.Write([]byte(": heartbeat\n\n"))
+			case <-sub.closed:
+				// The data that was written before the subscription was
+				// closed is still sent.
+				for {
+					select {
+					case d := <-sub.data:
+						if writeEvent(w, c, d) != nil {
+							return
+						}
+					default:
+						rc.Flush()
+						return
+					}
+				}
+			case <-r.Context().Done():
+				return
+			}
+
+			if err == nil {
+				err = rc.Flush()
+			}
+			if err != nil {
+				return
+			}
+		}
+	})
+}
+
+// writeEvent writes the data as an event. Data that can not be encoded is
+// skipped.
+func writeEvent(w http.ResponseWriter, c config, d interface{}) error {
+	d, err := c.encode(d)
+	if err != nil {
+		return nil
+	}
+
+	b, err := json.Marshal(d)
+	if err != nil {
+		return nil
+	}
+
+	event := make([]byte, 0, len(b)+8)
+	event = append(event, "data: "...)
+	event = append(event, b...)
+	event = append(event, "\n\n"...)
+
+	_, err = w.Write(event)
+	return err
+}
+
+// subscribeStatus returns the HTTP status for an error returned by
+// SubscribeErr.
+func subscribeStatus(err error) int {
+	switch {
+	case errors.Is(err, pubsub.ErrInvalidPath):
+		return http.StatusBadRequest
+	case errors.Is(err, pubsub.ErrLimitExceeded):
+		return http.StatusTooManyRequests
+	case errors.Is(err, pubsub.ErrClosed):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusForbidden
+	}
+}
+
+// sseSubscription buffers the data that is written to it until it is sent
+// to the client.
+type sseSubscription struct {
+	data   chan interface{}
+	closed chan struct{}
+	once   sync.Once
+}
+
+func newSSESubscription(size int) *sseSubscription {
+	return &sseSubscription{
+		data:   make(chan interface{}, size),
+		closed: make(chan struct{}),
+	}
+}
+
+// Write implements pubsub.Subscription.
+func (s *sseSubscription) Write(data interface{}) {
+	select {
+	case s.data <- data:
+	default:
+	}
+}
+
+// Close implements pubsub.Closer.
+func (s *sseSubscription) Close() {
+	s.once.Do(func() {
+		close(s.closed)
+	})
+}
@@ -0,0 +1,133 @@
+package pubsubhttp_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apoydence/pubsub"
+	"github.com/apoydence/pubsub/pubsubhttp"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+func TestSSEHandler(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) TW {
+		p := pubsub.New()
+		server := httptest.NewServer(pubsubhttp.SSEHandler(p, pathFromRequest))
+		t.Cleanup(server.Close)
+
+		return TW{
+			T:      t,
+			p:      p,
+			server: server,
+		}
+	})
+
+	o.Spec("it streams the data of a subscription", func(t TW) {
+		resp, r := getEvents(t, t.server.URL+"/a")
+		Expect(t, resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(t, resp.Header.Get("Content-Type")).To(Equal("text/event-stream"))
+
+		t.p.Publish(status{Status: 503}, pubsub.LinearTreeTraverser([]string{"a", "b"}))
+		t.p.Publish(func() {}, pubsub.LinearTreeTraverser([]string{"a"}))
+		t.p.Publish(status{Status: 200}, pubsub.LinearTreeTraverser([]string{"a"}))
+		Expect(t, readEvent(t, r)).To(Equal(`data: {"Status":503}`))
+		Expect(t, readEvent(t, r)).To(Equal(`data: {"Status":200}`))
+	})
+
+	o.Spec("it sends heartbeats", func(t TW) {
+		server := httptest.NewServer(pubsubhttp.SSEHandler(t.p, pathFromRequest, pubsubhttp.WithHeartbeat(time.Millisecond)))
+		t.Cleanup(server.Close)
+
+		_, r := getEvents(t, server.URL)
+		Expect(t, readEvent(t, r)).To(Equal(": heartbeat"))
+	})
+
+	o.Spec("it removes the subscription when the client disconnects", func(t TW) {
+		ctx, cancel := context.WithCancel(context.Background())
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.server.URL+"/a", nil)
+		Expect(t, err).To(BeNil())
+
+		resp, err := http.DefaultClient.Do(req)
+		Expect(t, err).To(BeNil())
+		defer resp.Body.Close()
+		Expect(t, t.p.Subscriptions("a")).To(Equal(1))
+
+		cancel()
+		Expect(t, func() int { return t.p.Subscriptions("a") }).To(ViaPolling(Equal(0)))
+	})
+
+	o.Spec("it ends the response when the PubSub is closed", func(t TW) {
+		_, r := getEvents(t, t.server.URL+"/a")
+		t.p.Publish(status{Status: 503}, pubsub.LinearTreeTraverser([]string{"a"}))
+		t.p.Close()
+
+		b, err := io.ReadAll(r)
+		Expect(t, err).To(BeNil())
+		Expect(t, string(b)).To(Equal("data: {\"Status\":503}\n\n"))
+	})
+
+	o.Spec("it rejects requests it can not subscribe for", func(t TW) {
+		resp, err := http.Get(t.server.URL + "/bad")
+		Expect(t, err).To(BeNil())
+		resp.Body.Close()
+		Expect(t, resp.StatusCode).To(Equal(http.StatusBadRequest))
+
+		resp, err = http.Get(t.server.URL + "/" + url.PathEscape(pubsub.Rest) + "/a")
+		Expect(t, err).To(BeNil())
+		resp.Body.Close()
+		Expect(t, resp.StatusCode).To(Equal(http.StatusBadRequest))
+
+		t.p.Close()
+		resp, err = http.Get(t.server.URL + "/a")
+		Expect(t, err).To(BeNil())
+		resp.Body.Close()
+		Expect(t, resp.StatusCode).To(Equal(http.StatusServiceUnavailable))
+	})
+}
+
+func pathFromRequest(r *http.Request) ([]string, error) {
+	path := strings.Trim(r.URL.Path, "/")
+	if path == "bad" {
+		return nil, errors.New("bad path")
+	}
+	if path == "" {
+		return nil, nil
+	}
+	return strings.Split(path, "/"), nil
+}
+
+func getEvents(t testing.TB, url string) (*http.Response, *bufio.Reader) {
+	resp, err := http.Get(url)
+	Expect(t, err).To(BeNil())
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp, bufio.NewReader(resp.Body)
+}
+
+// readEvent reads the lines up to the next blank line.
+func readEvent(t testing.TB, r *bufio.Reader) string {
+	var lines []string
+	for {
+		line, err := r.ReadString('\n')
+		Expect(t, err).To(BeNil())
+
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return strings.Join(lines, "\n")
+		}
+		lines = append(lines, line)
+	}
+}
@@ -0,0 +1,318 @@
+package pubsubhttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/apoydence/pubsub"
+)
+
+// WithOriginCheck configures the function that decides whether a
+// WebSocketHandler accepts a request based on its Origin header. By
+// default, requests without an Origin header and requests whose Origin has
+// the same host as the request are accepted.
+func WithOriginCheck(f func(r *http.Request) bool) Option {
+	return configFunc(func(c *config) {
+		c.checkOrigin = f
+	})
+}
+
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return u.Host == r.Host
+}
+
+var (
+	errUnknownType       = errors.New("unknown message type")
+	errAlreadySubscribed = errors.New("already subscribed")
+	errNotSubscribed     = errors.New("not subscribed")
+)
+
+// wsMessage is a message that is sent to or received from a client.
+type wsMessage struct {
+	Type   string      `json:"type"`
+	ID     string      `json:"id,omitempty"`
+	Path   []string    `json:"path,omitempty"`
+	Filter string      `json:"filter,omitempty"`
+	Data   interface{} `json:"data,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// WebSocketHandler returns an http.Handler that upgrades each request to a
+// WebSocket and subscribes to the PubSub on the client's behalf. The
+// client sends JSON messages to subscribe and unsubscribe, each with an ID
+// of its choosing:
+//
+//	{"type": "subscribe", "id": "errors", "path": ["logs"], "filter": "msg.Status >= 500"}
+//	{"type": "unsubscribe", "id": "errors"}
+//
+// The filter is optional and is compiled with pubsub.CompileExpr. Each
+// message is answered with a "subscribed" or "unsubscribed" message with
+// the same ID, or with an "error" message that describes why it failed
+// (e.g., pubsub.ErrInvalidPath). The data that is written to a
+// subscription is encoded as JSON (see WithCodec) and sent with the
+// subscription's ID:
+//
+//	{"type": "data", "id": "errors", "data": {"Status": 503}}
+//
+// If the PubSub is closed, an "error" message is sent for each
+// subscription. The subscriptions are added with the request's context, so
+// an Authorizer (see pubsub.WithAuthorizer) can inspect it, and are removed
+// when the connection is closed. Heartbeats (see WithHeartbeat) are sent as
+// ping frames.
+func WebSocketHandler(p *pubsub.PubSub, opts ...Option) http.Handler {
+	c := newConfig(opts)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn := upgrade(w, r, c)
+		if conn == nil {
+			return
+		}
+
+		ctx, cancel := context.WithCancel(r.Context())
+		s := &wsSession{
+			p:    p,
+			conn: conn,
+			ctx:  ctx,
+			c:    c,
+			out:  make(chan []byte, c.bufferSize),
+			subs: make(map[string]*wsSubscription),
+		}
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.write(c.heartbeat)
+		}()
+
+		for {
+			msg, err := conn.readMessage()
+			if err != nil {
+				break
+			}
+			s.handle(msg)
+		}
+
+		cancel()
+		s.unsubscribeAll()
+		wg.Wait()
+		conn.close()
+	})
+}
+
+// wsSession holds the subscriptions of a WebSocket connection.
+type wsSession struct {
+	p    *pubsub.PubSub
+	conn *wsConn
+	ctx  context.Context
+	c    config
+	out  chan []byte
+
+	mu   sync.Mutex
+	subs map[string]*wsSubscription
+}
+
+// write writes the queued messages and heartbeats until the session's
+// context is done. If a write fails, the connection is closed so that the
+// reader stops as well.
+func (s *wsSession) write(heartbeat time.Duration) {
+	var tick <-chan time.Time
+	if heartbeat > 0 {
+		t := time.NewTicker(heartbeat)
+		defer t.Stop()
+		tick = t.C
+	}
+
+	for {
+		var err error
+		select {
+		case b := <-s.out:
+			err = s.conn.writeFrame(opText, b)
+		case <-tick:
+			err = s.conn.writeFrame(opPing, nil)
+		case <-s.ctx.Done():
+			return
+		}
+
+		if err != nil {
+			s.conn.conn.Close()
+			return
+		}
+	}
+}
+
+func (s *wsSession) handle(b []byte) {
+	var msg wsMessage
+	if err := json.Unmarshal(b, &msg); err != nil {
+		s.reply(wsMessage{Type: "error", Error: err.Error()})
+		return
+	}
+
+	var err error
+	switch msg.Type {
+	case "subscribe":
+		err = s.subscribe(msg)
+	case "unsubscribe":
+		err = s.unsubscribe(msg.ID)
+	default:
+		err = errUnknownType
+	}
+
+	if err != nil {
+		s.reply(wsMessage{Type: "error", ID: msg.ID, Error: err.Error()})
+		return
+	}
+	s.reply(wsMessage{Type: msg.Type + "d", ID: msg.ID})
+}
+
+func (s *wsSession) subscribe(msg wsMessage) error {
+	opts := []pubsub.SubscribeOption{
+		pubsub.WithPath(msg.Path),
+		pubsub.WithContext(s.ctx),
+	}
+
+	if msg.Filter != "" {
+		e, err := pubsub.CompileExpr(msg.Filter)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, pubsub.WithFilter(e.Match))
+	}
+	opts = append(opts, s.c.subOpts...)
+
+	s.mu.Lock()
+	if _, ok := s.subs[msg.ID]; ok {
+		s.mu.Unlock()
+		return errAlreadySubscribed
+	}
+
+	// The ID is reserved while subscribing, as the PubSub may close the
+	// Subscription (and thereby remove it) before SubscribeErr returns.
+	sub := &wsSubscription{s: s, id: msg.ID}
+	s.subs[msg.ID] = sub
+	s.mu.Unlock()
+
+	unsubscribe, err := s.p.SubscribeErr(sub, opts...)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err != nil {
+		if s.subs[msg.ID] == sub {
+			delete(s.subs, msg.ID)
+		}
+		return err
+	}
+
+	if s.subs[msg.ID] != sub {
+		return pubsub.ErrClosed
+	}
+
+	sub.unsubscribe = unsubscribe
+	sub.added = true
+	return nil
+}
+
+func (s *wsSession) unsubscribe(id string) error {
+	s.mu.Lock()
+	sub, ok := s.subs[id]
+	delete(s.subs, id)
+	s.mu.Unlock()
+
+	if !ok {
+		return errNotSubscribed
+	}
+
+	sub.unsubscribe()
+	return nil
+}
+
+func (s *wsSession) unsubscribeAll() {
+	s.mu.Lock()
+	subs := s.subs
+	s.subs = nil
+	s.mu.Unlock()
+
+	for _, sub := range subs {
+		sub.unsubscribe()
+	}
+}
+
+// reply queues a response to a message. Unlike data, responses are not
+// dropped when the queue is full.
+func (s *wsSession) reply(msg wsMessage) {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+
+	select {
+	case s.out <- b:
+	case <-s.ctx.Done():
+	}
+}
+
+// send queues a message unless the queue is full.
+func (s *wsSession) send(msg wsMessage) {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+
+	select {
+	case s.out <- b:
+	default:
+	}
+}
+
+// wsSubscription sends the data that is written to it to the client.
+type wsSubscription struct {
+	s           *wsSession
+	id          string
+	unsubscribe pubsub.Unsubscriber
+
+	// added is set (while holding the session's lock) once the
+	// subscription has been added.
+	added bool
+}
+
+// Write implements pubsub.Subscription. Data that can not be encoded is
+// dropped.
+func (sub *wsSubscription) Write(data interface{}) {
+	d, err := sub.s.c.encode(data)
+	if err != nil {
+		return
+	}
+	sub.s.send(wsMessage{Type: "data", ID: sub.id, Data: d})
+}
+
+// Close implements pubsub.Closer. It is invoked when the PubSub is closed
+// or when the subscription could not be added. In the latter case, the
+// error is replied to the subscribe message instead.
+func (sub *wsSubscription) Close() {
+	sub.s.mu.Lock()
+	var added bool
+	if sub.s.subs[sub.id] == sub {
+		delete(sub.s.subs, sub.id)
+		added = sub.added
+	}
+	sub.s.mu.Unlock()
+
+	if added {
+		sub.s.send(wsMessage{Type: "error", ID: sub.id, Error: pubsub.ErrClosed.Error()})
+	}
+}
@@ -0,0 +1,286 @@
+package pubsubhttp_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apoydence/pubsub"
+	"github.com/apoydence/pubsub/codec"
+	"github.com/apoydence/pubsub/pubsubhttp"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+type TW struct {
+	*testing.T
+	p      *pubsub.PubSub
+	server *httptest.Server
+}
+
+type status struct {
+	Status int
+}
+
+func TestWebSocketHandler(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) TW {
+		p := pubsub.New()
+		server := httptest.NewServer(pubsubhttp.WebSocketHandler(p))
+		t.Cleanup(server.Close)
+
+		return TW{
+			T:      t,
+			p:      p,
+			server: server,
+		}
+	})
+
+	o.Spec("it streams the data of a subscription", func(t TW) {
+		c := dialWebSocket(t, t.server, nil)
+		c.send(t, map[string]interface{}{"type": "subscribe", "id": "x", "path": []string{"a"}})
+		Expect(t, c.recv(t)).To(Equal(map[string]interface{}{"type": "subscribed", "id": "x"}))
+
+		t.p.Publish(status{Status: 503}, pubsub.LinearTreeTraverser([]string{"a", "b"}))
+		Expect(t, c.recv(t)).To(Equal(map[string]interface{}{
+			"type": "data",
+			"id":   "x",
+			"data": map[string]interface{}{"Status": 503.0},
+		}))
+	})
+
+	o.Spec("it filters the data with an expression", func(t TW) {
+		c := dialWebSocket(t, t.server, nil)
+		c.send(t, map[string]interface{}{"type": "subscribe", "id": "x", "filter": "msg.Status >= 500"})
+		Expect(t, c.recv(t)["type"]).To(Equal("subscribed"))
+
+		t.p.Publish(status{Status: 200}, pubsub.LinearTreeTraverser(nil))
+		t.p.Publish(status{Status: 503}, pubsub.LinearTreeTraverser(nil))
+		Expect(t, c.recv(t)["data"]).To(Equal(map[string]interface{}{"Status": 503.0}))
+	})
+
+	o.Spec("it encodes the data with the configured Codec", func(t TW) {
+		server := httptest.NewServer(pubsubhttp.WebSocketHandler(t.p, pubsubhttp.WithCodec(codec.Gob[status]())))
+		t.Cleanup(server.Close)
+
+		c := dialWebSocket(t, server, nil)
+		c.send(t, map[string]interface{}{"type": "subscribe", "id": "x"})
+		Expect(t, c.recv(t)["type"]).To(Equal("subscribed"))
+
+		t.p.Publish(status{Status: 503}, pubsub.LinearTreeTraverser(nil))
+		b, err := base64.StdEncoding.DecodeString(c.recv(t)["data"].(string))
+		Expect(t, err).To(BeNil())
+
+		d, err := codec.Gob[status]().Unmarshal(b)
+		Expect(t, err).To(BeNil())
+		Expect(t, d).To(Equal(status{Status: 503}))
+	})
+
+	o.Spec("it subscribes with the configured options", func(t TW) {
+		server := httptest.NewServer(pubsubhttp.WebSocketHandler(t.p, pubsubhttp.WithSubscribeOptions(
+			pubsub.WithTransformer(func(ctx context.Context, data interface{}, path []string) (interface{}, error) {
+				return status{Status: 0}, nil
+			}),
+		)))
+		t.Cleanup(server.Close)
+
+		c := dialWebSocket(t, server, nil)
+		c.send(t, map[string]interface{}{"type": "subscribe", "id": "x", "filter": "msg.Status >= 500"})
+		Expect(t, c.recv(t)["type"]).To(Equal("subscribed"))
+
+		t.p.Publish(status{Status: 200}, pubsub.LinearTreeTraverser(nil))
+		t.p.Publish(status{Status: 503}, pubsub.LinearTreeTraverser(nil))
+		Expect(t, c.recv(t)["data"]).To(Equal(map[string]interface{}{"Status": 0.0}))
+	})
+
+	o.Spec("it unsubscribes", func(t TW) {
+		c := dialWebSocket(t, t.server, nil)
+		c.send(t, map[string]interface{}{"type": "subscribe", "id": "x", "path": []string{"a"}})
+		Expect(t, c.recv(t)["type"]).To(Equal("subscribed"))
+		Expect(t, t.p.Subscriptions("a")).To(Equal(1))
+
+		c.send(t, map[string]interface{}{"type": "unsubscribe", "id": "x"})
+		Expect(t, c.recv(t)).To(Equal(map[string]interface{}{"type": "unsubscribed", "id": "x"}))
+		Expect(t, t.p.Subscriptions("a")).To(Equal(0))
+	})
+
+	o.Spec("it replies with errors", func(t TW) {
+		c := dialWebSocket(t, t.server, nil)
+		c.send(t, map[string]interface{}{"type": "subscribe", "id": "x", "path": []string{pubsub.Rest, "a"}})
+		Expect(t, c.recv(t)).To(Equal(map[string]interface{}{"type": "error", "id": "x", "error": "invalid path"}))
+
+		c.send(t, map[string]interface{}{"type": "subscribe", "id": "x", "filter": "msg.Status >"})
+		Expect(t, c.recv(t)["type"]).To(Equal("error"))
+
+		c.send(t, map[string]interface{}{"type": "subscribe", "id": "x"})
+		Expect(t, c.recv(t)["type"]).To(Equal("subscribed"))
+		c.send(t, map[string]interface{}{"type": "subscribe", "id": "x"})
+		Expect(t, c.recv(t)).To(Equal(map[string]interface{}{"type": "error", "id": "x", "error": "already subscribed"}))
+
+		c.send(t, map[string]interface{}{"type": "unsubscribe", "id": "y"})
+		Expect(t, c.recv(t)).To(Equal(map[string]interface{}{"type": "error", "id": "y", "error": "not subscribed"}))
+
+		c.send(t, map[string]interface{}{"type": "publish"})
+		Expect(t, c.recv(t)).To(Equal(map[string]interface{}{"type": "error", "error": "unknown message type"}))
+	})
+
+	o.Spec("it sends an error when the PubSub is closed", func(t TW) {
+		c := dialWebSocket(t, t.server, nil)
+		c.send(t, map[string]interface{}{"type": "subscribe", "id": "x"})
+		Expect(t, c.recv(t)["type"]).To(Equal("subscribed"))
+
+		t.p.Close()
+		Expect(t, c.recv(t)).To(Equal(map[string]interface{}{"type": "error", "id": "x", "error": "pubsub is closed"}))
+	})
+
+	o.Spec("it removes the subscriptions when the connection is closed", func(t TW) {
+		c := dialWebSocket(t, t.server, nil)
+		c.send(t, map[string]interface{}{"type": "subscribe", "id": "x", "path": []string{"a"}})
+		Expect(t, c.recv(t)["type"]).To(Equal("subscribed"))
+
+		c.writeFrame(t, 0x8, binary.BigEndian.AppendUint16(nil, 1000))
+		op, payload := c.recvFrame(t)
+		Expect(t, op).To(Equal(byte(0x8)))
+		Expect(t, payload).To(Equal([]byte{0x03, 0xe8}))
+		Expect(t, func() int { return t.p.Subscriptions("a") }).To(ViaPolling(Equal(0)))
+	})
+
+	o.Spec("it sends heartbeats", func(t TW) {
+		server := httptest.NewServer(pubsubhttp.WebSocketHandler(t.p, pubsubhttp.WithHeartbeat(time.Millisecond)))
+		defer server.Close()
+
+		c := dialWebSocket(t, server, nil)
+		op, _ := c.recvFrame(t)
+		Expect(t, op).To(Equal(byte(0x9)))
+	})
+
+	o.Spec("it rejects requests that are not a handshake", func(t TW) {
+		resp, err := http.Get(t.server.URL)
+		Expect(t, err).To(BeNil())
+		resp.Body.Close()
+		Expect(t, resp.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+
+	o.Spec("it rejects cross-origin requests", func(t TW) {
+		_, resp := handshake(t, t.server, http.Header{"Origin": {"http://example.com"}})
+		Expect(t, resp.StatusCode).To(Equal(http.StatusForbidden))
+
+		server := httptest.NewServer(pubsubhttp.WebSocketHandler(t.p, pubsubhttp.WithOriginCheck(func(*http.Request) bool {
+			return true
+		})))
+		defer server.Close()
+
+		_, resp = handshake(t, server, http.Header{"Origin": {"http://example.com"}})
+		Expect(t, resp.StatusCode).To(Equal(http.StatusSwitchingProtocols))
+	})
+}
+
+// wsClient is a minimal WebSocket client.
+type wsClient struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func dialWebSocket(t testing.TB, server *httptest.Server, h http.Header) *wsClient {
+	c, resp := handshake(t, server, h)
+	Expect(t, resp.StatusCode).To(Equal(http.StatusSwitchingProtocols))
+	Expect(t, resp.Header.Get("Sec-WebSocket-Accept")).To(Equal("s3pPLMBiTxaQ9kYGzzhZRbK+xOo="))
+	return c
+}
+
+func handshake(t testing.TB, server *httptest.Server, h http.Header) (*wsClient, *http.Response) {
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	Expect(t, err).To(BeNil())
+	t.Cleanup(func() { conn.Close() })
+
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	Expect(t, err).To(BeNil())
+	for k, v := range h {
+		req.Header[k] = v
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	Expect(t, req.Write(conn)).To(BeNil())
+
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, req)
+	Expect(t, err).To(BeNil())
+
+	return &wsClient{conn: conn, r: r}, resp
+}
+
+func (c *wsClient) send(t testing.TB, v interface{}) {
+	b, err := json.Marshal(v)
+	Expect(t, err).To(BeNil())
+	c.writeFrame(t, 0x1, b)
+}
+
+func (c *wsClient) writeFrame(t testing.TB, op byte, payload []byte) {
+	mask := [4]byte{1, 2, 3, 4}
+	b := []byte{0x80 | op}
+	if len(payload) < 126 {
+		b = append(b, 0x80|byte(len(payload)))
+	} else {
+		b = append(b, 0x80|126)
+		b = binary.BigEndian.AppendUint16(b, uint16(len(payload)))
+	}
+	b = append(b, mask[:]...)
+	for i, x := range payload {
+		b = append(b, x^mask[i%4])
+	}
+
+	_, err := c.conn.Write(b)
+	Expect(t, err).To(BeNil())
+}
+
+// recv returns the next text message, skipping any pings.
+func (c *wsClient) recv(t testing.TB) map[string]interface{} {
+	for {
+		op, payload := c.recvFrame(t)
+		if op == 0x9 {
+			continue
+		}
+		Expect(t, op).To(Equal(byte(0x1)))
+
+		var m map[string]interface{}
+		Expect(t, json.Unmarshal(payload, &m)).To(BeNil())
+		return m
+	}
+}
+
+func (c *wsClient) recvFrame(t testing.TB) (byte, []byte) {
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	var h [2]byte
+	_, err := io.ReadFull(c.r, h[:])
+	Expect(t, err).To(BeNil())
+
+	n := int(h[1] & 0x7f)
+	if n == 126 {
+		var b [2]byte
+		_, err := io.ReadFull(c.r, b[:])
+		Expect(t, err).To(BeNil())
+		n = int(binary.BigEndian.Uint16(b[:]))
+	}
+
+	payload := make([]byte, n)
+	_, err = io.ReadFull(c.r, payload)
+	Expect(t, err).To(BeNil())
+
+	return h[0] & 0x0f, payload
+}
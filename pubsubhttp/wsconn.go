@@ -0,0 +1,280 @@
+package pubsubhttp
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// websocketGUID is appended to the client's key to compute the accept key
+// of the handshake (see RFC 6455).
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Opcodes of WebSocket frames.
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+)
+
+// Status codes of close frames.
+const (
+	closeNormal        = 1000
+	closeProtocolError = 1002
+	closeTooBig        = 1009
+)
+
+const (
+	// maxMessageSize is the size of the largest message a client may
+	// send.
+	maxMessageSize = 64 << 10
+
+	// writeTimeout is how long a frame may take to be written before the
+	// connection is considered dead.
+	writeTimeout = 10 * time.Second
+)
+
+var errProtocol = errors.New("websocket protocol error")
+
+// wsConn is a server side WebSocket connection. It only implements what
+// the WebSocketHandler needs: reading (possibly fragmented) messages and
+// writing unfragmented ones.
+type wsConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+
+	// mu serializes writes.
+	mu        sync.Mutex
+	closeSent bool
+}
+
+// upgrade completes the WebSocket handshake. If the request is not a valid
+// handshake, an error response is written and nil is returned.
+func upgrade(w http.ResponseWriter, r *http.Request, c config) *wsConn {
+	if r.Method != http.MethodGet ||
+		!headerHasToken(r.Header, "Connection", "upgrade") ||
+		!headerHasToken(r.Header, "Upgrade", "websocket") {
+		http.Error(w, "websocket upgrade required", http.StatusBadRequest)
+		return nil
+	}
+
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported websocket version", http.StatusUpgradeRequired)
+		return nil
+	}
+
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		http.Error(w, "missing Sec-WebSocket-Key", http.StatusBadRequest)
+		return nil
+	}
+
+	if !c.checkOrigin(r) {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return nil
+	}
+
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket upgrade not supported", http.StatusInternalServerError)
+		return nil
+	}
+
+	conn, brw, err := hj.Hijack()
+	if err != nil {
+		return nil
+	}
+
+	h := sha1.Sum([]byte(key + websocketGUID))
+	brw.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(h[:]) + "\r\n\r\n")
+	if err := brw.Flush(); err != nil {
+		conn.Close()
+		return nil
+	}
+
+	return &wsConn{
+		conn: conn,
+		r:    brw.Reader,
+	}
+}
+
+// headerHasToken reports whether the comma separated header contains the
+// token (case-insensitively).
+func headerHasToken(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// readMessage returns the payload of the next text or binary message.
+// Control frames are handled while reading: pings are answered and a close
+// frame is answered and io.EOF is returned. If the client violates the
+// protocol, the connection is closed with the corresponding status.
+func (c *wsConn) readMessage() ([]byte, error) {
+	var msg []byte
+	started := false
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+
+		switch op {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			// The status code (if any) is echoed back.
+			if len(payload) > 2 {
+				payload = payload[:2]
+			}
+			c.writeFrame(opClose, payload)
+			return nil, io.EOF
+		case opText, opBinary:
+			if started {
+				return nil, c.fail(closeProtocolError, errProtocol)
+			}
+			started = true
+		case opContinuation:
+			if !started {
+				return nil, c.fail(closeProtocolError, errProtocol)
+			}
+		default:
+			return nil, c.fail(closeProtocolError, errProtocol)
+		}
+
+		if len(msg)+len(payload) > maxMessageSize {
+			return nil, c.fail(closeTooBig, errProtocol)
+		}
+		msg = append(msg, payload...)
+
+		if fin {
+			return msg, nil
+		}
+	}
+}
+
+// readFrame reads the next frame and unmasks its payload.
+func (c *wsConn) readFrame() (bool, byte, []byte, error) {
+	var h [2]byte
+	if _, err := io.ReadFull(c.r, h[:]); err != nil {
+		return false, 0, nil, err
+	}
+
+	fin := h[0]&0x80 != 0
+	op := h[0] & 0x0f
+	masked := h[1]&0x80 != 0
+	n := uint64(h[1] & 0x7f)
+
+	// Reserved bits are not used without extensions, and clients must
+	// mask their frames.
+	if h[0]&0x70 != 0 || !masked {
+		return false, 0, nil, c.fail(closeProtocolError, errProtocol)
+	}
+
+	switch n {
+	case 126:
+		var b [2]byte
+		if _, err := io.ReadFull(c.r, b[:]); err != nil {
+			return false, 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(b[:]))
+	case 127:
+		var b [8]byte
+		if _, err := io.ReadFull(c.r, b[:]); err != nil {
+			return false, 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(b[:])
+	}
+
+	// Control frames can not be fragmented and have small payloads.
+	if op&0x8 != 0 && (!fin || n > 125) {
+		return false, 0, nil, c.fail(closeProtocolError, errProtocol)
+	}
+
+	if n > maxMessageSize {
+		return false, 0, nil, c.fail(closeTooBig, errProtocol)
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.r, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(c.r, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+
+	return fin, op, payload, nil
+}
+
+// writeFrame writes an unfragmented frame. It is safe to invoke
+// concurrently. Nothing is written after a close frame.
+func (c *wsConn) writeFrame(op byte, payload []byte) error {
+	b := make([]byte, 0, 10+len(payload))
+	b = append(b, 0x80|op)
+	switch n := len(payload); {
+	case n <= 125:
+		b = append(b, byte(n))
+	case n <= 0xffff:
+		b = append(b, 126)
+		b = binary.BigEndian.AppendUint16(b, uint16(n))
+	default:
+		b = append(b, 127)
+		b = binary.BigEndian.AppendUint64(b, uint64(n))
+	}
+	b = append(b, payload...)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closeSent {
+		return net.ErrClosed
+	}
+	c.closeSent = op == opClose
+
+	c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	_, err := c.conn.Write(b)
+	return err
+}
+
+// fail sends a close frame with the given status and closes the
+// connection. It returns err.
+func (c *wsConn) fail(code uint16, err error) error {
+	c.writeFrame(opClose, binary.BigEndian.AppendUint16(nil, code))
+	c.conn.Close()
+	return err
+}
+
+// close sends a normal close frame and closes the connection.
+func (c *wsConn) close() {
+	c.fail(closeNormal, nil)
+}
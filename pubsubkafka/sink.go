@@ -0,0 +1,140 @@
+package pubsubkafka
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/apoydence/pubsub"
+	"github.com/segmentio/kafka-go"
+)
+
+// WithKeyFunc configures a Sink to derive the key of each Kafka message
+// from the path the data was written at. It defaults to the path's
+// segments joined with '.'.
+func WithKeyFunc(f func(path []string) []byte) Option {
+	return configFunc(func(c *config) {
+		c.key = f
+	})
+}
+
+// WithBufferSize configures how much data a Sink buffers for each export
+// while it is written to Kafka. Publishers block once the buffer is full.
+// It defaults to 100.
+func WithBufferSize(size int) Option {
+	return configFunc(func(c *config) {
+		c.bufferSize = size
+	})
+}
+
+// Writer is the part of a *kafka.Writer that a Sink uses. As each message
+// has its topic set, the Writer must not be configured with a Topic.
+type Writer interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+// Encoder converts data that was written to an exported path to the value
+// of a Kafka message.
+type Encoder func(data interface{}) ([]byte, error)
+
+// Sink writes the data of exported paths to Kafka topics. It should be
+// constructed with NewSink.
+type Sink struct {
+	p      *pubsub.PubSub
+	w      Writer
+	encode Encoder
+	c      config
+
+	mu      sync.Mutex
+	closed  bool
+	removes []func()
+}
+
+// NewSink constructs a new Sink.
+func NewSink(p *pubsub.PubSub, w Writer, encode Encoder, opts ...Option) *Sink {
+	return &Sink{
+		p:      p,
+		w:      w,
+		encode: encode,
+		c:      newConfig(opts),
+	}
+}
+
+// Export subscribes to the path (which may include pattern segments, such
+// as pubsub.Any) and writes the data that is written to it to the given
+// topic, keyed by the path it was written at (see WithKeyFunc). Data that
+// is written without a known path (e.g., retained data) is keyed by the
+// given path. The data is written from the subscription's own goroutine,
+// so publishers are not blocked by Kafka unless the buffer is full. Any
+// error the PubSub returns for the subscription is returned (see
+// pubsub.PubSub.SubscribeErr).
+func (s *Sink) Export(path []string, topic string) error {
+	unsubscribe, err := s.p.SubscribeErr(
+		sinkSubscription{s: s, path: path, topic: topic},
+		pubsub.WithPath(path),
+		pubsub.WithBufferSize(s.c.bufferSize),
+		pubsub.WithOverflowStrategy(pubsub.OverflowBlock),
+	)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		unsubscribe()
+		return pubsub.ErrClosed
+	}
+	s.removes = append(s.removes, unsubscribe)
+	return nil
+}
+
+// sinkSubscription writes the data that is written to it to Kafka.
+type sinkSubscription struct {
+	s     *Sink
+	path  []string
+	topic string
+}
+
+// Write implements pubsub.Subscription.
+func (s sinkSubscription) Write(data interface{}) {
+	s.WritePath(data, s.path)
+}
+
+// WritePath implements pubsub.PathAwareSubscription.
+func (s sinkSubscription) WritePath(data interface{}, path []string) {
+	v, err := s.s.encode(data)
+	if err != nil {
+		s.s.c.errorf(err)
+		return
+	}
+
+	m := kafka.Message{
+		Topic: s.topic,
+		Key:   s.s.c.key(path),
+		Value: v,
+	}
+
+	if err := s.s.w.WriteMessages(context.Background(), m); err != nil {
+		s.s.c.errorf(err)
+	}
+}
+
+// Close removes every export. Any Export afterwards returns
+// pubsub.ErrClosed.
+func (s *Sink) Close() {
+	s.mu.Lock()
+	removes := s.removes
+	s.removes = nil
+	s.closed = true
+	s.mu.Unlock()
+
+	for _, remove := range removes {
+		remove()
+	}
+}
+
+func pathKey(path []string) []byte {
+	return []byte(strings.Join(path, "."))
+}
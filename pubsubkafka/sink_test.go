@@ -0,0 +1,100 @@
+package pubsubkafka_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/apoydence/pubsub/pubsubkafka"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+	"github.com/segmentio/kafka-go"
+)
+
+type TS struct {
+	*testing.T
+	p    *pubsub.PubSub
+	w    *fakeWriter
+	sink *pubsubkafka.Sink
+}
+
+func TestSink(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) TS {
+		p := pubsub.New()
+		w := newFakeWriter()
+
+		return TS{
+			T:    t,
+			p:    p,
+			w:    w,
+			sink: pubsubkafka.NewSink(p, w, json.Marshal),
+		}
+	})
+
+	o.Spec("it writes exported data to the topic keyed by its path", func(t TS) {
+		Expect(t, t.sink.Export([]string{"a", pubsub.Any}, "t")).To(BeNil())
+		t.p.Publish("x", pubsub.LinearTreeTraverser([]string{"a", "b"}))
+		t.p.Publish("y", pubsub.LinearTreeTraverser([]string{"b", "c"}))
+
+		m := <-t.w.msgs
+		Expect(t, m.Topic).To(Equal("t"))
+		Expect(t, string(m.Key)).To(Equal("a.b"))
+		Expect(t, string(m.Value)).To(Equal(`"x"`))
+	})
+
+	o.Spec("it uses the configured key function", func(t TS) {
+		sink := pubsubkafka.NewSink(t.p, t.w, json.Marshal, pubsubkafka.WithKeyFunc(func(path []string) []byte {
+			return []byte(path[len(path)-1])
+		}))
+		Expect(t, sink.Export([]string{"a", pubsub.Any}, "t")).To(BeNil())
+		t.p.Publish("x", pubsub.LinearTreeTraverser([]string{"a", "b"}))
+
+		Expect(t, string((<-t.w.msgs).Key)).To(Equal("b"))
+	})
+
+	o.Spec("it reports data that can not be encoded", func(t TS) {
+		errs := make(chan error, 1)
+		sink := pubsubkafka.NewSink(t.p, t.w, json.Marshal, pubsubkafka.WithErrorFunc(func(err error) {
+			errs <- err
+		}))
+		Expect(t, sink.Export([]string{"a"}, "t")).To(BeNil())
+		t.p.Publish(func() {}, pubsub.LinearTreeTraverser([]string{"a"}))
+
+		Expect(t, <-errs).To(Not(BeNil()))
+	})
+
+	o.Spec("it removes the exports when closed", func(t TS) {
+		Expect(t, t.sink.Export([]string{"a"}, "t")).To(BeNil())
+
+		t.sink.Close()
+		Expect(t, t.p.Subscriptions("a")).To(Equal(0))
+		Expect(t, t.sink.Export([]string{"a"}, "t")).To(Equal(pubsub.ErrClosed))
+	})
+
+	o.Spec("it returns the error of the PubSub for an export", func(t TS) {
+		Expect(t, t.sink.Export([]string{pubsub.Rest, "a"}, "t")).To(Equal(pubsub.ErrInvalidPath))
+	})
+}
+
+type fakeWriter struct {
+	msgs chan kafka.Message
+}
+
+func newFakeWriter() *fakeWriter {
+	return &fakeWriter{
+		msgs: make(chan kafka.Message, 100),
+	}
+}
+
+func (w *fakeWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	for _, m := range msgs {
+		w.msgs <- m
+	}
+	return nil
+}
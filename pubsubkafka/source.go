@@ -0,0 +1,286 @@
+// Package pubsubkafka connects a PubSub to Kafka. A Source consumes Kafka
+// messages and publishes them to the PubSub, while a Sink writes the data
+// of selected paths to Kafka topics.
+package pubsubkafka
+
+import (
+	"context"
+	"sync"
+
+	"github.com/apoydence/pubsub"
+	"github.com/segmentio/kafka-go"
+)
+
+// Option is used to configure a Source or a Sink.
+type Option interface {
+	configure(*config)
+}
+
+type configFunc func(*config)
+
+func (f configFunc) configure(c *config) {
+	f(c)
+}
+
+type config struct {
+	ack        bool
+	errorf     func(err error)
+	key        func(path []string) []byte
+	bufferSize int
+}
+
+func newConfig(opts []Option) config {
+	c := config{
+		errorf:     func(error) {},
+		key:        pathKey,
+		bufferSize: 100,
+	}
+
+	for _, o := range opts {
+		o.configure(&c)
+	}
+
+	return c
+}
+
+// WithErrorFunc configures a Source or Sink to invoke the given function
+// with any error that does not stop it, e.g., a message that could not be
+// decoded (which is skipped) or an offset that could not be committed.
+func WithErrorFunc(f func(err error)) Option {
+	return configFunc(func(c *config) {
+		c.errorf = f
+	})
+}
+
+// WithAcknowledgement configures a Source to publish a *Delivery instead of
+// the decoded data, and to only commit a message's offset once each
+// subscription that the Delivery was written to has acknowledged it.
+// Without it, an offset is committed once the message has been published.
+func WithAcknowledgement() Option {
+	return configFunc(func(c *config) {
+		c.ack = true
+	})
+}
+
+// Reader is the part of a *kafka.Reader that a Source uses. The Reader
+// should be part of a consumer group so that offsets can be committed.
+type Reader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+// Decoder converts a Kafka message to the data to publish and the path to
+// publish it to.
+type Decoder func(m kafka.Message) (data interface{}, path []string, err error)
+
+// Source publishes the messages of a Reader to a PubSub. It should be
+// constructed with NewSource.
+type Source struct {
+	p      *pubsub.PubSub
+	r      Reader
+	decode Decoder
+	c      config
+}
+
+// NewSource constructs a new Source.
+func NewSource(p *pubsub.PubSub, r Reader, decode Decoder, opts ...Option) *Source {
+	return &Source{
+		p:      p,
+		r:      r,
+		decode: decode,
+		c:      newConfig(opts),
+	}
+}
+
+// Delivery is published by a Source that is configured with
+// WithAcknowledgement.
+type Delivery struct {
+	Data    interface{}
+	Message kafka.Message
+
+	o *pendingOffset
+}
+
+// Ack acknowledges the Delivery. Each subscription that the Delivery is
+// written to must invoke it once (e.g., after it has processed the data)
+// for the message's offset to be committed. Deliveries that are
+// acknowledged after Run has returned are not committed.
+func (d *Delivery) Ack() {
+	d.o.c.ack(d.o)
+}
+
+// Run fetches messages and publishes them until the context is done or
+// fetching fails, and returns the error. Offsets are committed in order:
+// an offset is only committed once every earlier message of its partition
+// was committed as well. Messages that can not be decoded are skipped.
+func (s *Source) Run(ctx context.Context) error {
+	c := newCommitter()
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.commit(context.WithoutCancel(ctx), c, stop)
+	}()
+	defer func() {
+		close(stop)
+		<-done
+	}()
+
+	for {
+		m, err := s.r.FetchMessage(ctx)
+		if err != nil {
+			return err
+		}
+
+		o := c.track(m)
+
+		d, path, err := s.decode(m)
+		if err != nil {
+			s.c.errorf(err)
+			c.published(o, 0)
+			continue
+		}
+
+		if s.c.ack {
+			d = &Delivery{Data: d, Message: m, o: o}
+		}
+
+		result, err := s.p.PublishCtx(ctx, d, pubsub.LinearTreeTraverser(path))
+		if err != nil {
+			s.c.errorf(err)
+		}
+
+		if !s.c.ack {
+			result.Delivered = 0
+		}
+		c.published(o, result.Delivered)
+	}
+}
+
+// commit commits the offsets that are ready until stop is closed, and
+// then commits the remaining ones.
+func (s *Source) commit(ctx context.Context, c *committer, stop <-chan struct{}) {
+	for {
+		select {
+		case <-c.notify:
+		case <-stop:
+			s.commitReady(ctx, c)
+			return
+		}
+		s.commitReady(ctx, c)
+	}
+}
+
+func (s *Source) commitReady(ctx context.Context, c *committer) {
+	msgs := c.take()
+	if len(msgs) == 0 {
+		return
+	}
+
+	if err := s.r.CommitMessages(ctx, msgs...); err != nil {
+		s.c.errorf(err)
+	}
+}
+
+type partition struct {
+	topic string
+	id    int
+}
+
+// committer tracks the messages that have not been committed yet.
+type committer struct {
+	notify chan struct{}
+
+	mu      sync.Mutex
+	pending map[partition][]*pendingOffset
+
+	// ready holds the latest message of each partition that can be
+	// committed.
+	ready map[partition]kafka.Message
+}
+
+// pendingOffset is a message whose offset has not been committed yet.
+type pendingOffset struct {
+	c *committer
+	m kafka.Message
+
+	// The fields below are guarded by c's mutex.
+	published bool
+	delivered int
+	acks      int
+}
+
+func newCommitter() *committer {
+	return &committer{
+		notify:  make(chan struct{}, 1),
+		pending: make(map[partition][]*pendingOffset),
+		ready:   make(map[partition]kafka.Message),
+	}
+}
+
+func (c *committer) track(m kafka.Message) *pendingOffset {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	o := &pendingOffset{c: c, m: m}
+	k := partition{topic: m.Topic, id: m.Partition}
+	c.pending[k] = append(c.pending[k], o)
+	return o
+}
+
+// published records that the message was published and written to the
+// given number of subscriptions that will acknowledge it. The
+// acknowledgements may have happened already.
+func (c *committer) published(o *pendingOffset, delivered int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	o.published = true
+	o.delivered = delivered
+	c.advance(o)
+}
+
+func (c *committer) ack(o *pendingOffset) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	o.acks++
+	c.advance(o)
+}
+
+// advance moves the messages of o's partition that are done (up to the
+// first that is not) to ready. It must be invoked while holding the mutex.
+func (c *committer) advance(o *pendingOffset) {
+	k := partition{topic: o.m.Topic, id: o.m.Partition}
+	q := c.pending[k]
+
+	i := 0
+	for i < len(q) && q[i].published && q[i].acks >= q[i].delivered {
+		i++
+	}
+	if i == 0 {
+		return
+	}
+
+	c.ready[k] = q[i-1].m
+	c.pending[k] = q[i:]
+
+	select {
+	case c.notify <- struct{}{}:
+	default:
+	}
+}
+
+// take returns the messages that are ready to be committed.
+func (c *committer) take() []kafka.Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var msgs []kafka.Message
+	for k, m := range c.ready {
+		msgs = append(msgs, m)
+		delete(c.ready, k)
+	}
+	return msgs
+}
@@ -0,0 +1,190 @@
+package pubsubkafka_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/apoydence/pubsub/pubsubkafka"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+	"github.com/segmentio/kafka-go"
+)
+
+type TK struct {
+	*testing.T
+	p    *pubsub.PubSub
+	r    *fakeReader
+	errs chan error
+	run  func(opts ...pubsubkafka.Option) chan error
+}
+
+func TestSource(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) TK {
+		p := pubsub.New()
+		r := newFakeReader()
+		errs := make(chan error, 100)
+
+		return TK{
+			T:    t,
+			p:    p,
+			r:    r,
+			errs: errs,
+			run: func(opts ...pubsubkafka.Option) chan error {
+				opts = append(opts, pubsubkafka.WithErrorFunc(func(err error) { errs <- err }))
+				s := pubsubkafka.NewSource(p, r, decode, opts...)
+
+				ctx, cancel := context.WithCancel(context.Background())
+				done := make(chan error, 1)
+				stopped := make(chan struct{})
+				go func() {
+					defer close(stopped)
+					done <- s.Run(ctx)
+				}()
+				t.Cleanup(func() {
+					cancel()
+					<-stopped
+				})
+				return done
+			},
+		}
+	})
+
+	o.Spec("it publishes the decoded messages to their path", func(t TK) {
+		sub := newSpySubscription()
+		t.p.Subscribe(sub, pubsub.WithPath([]string{"a"}))
+		t.run()
+
+		t.r.add("t", 0, 0, "a.b", "x")
+		t.r.add("t", 0, 1, "b.c", "y")
+
+		Expect(t, <-sub.data).To(Equal("x"))
+		Expect(t, t.r.committedOffset).To(ViaPolling(Equal(int64(1))))
+	})
+
+	o.Spec("it skips and commits messages that can not be decoded", func(t TK) {
+		t.run()
+		t.r.add("t", 0, 0, "", "x")
+
+		Expect(t, <-t.errs).To(Not(BeNil()))
+		Expect(t, t.r.committedOffset).To(ViaPolling(Equal(int64(0))))
+	})
+
+	o.Spec("it returns the error of the Reader", func(t TK) {
+		done := t.run()
+		t.r.fail(errors.New("some-error"))
+		Expect(t, <-done).To(Equal(errors.New("some-error")))
+	})
+
+	o.Spec("it commits acknowledged deliveries in order", func(t TK) {
+		sub := newSpySubscription()
+		t.p.Subscribe(sub, pubsub.WithPath([]string{"a"}))
+		t.run(pubsubkafka.WithAcknowledgement())
+
+		t.r.add("t", 0, 0, "a", "x")
+		t.r.add("t", 0, 1, "a", "y")
+		t.r.add("t", 0, 2, "b", "z")
+		first := (<-sub.data).(*pubsubkafka.Delivery)
+		second := (<-sub.data).(*pubsubkafka.Delivery)
+		Expect(t, first.Data).To(Equal("x"))
+		Expect(t, first.Message.Offset).To(Equal(int64(0)))
+
+		second.Ack()
+		Expect(t, t.r.committedOffset()).To(Equal(int64(-1)))
+
+		first.Ack()
+		Expect(t, t.r.committedOffset).To(ViaPolling(Equal(int64(2))))
+	})
+}
+
+func decode(m kafka.Message) (interface{}, []string, error) {
+	if len(m.Key) == 0 {
+		return nil, nil, errors.New("missing key")
+	}
+	return string(m.Value), strings.Split(string(m.Key), "."), nil
+}
+
+type spySubscription struct {
+	data chan interface{}
+}
+
+func newSpySubscription() *spySubscription {
+	return &spySubscription{
+		data: make(chan interface{}, 100),
+	}
+}
+
+func (s *spySubscription) Write(data interface{}) {
+	s.data <- data
+}
+
+type fakeReader struct {
+	msgs chan kafka.Message
+	errs chan error
+
+	mu        sync.Mutex
+	committed map[int]int64
+}
+
+func newFakeReader() *fakeReader {
+	return &fakeReader{
+		msgs:      make(chan kafka.Message, 100),
+		errs:      make(chan error, 1),
+		committed: make(map[int]int64),
+	}
+}
+
+func (r *fakeReader) add(topic string, partition int, offset int64, key, value string) {
+	r.msgs <- kafka.Message{
+		Topic:     topic,
+		Partition: partition,
+		Offset:    offset,
+		Key:       []byte(key),
+		Value:     []byte(value),
+	}
+}
+
+func (r *fakeReader) fail(err error) {
+	r.errs <- err
+}
+
+func (r *fakeReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	select {
+	case m := <-r.msgs:
+		return m, nil
+	case err := <-r.errs:
+		return kafka.Message{}, err
+	case <-ctx.Done():
+		return kafka.Message{}, ctx.Err()
+	}
+}
+
+func (r *fakeReader) CommitMessages(ctx context.Context, msgs ...kafka.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, m := range msgs {
+		r.committed[m.Partition] = m.Offset
+	}
+	return nil
+}
+
+// committedOffset returns the committed offset of partition 0, or -1 if
+// there is none.
+func (r *fakeReader) committedOffset() int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if o, ok := r.committed[0]; ok {
+		return o
+	}
+	return -1
+}
@@ -0,0 +1,201 @@
+package pubsubnats
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"sync"
+
+	"github.com/apoydence/pubsub"
+	"github.com/nats-io/nats.go"
+)
+
+// OriginHeader is the header of the NATS messages a Bridge exports. It
+// holds an ID that is unique to the Bridge so that it can ignore its own
+// messages.
+const OriginHeader = "Pubsub-Origin"
+
+// Option is used to configure a Bridge.
+type Option interface {
+	configure(*Bridge)
+}
+
+type configFunc func(*Bridge)
+
+func (f configFunc) configure(b *Bridge) {
+	f(b)
+}
+
+// WithMapping configures how a Bridge converts between subjects and paths.
+// By default, a subject's tokens (separated by '.') are the segments of
+// its path, and vice versa.
+func WithMapping(toPath func(subject string) []string, toSubject func(path []string) string) Option {
+	return configFunc(func(b *Bridge) {
+		b.toPath = toPath
+		b.toSubject = toSubject
+	})
+}
+
+// WithErrorFunc configures a Bridge to invoke the given function when a
+// message can not be imported or exported, e.g., because the Codec could
+// not decode it. Such messages are dropped.
+func WithErrorFunc(f func(err error)) Option {
+	return configFunc(func(b *Bridge) {
+		b.errorf = f
+	})
+}
+
+// Bridge imports subjects from NATS to a PubSub and exports paths of the
+// PubSub to NATS. Messages that the Bridge exported are not imported again,
+// so a subject can be both imported and exported without looping. It
+// should be constructed with NewBridge.
+type Bridge struct {
+	p         *pubsub.PubSub
+	conn      Conn
+	codec     Codec
+	toPath    func(subject string) []string
+	toSubject func(path []string) string
+	errorf    func(err error)
+	origin    string
+
+	mu      sync.Mutex
+	closed  bool
+	removes []func()
+}
+
+// NewBridge constructs a new Bridge. The Codec must match the one the
+// other NATS clients use.
+func NewBridge(p *pubsub.PubSub, conn Conn, codec Codec, opts ...Option) *Bridge {
+	var id [8]byte
+	rand.Read(id[:])
+
+	b := &Bridge{
+		p:         p,
+		conn:      conn,
+		codec:     codec,
+		toPath:    subjectToPath,
+		toSubject: pathToSubject,
+		errorf:    func(error) {},
+		origin:    hex.EncodeToString(id[:]),
+	}
+
+	for _, o := range opts {
+		o.configure(b)
+	}
+
+	return b
+}
+
+// Import subscribes to the subject (which may include wildcards) and
+// publishes the data of each message to the path of the message's subject.
+func (b *Bridge) Import(subject string) error {
+	unsubscribe, err := b.conn.Subscribe(subject, b.importMsg)
+	if err != nil {
+		return err
+	}
+
+	if !b.add(func() { unsubscribe() }) {
+		unsubscribe()
+		return pubsub.ErrClosed
+	}
+	return nil
+}
+
+func (b *Bridge) importMsg(m *nats.Msg) {
+	if m.Header.Get(OriginHeader) == b.origin {
+		return
+	}
+
+	d, err := b.codec.Unmarshal(m.Data)
+	if err != nil {
+		b.errorf(err)
+		return
+	}
+
+	if _, err := b.p.PublishCtx(context.Background(), d, pubsub.LinearTreeTraverser(b.toPath(m.Subject))); err != nil {
+		b.errorf(err)
+	}
+}
+
+// Export subscribes to the path (which may include pattern segments, such
+// as pubsub.Any) and publishes the data that is written to it to the
+// subject of the path it was written at. Data that is written without a
+// known path (e.g., retained data) is published to the subject of the
+// given path. Any error the PubSub returns for the subscription is
+// returned (see pubsub.PubSub.SubscribeErr).
+func (b *Bridge) Export(path []string) error {
+	unsubscribe, err := b.p.SubscribeErr(exportSubscription{b: b, path: path}, pubsub.WithPath(path))
+	if err != nil {
+		return err
+	}
+
+	if !b.add(unsubscribe) {
+		unsubscribe()
+		return pubsub.ErrClosed
+	}
+	return nil
+}
+
+// exportSubscription publishes the data that is written to it to NATS.
+type exportSubscription struct {
+	b    *Bridge
+	path []string
+}
+
+// Write implements pubsub.Subscription.
+func (s exportSubscription) Write(data interface{}) {
+	s.WritePath(data, s.path)
+}
+
+// WritePath implements pubsub.PathAwareSubscription.
+func (s exportSubscription) WritePath(data interface{}, path []string) {
+	b, err := s.b.codec.Marshal(data)
+	if err != nil {
+		s.b.errorf(err)
+		return
+	}
+
+	m := nats.NewMsg(s.b.toSubject(path))
+	m.Header.Set(OriginHeader, s.b.origin)
+	m.Data = b
+
+	if err := s.b.conn.PublishMsg(m); err != nil {
+		s.b.errorf(err)
+	}
+}
+
+// Close removes every import and export. Any Import or Export afterwards
+// returns pubsub.ErrClosed.
+func (b *Bridge) Close() {
+	b.mu.Lock()
+	removes := b.removes
+	b.removes = nil
+	b.closed = true
+	b.mu.Unlock()
+
+	for _, remove := range removes {
+		remove()
+	}
+}
+
+// add registers a function that removes an import or export. It returns
+// false if the Bridge is closed.
+func (b *Bridge) add(remove func()) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return false
+	}
+	b.removes = append(b.removes, remove)
+	return true
+}
+
+func subjectToPath(subject string) []string {
+	return strings.Split(subject, ".")
+}
+
+func pathToSubject(path []string) string {
+	return strings.Join(path, ".")
+}
@@ -0,0 +1,217 @@
+package pubsubnats_test
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/apoydence/pubsub/codec"
+	"github.com/apoydence/pubsub/pubsubnats"
+	"github.com/nats-io/nats.go"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+type TN struct {
+	*testing.T
+	p      *pubsub.PubSub
+	conn   *fakeConn
+	bridge *pubsubnats.Bridge
+	errs   *[]error
+}
+
+func TestBridge(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) TN {
+		p := pubsub.New()
+		conn := newFakeConn()
+		var errs []error
+
+		return TN{
+			T:    t,
+			p:    p,
+			conn: conn,
+			bridge: pubsubnats.NewBridge(p, conn, codec.JSON[string](), pubsubnats.WithErrorFunc(func(err error) {
+				errs = append(errs, err)
+			})),
+			errs: &errs,
+		}
+	})
+
+	o.Spec("it publishes imported messages to the path of their subject", func(t TN) {
+		sub := newSpySubscription()
+		t.p.Subscribe(sub, pubsub.WithPath([]string{"a"}))
+
+		Expect(t, t.bridge.Import("a.*")).To(BeNil())
+		t.conn.publish("a.b", `"x"`)
+		t.conn.publish("b.c", `"y"`)
+
+		Expect(t, sub.data).To(Equal([]interface{}{"x"}))
+	})
+
+	o.Spec("it publishes exported data to the subject of its path", func(t TN) {
+		Expect(t, t.bridge.Export([]string{"a", pubsub.Any})).To(BeNil())
+		t.p.Publish("x", pubsub.LinearTreeTraverser([]string{"a", "b"}))
+		t.p.Publish("y", pubsub.LinearTreeTraverser([]string{"b", "c"}))
+
+		Expect(t, t.conn.published).To(HaveLen(1))
+		Expect(t, t.conn.published[0].Subject).To(Equal("a.b"))
+		Expect(t, string(t.conn.published[0].Data)).To(Equal(`"x"`))
+	})
+
+	o.Spec("it does not import the messages it exported", func(t TN) {
+		sub := newSpySubscription()
+		t.p.Subscribe(sub, pubsub.WithPath([]string{"a"}))
+
+		Expect(t, t.bridge.Import("a.*")).To(BeNil())
+		Expect(t, t.bridge.Export([]string{"a", pubsub.Any})).To(BeNil())
+
+		t.p.Publish("x", pubsub.LinearTreeTraverser([]string{"a", "b"}))
+		t.conn.publish("a.c", `"y"`)
+
+		Expect(t, sub.data).To(Equal([]interface{}{"x", "y"}))
+		Expect(t, t.conn.published).To(HaveLen(2))
+	})
+
+	o.Spec("it uses the configured mapping", func(t TN) {
+		bridge := pubsubnats.NewBridge(t.p, t.conn, codec.JSON[string](), pubsubnats.WithMapping(
+			func(subject string) []string { return strings.Split(strings.TrimPrefix(subject, "app."), ".") },
+			func(path []string) string { return "app." + strings.Join(path, ".") },
+		))
+
+		sub := newSpySubscription()
+		t.p.Subscribe(sub, pubsub.WithPath([]string{"a"}))
+		Expect(t, bridge.Import("app.>")).To(BeNil())
+		t.conn.publish("app.a", `"x"`)
+		Expect(t, sub.data).To(Equal([]interface{}{"x"}))
+
+		Expect(t, bridge.Export([]string{"b"})).To(BeNil())
+		t.p.Publish("y", pubsub.LinearTreeTraverser([]string{"b"}))
+		Expect(t, t.conn.published[0].Subject).To(Equal("app.b"))
+	})
+
+	o.Spec("it reports messages that can not be imported", func(t TN) {
+		Expect(t, t.bridge.Import("a")).To(BeNil())
+		t.conn.publish("a", "not-json")
+		Expect(t, *t.errs).To(HaveLen(1))
+	})
+
+	o.Spec("it removes the imports and exports when closed", func(t TN) {
+		Expect(t, t.bridge.Import("a")).To(BeNil())
+		Expect(t, t.bridge.Export([]string{"a"})).To(BeNil())
+
+		t.bridge.Close()
+		Expect(t, t.conn.subs).To(HaveLen(0))
+		Expect(t, t.p.Subscriptions("a")).To(Equal(0))
+
+		Expect(t, t.bridge.Import("a")).To(Equal(pubsub.ErrClosed))
+		Expect(t, t.bridge.Export([]string{"a"})).To(Equal(pubsub.ErrClosed))
+	})
+
+	o.Spec("it returns the error of the PubSub for an export", func(t TN) {
+		Expect(t, t.bridge.Export([]string{pubsub.Rest, "a"})).To(Equal(pubsub.ErrInvalidPath))
+	})
+}
+
+type spySubscription struct {
+	data []interface{}
+}
+
+func newSpySubscription() *spySubscription {
+	return &spySubscription{}
+}
+
+func (s *spySubscription) Write(data interface{}) {
+	s.data = append(s.data, data)
+}
+
+// fakeConn delivers the messages that are published to it to the
+// matching subscriptions synchronously.
+type fakeConn struct {
+	mu        sync.Mutex
+	subs      map[int]fakeSub
+	nextID    int
+	published []*nats.Msg
+}
+
+type fakeSub struct {
+	subject string
+	handler nats.MsgHandler
+}
+
+func newFakeConn() *fakeConn {
+	return &fakeConn{
+		subs: make(map[int]fakeSub),
+	}
+}
+
+func (c *fakeConn) PublishMsg(m *nats.Msg) error {
+	c.mu.Lock()
+	c.published = append(c.published, m)
+	c.mu.Unlock()
+
+	c.deliver(m)
+	return nil
+}
+
+func (c *fakeConn) Subscribe(subject string, handler nats.MsgHandler) (func() error, error) {
+	if subject == "" {
+		return nil, errors.New("invalid subject")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id := c.nextID
+	c.nextID++
+	c.subs[id] = fakeSub{subject: subject, handler: handler}
+
+	return func() error {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		delete(c.subs, id)
+		return nil
+	}, nil
+}
+
+// publish publishes a message as another NATS client would.
+func (c *fakeConn) publish(subject, data string) {
+	c.deliver(&nats.Msg{Subject: subject, Data: []byte(data)})
+}
+
+func (c *fakeConn) deliver(m *nats.Msg) {
+	c.mu.Lock()
+	var handlers []nats.MsgHandler
+	for _, s := range c.subs {
+		if subjectMatches(s.subject, m.Subject) {
+			handlers = append(handlers, s.handler)
+		}
+	}
+	c.mu.Unlock()
+
+	for _, h := range handlers {
+		h(m)
+	}
+}
+
+func subjectMatches(pattern, subject string) bool {
+	p := strings.Split(pattern, ".")
+	s := strings.Split(subject, ".")
+	for i, token := range p {
+		switch {
+		case token == ">":
+			return len(s) > i
+		case i >= len(s):
+			return false
+		case token != "*" && token != s[i]:
+			return false
+		}
+	}
+	return len(p) == len(s)
+}
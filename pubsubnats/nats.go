@@ -0,0 +1,44 @@
+// Package pubsubnats bridges a PubSub and NATS. Subjects can be imported
+// from NATS (their messages are published to the PubSub) and paths can be
+// exported to NATS (the data written to them is published to NATS).
+package pubsubnats
+
+import (
+	"github.com/apoydence/pubsub/codec"
+	"github.com/nats-io/nats.go"
+)
+
+// Conn is the part of a NATS connection that a Bridge uses. NewConn adapts
+// a *nats.Conn.
+type Conn interface {
+	PublishMsg(m *nats.Msg) error
+
+	// Subscribe invokes the handler with each message that is published to
+	// the subject until the returned function is invoked.
+	Subscribe(subject string, handler nats.MsgHandler) (unsubscribe func() error, err error)
+}
+
+// NewConn returns a Conn that uses the given NATS connection.
+func NewConn(nc *nats.Conn) Conn {
+	return natsConn{nc: nc}
+}
+
+type natsConn struct {
+	nc *nats.Conn
+}
+
+func (c natsConn) PublishMsg(m *nats.Msg) error {
+	return c.nc.PublishMsg(m)
+}
+
+func (c natsConn) Subscribe(subject string, handler nats.MsgHandler) (func() error, error) {
+	sub, err := c.nc.Subscribe(subject, handler)
+	if err != nil {
+		return nil, err
+	}
+	return sub.Unsubscribe, nil
+}
+
+// Codec converts published data to and from the payloads of NATS messages.
+// See the codec package for implementations.
+type Codec = codec.Codec
@@ -0,0 +1,56 @@
+// Package pubsubotel implements pubsub.Tracer with OpenTelemetry.
+package pubsubotel
+
+import (
+	"context"
+
+	"github.com/apoydence/pubsub"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracer implements pubsub.Tracer. Each Publish creates a span with an
+// event for each matched node and each write to a subscription. It should
+// be constructed with New().
+type Tracer struct {
+	t trace.Tracer
+}
+
+// New constructs a new Tracer that creates spans with the given
+// trace.Tracer.
+func New(t trace.Tracer) Tracer {
+	return Tracer{
+		t: t,
+	}
+}
+
+// StartPublish implements pubsub.Tracer.
+func (t Tracer) StartPublish(ctx context.Context) (context.Context, pubsub.PublishSpan) {
+	ctx, span := t.t.Start(ctx, "pubsub.Publish", trace.WithSpanKind(trace.SpanKindProducer))
+	return ctx, publishSpan{span: span}
+}
+
+type publishSpan struct {
+	span trace.Span
+}
+
+// Matched implements pubsub.PublishSpan.
+func (s publishSpan) Matched(path []string, subscriptions int) {
+	s.span.AddEvent("pubsub.match", trace.WithAttributes(
+		attribute.StringSlice("pubsub.path", path),
+		attribute.Int("pubsub.subscriptions", subscriptions),
+	))
+}
+
+// Wrote implements pubsub.PublishSpan.
+func (s publishSpan) Wrote(path []string) {
+	s.span.AddEvent("pubsub.write", trace.WithAttributes(
+		attribute.StringSlice("pubsub.path", path),
+	))
+}
+
+// End implements pubsub.PublishSpan.
+func (s publishSpan) End(deliveries int) {
+	s.span.SetAttributes(attribute.Int("pubsub.deliveries", deliveries))
+	s.span.End()
+}
@@ -0,0 +1,131 @@
+package pubsubotel_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/apoydence/pubsub/pubsubotel"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type TT struct {
+	*testing.T
+	recorder *tracetest.SpanRecorder
+	tracer   trace.Tracer
+	p        *pubsub.PubSub
+}
+
+func TestTracer(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) TT {
+		recorder := tracetest.NewSpanRecorder()
+		tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+
+		p := pubsub.New(pubsub.WithTracer(pubsubotel.New(tracer)))
+		p.Subscribe(pubsub.SubscriptionFunc(func(interface{}) {}), pubsub.WithPath([]string{"a"}))
+		p.Subscribe(pubsub.SubscriptionFunc(func(interface{}) {}), pubsub.WithPath([]string{"a", "b"}))
+		p.Subscribe(pubsub.SubscriptionFunc(func(interface{}) {}), pubsub.WithPath([]string{"a", "b"}))
+
+		return TT{
+			T:        t,
+			recorder: recorder,
+			tracer:   tracer,
+			p:        p,
+		}
+	})
+
+	o.Spec("it creates a producer span for each publish", func(t TT) {
+		t.p.Publish("x", pubsub.LinearTreeTraverser([]string{"a", "b"}))
+		t.p.Publish("x", pubsub.LinearTreeTraverser([]string{"c"}))
+
+		spans := t.recorder.Ended()
+		Expect(t, spans).To(HaveLen(2))
+		for _, s := range spans {
+			Expect(t, s.Name()).To(Equal("pubsub.Publish"))
+			Expect(t, s.SpanKind()).To(Equal(trace.SpanKindProducer))
+		}
+
+		Expect(t, spans[0].Attributes()).To(Equal([]attribute.KeyValue{
+			attribute.Int("pubsub.deliveries", 3),
+		}))
+		Expect(t, spans[1].Attributes()).To(Equal([]attribute.KeyValue{
+			attribute.Int("pubsub.deliveries", 0),
+		}))
+	})
+
+	o.Spec("it adds an event for each match and write", func(t TT) {
+		t.p.Publish("x", pubsub.LinearTreeTraverser([]string{"a", "b"}))
+
+		spans := t.recorder.Ended()
+		Expect(t, spans).To(HaveLen(1))
+
+		type event struct {
+			name  string
+			attrs []attribute.KeyValue
+		}
+		var events []event
+		for _, e := range spans[0].Events() {
+			events = append(events, event{name: e.Name, attrs: e.Attributes})
+		}
+
+		Expect(t, events).To(Equal([]event{
+			{name: "pubsub.match", attrs: []attribute.KeyValue{
+				attribute.StringSlice("pubsub.path", []string{"a"}),
+				attribute.Int("pubsub.subscriptions", 1),
+			}},
+			{name: "pubsub.write", attrs: []attribute.KeyValue{
+				attribute.StringSlice("pubsub.path", []string{"a"}),
+			}},
+			{name: "pubsub.match", attrs: []attribute.KeyValue{
+				attribute.StringSlice("pubsub.path", []string{"a", "b"}),
+				attribute.Int("pubsub.subscriptions", 2),
+			}},
+			{name: "pubsub.write", attrs: []attribute.KeyValue{
+				attribute.StringSlice("pubsub.path", []string{"a", "b"}),
+			}},
+			{name: "pubsub.write", attrs: []attribute.KeyValue{
+				attribute.StringSlice("pubsub.path", []string{"a", "b"}),
+			}},
+		}))
+	})
+
+	o.Spec("it starts the span as a child of the publish's context", func(t TT) {
+		ctx, parent := t.tracer.Start(context.Background(), "parent")
+		_, err := t.p.PublishCtx(ctx, "x", pubsub.LinearTreeTraverser([]string{"a"}))
+		Expect(t, err).To(BeNil())
+		parent.End()
+
+		spans := t.recorder.Ended()
+		Expect(t, spans).To(HaveLen(2))
+		Expect(t, spans[0].Name()).To(Equal("pubsub.Publish"))
+		Expect(t, spans[0].Parent().SpanID()).To(Equal(parent.SpanContext().SpanID()))
+		Expect(t, spans[0].SpanContext().TraceID()).To(Equal(parent.SpanContext().TraceID()))
+	})
+
+	o.Spec("it gives the span's context to the rest of the publish", func(t TT) {
+		var written trace.SpanContext
+		t.p.Subscribe(pubsub.SubscriptionFunc(func(interface{}) {}),
+			pubsub.WithPath([]string{"c"}),
+			pubsub.WithTransformer(func(ctx context.Context, data interface{}, path []string) (interface{}, error) {
+				written = trace.SpanContextFromContext(ctx)
+				return data, nil
+			}),
+		)
+		t.p.Publish("x", pubsub.LinearTreeTraverser([]string{"c"}))
+
+		spans := t.recorder.Ended()
+		Expect(t, spans).To(HaveLen(1))
+		Expect(t, written.IsValid()).To(BeTrue())
+		Expect(t, written).To(Equal(spans[0].SpanContext()))
+	})
+}
@@ -0,0 +1,242 @@
+// Package pubsubprom implements pubsub.Metrics with Prometheus collectors.
+package pubsubprom
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// OtherPrefix is the path label of the metrics of paths whose prefix was
+// not labeled because of the limit given to WithPathLabels.
+const OtherPrefix = "other"
+
+// Option is used to configure a Metrics.
+type Option interface {
+	configure(*Metrics)
+}
+
+type configFunc func(*Metrics)
+
+func (f configFunc) configure(m *Metrics) {
+	f(m)
+}
+
+// WithPathLabels configures a Metrics to label the metrics of
+// subscriptions (deliveries, drops, subscriptions and queue depths) with
+// the first depth segments of the path the subscription subscribed with,
+// e.g., "/logs/errors" for a depth of 2. Pattern segments (e.g.,
+// pubsub.Any) are labeled as "*". At most maxPrefixes distinct prefixes
+// are labeled; the metrics of any further ones are labeled as OtherPrefix
+// so that the number of series is bounded. By default, the metrics are not
+// labeled.
+func WithPathLabels(depth, maxPrefixes int) Option {
+	return configFunc(func(m *Metrics) {
+		m.depth = depth
+		m.maxPrefixes = maxPrefixes
+	})
+}
+
+// WithDurationBuckets configures the buckets of the publish duration
+// histogram in seconds. It defaults to prometheus.DefBuckets.
+func WithDurationBuckets(buckets []float64) Option {
+	return configFunc(func(m *Metrics) {
+		m.durationBuckets = buckets
+	})
+}
+
+// Metrics implements pubsub.Metrics and pubsub.QueueMetrics. It should be
+// constructed with New().
+type Metrics struct {
+	publishes     prometheus.Counter
+	duration      prometheus.Histogram
+	fanout        prometheus.Histogram
+	deliveries    *prometheus.CounterVec
+	drops         *prometheus.CounterVec
+	subscribes    *prometheus.CounterVec
+	unsubscribes  *prometheus.CounterVec
+	subscriptions *prometheus.GaugeVec
+	queueDepth    *prometheus.GaugeVec
+
+	depth           int
+	maxPrefixes     int
+	durationBuckets []float64
+
+	mu       sync.Mutex
+	prefixes map[string]bool
+}
+
+// New constructs a new Metrics and registers its collectors with the given
+// Registerer.
+func New(reg prometheus.Registerer, opts ...Option) (*Metrics, error) {
+	m := &Metrics{
+		durationBuckets: prometheus.DefBuckets,
+		prefixes:        make(map[string]bool),
+	}
+
+	for _, o := range opts {
+		o.configure(m)
+	}
+
+	var labels []string
+	if m.depth > 0 {
+		labels = []string{"path"}
+	}
+
+	m.publishes = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "pubsub",
+		Name:      "publishes_total",
+		Help:      "Number of publishes.",
+	})
+	m.duration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "pubsub",
+		Name:      "publish_duration_seconds",
+		Help:      "How long publishes took.",
+		Buckets:   m.durationBuckets,
+	})
+	m.fanout = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "pubsub",
+		Name:      "publish_fanout",
+		Help:      "Number of subscriptions the data of a publish was written to.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 11),
+	})
+	m.deliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "pubsub",
+		Name:      "deliveries_total",
+		Help:      "Number of times data was written to a subscription.",
+	}, labels)
+	m.drops = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "pubsub",
+		Name:      "drops_total",
+		Help:      "Number of times data was dropped instead of written to a subscription.",
+	}, labels)
+	m.subscribes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "pubsub",
+		Name:      "subscribes_total",
+		Help:      "Number of subscriptions that were added.",
+	}, labels)
+	m.unsubscribes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "pubsub",
+		Name:      "unsubscribes_total",
+		Help:      "Number of subscriptions that were removed.",
+	}, labels)
+	m.subscriptions = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "pubsub",
+		Name:      "subscriptions",
+		Help:      "Number of current subscriptions.",
+	}, labels)
+	m.queueDepth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "pubsub",
+		Name:      "queue_depth",
+		Help:      "Number of entries in the queues of buffered subscriptions.",
+	}, labels)
+
+	for _, c := range []prometheus.Collector{
+		m.publishes,
+		m.duration,
+		m.fanout,
+		m.deliveries,
+		m.drops,
+		m.subscribes,
+		m.unsubscribes,
+		m.subscriptions,
+		m.queueDepth,
+	} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+
+	// Without labels, the metrics are exported from the start.
+	if m.depth <= 0 {
+		m.deliveries.WithLabelValues()
+		m.drops.WithLabelValues()
+		m.subscribes.WithLabelValues()
+		m.unsubscribes.WithLabelValues()
+		m.subscriptions.WithLabelValues()
+		m.queueDepth.WithLabelValues()
+	}
+
+	return m, nil
+}
+
+// Published implements pubsub.Metrics.
+func (m *Metrics) Published(deliveries int, d time.Duration) {
+	m.publishes.Inc()
+	m.duration.Observe(d.Seconds())
+	m.fanout.Observe(float64(deliveries))
+}
+
+// Delivered implements pubsub.Metrics.
+func (m *Metrics) Delivered(path []string) {
+	m.deliveries.WithLabelValues(m.labels(path)...).Inc()
+}
+
+// Dropped implements pubsub.Metrics.
+func (m *Metrics) Dropped(path []string) {
+	m.drops.WithLabelValues(m.labels(path)...).Inc()
+}
+
+// Subscribed implements pubsub.Metrics.
+func (m *Metrics) Subscribed(path []string) {
+	labels := m.labels(path)
+	m.subscribes.WithLabelValues(labels...).Inc()
+	m.subscriptions.WithLabelValues(labels...).Inc()
+}
+
+// Unsubscribed implements pubsub.Metrics.
+func (m *Metrics) Unsubscribed(path []string) {
+	labels := m.labels(path)
+	m.unsubscribes.WithLabelValues(labels...).Inc()
+	m.subscriptions.WithLabelValues(labels...).Dec()
+}
+
+// Enqueued implements pubsub.QueueMetrics.
+func (m *Metrics) Enqueued(path []string) {
+	m.queueDepth.WithLabelValues(m.labels(path)...).Inc()
+}
+
+// Dequeued implements pubsub.QueueMetrics.
+func (m *Metrics) Dequeued(path []string) {
+	m.queueDepth.WithLabelValues(m.labels(path)...).Dec()
+}
+
+// labels returns the label values for the path.
+func (m *Metrics) labels(path []string) []string {
+	if m.depth <= 0 {
+		return nil
+	}
+
+	if len(path) > m.depth {
+		path = path[:m.depth]
+	}
+
+	var b strings.Builder
+	for _, segment := range path {
+		b.WriteByte('/')
+
+		// Pattern segments (e.g., pubsub.Any) start with a NUL byte.
+		if strings.HasPrefix(segment, "\x00") {
+			b.WriteByte('*')
+			continue
+		}
+		b.WriteString(segment)
+	}
+	prefix := b.String()
+	if prefix == "" {
+		prefix = "/"
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.prefixes[prefix] {
+		if len(m.prefixes) >= m.maxPrefixes {
+			return []string{OtherPrefix}
+		}
+		m.prefixes[prefix] = true
+	}
+	return []string{prefix}
+}
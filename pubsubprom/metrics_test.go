@@ -0,0 +1,158 @@
+package pubsubprom_test
+
+import (
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/apoydence/pubsub/pubsubprom"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.Spec("it counts publishes, deliveries and subscriptions", func(t *testing.T) {
+		reg := prometheus.NewRegistry()
+		m, err := pubsubprom.New(reg)
+		Expect(t, err == nil).To(BeTrue())
+
+		p := pubsub.New(pubsub.WithMetrics(m))
+		unsubscribe := p.Subscribe(pubsub.SubscriptionFunc(func(interface{}) {}))
+		p.Subscribe(pubsub.SubscriptionFunc(func(interface{}) {}))
+		p.Publish("some-data", pubsub.LinearTreeTraverser(nil))
+		unsubscribe()
+
+		Expect(t, gather(t, reg)).To(Equal(map[string]float64{
+			"pubsub_publishes_total":    1,
+			"pubsub_deliveries_total":   2,
+			"pubsub_drops_total":        0,
+			"pubsub_subscribes_total":   2,
+			"pubsub_unsubscribes_total": 1,
+			"pubsub_subscriptions":      1,
+			"pubsub_queue_depth":        0,
+		}))
+	})
+
+	o.Spec("it observes the duration and fan-out of publishes", func(t *testing.T) {
+		reg := prometheus.NewRegistry()
+		m, err := pubsubprom.New(reg)
+		Expect(t, err == nil).To(BeTrue())
+
+		p := pubsub.New(pubsub.WithMetrics(m))
+		p.Subscribe(pubsub.SubscriptionFunc(func(interface{}) {}))
+		p.Subscribe(pubsub.SubscriptionFunc(func(interface{}) {}))
+		p.Publish("some-data", pubsub.LinearTreeTraverser(nil))
+		p.Publish("some-data", pubsub.LinearTreeTraverser(nil))
+
+		h := histograms(t, reg)
+		Expect(t, h["pubsub_publish_duration_seconds"][0]).To(Equal(2.0))
+		Expect(t, h["pubsub_publish_fanout"]).To(Equal([2]float64{2, 4}))
+	})
+
+	o.Spec("it labels the metrics with a limited number of path prefixes", func(t *testing.T) {
+		reg := prometheus.NewRegistry()
+		m, err := pubsubprom.New(reg, pubsubprom.WithPathLabels(1, 2))
+		Expect(t, err == nil).To(BeTrue())
+
+		p := pubsub.New(pubsub.WithMetrics(m))
+		p.Subscribe(pubsub.SubscriptionFunc(func(interface{}) {}), pubsub.WithPath([]string{"a", "b"}))
+		p.Subscribe(pubsub.SubscriptionFunc(func(interface{}) {}), pubsub.WithPath([]string{"a", "c"}))
+		p.Subscribe(pubsub.SubscriptionFunc(func(interface{}) {}), pubsub.WithPath([]string{pubsub.Any}))
+		p.Subscribe(pubsub.SubscriptionFunc(func(interface{}) {}), pubsub.WithPath([]string{"d"}))
+		p.Subscribe(pubsub.SubscriptionFunc(func(interface{}) {}))
+
+		Expect(t, labeled(t, reg, "pubsub_subscriptions")).To(Equal(map[string]float64{
+			"/a":                   2,
+			"/*":                   1,
+			pubsubprom.OtherPrefix: 2,
+		}))
+	})
+
+	o.Spec("it tracks the depth of queues", func(t *testing.T) {
+		reg := prometheus.NewRegistry()
+		m, err := pubsubprom.New(reg, pubsubprom.WithPathLabels(1, 10))
+		Expect(t, err == nil).To(BeTrue())
+
+		p := pubsub.New(pubsub.WithMetrics(m))
+		writing := make(chan struct{}, 5)
+		block := make(chan struct{})
+		defer close(block)
+		p.Subscribe(pubsub.SubscriptionFunc(func(interface{}) {
+			writing <- struct{}{}
+			<-block
+		}),
+			pubsub.WithPath([]string{"a"}),
+			pubsub.WithBufferSize(2),
+			pubsub.WithOverflowStrategy(pubsub.OverflowDrop),
+		)
+
+		// The first publish is dequeued before the queue fills up.
+		p.Publish(0, pubsub.LinearTreeTraverser([]string{"a"}))
+		<-writing
+		for i := 1; i < 5; i++ {
+			p.Publish(i, pubsub.LinearTreeTraverser([]string{"a"}))
+		}
+
+		Expect(t, func() map[string]float64 { return labeled(t, reg, "pubsub_queue_depth") }).To(ViaPolling(Equal(map[string]float64{"/a": 2})))
+	})
+}
+
+// histograms returns the sample count and sum of each histogram.
+func histograms(t *testing.T, reg *prometheus.Registry) map[string][2]float64 {
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := make(map[string][2]float64)
+	for _, mf := range mfs {
+		if h := mf.GetMetric()[0].GetHistogram(); h != nil {
+			values[mf.GetName()] = [2]float64{float64(h.GetSampleCount()), h.GetSampleSum()}
+		}
+	}
+	return values
+}
+
+// labeled returns the value of each path label of the gauge.
+func labeled(t *testing.T, reg *prometheus.Registry, name string) map[string]float64 {
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := make(map[string]float64)
+	for _, mf := range mfs {
+		if mf.GetName() != name {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			values[m.GetLabel()[0].GetValue()] = m.GetGauge().GetValue()
+		}
+	}
+	return values
+}
+
+func gather(t *testing.T, reg *prometheus.Registry) map[string]float64 {
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := make(map[string]float64)
+	for _, mf := range mfs {
+		m := mf.GetMetric()[0]
+		switch {
+		case m.GetCounter() != nil:
+			values[mf.GetName()] = m.GetCounter().GetValue()
+		case m.GetGauge() != nil:
+			values[mf.GetName()] = m.GetGauge().GetValue()
+		}
+	}
+
+	return values
+}
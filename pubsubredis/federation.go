@@ -0,0 +1,172 @@
+package pubsubredis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+
+	"github.com/apoydence/pubsub"
+)
+
+// Option is used to configure a Federation.
+type Option interface {
+	configure(*Federation)
+}
+
+type configFunc func(*Federation)
+
+func (f configFunc) configure(fed *Federation) {
+	f(fed)
+}
+
+// WithChannel configures the Redis channel a Federation uses. Only PubSubs
+// that are federated via the same channel reach each other. It defaults to
+// "pubsub".
+func WithChannel(channel string) Option {
+	return configFunc(func(f *Federation) {
+		f.channel = channel
+	})
+}
+
+// WithErrorFunc configures a Federation to invoke the given function when
+// a message from Redis can not be published, e.g., because the Codec could
+// not decode it. Such messages are dropped.
+func WithErrorFunc(f func(err error)) Option {
+	return configFunc(func(fed *Federation) {
+		fed.errorf = f
+	})
+}
+
+// Federation publishes data to a local PubSub and to the PubSubs of other
+// processes that are federated via the same Redis channel. It should be
+// constructed with NewFederation.
+//
+// Data that is received from Redis is only published to the local PubSub,
+// and a Federation ignores the messages that it sent itself, so data is
+// published exactly once to each PubSub.
+type Federation struct {
+	p       *pubsub.PubSub
+	client  Client
+	codec   Codec
+	channel string
+	errorf  func(err error)
+	origin  string
+
+	unsubscribe func() error
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// envelope is the payload of the Redis messages.
+type envelope struct {
+	Origin string   `json:"origin"`
+	Path   []string `json:"path"`
+	Data   []byte   `json:"data"`
+}
+
+// NewFederation constructs a new Federation and subscribes to the Redis
+// channel. The Codec must match the one the other processes use.
+func NewFederation(p *pubsub.PubSub, client Client, codec Codec, opts ...Option) (*Federation, error) {
+	var id [8]byte
+	rand.Read(id[:])
+
+	f := &Federation{
+		p:       p,
+		client:  client,
+		codec:   codec,
+		channel: "pubsub",
+		errorf:  func(error) {},
+		origin:  hex.EncodeToString(id[:]),
+	}
+
+	for _, o := range opts {
+		o.configure(f)
+	}
+
+	unsubscribe, err := client.Subscribe(context.Background(), f.channel, f.receive)
+	if err != nil {
+		return nil, err
+	}
+	f.unsubscribe = unsubscribe
+
+	return f, nil
+}
+
+// Publish publishes the data to the given path of the local PubSub and
+// sends it to the other processes. It returns the result of the local
+// publish, or pubsub.ErrClosed if the Federation is closed.
+func (f *Federation) Publish(ctx context.Context, data interface{}, path []string) (pubsub.PublishResult, error) {
+	f.mu.RLock()
+	closed := f.closed
+	f.mu.RUnlock()
+	if closed {
+		return pubsub.PublishResult{}, pubsub.ErrClosed
+	}
+
+	result, err := f.p.PublishCtx(ctx, data, pubsub.LinearTreeTraverser(path))
+	if err != nil {
+		return result, err
+	}
+
+	b, err := f.codec.Marshal(data)
+	if err != nil {
+		return result, err
+	}
+
+	payload, err := json.Marshal(envelope{
+		Origin: f.origin,
+		Path:   path,
+		Data:   b,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	return result, f.client.Publish(ctx, f.channel, payload)
+}
+
+func (f *Federation) receive(payload []byte) {
+	f.mu.RLock()
+	closed := f.closed
+	f.mu.RUnlock()
+	if closed {
+		return
+	}
+
+	var e envelope
+	if err := json.Unmarshal(payload, &e); err != nil {
+		f.errorf(err)
+		return
+	}
+
+	if e.Origin == f.origin {
+		return
+	}
+
+	d, err := f.codec.Unmarshal(e.Data)
+	if err != nil {
+		f.errorf(err)
+		return
+	}
+
+	if _, err := f.p.PublishCtx(context.Background(), d, pubsub.LinearTreeTraverser(e.Path)); err != nil {
+		f.errorf(err)
+	}
+}
+
+// Close unsubscribes from the Redis channel. Any Publish afterwards returns
+// pubsub.ErrClosed.
+func (f *Federation) Close() error {
+	f.mu.Lock()
+	if f.closed {
+		f.mu.Unlock()
+		return nil
+	}
+	f.closed = true
+	f.mu.Unlock()
+
+	return f.unsubscribe()
+}
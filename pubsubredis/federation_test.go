@@ -0,0 +1,192 @@
+package pubsubredis_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/apoydence/pubsub/codec"
+	"github.com/apoydence/pubsub/pubsubredis"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+type TR struct {
+	*testing.T
+	broker *fakeBroker
+	p1, p2 *pubsub.PubSub
+	f1, f2 *pubsubredis.Federation
+}
+
+func TestFederation(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) TR {
+		broker := newFakeBroker()
+		p1, p2 := pubsub.New(), pubsub.New()
+
+		return TR{
+			T:      t,
+			broker: broker,
+			p1:     p1,
+			p2:     p2,
+			f1:     newFederation(t, p1, broker),
+			f2:     newFederation(t, p2, broker),
+		}
+	})
+
+	o.Spec("it publishes to the subscriptions of every process once", func(t TR) {
+		sub1, sub2 := newSpySubscription(), newSpySubscription()
+		t.p1.Subscribe(sub1, pubsub.WithPath([]string{"a"}))
+		t.p2.Subscribe(sub2, pubsub.WithPath([]string{"a"}))
+
+		result, err := t.f1.Publish(context.Background(), "x", []string{"a", "b"})
+		Expect(t, err).To(BeNil())
+		Expect(t, result.Delivered).To(Equal(1))
+		_, err = t.f2.Publish(context.Background(), "y", []string{"b"})
+		Expect(t, err).To(BeNil())
+
+		Expect(t, sub1.data).To(Equal([]interface{}{"x"}))
+		Expect(t, sub2.data).To(Equal([]interface{}{"x"}))
+	})
+
+	o.Spec("it only reaches processes on the same channel", func(t TR) {
+		p3 := pubsub.New()
+		newFederation(t, p3, t.broker, pubsubredis.WithChannel("other"))
+
+		sub := newSpySubscription()
+		p3.Subscribe(sub)
+
+		t.f1.Publish(context.Background(), "x", []string{"a"})
+		Expect(t, sub.data).To(HaveLen(0))
+	})
+
+	o.Spec("it reports messages that can not be published", func(t TR) {
+		var errs []error
+		newFederation(t, pubsub.New(), t.broker, pubsubredis.WithErrorFunc(func(err error) {
+			errs = append(errs, err)
+		}))
+
+		t.broker.Publish(context.Background(), "pubsub", []byte("not-json"))
+		Expect(t, errs).To(HaveLen(1))
+	})
+
+	o.Spec("it returns the error of the Codec", func(t TR) {
+		_, err := t.f1.Publish(context.Background(), func() {}, []string{"a"})
+		Expect(t, err).To(Not(BeNil()))
+	})
+
+	o.Spec("it stops federating when closed", func(t TR) {
+		sub := newSpySubscription()
+		t.p2.Subscribe(sub)
+
+		Expect(t, t.f2.Close()).To(BeNil())
+		Expect(t, t.broker.subscriptions("pubsub")).To(Equal(1))
+
+		t.f1.Publish(context.Background(), "x", []string{"a"})
+		Expect(t, sub.data).To(HaveLen(0))
+
+		_, err := t.f2.Publish(context.Background(), "x", []string{"a"})
+		Expect(t, err).To(Equal(pubsub.ErrClosed))
+	})
+
+	o.Spec("it returns the error of the Client", func(t TR) {
+		t.broker.err = errors.New("some-error")
+		_, err := pubsubredis.NewFederation(t.p1, t.broker, codec.JSON[string]())
+		Expect(t, err).To(Equal(errors.New("some-error")))
+	})
+}
+
+func newFederation(t testing.TB, p *pubsub.PubSub, c pubsubredis.Client, opts ...pubsubredis.Option) *pubsubredis.Federation {
+	f, err := pubsubredis.NewFederation(p, c, codec.JSON[string](), opts...)
+	Expect(t, err).To(BeNil())
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+type spySubscription struct {
+	data []interface{}
+}
+
+func newSpySubscription() *spySubscription {
+	return &spySubscription{}
+}
+
+func (s *spySubscription) Write(data interface{}) {
+	s.data = append(s.data, data)
+}
+
+// fakeBroker delivers the messages that are published to it to the
+// subscriptions of the channel synchronously, including the publisher's.
+type fakeBroker struct {
+	err error
+
+	mu     sync.Mutex
+	subs   map[int]fakeSub
+	nextID int
+}
+
+type fakeSub struct {
+	channel string
+	handler func(payload []byte)
+}
+
+func newFakeBroker() *fakeBroker {
+	return &fakeBroker{
+		subs: make(map[int]fakeSub),
+	}
+}
+
+func (b *fakeBroker) Publish(ctx context.Context, channel string, payload []byte) error {
+	b.mu.Lock()
+	var handlers []func([]byte)
+	for _, s := range b.subs {
+		if s.channel == channel {
+			handlers = append(handlers, s.handler)
+		}
+	}
+	b.mu.Unlock()
+
+	for _, h := range handlers {
+		h(payload)
+	}
+	return nil
+}
+
+func (b *fakeBroker) Subscribe(ctx context.Context, channel string, handler func(payload []byte)) (func() error, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.nextID
+	b.nextID++
+	b.subs[id] = fakeSub{channel: channel, handler: handler}
+
+	return func() error {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subs, id)
+		return nil
+	}, nil
+}
+
+func (b *fakeBroker) subscriptions(channel string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var n int
+	for _, s := range b.subs {
+		if s.channel == channel {
+			n++
+		}
+	}
+	return n
+}
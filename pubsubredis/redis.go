@@ -0,0 +1,59 @@
+// Package pubsubredis federates PubSubs of different processes via a Redis
+// channel. Data that is published through a Federation reaches the
+// matching subscriptions of every PubSub that is federated via the same
+// channel.
+package pubsubredis
+
+import (
+	"context"
+
+	"github.com/apoydence/pubsub/codec"
+	"github.com/redis/go-redis/v9"
+)
+
+// Client is the part of a Redis client that a Federation uses. NewClient
+// adapts a *redis.Client.
+type Client interface {
+	Publish(ctx context.Context, channel string, payload []byte) error
+
+	// Subscribe invokes the handler with the payload of each message that
+	// is published to the channel until the returned function is invoked.
+	Subscribe(ctx context.Context, channel string, handler func(payload []byte)) (unsubscribe func() error, err error)
+}
+
+// NewClient returns a Client that uses the given Redis client.
+func NewClient(rdb *redis.Client) Client {
+	return redisClient{rdb: rdb}
+}
+
+type redisClient struct {
+	rdb *redis.Client
+}
+
+func (c redisClient) Publish(ctx context.Context, channel string, payload []byte) error {
+	return c.rdb.Publish(ctx, channel, payload).Err()
+}
+
+func (c redisClient) Subscribe(ctx context.Context, channel string, handler func(payload []byte)) (func() error, error) {
+	ps := c.rdb.Subscribe(ctx, channel)
+
+	// Wait for the subscription to be confirmed so that no messages that
+	// are published afterwards are missed.
+	if _, err := ps.Receive(ctx); err != nil {
+		ps.Close()
+		return nil, err
+	}
+
+	ch := ps.Channel()
+	go func() {
+		for m := range ch {
+			handler([]byte(m.Payload))
+		}
+	}()
+
+	return ps.Close, nil
+}
+
+// Codec converts published data to and from the bytes that are sent via
+// Redis. See the codec package for implementations.
+type Codec = codec.Codec
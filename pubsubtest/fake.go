@@ -0,0 +1,204 @@
+// Package pubsubtest provides helpers for testing code that uses a PubSub.
+// A FakePubSub is a real PubSub that also records what is published,
+// subscribed and delivered so that tests can assert on it. A
+// SpySubscription records what is written to it and can be waited on.
+package pubsubtest
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/apoydence/pubsub"
+)
+
+// Publish is a recorded publish.
+type Publish struct {
+	// Data is the data that was published.
+	Data interface{}
+
+	// Path is the path that was published to. It is only known for a
+	// pubsub.LinearTreeTraverser and is nil otherwise.
+	Path []string
+
+	// Result and Err are what the publish returned.
+	Result pubsub.PublishResult
+	Err    error
+}
+
+// Delivery is recorded each time data is written to a subscription.
+type Delivery struct {
+	// Path is the path the subscription subscribed with.
+	Path []string
+
+	// Data is the data that was written.
+	Data interface{}
+}
+
+// FakePubSub is a pubsub.PubSub that records each publish, subscription
+// and delivery. It should be constructed with New().
+//
+// Publishes and subscriptions are recorded with a pubsub.PublishInterceptor
+// and a pubsub.SubscribeInterceptor. Like with any SubscribeInterceptor,
+// the optional interfaces of a Subscription (e.g.,
+// pubsub.PathAwareSubscription) are therefore not used.
+type FakePubSub struct {
+	*pubsub.PubSub
+
+	mu         sync.Mutex
+	publishes  []Publish
+	subscribed [][]string
+	deliveries []Delivery
+}
+
+// New constructs a new FakePubSub. The options are given to the underlying
+// PubSub after those that record.
+func New(opts ...pubsub.PubSubOption) *FakePubSub {
+	f := &FakePubSub{}
+	f.PubSub = pubsub.New(append([]pubsub.PubSubOption{
+		pubsub.WithPublishInterceptor(f.interceptPublish),
+		pubsub.WithSubscribeInterceptor(f.interceptSubscribe),
+	}, opts...)...)
+	return f
+}
+
+// Published returns the recorded publishes in the order they were made.
+func (f *FakePubSub) Published() []Publish {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Publish(nil), f.publishes...)
+}
+
+// Subscribed returns the paths of the recorded subscriptions in the order
+// they were added. Subscriptions that were since removed are included.
+func (f *FakePubSub) Subscribed() [][]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([][]string(nil), f.subscribed...)
+}
+
+// Delivered returns the recorded deliveries in the order they were made.
+func (f *FakePubSub) Delivered() []Delivery {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Delivery(nil), f.deliveries...)
+}
+
+// Reset forgets what was recorded. The PubSub's subscriptions are not
+// removed.
+func (f *FakePubSub) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.publishes = nil
+	f.subscribed = nil
+	f.deliveries = nil
+}
+
+// ExpectPublished fails the test if the data was not published to the
+// path. Data is compared with reflect.DeepEqual.
+func (f *FakePubSub) ExpectPublished(t testing.TB, path []string, data interface{}) {
+	t.Helper()
+	for _, p := range f.Published() {
+		if slices.Equal(p.Path, path) && reflect.DeepEqual(p.Data, data) {
+			return
+		}
+	}
+	t.Errorf("expected %#v to be published to %q, got publishes: %s", data, path, formatPublishes(f.Published()))
+}
+
+// ExpectSubscribed fails the test if there was no subscription to the
+// path.
+func (f *FakePubSub) ExpectSubscribed(t testing.TB, path []string) {
+	t.Helper()
+	for _, p := range f.Subscribed() {
+		if slices.Equal(p, path) {
+			return
+		}
+	}
+	t.Errorf("expected a subscription to %q, got subscriptions: %q", path, f.Subscribed())
+}
+
+// ExpectDelivered fails the test if the data was not written to a
+// subscription that subscribed with the path. Data is compared with
+// reflect.DeepEqual. Data that is delivered asynchronously (e.g., with
+// pubsub.WithAsyncDelivery) might not have been written yet.
+func (f *FakePubSub) ExpectDelivered(t testing.TB, path []string, data interface{}) {
+	t.Helper()
+	if !f.delivered(path, data) {
+		t.Errorf("expected %#v to be delivered to %q, got deliveries: %s", data, path, formatDeliveries(f.Delivered()))
+	}
+}
+
+// ExpectNotDelivered fails the test if the data was written to a
+// subscription that subscribed with the path.
+func (f *FakePubSub) ExpectNotDelivered(t testing.TB, path []string, data interface{}) {
+	t.Helper()
+	if f.delivered(path, data) {
+		t.Errorf("expected %#v not to be delivered to %q", data, path)
+	}
+}
+
+func (f *FakePubSub) delivered(path []string, data interface{}) bool {
+	for _, d := range f.Delivered() {
+		if slices.Equal(d.Path, path) && reflect.DeepEqual(d.Data, data) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *FakePubSub) interceptPublish(next pubsub.PublishFunc) pubsub.PublishFunc {
+	return func(ctx context.Context, d interface{}, a pubsub.TreeTraverser) (pubsub.PublishResult, error) {
+		var path []string
+		if l, ok := a.(pubsub.LinearTreeTraverser); ok {
+			path = append([]string{}, l...)
+		}
+
+		r, err := next(ctx, d, a)
+
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.publishes = append(f.publishes, Publish{
+			Data:   d,
+			Path:   path,
+			Result: r,
+			Err:    err,
+		})
+		return r, err
+	}
+}
+
+func (f *FakePubSub) interceptSubscribe(path []string, next pubsub.Subscription) pubsub.Subscription {
+	path = append([]string{}, path...)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.subscribed = append(f.subscribed, path)
+
+	return pubsub.SubscriptionFunc(func(data interface{}) {
+		f.mu.Lock()
+		f.deliveries = append(f.deliveries, Delivery{Path: path, Data: data})
+		f.mu.Unlock()
+
+		next.Write(data)
+	})
+}
+
+func formatPublishes(ps []Publish) string {
+	s := make([]string, 0, len(ps))
+	for _, p := range ps {
+		s = append(s, fmt.Sprintf("%#v to %q", p.Data, p.Path))
+	}
+	return fmt.Sprint(s)
+}
+
+func formatDeliveries(ds []Delivery) string {
+	s := make([]string, 0, len(ds))
+	for _, d := range ds {
+		s = append(s, fmt.Sprintf("%#v to %q", d.Data, d.Path))
+	}
+	return fmt.Sprint(s)
+}
@@ -0,0 +1,111 @@
+package pubsubtest_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/apoydence/pubsub/pubsubtest"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+type TF struct {
+	*testing.T
+	f  *pubsubtest.FakePubSub
+	tb *spyTB
+}
+
+func TestFakePubSub(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) TF {
+		return TF{
+			T:  t,
+			f:  pubsubtest.New(),
+			tb: &spyTB{TB: t},
+		}
+	})
+
+	o.Spec("it records publishes", func(t TF) {
+		t.f.Subscribe(pubsub.SubscriptionFunc(func(interface{}) {}), pubsub.WithPath([]string{"a"}))
+		t.f.Publish("x", pubsub.LinearTreeTraverser([]string{"a", "b"}))
+		t.f.PublishCtx(context.Background(), "y", pubsub.TreeTraverserFunc(func(interface{}, []string) pubsub.Paths {
+			return pubsub.EmptyPaths
+		}))
+
+		Expect(t, t.f.Published()).To(Equal([]pubsubtest.Publish{
+			{Data: "x", Path: []string{"a", "b"}, Result: pubsub.PublishResult{Matched: 1, Delivered: 1}},
+			{Data: "y"},
+		}))
+
+		t.f.ExpectPublished(t.tb, []string{"a", "b"}, "x")
+		Expect(t, t.tb.errors).To(HaveLen(0))
+
+		t.f.ExpectPublished(t.tb, []string{"a"}, "x")
+		Expect(t, t.tb.errors).To(HaveLen(1))
+	})
+
+	o.Spec("it records subscriptions", func(t TF) {
+		unsubscribe := t.f.Subscribe(pubsub.SubscriptionFunc(func(interface{}) {}), pubsub.WithPath([]string{"a", pubsub.Any}))
+		unsubscribe()
+
+		Expect(t, t.f.Subscribed()).To(Equal([][]string{{"a", pubsub.Any}}))
+
+		t.f.ExpectSubscribed(t.tb, []string{"a", pubsub.Any})
+		Expect(t, t.tb.errors).To(HaveLen(0))
+
+		t.f.ExpectSubscribed(t.tb, []string{"a"})
+		Expect(t, t.tb.errors).To(HaveLen(1))
+	})
+
+	o.Spec("it records deliveries and still writes to the subscriptions", func(t TF) {
+		var written []interface{}
+		t.f.Subscribe(pubsub.SubscriptionFunc(func(data interface{}) {
+			written = append(written, data)
+		}), pubsub.WithPath([]string{"a"}))
+		t.f.Subscribe(pubsub.SubscriptionFunc(func(interface{}) {}), pubsub.WithPath([]string{"b"}))
+
+		t.f.Publish("x", pubsub.LinearTreeTraverser([]string{"a", "c"}))
+
+		Expect(t, written).To(Equal([]interface{}{"x"}))
+		Expect(t, t.f.Delivered()).To(Equal([]pubsubtest.Delivery{
+			{Path: []string{"a"}, Data: "x"},
+		}))
+
+		t.f.ExpectDelivered(t.tb, []string{"a"}, "x")
+		t.f.ExpectNotDelivered(t.tb, []string{"b"}, "x")
+		Expect(t, t.tb.errors).To(HaveLen(0))
+
+		t.f.ExpectDelivered(t.tb, []string{"b"}, "x")
+		t.f.ExpectNotDelivered(t.tb, []string{"a"}, "x")
+		Expect(t, t.tb.errors).To(HaveLen(2))
+	})
+
+	o.Spec("it forgets what was recorded", func(t TF) {
+		t.f.Subscribe(pubsub.SubscriptionFunc(func(interface{}) {}))
+		t.f.Publish("x", pubsub.LinearTreeTraverser(nil))
+		t.f.Reset()
+
+		Expect(t, t.f.Published()).To(HaveLen(0))
+		Expect(t, t.f.Subscribed()).To(HaveLen(0))
+		Expect(t, t.f.Delivered()).To(HaveLen(0))
+		Expect(t, t.f.Subscriptions()).To(Equal(1))
+	})
+}
+
+// spyTB records the errors instead of failing the test.
+type spyTB struct {
+	testing.TB
+	errors []string
+}
+
+func (t *spyTB) Helper() {}
+
+func (t *spyTB) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
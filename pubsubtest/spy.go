@@ -0,0 +1,91 @@
+package pubsubtest
+
+import (
+	"sync"
+	"time"
+)
+
+// SpySubscription is a pubsub.Subscription (and a
+// pubsub.PathAwareSubscription) that records what is written to it. It is
+// safe to access concurrently, so it can be used with asynchronous delivery
+// (e.g., pubsub.WithAsyncDelivery). It should be constructed with
+// NewSpySubscription().
+type SpySubscription struct {
+	mu    sync.Mutex
+	data  []interface{}
+	paths [][]string
+
+	// written is closed and replaced each time data is written.
+	written chan struct{}
+}
+
+// NewSpySubscription constructs a new SpySubscription.
+func NewSpySubscription() *SpySubscription {
+	return &SpySubscription{
+		written: make(chan struct{}),
+	}
+}
+
+// Write implements pubsub.Subscription.
+func (s *SpySubscription) Write(data interface{}) {
+	s.WritePath(data, nil)
+}
+
+// WritePath implements pubsub.PathAwareSubscription.
+func (s *SpySubscription) WritePath(data interface{}, path []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data = append(s.data, data)
+	s.paths = append(s.paths, path)
+
+	close(s.written)
+	s.written = make(chan struct{})
+}
+
+// Data returns the data that was written in the order it was written.
+func (s *SpySubscription) Data() []interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]interface{}(nil), s.data...)
+}
+
+// Paths returns the path each entry of Data was published to, as given to
+// WritePath. It is nil for entries whose path is not known (see
+// pubsub.PathAwareSubscription).
+func (s *SpySubscription) Paths() [][]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([][]string(nil), s.paths...)
+}
+
+// Len returns how many times data was written.
+func (s *SpySubscription) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.data)
+}
+
+// Wait waits until data was written at least n times. It returns false if
+// that did not happen within the timeout.
+func (s *SpySubscription) Wait(n int, timeout time.Duration) bool {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		s.mu.Lock()
+		written := s.written
+		l := len(s.data)
+		s.mu.Unlock()
+
+		if l >= n {
+			return true
+		}
+
+		select {
+		case <-written:
+		case <-timer.C:
+			return false
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package pubsubtest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apoydence/pubsub"
+	"github.com/apoydence/pubsub/pubsubtest"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+type TS struct {
+	*testing.T
+	spy *pubsubtest.SpySubscription
+}
+
+func TestSpySubscription(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) TS {
+		return TS{
+			T:   t,
+			spy: pubsubtest.NewSpySubscription(),
+		}
+	})
+
+	o.Spec("it records the data and paths", func(t TS) {
+		p := pubsub.New()
+		p.Subscribe(t.spy, pubsub.WithPath([]string{"a", pubsub.Any}))
+		p.Publish("x", pubsub.LinearTreeTraverser([]string{"a", "b"}))
+		t.spy.Write("y")
+
+		Expect(t, t.spy.Len()).To(Equal(2))
+		Expect(t, t.spy.Data()).To(Equal([]interface{}{"x", "y"}))
+		Expect(t, t.spy.Paths()).To(Equal([][]string{{"a", "b"}, nil}))
+	})
+
+	o.Spec("it waits for asynchronous writes", func(t TS) {
+		p := pubsub.New(pubsub.WithAsyncDelivery(10, pubsub.OverflowBlock))
+		p.Subscribe(t.spy)
+
+		for i := 0; i < 3; i++ {
+			p.Publish(i, pubsub.LinearTreeTraverser(nil))
+		}
+
+		Expect(t, t.spy.Wait(3, 5*time.Second)).To(BeTrue())
+		Expect(t, t.spy.Data()).To(Equal([]interface{}{0, 1, 2}))
+	})
+
+	o.Spec("it gives up waiting after the timeout", func(t TS) {
+		t.spy.Write("x")
+		Expect(t, t.spy.Wait(1, time.Millisecond)).To(BeTrue())
+		Expect(t, t.spy.Wait(2, time.Millisecond)).To(BeFalse())
+	})
+}
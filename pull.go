@@ -0,0 +1,179 @@
+package pubsub
+
+import (
+	"context"
+	"math"
+	"sync"
+)
+
+// PullSubscription is a subscription that is only written the data it has
+// requested (see Request). It is returned by SubscribePull. All of its
+// methods are safe to access concurrently.
+type PullSubscription struct {
+	sr *subscriber
+}
+
+// SubscribePull adds a subscription to the PubSub (see Subscribe) that is
+// pulled rather than pushed to: data is only written to it once it has been
+// requested with Request. Until then, the data is buffered (see
+// WithBufferSize). Once the buffer is full, the oldest data is dropped.
+//
+// This lets a consumer control the flow, much like the demand of reactive
+// streams. The Subscription may invoke Request from its Write.
+func (s *PubSub) SubscribePull(sub Subscription, opts ...SubscribeOption) *PullSubscription {
+	c := newSubscribeConfig(opts)
+	c.pull = true
+
+	sr, _, _ := s.subscribe(sub, c)
+	return &PullSubscription{
+		sr: sr,
+	}
+}
+
+// Request adds n to the amount of data that may be written to the
+// subscription. Buffered data is written right away (from the calling
+// goroutine) and the rest of the demand is used up by the data that is
+// published next. A demand of math.MaxInt64 is unbounded. Request does
+// nothing if n is not positive.
+func (h *PullSubscription) Request(n int64) {
+	if h.sr == nil || n <= 0 {
+		return
+	}
+	h.sr.demand.request(n)
+}
+
+// Demand returns how much data may still be written to the subscription.
+func (h *PullSubscription) Demand() int64 {
+	if h.sr == nil {
+		return 0
+	}
+	return h.sr.demand.outstanding()
+}
+
+// Buffered returns how much data is waiting to be requested.
+func (h *PullSubscription) Buffered() int {
+	if h.sr == nil {
+		return 0
+	}
+	return h.sr.demand.buffered()
+}
+
+// Unsubscribe removes the subscription from the PubSub. Any buffered data is
+// discarded.
+func (h *PullSubscription) Unsubscribe() {
+	if h.sr == nil {
+		return
+	}
+	h.sr.p.unsubscribe(h.sr)
+}
+
+// demander holds back data until the subscription requests it.
+type demander struct {
+	size int
+	next func(ctx context.Context, data interface{}, path []string) (bool, int, error)
+
+	mu      sync.Mutex
+	demand  int64
+	buf     []pausedData
+	stopped bool
+
+	// draining is set while a goroutine writes the buffered data. Only one
+	// does at a time so that the data is written in order and so that the
+	// Subscription can invoke Request from its Write.
+	draining bool
+}
+
+func newDemander(size int, next func(ctx context.Context, data interface{}, path []string) (bool, int, error)) *demander {
+	if size <= 0 {
+		size = defaultBufferSize
+	}
+
+	return &demander{
+		size: size,
+		next: next,
+	}
+}
+
+// write buffers the data and, if it has been requested, writes it.
+func (d *demander) write(ctx context.Context, data interface{}, path []string) (bool, int, error) {
+	d.mu.Lock()
+	if d.stopped {
+		d.mu.Unlock()
+		return false, 0, nil
+	}
+
+	var dropped int
+	if len(d.buf) >= d.size {
+		d.buf[0] = pausedData{}
+		d.buf = d.buf[1:]
+		dropped++
+	}
+	// The path is reused by the publish.
+	if path != nil {
+		path = append([]string{}, path...)
+	}
+	d.buf = append(d.buf, pausedData{data: data, path: path})
+	d.mu.Unlock()
+
+	d.drain()
+	return true, dropped, nil
+}
+
+func (d *demander) request(n int64) {
+	d.mu.Lock()
+	if n > math.MaxInt64-d.demand {
+		d.demand = math.MaxInt64
+	} else {
+		d.demand += n
+	}
+	d.mu.Unlock()
+
+	d.drain()
+}
+
+// drain writes buffered data while there is demand for it, unless another
+// goroutine already is.
+func (d *demander) drain() {
+	d.mu.Lock()
+	if d.draining {
+		d.mu.Unlock()
+		return
+	}
+	d.draining = true
+
+	for !d.stopped && d.demand > 0 && len(d.buf) > 0 {
+		x := d.buf[0]
+		d.buf[0] = pausedData{}
+		d.buf = d.buf[1:]
+		if d.demand != math.MaxInt64 {
+			d.demand--
+		}
+		d.mu.Unlock()
+
+		d.next(context.Background(), x.data, x.path)
+
+		d.mu.Lock()
+	}
+
+	d.draining = false
+	d.mu.Unlock()
+}
+
+func (d *demander) outstanding() int64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.demand
+}
+
+func (d *demander) buffered() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.buf)
+}
+
+func (d *demander) stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.stopped = true
+	d.buf = nil
+}
@@ -0,0 +1,101 @@
+package pubsub_test
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+func TestPubSubPull(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	publish := func(p *pubsub.PubSub, data ...interface{}) {
+		for _, d := range data {
+			p.Publish(d, pubsub.LinearTreeTraverser([]string{"a"}))
+		}
+	}
+
+	o.Spec("it only writes requested data", func(t *testing.T) {
+		p := pubsub.New()
+		sub := newSpySubscrption()
+		h := p.SubscribePull(sub, pubsub.WithPath([]string{"a"}))
+
+		publish(p, 1, 2, 3)
+		Expect(t, sub.Len()).To(Equal(0))
+		Expect(t, h.Buffered()).To(Equal(3))
+
+		h.Request(2)
+		Expect(t, sub.Data()).To(Equal([]interface{}{1, 2}))
+		Expect(t, h.Buffered()).To(Equal(1))
+		Expect(t, h.Demand()).To(Equal(int64(0)))
+
+		h.Request(2)
+		Expect(t, sub.Data()).To(Equal([]interface{}{1, 2, 3}))
+		Expect(t, h.Demand()).To(Equal(int64(1)))
+
+		publish(p, 4, 5)
+		Expect(t, sub.Data()).To(Equal([]interface{}{1, 2, 3, 4}))
+		Expect(t, h.Buffered()).To(Equal(1))
+	})
+
+	o.Spec("it drops the oldest data once the buffer is full", func(t *testing.T) {
+		p := pubsub.New()
+		sub := newSpySubscrption()
+		h := p.SubscribePull(sub, pubsub.WithPath([]string{"a"}), pubsub.WithBufferSize(2))
+
+		publish(p, 1, 2)
+		r, _ := p.PublishCtx(context.Background(), 3, pubsub.LinearTreeTraverser([]string{"a"}))
+		Expect(t, r.Dropped).To(Equal(1))
+
+		h.Request(10)
+		Expect(t, sub.Data()).To(Equal([]interface{}{2, 3}))
+	})
+
+	o.Spec("it allows the subscription to request from its Write", func(t *testing.T) {
+		p := pubsub.New()
+		sub := newSpySubscrption()
+		var h *pubsub.PullSubscription
+		h = p.SubscribePull(pubsub.SubscriptionFunc(func(data interface{}) {
+			sub.Write(data)
+			h.Request(1)
+		}), pubsub.WithPath([]string{"a"}))
+
+		publish(p, 1, 2, 3)
+		h.Request(1)
+		Expect(t, sub.Data()).To(Equal([]interface{}{1, 2, 3}))
+
+		publish(p, 4)
+		Expect(t, sub.Data()).To(Equal([]interface{}{1, 2, 3, 4}))
+	})
+
+	o.Spec("it treats math.MaxInt64 as unbounded", func(t *testing.T) {
+		p := pubsub.New()
+		sub := newSpySubscrption()
+		h := p.SubscribePull(sub, pubsub.WithPath([]string{"a"}))
+
+		h.Request(math.MaxInt64)
+		h.Request(1)
+		publish(p, 1, 2)
+		Expect(t, sub.Len()).To(Equal(2))
+		Expect(t, h.Demand()).To(Equal(int64(math.MaxInt64)))
+	})
+
+	o.Spec("it discards the buffered data when unsubscribed", func(t *testing.T) {
+		p := pubsub.New()
+		sub := newSpySubscrption()
+		h := p.SubscribePull(sub, pubsub.WithPath([]string{"a"}))
+
+		publish(p, 1)
+		h.Unsubscribe()
+		h.Request(1)
+		Expect(t, sub.Len()).To(Equal(0))
+		Expect(t, h.Buffered()).To(Equal(0))
+	})
+}
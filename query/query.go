@@ -0,0 +1,310 @@
+// Package query compiles SQL-like WHERE clauses into subscriptions for the
+// TreeTraversers that pubsub-gen generates. This allows the subscriptions
+// to be given as text (e.g., by an operator) instead of by constructing the
+// generated filter structs:
+//
+//	q, err := query.Compile("I = 1 AND Y1.J = 'x' AND Y1.I > 3", NewStructTraverser())
+//	if err != nil {
+//		return err
+//	}
+//	ps.Subscribe(sub, q.SubscribeOptions()...)
+//
+// Fields are named as they are in the generated filter structs, with the
+// fields of nested filters joined by a '.' (e.g., "Y1.J" or "M_M1.A" for the
+// M1 implementation of the interface field M). They are compared to
+// literals with =, != (or <>), <, <=, > and >=, and comparisons are
+// combined with AND, OR and NOT (and parentheses). Strings are quoted with
+// single quotes (which are escaped by doubling them) and booleans are TRUE
+// or FALSE.
+package query
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/apoydence/pubsub"
+	"github.com/apoydence/pubsub/internal/expr"
+	"github.com/apoydence/pubsub/internal/field"
+)
+
+// ErrInvalidQuery is returned when a query can not be compiled.
+var ErrInvalidQuery = errors.New("invalid query")
+
+// Query is a compiled query.
+type Query struct {
+	// Path is the path that the generated CreatePath method created for
+	// the equalities of the query.
+	Path []string
+
+	// Filter reports whether the data satisfies the rest of the query. It
+	// is nil if the Path expresses all of it.
+	Filter func(data interface{}) bool
+}
+
+// SubscribeOptions returns the options to subscribe with (see
+// pubsub.WithPath and pubsub.WithFilter).
+func (q Query) SubscribeOptions() []pubsub.SubscribeOption {
+	opts := []pubsub.SubscribeOption{pubsub.WithPath(q.Path)}
+	if q.Filter != nil {
+		opts = append(opts, pubsub.WithFilter(q.Filter))
+	}
+	return opts
+}
+
+// Compile compiles the WHERE clause for the given pubsub-gen generated
+// traverser. The fields and literals are checked against the traverser's
+// filter struct (the argument of its CreatePath method). The equalities of
+// the top level conjunction are set in a filter to create the Path, while
+// the rest of the query is left to the Filter. As a generated path can only
+// take one branch (e.g., Y1 or Y2), equalities for other branches are also
+// left to the Filter.
+func Compile(where string, traverser interface{}) (Query, error) {
+	createPath := reflect.ValueOf(traverser).MethodByName("CreatePath")
+	if !createPath.IsValid() {
+		return Query{}, fmt.Errorf("%w: %T does not have a CreatePath method", ErrInvalidQuery, traverser)
+	}
+
+	t := createPath.Type()
+	if t.NumIn() != 1 || t.In(0).Kind() != reflect.Pointer || t.In(0).Elem().Kind() != reflect.Struct ||
+		t.NumOut() != 1 || t.Out(0) != reflect.TypeOf([]string(nil)) {
+		return Query{}, fmt.Errorf("%w: %T does not have a generated CreatePath method", ErrInvalidQuery, traverser)
+	}
+
+	n, err := expr.Parse(where, expr.SQL)
+	if err == nil {
+		n, err = expr.Map(n, func(c expr.Cmp) (expr.Node, error) {
+			return check(c, t.In(0).Elem())
+		})
+	}
+	if err != nil {
+		return Query{}, fmt.Errorf("%w %q: %s", ErrInvalidQuery, where, err)
+	}
+
+	conjuncts := []expr.Node{n}
+	if a, ok := n.(expr.And); ok {
+		conjuncts = a
+	}
+
+	filter := reflect.New(t.In(0).Elem())
+	var residual expr.And
+	for _, n := range conjuncts {
+		if c, ok := n.(cmp); !ok || !setFilter(filter, c) {
+			residual = append(residual, n)
+		}
+	}
+
+	q := Query{
+		Path: createPath.Call([]reflect.Value{filter})[0].Interface().([]string),
+	}
+
+	switch len(residual) {
+	case 0:
+	case 1:
+		r := residual[0]
+		q.Filter = func(data interface{}) bool { return expr.Eval(r, data) }
+	default:
+		q.Filter = func(data interface{}) bool { return expr.Eval(residual, data) }
+	}
+
+	return q, nil
+}
+
+// cmp compares the field with the value of a literal.
+type cmp struct {
+	field []string
+	op    string
+
+	// value is the literal as the type of the field.
+	value reflect.Value
+}
+
+// check resolves the field of the comparison in the filter struct type and
+// parses its literal as the field's type.
+func check(x expr.Cmp, filterType reflect.Type) (expr.Node, error) {
+	c := cmp{
+		field: strings.Split(x.Field, "."),
+		op:    x.Op,
+	}
+	errorf := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%s: %s", x.Field, fmt.Sprintf(format, args...))
+	}
+
+	t := filterType
+	for i, name := range c.field {
+		f, ok := t.FieldByName(name)
+		if !ok || !f.IsExported() || f.Type.Kind() != reflect.Pointer {
+			return nil, errorf("unknown field")
+		}
+
+		t = f.Type.Elem()
+		last := i == len(c.field)-1
+		if (t.Kind() == reflect.Struct) == last {
+			return nil, errorf("not a field that can be compared")
+		}
+	}
+
+	c.value = reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.String:
+		if x.Lit.Kind != expr.String {
+			return nil, errorf("%s can not be compared with a string", x.Lit.Kind)
+		}
+		c.value.SetString(x.Lit.Text)
+	case reflect.Bool:
+		if x.Lit.Kind != expr.Bool {
+			return nil, errorf("%s can not be compared with a boolean", x.Lit.Kind)
+		}
+		c.value.SetBool(x.Lit.Text == "true")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(x.Lit.Text, 10, t.Bits())
+		if x.Lit.Kind != expr.Number || err != nil {
+			return nil, errorf("%s is not a valid %s", x.Lit.Text, t)
+		}
+		c.value.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(x.Lit.Text, 10, t.Bits())
+		if x.Lit.Kind != expr.Number || err != nil {
+			return nil, errorf("%s is not a valid %s", x.Lit.Text, t)
+		}
+		c.value.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(x.Lit.Text, t.Bits())
+		if x.Lit.Kind != expr.Number || err != nil {
+			return nil, errorf("%s is not a valid %s", x.Lit.Text, t)
+		}
+		c.value.SetFloat(n)
+	default:
+		return nil, errorf("%s can not be compared", t)
+	}
+
+	return c, nil
+}
+
+// setFilter sets the field of the filter for an equality. It returns false
+// if the comparison is not an equality, the field is already set to
+// something else or the field is in a different branch than one that is
+// already set.
+func setFilter(filter reflect.Value, c cmp) bool {
+	if c.op != "==" {
+		return false
+	}
+
+	v := filter
+	for _, name := range c.field[:len(c.field)-1] {
+		f := v.Elem().FieldByName(name)
+		if f.IsNil() {
+			if branched(v.Elem()) {
+				return false
+			}
+			f.Set(reflect.New(f.Type().Elem()))
+		}
+		v = f
+	}
+
+	f := v.Elem().FieldByName(c.field[len(c.field)-1])
+	if !f.IsNil() {
+		return f.Elem().Equal(c.value)
+	}
+
+	f.Set(reflect.New(c.value.Type()))
+	f.Elem().Set(c.value)
+	return true
+}
+
+// branched reports whether any nested filter of the filter is set.
+func branched(filter reflect.Value) bool {
+	for i := 0; i < filter.NumField(); i++ {
+		f := filter.Field(i)
+		if f.Kind() == reflect.Pointer && f.Type().Elem().Kind() == reflect.Struct && !f.IsNil() {
+			return true
+		}
+	}
+	return false
+}
+
+// Eval implements expr.Predicate.
+func (c cmp) Eval(data interface{}) bool {
+	v := reflect.ValueOf(data)
+	for _, name := range c.field {
+		var ok bool
+		if v, ok = lookup(v, name); !ok {
+			return false
+		}
+	}
+
+	v, ok := field.Indirect(v)
+	if !ok || v.Kind() != c.value.Kind() {
+		return false
+	}
+
+	var r int
+	switch v.Kind() {
+	case reflect.String:
+		r = strings.Compare(v.String(), c.value.String())
+	case reflect.Bool:
+		if v.Bool() != c.value.Bool() {
+			r = 1
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		r = compare(v.Int(), c.value.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		r = compare(v.Uint(), c.value.Uint())
+	default:
+		r = compare(v.Float(), c.value.Float())
+	}
+
+	switch c.op {
+	case "==":
+		return r == 0
+	case "!=":
+		return r != 0
+	case "<":
+		return r < 0
+	case "<=":
+		return r <= 0
+	case ">":
+		return r > 0
+	default:
+		return r >= 0
+	}
+}
+
+func compare[T int64 | uint64 | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// lookup returns the named field of the struct that v holds (or points
+// to). As with the generated filters, a name of "M_M1" is the field M if it
+// holds an M1.
+func lookup(v reflect.Value, name string) (reflect.Value, bool) {
+	if f, ok := field.Lookup(v, name); ok {
+		return f, true
+	}
+
+	for i := 0; i < len(name); i++ {
+		if name[i] != '_' {
+			continue
+		}
+
+		f, ok := field.Lookup(v, name[:i])
+		if !ok {
+			continue
+		}
+
+		if f, ok := field.Indirect(f); ok && f.Type().Name() == name[i+1:] {
+			return f, true
+		}
+	}
+
+	return reflect.Value{}, false
+}
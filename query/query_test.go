@@ -0,0 +1,127 @@
+package query_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/apoydence/pubsub/query"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+type TQ struct {
+	*testing.T
+	p *pubsub.PubSub
+}
+
+func TestCompile(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) TQ {
+		return TQ{
+			T: t,
+			p: pubsub.New(),
+		}
+	})
+
+	compile := func(t *testing.T, where string) query.Query {
+		q, err := query.Compile(where, NewStructTraverser())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return q
+	}
+
+	o.Spec("it creates the path from the equalities", func(t TQ) {
+		q := compile(t.T, "I = 1 AND Y1.J = 'x'")
+		Expect(t, q.Path).To(Equal([]string{"1", "", "Y1", "", "x"}))
+		Expect(t, q.Filter == nil).To(BeTrue())
+
+		q = compile(t.T, "M_M2.B = 3 AND J = 'y'")
+		Expect(t, q.Path).To(Equal([]string{"", "y", "M2", "", "3"}))
+	})
+
+	o.Spec("it leaves the rest of the query to the filter", func(t TQ) {
+		q := compile(t.T, "I = 1 AND Y1.I > 3 AND Y2.J = 'x' AND (J = 'a' OR NOT J <> 'b')")
+		Expect(t, q.Path).To(Equal([]string{"1", "", "Y2", "", "x"}))
+
+		Expect(t, q.Filter(&X{I: 1, J: "a", Y1: Y{I: 4}, Y2: &Y{J: "x"}})).To(BeTrue())
+		Expect(t, q.Filter(&X{I: 1, J: "b", Y1: Y{I: 4}, Y2: &Y{J: "x"}})).To(BeTrue())
+		Expect(t, q.Filter(&X{I: 1, J: "c", Y1: Y{I: 4}, Y2: &Y{J: "x"}})).To(BeFalse())
+		Expect(t, q.Filter(&X{I: 1, J: "a", Y1: Y{I: 3}, Y2: &Y{J: "x"}})).To(BeFalse())
+		Expect(t, q.Filter(&X{I: 1, J: "a", Y1: Y{I: 4}})).To(BeTrue())
+	})
+
+	o.Spec("it only uses one branch for the path", func(t TQ) {
+		q := compile(t.T, "Y1.J = 'a' AND Y2.J = 'b' AND Y1.J = 'c'")
+		Expect(t, q.Path).To(Equal([]string{"", "", "Y1", "", "a"}))
+
+		Expect(t, q.Filter(&X{Y1: Y{J: "c"}, Y2: &Y{J: "b"}})).To(BeTrue())
+		Expect(t, q.Filter(&X{Y1: Y{J: "c"}})).To(BeFalse())
+	})
+
+	o.Spec("it matches interface fields by their type", func(t TQ) {
+		q := compile(t.T, "I = 1 OR M_M1.A >= 2")
+
+		Expect(t, q.Filter(&X{M: M1{A: 2}})).To(BeTrue())
+		Expect(t, q.Filter(&X{M: M1{A: 1}})).To(BeFalse())
+		Expect(t, q.Filter(&X{M: M2{A: 2}})).To(BeFalse())
+	})
+
+	o.Spec("it subscribes with the query", func(t TQ) {
+		sub := newSpySubscription()
+		t.p.Subscribe(sub, compile(t.T, "J = 'x' AND Y2.I = 2 AND Y2.J != 'skip' AND 10 > I").SubscribeOptions()...)
+
+		traverser := NewStructTraverser()
+		t.p.Publish(&X{I: 1, J: "x", Y2: &Y{I: 2, J: "a"}}, traverser)
+		t.p.Publish(&X{I: 1, J: "x", Y2: &Y{I: 2, J: "skip"}}, traverser)
+		t.p.Publish(&X{I: 10, J: "x", Y2: &Y{I: 2, J: "a"}}, traverser)
+		t.p.Publish(&X{I: 1, J: "y", Y2: &Y{I: 2, J: "a"}}, traverser)
+		t.p.Publish(&X{I: 1, J: "x", Y2: &Y{I: 3, J: "a"}}, traverser)
+
+		Expect(t, sub.data).To(Equal([]interface{}{&X{I: 1, J: "x", Y2: &Y{I: 2, J: "a"}}}))
+	})
+
+	o.Spec("it returns an error for an invalid query", func(t TQ) {
+		for _, where := range []string{
+			"",
+			"I =",
+			"I == 1",
+			"K = 1",
+			"Y1 = 1",
+			"Y1.K = 1",
+			"I = 'x'",
+			"I = 1.5",
+			"J = 1",
+			"J = TRUE",
+			"I = J",
+			"(I = 1",
+			"J = 'x",
+			"I = 1 J = 'x'",
+		} {
+			_, err := query.Compile(where, NewStructTraverser())
+			Expect(t, errors.Is(err, query.ErrInvalidQuery)).To(BeTrue())
+		}
+	})
+
+	o.Spec("it returns an error for a traverser that is not generated", func(t TQ) {
+		_, err := query.Compile("I = 1", pubsub.LinearTreeTraverser(nil))
+		Expect(t, errors.Is(err, query.ErrInvalidQuery)).To(BeTrue())
+	})
+}
+
+type spySubscription struct {
+	data []interface{}
+}
+
+func newSpySubscription() *spySubscription {
+	return &spySubscription{}
+}
+
+func (s *spySubscription) Write(data interface{}) {
+	s.data = append(s.data, data)
+}
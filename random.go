@@ -0,0 +1,59 @@
+package pubsub
+
+import (
+	"math/rand"
+	"sync"
+)
+
+// WithRandSource configures the source of randomness that a PubSub uses for
+// the default RandSharding and WithSampleRate (e.g., to make tests
+// deterministic). The source does not have to be safe for concurrent use.
+// By default, the global source of math/rand is used, which is safe for
+// concurrent use without serializing publishes.
+func WithRandSource(src rand.Source) PubSubOption {
+	return pubsubConfigFunc(func(p *PubSub) {
+		p.rand = rand.New(newLockedSource(src))
+	})
+}
+
+// intn returns a random number in [0, n) from r, or from the global source
+// if r is nil.
+func intn(r *rand.Rand, n int) int {
+	if r == nil {
+		return rand.Intn(n)
+	}
+	return r.Intn(n)
+}
+
+// float64n returns a random number in [0, 1) from r, or from the global
+// source if r is nil.
+func float64n(r *rand.Rand) float64 {
+	if r == nil {
+		return rand.Float64()
+	}
+	return r.Float64()
+}
+
+// lockedSource makes a rand.Source safe for concurrent use. A rand.Rand
+// with a lockedSource is then safe for concurrent use as well (other than
+// its Seed and Read methods).
+type lockedSource struct {
+	mu  sync.Mutex
+	src rand.Source
+}
+
+func newLockedSource(src rand.Source) *lockedSource {
+	return &lockedSource{src: src}
+}
+
+func (s *lockedSource) Int63() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Int63()
+}
+
+func (s *lockedSource) Seed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.src.Seed(seed)
+}
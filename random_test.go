@@ -0,0 +1,85 @@
+package pubsub_test
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+func TestPubSubRandSource(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	// shard publishes to a shard group and returns which subscription each
+	// publish was written to.
+	shard := func(p *pubsub.PubSub) []int {
+		var (
+			mu     sync.Mutex
+			writes []int
+		)
+		for i := 0; i < 3; i++ {
+			i := i
+			p.Subscribe(pubsub.SubscriptionFunc(func(interface{}) {
+				mu.Lock()
+				defer mu.Unlock()
+				writes = append(writes, i)
+			}), pubsub.WithShardID("a"))
+		}
+
+		for i := 0; i < 20; i++ {
+			p.Publish(i, pubsub.LinearTreeTraverser(nil))
+		}
+		return writes
+	}
+
+	o.Spec("it shards deterministically", func(t *testing.T) {
+		w1 := shard(pubsub.New(pubsub.WithRandSource(rand.NewSource(99))))
+		w2 := shard(pubsub.New(pubsub.WithRandSource(rand.NewSource(99))))
+
+		Expect(t, w1).To(HaveLen(20))
+		Expect(t, w1).To(Equal(w2))
+	})
+
+	o.Spec("it samples deterministically", func(t *testing.T) {
+		sample := func() []interface{} {
+			p := pubsub.New(pubsub.WithRandSource(rand.NewSource(99)))
+			sub := newSpySubscrption()
+			p.Subscribe(sub, pubsub.WithSampleRate(0.5))
+			for i := 0; i < 20; i++ {
+				p.Publish(i, pubsub.LinearTreeTraverser(nil))
+			}
+			return sub.Data()
+		}
+
+		d := sample()
+		Expect(t, d).To(And(Not(HaveLen(0)), Not(HaveLen(20))))
+		Expect(t, sample()).To(Equal(d))
+	})
+
+	o.Spec("it can be published to concurrently", func(t *testing.T) {
+		p := pubsub.New(pubsub.WithRandSource(rand.NewSource(99)))
+		sub := newSpySubscrption()
+		p.Subscribe(sub, pubsub.WithShardID("a"))
+		p.Subscribe(sub, pubsub.WithShardID("a"))
+
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					p.Publish(j, pubsub.LinearTreeTraverser(nil))
+				}
+			}()
+		}
+		wg.Wait()
+
+		Expect(t, sub.Len()).To(Equal(400))
+	})
+}
@@ -0,0 +1,92 @@
+package pubsub
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/apoydence/pubsub/internal/field"
+)
+
+// ReflectTraverser returns a TreeTraverser that uses reflection to build
+// the path from the values of the given fields of the published data (a
+// struct or a pointer to one). Each field is a segment of the path, in the
+// given order. A field of a nested struct is named by joining the field
+// names with a '.' (e.g., "Meta.Region"). For example, with
+// ReflectTraverser("Region", "Kind"), a subscription with the path
+// []string{"us", pubsub.Any} receives each Event with a Region of "us".
+//
+// Pointers and interfaces are followed to their values. Values are
+// formatted with their String method if they have one, floats with Number
+// (so they can be matched by Range) and other values with fmt.Sprint. The
+// traversal ends at a field that does not exist or can not be reached
+// (e.g., through a nil pointer). The fields of each type are looked up once
+// and cached.
+func ReflectTraverser(fields ...string) TreeTraverser {
+	r := &reflectTraverser{
+		fields: make([][]string, len(fields)),
+		next:   make([]TreeTraverser, len(fields)+1),
+	}
+	for i, f := range fields {
+		r.fields[i] = strings.Split(f, ".")
+	}
+
+	r.next[len(fields)] = TreeTraverserFunc(func(interface{}, []string) Paths {
+		return EmptyPaths
+	})
+	for i := len(fields) - 1; i >= 0; i-- {
+		r.next[i] = TreeTraverserFunc(func(data interface{}, currentPath []string) Paths {
+			return r.traverse(data, i)
+		})
+	}
+
+	return r.next[0]
+}
+
+type reflectTraverser struct {
+	fields [][]string
+
+	// next[i] is the TreeTraverser for fields[i].
+	next []TreeTraverser
+}
+
+func (r *reflectTraverser) traverse(data interface{}, i int) Paths {
+	v := reflect.ValueOf(data)
+	for _, name := range r.fields[i] {
+		var ok bool
+		if v, ok = field.Lookup(v, name); !ok {
+			return EmptyPaths
+		}
+	}
+
+	v, ok := field.Indirect(v)
+	if !ok {
+		return EmptyPaths
+	}
+
+	return NewPathsWithTraverser(FlatPaths{formatValue(v)}, r.next[i+1])
+}
+
+var stringerType = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+
+func formatValue(v reflect.Value) string {
+	if v.Type().Implements(stringerType) && v.CanInterface() {
+		return v.Interface().(fmt.Stringer).String()
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return formatNumber(v.Float())
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,108 @@
+package pubsub_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+type reflectEvent struct {
+	Region string
+	Code   int
+	Load   float64
+	Kind   reflectKind
+	Meta   *reflectMeta
+	Body   interface{}
+
+	reflectEmbedded
+}
+
+type reflectMeta struct {
+	Host string
+}
+
+type reflectEmbedded struct {
+	Source string
+}
+
+type reflectKind int
+
+func (k reflectKind) String() string {
+	return [...]string{"created", "deleted"}[k]
+}
+
+func TestReflectTraverser(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) TPS {
+		return TPS{
+			T:            t,
+			p:            pubsub.New(),
+			subscription: newSpySubscrption(),
+		}
+	})
+
+	o.Spec("it builds the path from the fields", func(t TPS) {
+		t.p.Subscribe(t.subscription, pubsub.WithPath([]string{"us", "200", "deleted"}))
+
+		e := reflectEvent{Region: "us", Code: 200, Kind: 1}
+		t.p.Publish(e, pubsub.ReflectTraverser("Region", "Code", "Kind"))
+		t.p.Publish(reflectEvent{Region: "us", Code: 200}, pubsub.ReflectTraverser("Region", "Code", "Kind"))
+
+		Expect(t, t.subscription.Data()).To(Equal([]interface{}{e}))
+	})
+
+	o.Spec("it follows pointers, interfaces and nested fields", func(t TPS) {
+		t.p.Subscribe(t.subscription, pubsub.WithPath([]string{"a", "b", "c"}))
+
+		e := &reflectEvent{
+			Meta:            &reflectMeta{Host: "a"},
+			Body:            &reflectMeta{Host: "b"},
+			reflectEmbedded: reflectEmbedded{Source: "c"},
+		}
+		t.p.Publish(e, pubsub.ReflectTraverser("Meta.Host", "Body.Host", "Source"))
+
+		Expect(t, t.subscription.Data()).To(Equal([]interface{}{e}))
+	})
+
+	o.Spec("it formats floats for Range", func(t TPS) {
+		t.p.Subscribe(t.subscription, pubsub.WithPath([]string{pubsub.Range(0.5, math.Inf(1))}))
+
+		traverser := pubsub.ReflectTraverser("Load")
+		t.p.Publish(reflectEvent{Load: 0.25}, traverser)
+		t.p.Publish(reflectEvent{Load: 0.75}, traverser)
+
+		Expect(t, t.subscription.Data()).To(Equal([]interface{}{reflectEvent{Load: 0.75}}))
+	})
+
+	o.Spec("it ends the traversal at a field that can not be reached", func(t TPS) {
+		shallow := newSpySubscrption()
+		t.p.Subscribe(shallow, pubsub.WithPath([]string{"us"}))
+		t.p.Subscribe(t.subscription, pubsub.WithPath([]string{"us", pubsub.Any}))
+
+		traverser := pubsub.ReflectTraverser("Region", "Meta.Host")
+		t.p.Publish(reflectEvent{Region: "us"}, traverser)
+		t.p.Publish(reflectEvent{Region: "us"}, pubsub.ReflectTraverser("Region", "Missing"))
+		t.p.Publish("not-a-struct", traverser)
+
+		Expect(t, shallow.Len()).To(Equal(2))
+		Expect(t, t.subscription.Len()).To(Equal(0))
+	})
+
+	o.Spec("it can be used with different types", func(t TPS) {
+		t.p.Subscribe(t.subscription, pubsub.WithPath([]string{"us"}))
+
+		traverser := pubsub.ReflectTraverser("Region")
+		t.p.Publish(reflectEvent{Region: "us"}, traverser)
+		t.p.Publish(reflectMeta{Host: "us"}, traverser)
+		t.p.Publish(&struct{ Region string }{Region: "us"}, traverser)
+
+		Expect(t, t.subscription.Len()).To(Equal(2))
+	})
+}
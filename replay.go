@@ -0,0 +1,110 @@
+package pubsub
+
+import "sort"
+
+// WithReplayBuffer configures a PubSub to keep the last size entries of data
+// published to each path. A subscription can request to have them written to
+// it before any new data via WithReplay.
+//
+// Each path that data is published to keeps its own buffer, so this should
+// only be used when there is a bounded number of paths.
+func WithReplayBuffer(size int) PubSubOption {
+	return pubsubConfigFunc(func(p *PubSub) {
+		p.replaySize = size
+	})
+}
+
+// WithReplay configures a subscription to have up to n of the most recent
+// entries of data that were published to its path written to it before any
+// new data. It requires the PubSub to be configured with WithReplayBuffer.
+func WithReplay(n int) SubscribeOption {
+	return subscribeConfigFunc(func(c *subscribeConfig) {
+		c.replay = n
+	})
+}
+
+// replayBuffer is a ring buffer of published data.
+type replayBuffer struct {
+	entries []replayEntry
+	next    int
+}
+
+type replayEntry struct {
+	seq  uint64
+	data interface{}
+}
+
+// record must be invoked while holding at least the history read lock.
+func (s *PubSub) record(seq uint64, d interface{}, path []string) {
+	s.historyMu.Lock()
+	defer s.historyMu.Unlock()
+
+	n := s.history.fetch(path)
+	if n.replay == nil {
+		n.replay = &replayBuffer{
+			entries: make([]replayEntry, 0, s.replaySize),
+		}
+	}
+	n.replay.add(replayEntry{seq: seq, data: d})
+}
+
+func (b *replayBuffer) add(e replayEntry) {
+	// A publish can traverse to the same path more than once (e.g., via
+	// Any).
+	if len(b.entries) > 0 && b.last().seq == e.seq {
+		return
+	}
+
+	if len(b.entries) < cap(b.entries) {
+		b.entries = append(b.entries, e)
+		return
+	}
+
+	b.entries[b.next] = e
+	b.next = (b.next + 1) % len(b.entries)
+}
+
+func (b *replayBuffer) last() replayEntry {
+	if b.next == 0 {
+		return b.entries[len(b.entries)-1]
+	}
+
+	return b.entries[b.next-1]
+}
+
+// replayed appends up to n of the most recent entries that match the path
+// to data. It must be invoked while holding the history write lock.
+func (s *PubSub) replayed(data []interface{}, path []string, n int) []interface{} {
+	if n <= 0 {
+		return data
+	}
+
+	var entries []replayEntry
+	s.forEachHistoryMatch(path, func(h *historyNode) {
+		if h.replay != nil {
+			entries = append(entries, h.replay.entries...)
+		}
+	})
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].seq < entries[j].seq
+	})
+
+	// A single publish can be recorded at several paths.
+	var deduped []replayEntry
+	for _, e := range entries {
+		if len(deduped) > 0 && deduped[len(deduped)-1].seq == e.seq {
+			continue
+		}
+		deduped = append(deduped, e)
+	}
+
+	if len(deduped) > n {
+		deduped = deduped[len(deduped)-n:]
+	}
+
+	for _, e := range deduped {
+		data = append(data, e.data)
+	}
+	return data
+}
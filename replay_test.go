@@ -0,0 +1,72 @@
+package pubsub_test
+
+import (
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+func TestPubSubReplay(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+	o.BeforeEach(func(t *testing.T) TPS {
+		return TPS{
+			T: t,
+			p: pubsub.New(pubsub.WithReplayBuffer(3)),
+		}
+	})
+
+	o.Spec("it writes the most recent data to new subscriptions", func(t TPS) {
+		for i := 0; i < 5; i++ {
+			t.p.Publish(i, pubsub.LinearTreeTraverser([]string{"a", "b"}))
+		}
+		t.p.Publish(5, pubsub.LinearTreeTraverser([]string{"a", "c"}))
+		t.p.Publish(6, pubsub.LinearTreeTraverser([]string{"x"}))
+
+		sub1 := newSpySubscrption()
+		t.p.Subscribe(sub1, pubsub.WithPath([]string{"a", "b"}), pubsub.WithReplay(10))
+		Expect(t, sub1.data).To(Equal([]interface{}{2, 3, 4}))
+
+		sub2 := newSpySubscrption()
+		t.p.Subscribe(sub2, pubsub.WithPath([]string{"a"}), pubsub.WithReplay(2))
+		Expect(t, sub2.data).To(Equal([]interface{}{4, 5}))
+
+		sub3 := newSpySubscrption()
+		t.p.Subscribe(sub3, pubsub.WithPath([]string{"a"}))
+		Expect(t, sub3.data).To(HaveLen(0))
+
+		t.p.Publish(7, pubsub.LinearTreeTraverser([]string{"a", "b"}))
+		Expect(t, sub1.data).To(Equal([]interface{}{2, 3, 4, 7}))
+	})
+
+	o.Spec("it writes data published to several paths once", func(t TPS) {
+		t.p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{pubsub.Any}))
+		t.p.Publish("some-data", pubsub.TreeTraverserFunc(func(data interface{}, currentPath []string) pubsub.Paths {
+			if len(currentPath) > 0 {
+				return pubsub.FlatPaths(nil)
+			}
+			return pubsub.FlatPaths([]string{"a", "b"})
+		}))
+
+		sub := newSpySubscrption()
+		t.p.Subscribe(sub, pubsub.WithReplay(10))
+		Expect(t, sub.data).To(Equal([]interface{}{"some-data"}))
+	})
+
+	o.Spec("it retains data that is published from within a Write", func(t TPS) {
+		t.p.Subscribe(pubsub.SubscriptionFunc(func(data interface{}) {
+			if data == 1 {
+				t.p.Publish(2, pubsub.LinearTreeTraverser([]string{"b"}), pubsub.WithRetain())
+			}
+		}), pubsub.WithPath([]string{"a"}))
+		t.p.Publish(1, pubsub.LinearTreeTraverser([]string{"a"}))
+
+		sub := newSpySubscrption()
+		t.p.Subscribe(sub, pubsub.WithPath([]string{"b"}))
+		Expect(t, sub.Data()).To(Equal([]interface{}{2}))
+	})
+}
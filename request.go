@@ -0,0 +1,128 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrNoResponders is returned by Request when the request did not match
+	// any subscription (other than catch-all ones).
+	ErrNoResponders = errors.New("no responders")
+
+	// ErrRequestTimeout is returned by Request when no reply was published
+	// in time.
+	ErrRequestTimeout = errors.New("request timed out")
+)
+
+// replyPrefix is the first segment of the reply paths of requests.
+const replyPrefix = "_reply"
+
+// requestIDs makes the reply paths unique.
+var requestIDs atomic.Uint64
+
+// RequestMessage is published by Request. Subscriptions that handle
+// requests are written a RequestMessage and reply with Reply.
+type RequestMessage struct {
+	// Data is the data of the request.
+	Data interface{}
+
+	// ReplyPath is the path that the reply is published to. It is unique to
+	// the request.
+	ReplyPath []string
+
+	p *PubSub
+
+	// responders counts the subscriptions the request was written to,
+	// other than catch-all ones.
+	responders *atomic.Int64
+}
+
+// Reply publishes the reply to the request. Only the first reply is
+// returned by Request.
+func (m RequestMessage) Reply(data interface{}) {
+	m.p.Publish(data, LinearTreeTraverser(m.ReplyPath))
+}
+
+// Request publishes a RequestMessage that holds the data using the
+// TreeTraverser and returns the data of the first reply (see
+// RequestMessage.Reply). The TreeTraverser is given the data rather than
+// the RequestMessage. The reply is published to a path that starts with
+// "_reply" and is only subscribed to until Request returns.
+//
+// If the request did not match any subscription, ErrNoResponders is
+// returned. Subscriptions that receive data published to any path (those
+// at the root, or whose path starts with Any or is only Rest) are still
+// written the RequestMessage, but are not counted as responders. If there is no reply within the timeout (measured with the
+// PubSub's Clock), ErrRequestTimeout is returned.
+func Request(p *PubSub, data interface{}, a TreeTraverser, timeout time.Duration) (interface{}, error) {
+	m := RequestMessage{
+		Data:       data,
+		ReplyPath:  []string{replyPrefix, strconv.FormatUint(requestIDs.Add(1), 10)},
+		p:          p,
+		responders: new(atomic.Int64),
+	}
+
+	replies := make(chan interface{}, 1)
+	unsubscribe, err := p.SubscribeErr(SubscriptionFunc(func(data interface{}) {
+		select {
+		case replies <- data:
+		default:
+		}
+	}), WithPath(m.ReplyPath))
+	if err != nil {
+		return nil, err
+	}
+	defer unsubscribe()
+
+	timedOut := make(chan struct{})
+	timer := p.clock.AfterFunc(timeout, func() {
+		close(timedOut)
+	})
+	defer timer.Stop()
+
+	if _, ok := a.(LinearTreeTraverser); !ok {
+		a = requestTraverser{a: a}
+	}
+	if _, err := p.PublishCtx(context.Background(), m, a); err != nil {
+		return nil, err
+	}
+	if m.responders.Load() == 0 {
+		return nil, ErrNoResponders
+	}
+
+	select {
+	case reply := <-replies:
+		return reply, nil
+	case <-timedOut:
+		return nil, ErrRequestTimeout
+	}
+}
+
+// requestTraverser gives the TreeTraverser the data of a RequestMessage.
+type requestTraverser struct {
+	a TreeTraverser
+}
+
+// Traverse implements TreeTraverser. The next TreeTraversers are wrapped
+// so that they are also given the data of the RequestMessage.
+func (t requestTraverser) Traverse(data interface{}, currentPath []string) Paths {
+	if m, ok := data.(RequestMessage); ok {
+		data = m.Data
+	}
+
+	paths := t.a.Traverse(data, currentPath)
+	return PathsSeq(func(yield func(string, TreeTraverser) bool) {
+		for path, next := range AllPaths(paths) {
+			if next != nil {
+				next = requestTraverser{a: next}
+			}
+			if !yield(path, next) {
+				return
+			}
+		}
+	})
+}
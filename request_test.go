@@ -0,0 +1,105 @@
+package pubsub_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+func TestPubSubRequest(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) (*testing.T, *pubsub.PubSub) {
+		p := pubsub.New()
+		p.Subscribe(pubsub.SubscriptionFunc(func(data interface{}) {
+			m := data.(pubsub.RequestMessage)
+			m.Reply(m.Data.(string) + "!")
+		}), pubsub.WithPath([]string{"echo"}))
+		return t, p
+	})
+
+	o.Spec("it returns the first reply", func(t *testing.T, p *pubsub.PubSub) {
+		reply, err := pubsub.Request(p, "hi", pubsub.LinearTreeTraverser([]string{"echo"}), time.Second)
+		Expect(t, err).To(BeNil())
+		Expect(t, reply).To(Equal("hi!"))
+	})
+
+	o.Spec("it gives the TreeTraverser the data", func(t *testing.T, p *pubsub.PubSub) {
+		a := pubsub.TreeTraverserFunc(func(data interface{}, currentPath []string) pubsub.Paths {
+			if len(currentPath) > 0 {
+				return pubsub.FlatPaths(nil)
+			}
+			return pubsub.FlatPaths([]string{data.(string)})
+		})
+
+		reply, err := pubsub.Request(p, "echo", a, time.Second)
+		Expect(t, err).To(BeNil())
+		Expect(t, reply).To(Equal("echo!"))
+	})
+
+	o.Spec("it waits for a reply that is published later", func(t *testing.T, p *pubsub.PubSub) {
+		p.Subscribe(pubsub.SubscriptionFunc(func(data interface{}) {
+			go data.(pubsub.RequestMessage).Reply("later")
+		}), pubsub.WithPath([]string{"async"}))
+
+		reply, err := pubsub.Request(p, "hi", pubsub.LinearTreeTraverser([]string{"async"}), time.Second)
+		Expect(t, err).To(BeNil())
+		Expect(t, reply).To(Equal("later"))
+	})
+
+	o.Spec("it returns ErrNoResponders if nothing matched", func(t *testing.T, p *pubsub.PubSub) {
+		_, err := pubsub.Request(p, "hi", pubsub.LinearTreeTraverser([]string{"other"}), time.Second)
+		Expect(t, err).To(Equal(pubsub.ErrNoResponders))
+	})
+
+	o.Spec("it does not count catch-all subscriptions as responders", func(t *testing.T, p *pubsub.PubSub) {
+		root := newSpySubscrption()
+		p.Subscribe(root)
+		p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{pubsub.Rest}))
+		p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{pubsub.Any, "other"}))
+
+		_, err := pubsub.Request(p, "hi", pubsub.LinearTreeTraverser([]string{"other"}), time.Second)
+		Expect(t, err).To(Equal(pubsub.ErrNoResponders))
+		Expect(t, root.Len()).To(Equal(1))
+	})
+
+	o.Spec("it counts subscriptions beneath the first segment as responders", func(t *testing.T, p *pubsub.PubSub) {
+		p.Subscribe(pubsub.SubscriptionFunc(func(data interface{}) {
+			data.(pubsub.RequestMessage).Reply("any")
+		}), pubsub.WithPath([]string{"svc", pubsub.Any}))
+
+		reply, err := pubsub.Request(p, "hi", pubsub.LinearTreeTraverser([]string{"svc", "x"}), time.Second)
+		Expect(t, err).To(BeNil())
+		Expect(t, reply).To(Equal("any"))
+	})
+
+	o.Spec("it counts subscriptions at the root of a mounted PubSub as responders", func(t *testing.T, p *pubsub.PubSub) {
+		child := pubsub.New()
+		child.Subscribe(pubsub.SubscriptionFunc(func(data interface{}) {
+			data.(pubsub.RequestMessage).Reply("mounted")
+		}))
+		Expect(t, p.Mount([]string{"svc"}, child)).To(BeNil())
+
+		reply, err := pubsub.Request(p, "hi", pubsub.LinearTreeTraverser([]string{"svc", "x"}), time.Second)
+		Expect(t, err).To(BeNil())
+		Expect(t, reply).To(Equal("mounted"))
+	})
+
+	o.Spec("it returns ErrRequestTimeout if there is no reply", func(t *testing.T, p *pubsub.PubSub) {
+		p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"silent"}))
+
+		_, err := pubsub.Request(p, "hi", pubsub.LinearTreeTraverser([]string{"silent"}), 10*time.Millisecond)
+		Expect(t, err).To(Equal(pubsub.ErrRequestTimeout))
+	})
+
+	o.Spec("it unsubscribes from the reply path", func(t *testing.T, p *pubsub.PubSub) {
+		pubsub.Request(p, "hi", pubsub.LinearTreeTraverser([]string{"echo"}), time.Second)
+		Expect(t, p.Paths()).To(Equal([][]string{{"echo"}}))
+	})
+}
@@ -0,0 +1,48 @@
+package pubsub
+
+// WithRetain configures a Publish to store the data at the end of each path
+// that is traversed. Any subscription that subscribes afterwards with a
+// matching path will immediately have the retained data written to it.
+// Only the last retained data for each path is stored.
+//
+// Publishing nil data with WithRetain removes the retained data.
+func WithRetain() PublishOption {
+	return publishConfigFunc(func(c *publishConfig) {
+		c.retain = true
+	})
+}
+
+// recordHistory records the data for the path that a publish traversed to.
+func (s *PubSub) recordHistory(p *publish, path []string) {
+	if p.retain {
+		s.retain(p.data, path)
+	}
+
+	if p.seq != 0 {
+		s.record(p.seq, p.data, path)
+	}
+}
+
+// retain must be invoked while holding the history write lock.
+func (s *PubSub) retain(d interface{}, path []string) {
+	if d == nil {
+		if n := s.history.lookup(path); n != nil && n.retained != nil {
+			n.retained = nil
+			s.history.prune(path)
+		}
+		return
+	}
+
+	s.history.fetch(path).retained = d
+}
+
+// retained appends all the retained data that matches the path to data. It
+// must be invoked while holding the history write lock.
+func (s *PubSub) retained(data []interface{}, path []string) []interface{} {
+	s.forEachHistoryMatch(path, func(n *historyNode) {
+		if n.retained != nil {
+			data = append(data, n.retained)
+		}
+	})
+	return data
+}
@@ -0,0 +1,59 @@
+package pubsub_test
+
+import (
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+func TestPubSubRetain(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+	o.BeforeEach(func(t *testing.T) TPS {
+		return TPS{
+			T: t,
+			p: pubsub.New(),
+		}
+	})
+
+	o.Spec("it writes retained data to new subscriptions", func(t TPS) {
+		sub1 := newSpySubscrption()
+		t.p.Subscribe(sub1, pubsub.WithPath([]string{"a", "b"}))
+
+		t.p.Publish("data-1", pubsub.LinearTreeTraverser([]string{"a", "b"}), pubsub.WithRetain())
+		t.p.Publish("data-2", pubsub.LinearTreeTraverser([]string{"a", "b"}), pubsub.WithRetain())
+		t.p.Publish("data-3", pubsub.LinearTreeTraverser([]string{"a", "c"}), pubsub.WithRetain())
+		t.p.Publish("data-4", pubsub.LinearTreeTraverser([]string{"a", "d"}))
+		Expect(t, sub1.data).To(Equal([]interface{}{"data-1", "data-2"}))
+
+		sub2 := newSpySubscrption()
+		t.p.Subscribe(sub2, pubsub.WithPath([]string{"a", "b"}))
+		Expect(t, sub2.data).To(Equal([]interface{}{"data-2"}))
+
+		sub3 := newSpySubscrption()
+		t.p.Subscribe(sub3, pubsub.WithPath([]string{"a"}))
+		Expect(t, sub3.data).To(HaveLen(2))
+		Expect(t, sub3.data).To(Contain("data-2", "data-3"))
+
+		sub4 := newSpySubscrption()
+		t.p.Subscribe(sub4, pubsub.WithPath([]string{pubsub.Any, "c"}))
+		Expect(t, sub4.data).To(Equal([]interface{}{"data-3"}))
+
+		sub5 := newSpySubscrption()
+		t.p.Subscribe(sub5, pubsub.WithPath([]string{"x"}))
+		Expect(t, sub5.data).To(HaveLen(0))
+	})
+
+	o.Spec("it removes retained data when publishing nil", func(t TPS) {
+		t.p.Publish("data-1", pubsub.LinearTreeTraverser([]string{"a", "b"}), pubsub.WithRetain())
+		t.p.Publish(nil, pubsub.LinearTreeTraverser([]string{"a", "b"}), pubsub.WithRetain())
+
+		sub := newSpySubscrption()
+		t.p.Subscribe(sub, pubsub.WithPath([]string{"a", "b"}))
+		Expect(t, sub.data).To(HaveLen(0))
+	})
+}
@@ -0,0 +1,60 @@
+package pubsub
+
+import (
+	"math/rand"
+	"sync/atomic"
+)
+
+// WithSampling configures a subscription to only be written every nth
+// message that matches it (and passes any filters). The first matching
+// message is always written.
+func WithSampling(n int) SubscribeOption {
+	return subscribeConfigFunc(func(c *subscribeConfig) {
+		c.sampleEvery = n
+	})
+}
+
+// WithSampleRate configures a subscription to only be written the given
+// fraction (0 to 1) of the messages that match it (and pass any filters).
+// Which messages are written is random. A fraction of 0 (or less) writes
+// none of them.
+func WithSampleRate(fraction float64) SubscribeOption {
+	return subscribeConfigFunc(func(c *subscribeConfig) {
+		c.sampleRate = &fraction
+	})
+}
+
+// sampler decides which messages are written to a subscription.
+type sampler struct {
+	every uint64
+	rate  float64
+	count uint64
+	rand  *rand.Rand
+}
+
+func newSampler(c subscribeConfig, r *rand.Rand) *sampler {
+	if c.sampleEvery <= 1 && c.sampleRate == nil {
+		return nil
+	}
+
+	s := &sampler{rate: 1, rand: r}
+	if c.sampleRate != nil {
+		s.rate = *c.sampleRate
+	}
+	if c.sampleEvery > 1 {
+		s.every = uint64(c.sampleEvery)
+	}
+	return s
+}
+
+func (s *sampler) sample() bool {
+	if s.every > 0 && (atomic.AddUint64(&s.count, 1)-1)%s.every != 0 {
+		return false
+	}
+
+	if s.rate < 1 && float64n(s.rand) >= s.rate {
+		return false
+	}
+
+	return true
+}
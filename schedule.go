@@ -0,0 +1,212 @@
+package pubsub
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+)
+
+// ScheduledPublish is returned by PublishAt and PublishAfter. It can be
+// used to cancel the publish.
+type ScheduledPublish struct {
+	at  time.Time
+	seq uint64
+
+	d    interface{}
+	a    TreeTraverser
+	opts []PublishOption
+
+	// index is the ScheduledPublish's index in the scheduler's heap. It is
+	// -1 once it has been published or cancelled. It is guarded by the
+	// scheduler's mutex.
+	index int
+	s     *scheduler
+}
+
+// At returns when the data is published.
+func (p *ScheduledPublish) At() time.Time {
+	return p.at
+}
+
+// Cancel prevents the data from being published. It returns false if the
+// data has already been published or the publish was already cancelled.
+func (p *ScheduledPublish) Cancel() bool {
+	return p.s.cancel(p)
+}
+
+// PublishAt publishes the data (see Publish) once the given time has been
+// reached according to the PubSub's Clock. Publishes that are scheduled for
+// the same time are published in the order they were scheduled. Scheduled
+// publishes are cancelled when the PubSub is closed and nothing is
+// scheduled afterwards.
+func (s *PubSub) PublishAt(t time.Time, d interface{}, a TreeTraverser, opts ...PublishOption) *ScheduledPublish {
+	return s.scheduler.schedule(&ScheduledPublish{
+		at:    t,
+		d:     d,
+		a:     a,
+		opts:  opts,
+		index: -1,
+	})
+}
+
+// PublishAfter publishes the data (see Publish) once the given duration has
+// elapsed. See PublishAt.
+func (s *PubSub) PublishAfter(delay time.Duration, d interface{}, a TreeTraverser, opts ...PublishOption) *ScheduledPublish {
+	return s.PublishAt(s.clock.Now().Add(delay), d, a, opts...)
+}
+
+// Scheduled returns the number of publishes that are waiting to be
+// published (see PublishAt).
+func (s *PubSub) Scheduled() int {
+	s.scheduler.mu.Lock()
+	defer s.scheduler.mu.Unlock()
+	return len(s.scheduler.pending)
+}
+
+// scheduler holds the scheduled publishes in a heap ordered by when they
+// are due. A single Timer is armed for the earliest of them.
+type scheduler struct {
+	p *PubSub
+
+	mu      sync.Mutex
+	pending scheduledHeap
+	seq     uint64
+	closed  bool
+
+	// timer fires at next. gen is incremented each time it is armed so that
+	// a timer that could not be stopped in time does nothing.
+	timer Timer
+	next  time.Time
+	gen   uint64
+}
+
+func (s *scheduler) schedule(sp *ScheduledPublish) *ScheduledPublish {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sp.s = s
+	if s.closed {
+		return sp
+	}
+
+	s.seq++
+	sp.seq = s.seq
+	heap.Push(&s.pending, sp)
+	s.arm()
+
+	return sp
+}
+
+func (s *scheduler) cancel(sp *ScheduledPublish) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if sp.index < 0 {
+		return false
+	}
+
+	heap.Remove(&s.pending, sp.index)
+	if len(s.pending) == 0 && s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	return true
+}
+
+// arm ensures the timer fires for the earliest scheduled publish. It must
+// be invoked while holding the mutex.
+func (s *scheduler) arm() {
+	if len(s.pending) == 0 {
+		return
+	}
+
+	at := s.pending[0].at
+	if s.timer != nil {
+		if !at.Before(s.next) {
+			return
+		}
+		s.timer.Stop()
+	}
+
+	s.gen++
+	gen := s.gen
+	s.next = at
+	s.timer = s.p.clock.AfterFunc(at.Sub(s.p.clock.Now()), func() {
+		s.fire(gen)
+	})
+}
+
+// fire publishes everything that is due and then re-arms the timer.
+func (s *scheduler) fire(gen uint64) {
+	s.mu.Lock()
+	if gen != s.gen {
+		s.mu.Unlock()
+		return
+	}
+	s.timer = nil
+	now := s.p.clock.Now()
+
+	var due []*ScheduledPublish
+	for len(s.pending) > 0 && !s.pending[0].at.After(now) {
+		due = append(due, heap.Pop(&s.pending).(*ScheduledPublish))
+	}
+	s.arm()
+	s.mu.Unlock()
+
+	for _, sp := range due {
+		s.p.PublishCtx(context.Background(), sp.d, sp.a, sp.opts...)
+	}
+}
+
+// close cancels every scheduled publish.
+func (s *scheduler) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	for _, sp := range s.pending {
+		sp.index = -1
+	}
+	s.pending = nil
+
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+}
+
+// scheduledHeap implements heap.Interface.
+type scheduledHeap []*ScheduledPublish
+
+func (h scheduledHeap) Len() int {
+	return len(h)
+}
+
+func (h scheduledHeap) Less(i, j int) bool {
+	if h[i].at.Equal(h[j].at) {
+		return h[i].seq < h[j].seq
+	}
+	return h[i].at.Before(h[j].at)
+}
+
+func (h scheduledHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *scheduledHeap) Push(x interface{}) {
+	sp := x.(*ScheduledPublish)
+	sp.index = len(*h)
+	*h = append(*h, sp)
+}
+
+func (h *scheduledHeap) Pop() interface{} {
+	old := *h
+	sp := old[len(old)-1]
+	old[len(old)-1] = nil
+	sp.index = -1
+	*h = old[:len(old)-1]
+	return sp
+}
@@ -0,0 +1,83 @@
+package pubsub_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+func TestPubSubSchedule(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) TC {
+		clock := newSpyClock()
+		p := pubsub.New(pubsub.WithClock(clock))
+		sub := newSpySubscrption()
+		p.Subscribe(sub)
+
+		return TC{
+			T:            t,
+			p:            p,
+			clock:        clock,
+			subscription: sub,
+		}
+	})
+
+	o.Spec("it publishes once the time is reached", func(t TC) {
+		t.p.PublishAt(time.Unix(10, 0), 2, pubsub.LinearTreeTraverser(nil))
+		t.p.PublishAfter(5*time.Second, 1, pubsub.LinearTreeTraverser(nil))
+		t.p.PublishAt(time.Unix(10, 0), 3, pubsub.LinearTreeTraverser(nil))
+		Expect(t, t.p.Scheduled()).To(Equal(3))
+
+		t.clock.advance(4 * time.Second)
+		Expect(t, t.subscription.Len()).To(Equal(0))
+
+		t.clock.advance(time.Second)
+		Expect(t, t.subscription.Data()).To(Equal([]interface{}{1}))
+
+		t.clock.advance(5 * time.Second)
+		Expect(t, t.subscription.Data()).To(Equal([]interface{}{1, 2, 3}))
+		Expect(t, t.p.Scheduled()).To(Equal(0))
+	})
+
+	o.Spec("it publishes data scheduled in the past right away", func(t TC) {
+		t.p.PublishAfter(-time.Second, 1, pubsub.LinearTreeTraverser(nil))
+		t.clock.advance(0)
+
+		Expect(t, t.subscription.Data()).To(Equal([]interface{}{1}))
+	})
+
+	o.Spec("it does not publish cancelled data", func(t TC) {
+		sp := t.p.PublishAfter(time.Second, 1, pubsub.LinearTreeTraverser(nil))
+		t.p.PublishAfter(2*time.Second, 2, pubsub.LinearTreeTraverser(nil))
+		Expect(t, sp.At()).To(Equal(time.Unix(1, 0)))
+
+		Expect(t, sp.Cancel()).To(BeTrue())
+		Expect(t, sp.Cancel()).To(BeFalse())
+		t.clock.advance(2 * time.Second)
+
+		Expect(t, t.subscription.Data()).To(Equal([]interface{}{2}))
+	})
+
+	o.Spec("it can not cancel data that was published", func(t TC) {
+		sp := t.p.PublishAfter(time.Second, 1, pubsub.LinearTreeTraverser(nil))
+		t.clock.advance(time.Second)
+
+		Expect(t, sp.Cancel()).To(BeFalse())
+	})
+
+	o.Spec("it cancels scheduled publishes when closed", func(t TC) {
+		t.p.PublishAfter(time.Second, 1, pubsub.LinearTreeTraverser(nil))
+		t.p.Close()
+		sp := t.p.PublishAfter(time.Second, 2, pubsub.LinearTreeTraverser(nil))
+
+		Expect(t, t.p.Scheduled()).To(Equal(0))
+		Expect(t, sp.Cancel()).To(BeFalse())
+	})
+}
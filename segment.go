@@ -0,0 +1,253 @@
+package pubsub
+
+import (
+	"errors"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/apoydence/pubsub/internal/node"
+)
+
+// Pattern segments are path segments (like Any and Rest) that match
+// published segments with a segmentMatcher instead of by equality. They are
+// encoded as strings so they can be used with WithPath. The encoding starts
+// with a NUL byte followed by the kind of pattern and its argument.
+const (
+	regexpSegmentPrefix = "\x00regexp:"
+	rangeSegmentPrefix  = "\x00range:"
+	notSegmentPrefix    = "\x00not:"
+)
+
+// Regexp returns a path segment that matches any published segment that
+// the regular expression matches. The match is unanchored, so for example,
+// regexp.MustCompile("^sensor-") matches segments starting with "sensor-".
+// The returned segment can be used anywhere Any can be.
+func Regexp(re *regexp.Regexp) string {
+	return regexpSegmentPrefix + re.String()
+}
+
+// Range returns a path segment that matches any published segment that is
+// a number (see Number) in the interval [min, max). Either bound may be
+// infinite (see math.Inf), so for example, Range(500, math.Inf(1)) matches
+// any number that is at least 500. The returned segment can be used
+// anywhere Any can be. A node's ranges are indexed by their bounds, so a
+// publish only considers the ranges that can contain the number.
+func Range(min, max float64) string {
+	return rangeSegmentPrefix + formatNumber(min) + ":" + formatNumber(max)
+}
+
+// Not returns a path segment that matches any published segment that the
+// given segment does not match. The given segment may be a literal or a
+// pattern (e.g., Regexp or Range), so for example, a subscription with the
+// path []string{"orders", pubsub.Not("eu")} receives data published to
+// []string{"orders", "us"} but not to []string{"orders", "eu"}. Not(Any)
+// matches nothing. The returned segment can be used anywhere Any can be.
+func Not(segment string) string {
+	return notSegmentPrefix + segment
+}
+
+// Number formats the number as a path segment for a TreeTraverser to yield
+// so that it can be matched by Range segments.
+func Number(x float64) string {
+	return formatNumber(x)
+}
+
+func formatNumber(x float64) string {
+	return strconv.FormatFloat(x, 'g', -1, 64)
+}
+
+// ErrInvalidPath is returned when subscribing with a path that has a
+// segment that is not a literal, Any or a pattern returned by this package
+// (e.g., Regexp), or that has Rest anywhere but at the end.
+var ErrInvalidPath = errors.New("invalid path")
+
+// validPath reports if every segment of the path is valid (see
+// ErrInvalidPath).
+func validPath(path []string) bool {
+	for i, segment := range path {
+		if segment == Rest && i != len(path)-1 {
+			return false
+		}
+
+		if !validSegment(segment) {
+			return false
+		}
+	}
+	return true
+}
+
+func validSegment(segment string) bool {
+	switch {
+	case !node.IsPattern(segment), segment == Any, segment == Rest:
+		return true
+	case strings.HasPrefix(segment, notSegmentPrefix):
+		return validSegment(segment[len(notSegmentPrefix):])
+	case strings.HasPrefix(segment, regexpSegmentPrefix):
+		_, err := regexp.Compile(segment[len(regexpSegmentPrefix):])
+		return err == nil
+	default:
+		return newSegmentMatcher(segment) != nil
+	}
+}
+
+// segmentMatcher matches published segments for a pattern segment.
+type segmentMatcher interface {
+	match(segment string) bool
+}
+
+// newSegmentMatcher returns the segmentMatcher for a pattern segment. It
+// returns nil if the segment is not one (e.g., a literal, Any or Rest).
+func newSegmentMatcher(segment string) segmentMatcher {
+	if !node.IsPattern(segment) {
+		return nil
+	}
+
+	switch {
+	case strings.HasPrefix(segment, regexpSegmentPrefix):
+		// The expression came from a compiled Regexp, so it compiles.
+		return regexpMatcher{regexp.MustCompile(segment[len(regexpSegmentPrefix):])}
+	case strings.HasPrefix(segment, rangeSegmentPrefix):
+		bounds := strings.SplitN(segment[len(rangeSegmentPrefix):], ":", 2)
+		if len(bounds) != 2 {
+			return nil
+		}
+
+		min, err1 := strconv.ParseFloat(bounds[0], 64)
+		max, err2 := strconv.ParseFloat(bounds[1], 64)
+		if err1 != nil || err2 != nil {
+			return nil
+		}
+		return rangeMatcher{min: min, max: max}
+	case strings.HasPrefix(segment, notSegmentPrefix):
+		inner := segment[len(notSegmentPrefix):]
+		switch inner {
+		case Any, Rest:
+			return notMatcher{all: true}
+		}
+
+		return notMatcher{m: newSegmentMatcher(inner), literal: inner}
+	default:
+		return nil
+	}
+}
+
+type regexpMatcher struct {
+	re *regexp.Regexp
+}
+
+func (m regexpMatcher) match(segment string) bool {
+	return m.re.MatchString(segment)
+}
+
+// notMatcher inverts m, or the literal if m is nil. If all is set, it
+// inverts a segment that matches everything.
+type notMatcher struct {
+	m       segmentMatcher
+	literal string
+	all     bool
+}
+
+func (m notMatcher) match(segment string) bool {
+	switch {
+	case m.all:
+		return false
+	case m.m != nil:
+		return !m.m.match(segment)
+	default:
+		return segment != m.literal
+	}
+}
+
+type rangeMatcher struct {
+	min, max float64
+}
+
+func (m rangeMatcher) match(segment string) bool {
+	x, err := strconv.ParseFloat(segment, 64)
+	return err == nil && m.contains(x)
+}
+
+func (m rangeMatcher) contains(x float64) bool {
+	return m.min <= x && x < m.max
+}
+
+// patternIndex is stored as the index of a node with pattern children. The
+// ranges are sorted by their minimum so that only the ones that can contain
+// a number have to be checked.
+type patternIndex struct {
+	ranges []rangeChild
+
+	// maxes[i] is the largest maximum of ranges[:i+1].
+	maxes []float64
+
+	// others are the pattern children that are not ranges.
+	others []*node.Node
+}
+
+type rangeChild struct {
+	rangeMatcher
+	n *node.Node
+}
+
+func newPatternIndex(n *node.Node) *patternIndex {
+	idx := &patternIndex{}
+	for i := 0; i < n.PatternLen(); i++ {
+		_, c := n.Pattern(i)
+		if m, ok := c.Data().(rangeMatcher); ok {
+			idx.ranges = append(idx.ranges, rangeChild{rangeMatcher: m, n: c})
+			continue
+		}
+		idx.others = append(idx.others, c)
+	}
+
+	sort.SliceStable(idx.ranges, func(i, j int) bool {
+		return idx.ranges[i].min < idx.ranges[j].min
+	})
+
+	max := math.Inf(-1)
+	for _, r := range idx.ranges {
+		max = math.Max(max, r.max)
+		idx.maxes = append(idx.maxes, max)
+	}
+
+	return idx
+}
+
+// pushPatterns pushes each child of n that is a pattern which matches the
+// published segment.
+func (p *publish) pushPatterns(a TreeTraverser, n *node.Node, depth int, segment string, catchAll bool) {
+	idx, ok := n.Index().(*patternIndex)
+	if !ok {
+		return
+	}
+
+	for _, c := range idx.others {
+		if m, ok := c.Data().(segmentMatcher); ok && m.match(segment) {
+			p.push(a, c, depth, segment, catchAll)
+		}
+	}
+
+	if len(idx.ranges) == 0 {
+		return
+	}
+
+	x, err := strconv.ParseFloat(segment, 64)
+	if err != nil {
+		return
+	}
+
+	// Only the ranges with a minimum of at most x can contain it. They are
+	// checked from the largest minimum down until none of the remaining
+	// ones reach x.
+	i := sort.Search(len(idx.ranges), func(i int) bool {
+		return idx.ranges[i].min > x
+	})
+	for i--; i >= 0 && idx.maxes[i] > x; i-- {
+		if idx.ranges[i].contains(x) {
+			p.push(a, idx.ranges[i].n, depth, segment, catchAll)
+		}
+	}
+}
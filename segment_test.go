@@ -0,0 +1,156 @@
+package pubsub_test
+
+import (
+	"math"
+	"regexp"
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+func TestPubSubPatternSegments(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) TPS {
+		return TPS{
+			T:            t,
+			p:            pubsub.New(),
+			subscription: newSpySubscrption(),
+		}
+	})
+
+	o.Spec("it matches segments with a Regexp", func(t TPS) {
+		t.p.Subscribe(t.subscription, pubsub.WithPath([]string{
+			"devices", pubsub.Regexp(regexp.MustCompile("^sensor-")), "temp",
+		}))
+
+		t.p.Publish(1, pubsub.LinearTreeTraverser([]string{"devices", "sensor-1", "temp"}))
+		t.p.Publish(2, pubsub.LinearTreeTraverser([]string{"devices", "camera-1", "temp"}))
+		t.p.Publish(3, pubsub.LinearTreeTraverser([]string{"devices", "sensor-2", "humidity"}))
+		t.p.Publish(4, pubsub.LinearTreeTraverser([]string{"devices", "sensor-3", "temp"}))
+
+		Expect(t, t.subscription.Data()).To(Equal([]interface{}{1, 4}))
+	})
+
+	o.Spec("it writes once when several patterns match", func(t TPS) {
+		sub := newSpySubscrption()
+		t.p.Subscribe(t.subscription, pubsub.WithPath([]string{pubsub.Regexp(regexp.MustCompile("a"))}))
+		t.p.Subscribe(sub, pubsub.WithPath([]string{pubsub.Regexp(regexp.MustCompile("b"))}))
+
+		t.p.Publish(1, pubsub.LinearTreeTraverser([]string{"ab"}))
+		t.p.Publish(2, pubsub.LinearTreeTraverser([]string{"b"}))
+
+		Expect(t, t.subscription.Data()).To(Equal([]interface{}{1}))
+		Expect(t, sub.Data()).To(Equal([]interface{}{1, 2}))
+	})
+
+	o.Spec("it removes pattern nodes once they are unused", func(t TPS) {
+		unsubscribe := t.p.Subscribe(t.subscription, pubsub.WithPath([]string{
+			"devices", pubsub.Regexp(regexp.MustCompile("^sensor-")),
+		}))
+		unsubscribe()
+
+		Expect(t, t.p.Paths()).To(HaveLen(0))
+	})
+
+	o.Spec("it writes retained data that matches the pattern", func(t TPS) {
+		t.p.Publish(1, pubsub.LinearTreeTraverser([]string{"devices", "sensor-1"}), pubsub.WithRetain())
+		t.p.Publish(2, pubsub.LinearTreeTraverser([]string{"devices", "camera-1"}), pubsub.WithRetain())
+
+		t.p.Subscribe(t.subscription, pubsub.WithPath([]string{
+			"devices", pubsub.Regexp(regexp.MustCompile("^sensor-")),
+		}))
+		Expect(t, t.subscription.Data()).To(Equal([]interface{}{1}))
+	})
+
+	o.Spec("it matches numbers with a Range", func(t TPS) {
+		slow := newSpySubscrption()
+		t.p.Subscribe(t.subscription, pubsub.WithPath([]string{"latency", pubsub.Range(0, 100)}))
+		t.p.Subscribe(slow, pubsub.WithPath([]string{"latency", pubsub.Range(500, math.Inf(1))}))
+
+		for i, x := range []float64{0, 99.5, 100, 499, 500, 1e9} {
+			t.p.Publish(i, pubsub.LinearTreeTraverser([]string{"latency", pubsub.Number(x)}))
+		}
+		t.p.Publish("nan", pubsub.LinearTreeTraverser([]string{"latency", "fast"}))
+
+		Expect(t, t.subscription.Data()).To(Equal([]interface{}{0, 1}))
+		Expect(t, slow.Data()).To(Equal([]interface{}{4, 5}))
+	})
+
+	o.Spec("it matches overlapping ranges", func(t TPS) {
+		subs := make([]*spySubscription, 4)
+		ranges := [][2]float64{{0, 10}, {5, 6}, {2, 100}, {50, 60}}
+		for i, r := range ranges {
+			subs[i] = newSpySubscrption()
+			t.p.Subscribe(subs[i], pubsub.WithPath([]string{pubsub.Range(r[0], r[1])}))
+		}
+
+		t.p.Publish(1, pubsub.LinearTreeTraverser([]string{pubsub.Number(5.5)}))
+		t.p.Publish(2, pubsub.LinearTreeTraverser([]string{pubsub.Number(55)}))
+
+		Expect(t, subs[0].Data()).To(Equal([]interface{}{1}))
+		Expect(t, subs[1].Data()).To(Equal([]interface{}{1}))
+		Expect(t, subs[2].Data()).To(Equal([]interface{}{1, 2}))
+		Expect(t, subs[3].Data()).To(Equal([]interface{}{2}))
+	})
+
+	o.Spec("it updates the ranges when subscriptions are removed", func(t TPS) {
+		unsubscribe := t.p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{pubsub.Range(0, 10)}))
+		t.p.Subscribe(t.subscription, pubsub.WithPath([]string{pubsub.Range(5, 20)}))
+		unsubscribe()
+
+		t.p.Publish(1, pubsub.LinearTreeTraverser([]string{pubsub.Number(7)}))
+		Expect(t, t.subscription.Data()).To(Equal([]interface{}{1}))
+		Expect(t, t.p.Paths()).To(HaveLen(1))
+	})
+
+	o.Spec("it excludes a segment with Not", func(t TPS) {
+		t.p.Subscribe(t.subscription, pubsub.WithPath([]string{"orders", pubsub.Not("eu")}))
+
+		t.p.Publish(1, pubsub.LinearTreeTraverser([]string{"orders", "us", "created"}))
+		t.p.Publish(2, pubsub.LinearTreeTraverser([]string{"orders", "eu", "created"}))
+		t.p.Publish(3, pubsub.LinearTreeTraverser([]string{"orders", "apac"}))
+		t.p.Publish(4, pubsub.LinearTreeTraverser([]string{"orders"}))
+
+		Expect(t, t.subscription.Data()).To(Equal([]interface{}{1, 3}))
+	})
+
+	o.Spec("it excludes patterns with Not", func(t TPS) {
+		sub := newSpySubscrption()
+		t.p.Subscribe(t.subscription, pubsub.WithPath([]string{pubsub.Not(pubsub.Regexp(regexp.MustCompile("^eu-")))}))
+		t.p.Subscribe(sub, pubsub.WithPath([]string{pubsub.Not(pubsub.Range(0, 10))}))
+
+		t.p.Publish(1, pubsub.LinearTreeTraverser([]string{"eu-west"}))
+		t.p.Publish(2, pubsub.LinearTreeTraverser([]string{"us-west"}))
+		t.p.Publish(3, pubsub.LinearTreeTraverser([]string{pubsub.Number(5)}))
+		t.p.Publish(4, pubsub.LinearTreeTraverser([]string{pubsub.Number(50)}))
+
+		Expect(t, t.subscription.Data()).To(Equal([]interface{}{2, 3, 4}))
+		Expect(t, sub.Data()).To(Equal([]interface{}{1, 2, 4}))
+	})
+
+	o.Spec("it matches nothing with Not(Any)", func(t TPS) {
+		t.p.Subscribe(t.subscription, pubsub.WithPath([]string{pubsub.Not(pubsub.Any)}))
+		t.p.Publish(1, pubsub.LinearTreeTraverser([]string{"a"}))
+
+		Expect(t, t.subscription.Len()).To(Equal(0))
+	})
+
+	o.Spec("it writes retained data that is not excluded", func(t TPS) {
+		t.p.Publish(1, pubsub.LinearTreeTraverser([]string{"orders", "us"}), pubsub.WithRetain())
+		t.p.Publish(2, pubsub.LinearTreeTraverser([]string{"orders", "eu"}), pubsub.WithRetain())
+
+		t.p.Subscribe(t.subscription, pubsub.WithPath([]string{"orders", pubsub.Not("eu")}))
+		Expect(t, t.subscription.Data()).To(Equal([]interface{}{1}))
+	})
+
+	o.Spec("it can not be mounted at", func(t TPS) {
+		err := t.p.Mount([]string{pubsub.Regexp(regexp.MustCompile("a"))}, pubsub.New())
+		Expect(t, err).To(Not(BeNil()))
+	})
+}
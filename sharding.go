@@ -0,0 +1,194 @@
+package pubsub
+
+import (
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/apoydence/pubsub/internal/node"
+)
+
+// RoundRobinSharding implements ShardingAlgorithm. It rotates through the
+// subscriptions of each shard group in order. It should be constructed with
+// NewRoundRobinSharding().
+type RoundRobinSharding struct {
+	mu *sync.Mutex
+	c  *roundRobinCounters
+}
+
+// maxRoundRobinCounters is how many shard groups RoundRobinSharding counts
+// before it forgets the ones that have not been written to since it last
+// did.
+const maxRoundRobinCounters = 1024
+
+// roundRobinCounters holds the position of each shard group. Shard groups
+// are keyed by their oldest subscription, so a key is no longer used once
+// that subscription is removed. To forget such keys, the counters are moved
+// to old once there are too many and only the ones that are used again are
+// kept.
+type roundRobinCounters struct {
+	counter map[int64]int
+	old     map[int64]int
+}
+
+// NewRoundRobinSharding constructs a new RoundRobinSharding.
+func NewRoundRobinSharding() RoundRobinSharding {
+	return RoundRobinSharding{
+		mu: &sync.Mutex{},
+		c: &roundRobinCounters{
+			counter: make(map[int64]int),
+		},
+	}
+}
+
+// Write implements ShardingAlgorithm.
+func (r RoundRobinSharding) Write(data interface{}, subscriptions []Subscription) {
+	key := shardGroupKey(subscriptions)
+
+	r.mu.Lock()
+	idx := r.c.next(key) % len(subscriptions)
+	r.c.counter[key] = idx + 1
+	r.mu.Unlock()
+
+	subscriptions[idx].Write(data)
+}
+
+// next returns the counter of the shard group.
+func (c *roundRobinCounters) next(key int64) int {
+	if n, ok := c.counter[key]; ok {
+		return n
+	}
+
+	if len(c.counter) >= maxRoundRobinCounters {
+		c.old, c.counter = c.counter, make(map[int64]int)
+	}
+	return c.old[key]
+}
+
+// shardGroupKey returns a key that identifies the shard group. It is the
+// ID of the oldest subscription in the group. Subscriptions that were not
+// given by the PubSub all share the zero key.
+func shardGroupKey(subscriptions []Subscription) int64 {
+	if x, ok := subscriptions[0].(interface{ ID() int64 }); ok {
+		return x.ID()
+	}
+
+	return 0
+}
+
+// HashSharding implements ShardingAlgorithm. It writes data with the same
+// key to the same subscription of a shard group. When subscriptions join or
+// leave the shard group, only the keys of the affected subscriptions are
+// moved (rendezvous hashing). It should be constructed with
+// NewHashSharding().
+type HashSharding struct {
+	keyFn func(data interface{}) uint64
+}
+
+// NewHashSharding constructs a new HashSharding. The keyFn is used to
+// derive the key from the published data.
+func NewHashSharding(keyFn func(data interface{}) uint64) HashSharding {
+	return HashSharding{
+		keyFn: keyFn,
+	}
+}
+
+// Write implements ShardingAlgorithm.
+func (h HashSharding) Write(data interface{}, subscriptions []Subscription) {
+	key := h.keyFn(data)
+
+	var (
+		idx  int
+		best uint64
+	)
+	for i, s := range subscriptions {
+		id := uint64(i)
+		if x, ok := s.(interface{ ID() int64 }); ok {
+			id = uint64(x.ID())
+		}
+
+		if score := mix(key ^ mix(id)); i == 0 || score > best {
+			idx, best = i, score
+		}
+	}
+
+	subscriptions[idx].Write(data)
+}
+
+// mix is the finalizer of splitmix64.
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// WithCrossNodeSharding configures a PubSub to form shard groups across
+// every node that a publish traverses instead of only within a single node.
+// This means exactly one subscription with a given shardID receives the
+// data, regardless of the path it subscribed with.
+func WithCrossNodeSharding() PubSubOption {
+	return pubsubConfigFunc(func(p *PubSub) {
+		p.crossNodeSharding = true
+	})
+}
+
+// writeShardGroups writes to the shard groups that were gathered while
+// traversing with WithCrossNodeSharding.
+func (s *PubSub) writeShardGroups(p *publish) {
+	if s.deterministic {
+		for _, shardID := range slices.Sorted(maps.Keys(p.shardGroups)) {
+			if p.ctx.Err() != nil {
+				return
+			}
+
+			s.writeShardGroup(p, p.shardGroups[shardID])
+		}
+		return
+	}
+
+	for _, members := range p.shardGroups {
+		if p.ctx.Err() != nil {
+			return
+		}
+
+		s.writeShardGroup(p, members)
+	}
+}
+
+// writeShardGroup has the ShardingAlgorithm pick which members of the shard
+// group to write to.
+func (s *PubSub) writeShardGroup(p *publish, members []shardMember) {
+	subs := make([]Subscription, len(members))
+	for i := range members {
+		subs[i] = &members[i]
+	}
+
+	s.sa.Write(p.data, subs)
+}
+
+// shardMember is a subscription of a shard group as it is given to the
+// ShardingAlgorithm. It is written like any other subscription of the
+// publish: with its context and the path that reached it (which, with
+// WithCrossNodeSharding, differs between the members).
+type shardMember struct {
+	node.SubscriptionEnvelope
+	p     *publish
+	path  []string
+	stats *node.Stats
+}
+
+// Write implements Subscription.
+func (m *shardMember) Write(data interface{}) {
+	delivered, dropped, err := m.p.deliverData(m.Subscription, data, m.path)
+	m.p.result.Dropped += dropped
+	if err != nil {
+		m.p.result.Errors = append(m.p.result.Errors, err)
+	}
+	if delivered {
+		m.p.wrote(m.path)
+	}
+	countWrite(m.stats, delivered, dropped)
+}
@@ -0,0 +1,211 @@
+package pubsub_test
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+func TestRandSharding(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.Spec("it writes to a single subscription concurrently", func(t *testing.T) {
+		var r pubsub.RandSharding
+		sub1 := newSpySubscrption()
+		sub2 := newSpySubscrption()
+
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					r.Write(j, []pubsub.Subscription{sub1, sub2})
+				}
+			}()
+		}
+		wg.Wait()
+
+		Expect(t, sub1.Len()+sub2.Len()).To(Equal(400))
+		Expect(t, sub1.Len()).To(BeAbove(0))
+		Expect(t, sub2.Len()).To(BeAbove(0))
+	})
+
+	o.Spec("it uses the given source", func(t *testing.T) {
+		picks := func() []int {
+			r := pubsub.NewRandShardingWithSource(rand.NewSource(99))
+			var idxs []int
+			for i := 0; i < 20; i++ {
+				subs := make([]pubsub.Subscription, 3)
+				for j := range subs {
+					j := j
+					subs[j] = pubsub.SubscriptionFunc(func(interface{}) {
+						idxs = append(idxs, j)
+					})
+				}
+				r.Write(i, subs)
+			}
+			return idxs
+		}
+
+		Expect(t, picks()).To(Equal(picks()))
+	})
+}
+
+func TestRoundRobinSharding(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.Spec("it rotates through each shard group", func(t *testing.T) {
+		p := pubsub.New(pubsub.WithShardingAlgorithm(pubsub.NewRoundRobinSharding()))
+		sub1 := newSpySubscrption()
+		sub2 := newSpySubscrption()
+		sub3 := newSpySubscrption()
+		sub4 := newSpySubscrption()
+		p.Subscribe(sub1, pubsub.WithShardID("1"))
+		p.Subscribe(sub2, pubsub.WithShardID("1"))
+		p.Subscribe(sub3, pubsub.WithShardID("1"))
+		p.Subscribe(sub4, pubsub.WithShardID("2"))
+
+		for i := 0; i < 7; i++ {
+			p.Publish(i, pubsub.LinearTreeTraverser(nil))
+		}
+
+		Expect(t, sub1.data).To(Equal([]interface{}{0, 3, 6}))
+		Expect(t, sub2.data).To(Equal([]interface{}{1, 4}))
+		Expect(t, sub3.data).To(Equal([]interface{}{2, 5}))
+		Expect(t, sub4.data).To(HaveLen(7))
+	})
+
+	o.Spec("it keeps rotating while shard groups come and go", func(t *testing.T) {
+		p := pubsub.New(pubsub.WithShardingAlgorithm(pubsub.NewRoundRobinSharding()))
+		sub1 := newSpySubscrption()
+		sub2 := newSpySubscrption()
+		p.Subscribe(sub1, pubsub.WithShardID("1"), pubsub.WithPath([]string{"a"}))
+		p.Subscribe(sub2, pubsub.WithShardID("1"), pubsub.WithPath([]string{"a"}))
+
+		for i := 0; i < 3000; i++ {
+			path := []string{"b", fmt.Sprint(i)}
+			unsubscribe1 := p.Subscribe(newSpySubscrption(), pubsub.WithShardID("2"), pubsub.WithPath(path))
+			unsubscribe2 := p.Subscribe(newSpySubscrption(), pubsub.WithShardID("2"), pubsub.WithPath(path))
+			p.Publish(i, pubsub.LinearTreeTraverser(path))
+			unsubscribe1()
+			unsubscribe2()
+
+			if i%1000 == 0 {
+				p.Publish(i, pubsub.LinearTreeTraverser([]string{"a"}))
+			}
+		}
+
+		Expect(t, sub1.data).To(Equal([]interface{}{0, 2000}))
+		Expect(t, sub2.data).To(Equal([]interface{}{1000}))
+	})
+}
+
+func TestHashSharding(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.Spec("it writes the same key to the same subscription", func(t *testing.T) {
+		p := pubsub.New(pubsub.WithShardingAlgorithm(pubsub.NewHashSharding(func(data interface{}) uint64 {
+			return uint64(data.(int) % 10)
+		})))
+
+		var subs []*spySubscription
+		var unsubscribes []pubsub.Unsubscriber
+		for i := 0; i < 5; i++ {
+			sub := newSpySubscrption()
+			subs = append(subs, sub)
+			unsubscribes = append(unsubscribes, p.Subscribe(sub, pubsub.WithShardID("1")))
+		}
+
+		owners := func() map[int]int {
+			m := make(map[int]int)
+			for i, s := range subs {
+				for _, d := range s.data {
+					if owner, ok := m[d.(int)%10]; ok {
+						Expect(t, owner).To(Equal(i))
+					}
+					m[d.(int)%10] = i
+				}
+				s.data = nil
+			}
+			return m
+		}
+
+		for i := 0; i < 100; i++ {
+			p.Publish(i, pubsub.LinearTreeTraverser(nil))
+		}
+		before := owners()
+		Expect(t, before).To(HaveLen(10))
+
+		unsubscribes[0]()
+		for i := 0; i < 10; i++ {
+			p.Publish(i, pubsub.LinearTreeTraverser(nil))
+		}
+		after := owners()
+
+		for k, owner := range before {
+			if owner != 0 {
+				Expect(t, after[k]).To(Equal(owner))
+			}
+		}
+	})
+}
+
+func TestCrossNodeSharding(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.Spec("it writes to one subscription per shardID across nodes", func(t *testing.T) {
+		p := pubsub.New(pubsub.WithCrossNodeSharding())
+		sub1 := newSpySubscrption()
+		sub2 := newSpySubscrption()
+		sub3 := newSpySubscrption()
+		sub4 := newSpySubscrption()
+		p.Subscribe(sub1, pubsub.WithShardID("1"))
+		p.Subscribe(sub2, pubsub.WithShardID("1"), pubsub.WithPath([]string{"a"}))
+		p.Subscribe(sub3, pubsub.WithShardID("1"), pubsub.WithPath([]string{"a", "b"}))
+		p.Subscribe(sub4, pubsub.WithPath([]string{"a", "b"}))
+
+		for i := 0; i < 100; i++ {
+			p.Publish(i, pubsub.LinearTreeTraverser([]string{"a", "b"}))
+		}
+
+		Expect(t, len(sub1.data)+len(sub2.data)+len(sub3.data)).To(Equal(100))
+		Expect(t, len(sub1.data)).To(BeAbove(0))
+		Expect(t, len(sub2.data)).To(BeAbove(0))
+		Expect(t, len(sub3.data)).To(BeAbove(0))
+		Expect(t, sub4.data).To(HaveLen(100))
+
+		p.Publish("some-data", pubsub.LinearTreeTraverser([]string{"a"}))
+		Expect(t, len(sub1.data)+len(sub2.data)+len(sub3.data)).To(Equal(101))
+		Expect(t, sub3.data).To(Not(Contain("some-data")))
+	})
+
+	o.Spec("it writes shard groups with the path that reached them", func(t *testing.T) {
+		for _, opts := range [][]pubsub.PubSubOption{nil, {pubsub.WithCrossNodeSharding()}} {
+			p := pubsub.New(opts...)
+			sub1 := newSpyPathSubscription()
+			sub2 := newSpyPathSubscription()
+			p.Subscribe(sub1, pubsub.WithShardID("1"), pubsub.WithPath([]string{"a", pubsub.Any}))
+			p.Subscribe(sub2, pubsub.WithShardID("1"), pubsub.WithPath([]string{"a", pubsub.Any}))
+
+			r, _ := p.PublishCtx(context.Background(), 1, pubsub.LinearTreeTraverser([]string{"a", "b"}))
+			Expect(t, r.Delivered).To(Equal(1))
+			Expect(t, append(sub1.paths(), sub2.paths()...)).To(Equal([][]string{{"a", "b"}}))
+		}
+	})
+}
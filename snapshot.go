@@ -0,0 +1,114 @@
+package pubsub
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+
+	"github.com/apoydence/pubsub/internal/node"
+)
+
+// snapshotVersion is the version of the format that Snapshot encodes.
+const snapshotVersion = 1
+
+type snapshot struct {
+	Version       int                    `json:"version"`
+	Subscriptions []snapshotSubscription `json:"subscriptions"`
+}
+
+type snapshotSubscription struct {
+	Path     []string          `json:"path"`
+	ShardID  string            `json:"shard_id,omitempty"`
+	Priority int               `json:"priority,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Name     string            `json:"name,omitempty"`
+}
+
+// Snapshot encodes the subscription topology (the path, shardID, priority,
+// metadata and name of each subscription) so that it can be rebuilt with
+// Restore, e.g., after a restart. Subscriptions held by mounted PubSubs are
+// included with their full paths. The Subscriptions themselves and any
+// other options (e.g., filters) are not encoded.
+func (s *PubSub) Snapshot() ([]byte, error) {
+	snap := snapshot{
+		Version:       snapshotVersion,
+		Subscriptions: s.snapshot(nil, nil),
+	}
+	return json.Marshal(snap)
+}
+
+// snapshot appends the subscriptions to subs with the prefix prepended to
+// their paths.
+func (s *PubSub) snapshot(prefix []string, subs []snapshotSubscription) []snapshotSubscription {
+	walk(s.tree.Load().root, nil, func(path []string, n *node.Node) {
+		path = append(append([]string(nil), prefix...), path...)
+
+		if m, ok := n.Data().(*PubSub); ok {
+			subs = m.snapshot(path, subs)
+			return
+		}
+
+		s.forEachSubscription(n, func(shardID string, ss []node.SubscriptionEnvelope) {
+			for _, x := range ss {
+				ssub := snapshotSubscription{
+					Path:     path,
+					ShardID:  shardID,
+					Priority: x.Priority(),
+				}
+				if sr, ok := x.Subscription.(*subscriber); ok {
+					ssub.Metadata = maps.Clone(sr.metadata)
+					ssub.Name = sr.name
+				}
+				subs = append(subs, ssub)
+			}
+		})
+	})
+
+	return subs
+}
+
+// Restore subscribes each subscription of a Snapshot. The resolver is
+// given the description of each subscription (without its Subscription)
+// and returns the Subscription to subscribe with, or nil to skip it. The
+// subscriptions are added atomically (see Batch) and do not replace the
+// existing ones, except for named ones (see WithName). Only the options
+// that Snapshot encodes are restored.
+func (s *PubSub) Restore(data []byte, resolver func(info SubscriptionInfo) Subscription) error {
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return err
+	}
+
+	if snap.Version != snapshotVersion {
+		return fmt.Errorf("unsupported snapshot version %d", snap.Version)
+	}
+
+	b := s.Batch()
+	for _, ssub := range snap.Subscriptions {
+		sub := resolver(SubscriptionInfo{
+			Path:     ssub.Path,
+			ShardID:  ssub.ShardID,
+			Metadata: maps.Clone(ssub.Metadata),
+			Name:     ssub.Name,
+		})
+		if sub == nil {
+			continue
+		}
+
+		opts := []SubscribeOption{
+			WithPath(ssub.Path),
+			WithShardID(ssub.ShardID),
+			WithPriority(ssub.Priority),
+		}
+		if ssub.Metadata != nil {
+			opts = append(opts, WithMetadata(ssub.Metadata))
+		}
+		if ssub.Name != "" {
+			opts = append(opts, WithName(ssub.Name))
+		}
+		b.Subscribe(sub, opts...)
+	}
+	b.Commit()
+
+	return nil
+}
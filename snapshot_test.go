@@ -0,0 +1,100 @@
+package pubsub_test
+
+import (
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+func TestPubSubSnapshot(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) TPS {
+		return TPS{
+			T:            t,
+			p:            pubsub.New(),
+			subscription: newSpySubscrption(),
+		}
+	})
+
+	o.Spec("it restores the subscription topology", func(t TPS) {
+		t.p.Subscribe(t.subscription,
+			pubsub.WithPath([]string{"a", pubsub.Any}),
+			pubsub.WithMetadata(map[string]string{"client": "x"}),
+			pubsub.WithName("x"),
+		)
+		t.p.Subscribe(t.subscription, pubsub.WithPath([]string{"b"}), pubsub.WithShardID("s"))
+		t.p.Subscribe(t.subscription, pubsub.WithPath([]string{"b"}), pubsub.WithPriority(1))
+
+		data, err := t.p.Snapshot()
+		Expect(t, err).To(BeNil())
+
+		p := pubsub.New()
+		subs := map[string]*spySubscription{}
+		var infos []pubsub.SubscriptionInfo
+		err = p.Restore(data, func(info pubsub.SubscriptionInfo) pubsub.Subscription {
+			infos = append(infos, info)
+			sub := newSpySubscrption()
+			subs[info.ShardID+info.Name] = sub
+			return sub
+		})
+		Expect(t, err).To(BeNil())
+
+		Expect(t, infos).To(Equal([]pubsub.SubscriptionInfo{
+			{Path: []string{"a", pubsub.Any}, Metadata: map[string]string{"client": "x"}, Name: "x"},
+			{Path: []string{"b"}},
+			{Path: []string{"b"}, ShardID: "s"},
+		}))
+		Expect(t, p.Paths()).To(Equal(t.p.Paths()))
+
+		info, ok := p.LookupName("x")
+		Expect(t, ok).To(BeTrue())
+		Expect(t, info.Metadata).To(Equal(map[string]string{"client": "x"}))
+
+		p.Publish(1, pubsub.LinearTreeTraverser([]string{"a", "z"}))
+		Expect(t, subs["x"].Data()).To(Equal([]interface{}{1}))
+	})
+
+	o.Spec("it includes the subscriptions of mounted PubSubs", func(t TPS) {
+		child := pubsub.New()
+		Expect(t, t.p.Mount([]string{"m"}, child)).To(BeNil())
+		t.p.Subscribe(t.subscription, pubsub.WithPath([]string{"m", "x"}))
+
+		data, err := t.p.Snapshot()
+		Expect(t, err).To(BeNil())
+
+		p := pubsub.New()
+		err = p.Restore(data, func(info pubsub.SubscriptionInfo) pubsub.Subscription {
+			return newSpySubscrption()
+		})
+		Expect(t, err).To(BeNil())
+		Expect(t, p.Paths()).To(Equal([][]string{{"m", "x"}}))
+	})
+
+	o.Spec("it skips subscriptions the resolver does not return", func(t TPS) {
+		t.p.Subscribe(t.subscription, pubsub.WithPath([]string{"a"}))
+		t.p.Subscribe(t.subscription, pubsub.WithPath([]string{"b"}))
+		data, err := t.p.Snapshot()
+		Expect(t, err).To(BeNil())
+
+		p := pubsub.New()
+		err = p.Restore(data, func(info pubsub.SubscriptionInfo) pubsub.Subscription {
+			if info.Path[0] == "a" {
+				return nil
+			}
+			return newSpySubscrption()
+		})
+		Expect(t, err).To(BeNil())
+		Expect(t, p.Paths()).To(Equal([][]string{{"b"}}))
+	})
+
+	o.Spec("it returns an error for invalid data", func(t TPS) {
+		Expect(t, t.p.Restore([]byte("{"), nil)).To(Not(BeNil()))
+		Expect(t, t.p.Restore([]byte(`{"version":2}`), nil)).To(Not(BeNil()))
+	})
+}
@@ -0,0 +1,562 @@
+package pubsub
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// subscriber is what is stored in the subscription tree for each
+// subscription. It applies the subscription's configuration before writing
+// to the Subscription.
+type subscriber struct {
+	orig    Subscription
+	sub     Subscription
+	q       *queuedSubscription
+	filters []func(data interface{}) bool
+	mappers []func(data interface{}) interface{}
+	sampler *sampler
+	deduper *deduper
+	pauser  *pauser
+	demand  *demander
+
+	// transformers are given by WithTransformer.
+	transformers []func(ctx context.Context, data interface{}, path []string) (interface{}, error)
+
+	// maxDeliveries is the number of writes before the subscription removes
+	// itself. Zero means there is no limit.
+	maxDeliveries int64
+	deliveries    int64
+
+	// pathAware is set when the Subscription implements
+	// PathAwareSubscription.
+	pathAware bool
+
+	// errs is set when the Subscription implements ErrSubscription.
+	errs bool
+
+	// aliases are the subscribers at the other paths of a subscription
+	// with several paths (see WithPaths). An alias only has primary, p and
+	// the fields that locate it in the tree set, and writes to primary.
+	aliases []*subscriber
+	primary *subscriber
+
+	// once is set if the subscriber must only be written once per publish,
+	// even if several of its paths (or other subscribers with the same
+	// once) match. It is either the Subscription (see
+	// WithSubscriptionDeduplication) or the primary subscriber (see
+	// WithPaths).
+	once interface{}
+
+	// priority orders the subscriber among the others at its node (see
+	// WithPriority).
+	priority int
+
+	// metadata is given by WithMetadata.
+	metadata map[string]string
+
+	// name is given by WithName. names is the registry that holds it.
+	name  string
+	names *nameRegistry
+
+	batcher   *batcher
+	coalescer *coalescer
+
+	// acked is set when the Subscription implements AckedSubscription.
+	acked *ackedSubscription
+
+	// subscribed is when the subscriber was added. lastDelivered is when
+	// data was last forwarded (in Unix nanoseconds), but it is only tracked
+	// if track is set as it requires reading the clock.
+	subscribed    time.Time
+	lastDelivered atomic.Int64
+	track         bool
+
+	// disconnect removes the subscription from the PubSub.
+	disconnect func()
+
+	// p is the PubSub that holds the subscriber. It may be a mounted one.
+	p *PubSub
+
+	// The fields below are guarded by p's lock. path, shardID and id locate
+	// the subscriber in the subscription tree.
+	path    []string
+	shardID string
+	id      int64
+	stopCtx func() bool
+	stopTTL func() bool
+
+	// evictPrev and evictNext link the subscriber into p's evictionIndex
+	// (if evictIndexed is set). They are guarded by the index.
+	evictPrev, evictNext *subscriber
+	evictIndexed         bool
+
+	// removed is set while holding p's lock. Publishes check it so that
+	// they do not write to the subscriber once it has been removed.
+	removed atomic.Bool
+
+	// writing is the number of writes to the subscriber that are in
+	// progress (see enter). Once it has been removed and none are left,
+	// idle is closed.
+	writing  atomic.Int64
+	idle     chan struct{}
+	idleOnce sync.Once
+
+	// subtree is the subtree lock that guards the fields above (see
+	// lockSubscriber). It is only changed while holding that lock.
+	subtree atomic.Int32
+
+	// busy is set while a goroutine writes the subscriber's retained and
+	// replayed data (see writeHistory) or the data of ordered publishes
+	// (see order). Publishes that reach the subscriber meanwhile are held
+	// (guarded by heldMu) and written by that goroutine in turn.
+	busy   atomic.Bool
+	heldMu sync.Mutex
+	held   []heldWrite
+}
+
+// heldWrite is data that is waiting for the subscriber to no longer be
+// busy. admitted is set if it has already passed the filters and sampling.
+type heldWrite struct {
+	ctx      context.Context
+	data     interface{}
+	path     []string
+	admitted bool
+}
+
+// newSubscriber must be invoked while holding the write lock.
+func (s *PubSub) newSubscriber(sub Subscription, c subscribeConfig) *subscriber {
+	orig := sub
+	sub = s.intercept(sub, c.path)
+
+	var acked *ackedSubscription
+	if a, ok := sub.(AckedSubscription); ok {
+		acked = newAckedSubscription(a, c, s)
+		sub = acked
+	}
+
+	var errs bool
+	if e, ok := sub.(ErrSubscription); ok {
+		sub = &errSubscription{sub: e, path: c.path, retries: c.retries, p: s}
+		errs = true
+	}
+	_, pathAware := sub.(PathAwareSubscription)
+
+	if s.metrics != nil {
+		sub = meteredSubscription{Subscription: sub, path: c.path, m: s.metrics}
+		s.metrics.Subscribed(c.path)
+	}
+	s.debug(context.Background(), "subscribed", pathAttr(c.path))
+
+	sr := &subscriber{
+		idle:    make(chan struct{}),
+		orig:    orig,
+		sub:     sub,
+		q:       s.newQueuedSubscription(sub, c, errs),
+		filters: c.filters,
+		mappers: c.mappers,
+		sampler: newSampler(c, s.rand),
+		deduper: newDeduper(c, s.clock),
+
+		transformers: c.transformers,
+
+		name:  c.name,
+		names: c.names,
+
+		maxDeliveries: c.maxDeliveries,
+		pathAware:     pathAware,
+		errs:          errs,
+		acked:         acked,
+		track:         s.evictionPolicy != nil || s.idleTimeout > 0,
+	}
+
+	if s.dedupeSubscriptions && orig != nil && reflect.TypeOf(orig).Comparable() {
+		sr.once = orig
+	}
+
+	if c.pausable {
+		sr.pauser = newPauser(c.pauseBufferSize, sr.forward)
+	}
+
+	if c.pull {
+		sr.demand = newDemander(c.bufferSize, sr.forward)
+	}
+
+	if c.batchSize > 0 {
+		sr.batcher = newBatcher(c.batchSize, c.batchDelay, s.clock, func(batch []interface{}) {
+			sr.deliver(context.Background(), batch, nil)
+		})
+	}
+
+	if c.coalesce > 0 {
+		sr.coalescer = newCoalescer(c.coalesce, c.throttle, s.clock, sr.emit)
+	}
+
+	return sr
+}
+
+// Write implements Subscription.
+func (s *subscriber) Write(data interface{}) {
+	s.write(context.Background(), data, nil)
+}
+
+// write returns if the data was written (or enqueued), how many entries
+// were dropped and the error of an ErrSubscription. The context is from
+// the publish and the path is the one that was traversed to reach the
+// subscriber (nil if it is not known).
+func (s *subscriber) write(ctx context.Context, data interface{}, path []string) (bool, int, error) {
+	if s.primary != nil {
+		return s.primary.write(ctx, data, path)
+	}
+
+	if s.busy.Load() && s.hold(ctx, data, path) {
+		return true, 0, nil
+	}
+
+	return s.receive(ctx, data, path)
+}
+
+// receive is like write, but it does not wait for the subscriber to no
+// longer be busy.
+func (s *subscriber) receive(ctx context.Context, data interface{}, path []string) (bool, int, error) {
+	// A publish that started before the subscriber was removed might still
+	// reach it.
+	if !s.enter() {
+		return false, 0, nil
+	}
+	defer s.exit()
+
+	if !s.admit(data) {
+		return false, 0, nil
+	}
+
+	return s.accept(ctx, data, path)
+}
+
+// admit returns false if the data is filtered out or sampled out.
+func (s *subscriber) admit(data interface{}) bool {
+	for _, f := range s.filters {
+		if !f(data) {
+			return false
+		}
+	}
+
+	if s.deduper != nil && s.deduper.duplicate(data) {
+		return false
+	}
+
+	return s.sampler == nil || s.sampler.sample()
+}
+
+// accept writes data that has been admitted.
+func (s *subscriber) accept(ctx context.Context, data interface{}, path []string) (bool, int, error) {
+	if s.pauser != nil {
+		return s.pauser.write(ctx, data, path)
+	}
+
+	if s.demand != nil {
+		return s.demand.write(ctx, data, path)
+	}
+
+	return s.forward(ctx, data, path)
+}
+
+// hold holds data that is published while the subscriber is busy. It
+// returns false if it is no longer busy.
+func (s *subscriber) hold(ctx context.Context, data interface{}, path []string) bool {
+	s.heldMu.Lock()
+	defer s.heldMu.Unlock()
+
+	if !s.busy.Load() {
+		return false
+	}
+
+	s.held = append(s.held, heldWrite{ctx: ctx, data: data, path: slices.Clone(path)})
+	return true
+}
+
+// order holds the data of an ordered publish (see WithOrderedDelivery). It
+// must be invoked while holding the PubSub's orderMu, so that the data is
+// held in publish order. It returns false if the data was not admitted.
+// If the subscriber was not busy, claimed is set and the caller must write
+// the held data with flush once orderMu is released.
+func (s *subscriber) order(ctx context.Context, data interface{}, path []string) (ok, claimed bool) {
+	if s.primary != nil {
+		return s.primary.order(ctx, data, path)
+	}
+
+	if !s.enter() {
+		return false, false
+	}
+	defer s.exit()
+
+	if !s.admit(data) {
+		return false, false
+	}
+
+	s.heldMu.Lock()
+	defer s.heldMu.Unlock()
+
+	s.held = append(s.held, heldWrite{ctx: ctx, data: data, path: slices.Clone(path), admitted: true})
+	if s.busy.Load() {
+		return true, false
+	}
+	s.busy.Store(true)
+	return true, true
+}
+
+// writeHistory writes the retained and replayed data (see
+// PubSub.historyFor) and then the data that was held meanwhile. It must be
+// invoked without holding any of the PubSub's locks, as the Subscription
+// may subscribe or publish from within its Write.
+func (s *subscriber) writeHistory(data []interface{}) {
+	for _, d := range data {
+		s.receive(context.Background(), d, nil)
+	}
+	s.flush()
+}
+
+// flush writes the held data until there is none left and the subscriber
+// is no longer busy. It must only be invoked by the goroutine that made
+// the subscriber busy and without holding any of the PubSub's locks.
+func (s *subscriber) flush() {
+	for {
+		s.heldMu.Lock()
+		held := s.held
+		s.held = nil
+		if len(held) == 0 {
+			s.busy.Store(false)
+		}
+		s.heldMu.Unlock()
+
+		if len(held) == 0 {
+			return
+		}
+
+		for _, h := range held {
+			if !h.admitted {
+				s.receive(h.ctx, h.data, h.path)
+				continue
+			}
+
+			if s.enter() {
+				s.accept(h.ctx, h.data, h.path)
+				s.exit()
+			}
+		}
+	}
+}
+
+// forward writes data that has passed the filters and sampling.
+func (s *subscriber) forward(ctx context.Context, data interface{}, path []string) (bool, int, error) {
+	data, err := s.transform(ctx, data, path)
+	if err != nil {
+		if c := completionFrom(ctx); c != nil {
+			c.fail(err)
+		}
+		return false, 0, nil
+	}
+
+	if s.maxDeliveries > 0 {
+		n := atomic.AddInt64(&s.deliveries, 1)
+		if n > s.maxDeliveries {
+			return false, 0, nil
+		}
+
+		if n == s.maxDeliveries {
+			// The disconnect has to wait for the lock that the publisher
+			// is holding.
+			defer func() {
+				go s.disconnect()
+			}()
+		}
+	}
+
+	if s.track {
+		s.lastDelivered.Store(s.p.clock.Now().UnixNano())
+		if s.p.evictionIndex != nil {
+			s.p.evictionIndex.touch(s)
+		}
+	}
+
+	if s.coalescer != nil {
+		s.coalescer.add(data, s.copyPath(path))
+		return true, 0, nil
+	}
+
+	if s.batcher != nil {
+		s.batcher.add(data)
+		return true, 0, nil
+	}
+
+	return s.deliver(ctx, data, path)
+}
+
+// emit is invoked with data that has been debounced or throttled.
+func (s *subscriber) emit(data interface{}, path []string) {
+	if s.batcher != nil {
+		s.batcher.add(data)
+		return
+	}
+
+	s.deliver(context.Background(), data, path)
+}
+
+// deliver writes the data to the queue or Subscription.
+func (s *subscriber) deliver(ctx context.Context, data interface{}, path []string) (bool, int, error) {
+	// Data that was buffered (e.g., by a batch) is still delivered once the
+	// subscriber is removed, but it is counted as a write in progress.
+	s.writing.Add(1)
+	defer s.exit()
+
+	if s.pathAware && path != nil {
+		path = s.copyPath(path)
+		if s.q != nil {
+			ok, dropped := s.q.writeCtx(ctx, pathData{data: data, path: path})
+			return ok, dropped, nil
+		}
+
+		s.sub.(PathAwareSubscription).WritePath(data, path)
+		return true, 0, nil
+	}
+
+	if s.q != nil {
+		ok, dropped := s.q.writeCtx(ctx, data)
+		return ok, dropped, nil
+	}
+
+	if f, ok := s.sub.(forwardingSubscription); ok {
+		f.forward(ctx, s.p, data)
+		return true, 0, nil
+	}
+
+	if s.errs {
+		return true, 0, s.sub.(errWriter).writeErr(data)
+	}
+
+	s.sub.Write(data)
+	return true, 0, nil
+}
+
+// copyPath returns a copy of the path if it will be handed to the
+// Subscription. The path that is traversed is reused by the publish.
+func (s *subscriber) copyPath(path []string) []string {
+	if !s.pathAware || path == nil {
+		return nil
+	}
+	return append([]string{}, path...)
+}
+
+// Close implements Closer.
+func (s *subscriber) Close() {
+	if s.coalescer != nil {
+		s.coalescer.stop()
+	}
+
+	if s.batcher != nil {
+		s.batcher.stop()
+	}
+
+	if s.q != nil {
+		s.q.Close()
+		return
+	}
+
+	closeSubscription(s.sub)
+}
+
+// drain waits for the data that is queued for the subscriber to be written.
+// It must only be invoked once the subscriber has been removed.
+func (s *subscriber) drain() {
+	if s.q != nil {
+		<-s.q.done
+	}
+}
+
+// enter counts a write to the subscriber as in progress. It returns false
+// (and does not count it) if the subscriber has been removed. Otherwise,
+// exit must be invoked once the write is done. As removed is set before
+// writing is checked (see afterWrites), a write either sees that the
+// subscriber was removed or is waited on.
+func (s *subscriber) enter() bool {
+	s.writing.Add(1)
+	if s.removed.Load() {
+		s.exit()
+		return false
+	}
+	return true
+}
+
+func (s *subscriber) exit() {
+	if s.writing.Add(-1) == 0 && s.removed.Load() {
+		s.idleOnce.Do(func() {
+			close(s.idle)
+		})
+	}
+}
+
+// afterWrites invokes f once no writes to the subscriber are in progress.
+// It must only be invoked once the subscriber has been removed. If writes
+// are in progress, it does not wait for them, as it might have been invoked
+// from within one of them (e.g., by a Subscription that unsubscribes
+// itself). f is instead invoked in its own goroutine once they are done.
+func (s *subscriber) afterWrites(f func()) {
+	if s.writing.Load() == 0 {
+		f()
+		return
+	}
+
+	go func() {
+		<-s.idle
+		f()
+	}()
+}
+
+// lastDeliveredAt returns when data was last forwarded to the subscriber
+// (see track). It returns the zero time if nothing has been.
+func (s *subscriber) lastDeliveredAt() time.Time {
+	ns := s.lastDelivered.Load()
+	if ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ns)
+}
+
+// evict is invoked once a subscriber that was evicted (see
+// WithEvictionPolicy and WithIdleEviction) has been removed from the
+// subscription tree. Unlike an unsubscribe, the Subscription is closed.
+func (s *subscriber) evict() {
+	s.Close()
+	s.stop()
+}
+
+// stop is invoked once the subscriber has been removed from the
+// subscription tree.
+func (s *subscriber) stop() {
+	if s.pauser != nil {
+		s.pauser.stop()
+	}
+
+	if s.demand != nil {
+		s.demand.stop()
+	}
+
+	if s.coalescer != nil {
+		s.coalescer.stop()
+	}
+
+	if s.batcher != nil {
+		s.batcher.stop()
+	}
+
+	if s.q != nil {
+		s.q.stop()
+	}
+
+	if s.acked != nil {
+		s.acked.stop()
+	}
+}
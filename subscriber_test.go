@@ -0,0 +1,233 @@
+package pubsub_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+func TestPubSubSubscribeOptions(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+	o.BeforeEach(func(t *testing.T) TPS {
+		return TPS{
+			T: t,
+			p: pubsub.New(),
+		}
+	})
+
+	o.Spec("it only writes data that passes each filter", func(t TPS) {
+		sub := newSpySubscrption()
+		t.p.Subscribe(sub,
+			pubsub.WithFilter(func(data interface{}) bool {
+				return data.(int)%2 == 0
+			}),
+			pubsub.WithFilter(func(data interface{}) bool {
+				return data.(int)%3 == 0
+			}),
+		)
+
+		var delivered int
+		for i := 0; i < 10; i++ {
+			r, _ := t.p.PublishCtx(context.Background(), i, pubsub.LinearTreeTraverser(nil))
+			delivered += r.Delivered
+		}
+
+		Expect(t, sub.data).To(Equal([]interface{}{0, 6}))
+		Expect(t, delivered).To(Equal(2))
+	})
+
+	o.Spec("it writes the mapped data", func(t TPS) {
+		sub1 := newSpySubscrption()
+		sub2 := newSpySubscrption()
+		t.p.Subscribe(sub1,
+			pubsub.WithFilter(func(data interface{}) bool {
+				return data.(int) > 0
+			}),
+			pubsub.WithMapper(func(data interface{}) interface{} {
+				return data.(int) * 2
+			}),
+			pubsub.WithMapper(func(data interface{}) interface{} {
+				return data.(int) + 1
+			}),
+		)
+		t.p.Subscribe(sub2)
+
+		t.p.Publish(0, pubsub.LinearTreeTraverser(nil))
+		t.p.Publish(1, pubsub.LinearTreeTraverser(nil))
+		t.p.Publish(2, pubsub.LinearTreeTraverser(nil))
+
+		Expect(t, sub1.data).To(Equal([]interface{}{3, 5}))
+		Expect(t, sub2.data).To(Equal([]interface{}{0, 1, 2}))
+	})
+
+	o.Spec("it writes batches once they are full", func(t TPS) {
+		sub := newSpySubscrption()
+		unsubscribe := t.p.Subscribe(sub, pubsub.WithBatching(2, 0))
+
+		for i := 0; i < 5; i++ {
+			t.p.Publish(i, pubsub.LinearTreeTraverser(nil))
+		}
+		Expect(t, sub.Data()).To(Equal([]interface{}{
+			[]interface{}{0, 1},
+			[]interface{}{2, 3},
+		}))
+
+		unsubscribe()
+		Expect(t, sub.Data()).To(Equal([]interface{}{
+			[]interface{}{0, 1},
+			[]interface{}{2, 3},
+			[]interface{}{4},
+		}))
+	})
+
+	o.Spec("it writes batches after the delay", func(t TPS) {
+		sub := newSpySubscrption()
+		t.p.Subscribe(sub, pubsub.WithBatching(100, time.Millisecond))
+
+		t.p.Publish(0, pubsub.LinearTreeTraverser(nil))
+		t.p.Publish(1, pubsub.LinearTreeTraverser(nil))
+
+		Expect(t, sub.Len).To(ViaPolling(Equal(1)))
+		Expect(t, sub.Data()).To(Equal([]interface{}{
+			[]interface{}{0, 1},
+		}))
+	})
+
+	o.Spec("it only writes the latest data once debounced", func(t TPS) {
+		sub := newSpySubscrption()
+		t.p.Subscribe(sub, pubsub.WithDebounce(10*time.Millisecond))
+
+		for i := 0; i < 5; i++ {
+			t.p.Publish(i, pubsub.LinearTreeTraverser(nil))
+		}
+
+		Expect(t, sub.Len).To(ViaPolling(Equal(1)))
+		Expect(t, sub.Data()).To(Equal([]interface{}{4}))
+	})
+
+	o.Spec("it writes the first and latest data when throttled", func(t TPS) {
+		sub := newSpySubscrption()
+		unsubscribe := t.p.Subscribe(sub, pubsub.WithThrottle(time.Hour))
+
+		for i := 0; i < 5; i++ {
+			t.p.Publish(i, pubsub.LinearTreeTraverser(nil))
+		}
+		Expect(t, sub.Data()).To(Equal([]interface{}{0}))
+
+		unsubscribe()
+		Expect(t, sub.Data()).To(Equal([]interface{}{0, 4}))
+	})
+
+	o.Spec("it only writes every nth message when sampled", func(t TPS) {
+		sub := newSpySubscrption()
+		t.p.Subscribe(sub,
+			pubsub.WithFilter(func(data interface{}) bool {
+				return data.(int)%2 == 0
+			}),
+			pubsub.WithSampling(3),
+		)
+
+		for i := 0; i < 20; i++ {
+			t.p.Publish(i, pubsub.LinearTreeTraverser(nil))
+		}
+
+		Expect(t, sub.Data()).To(Equal([]interface{}{0, 6, 12, 18}))
+	})
+
+	o.Spec("it writes a fraction of the messages when sampled by rate", func(t TPS) {
+		sub := newSpySubscrption()
+		t.p.Subscribe(sub, pubsub.WithSampleRate(0.5))
+
+		for i := 0; i < 1000; i++ {
+			t.p.Publish(i, pubsub.LinearTreeTraverser(nil))
+		}
+
+		Expect(t, sub.Len()).To(And(BeAbove(300), BeBelow(700)))
+	})
+
+	o.Spec("it writes nothing when sampled at a rate of zero", func(t TPS) {
+		sub := newSpySubscrption()
+		t.p.Subscribe(sub, pubsub.WithSampleRate(0))
+
+		for i := 0; i < 100; i++ {
+			t.p.Publish(i, pubsub.LinearTreeTraverser(nil))
+		}
+
+		Expect(t, sub.Len()).To(Equal(0))
+	})
+
+	o.Spec("it removes the subscription after the max deliveries", func(t TPS) {
+		sub := newSpySubscrption()
+		t.p.Subscribe(sub, pubsub.WithMaxDeliveries(2))
+
+		for i := 0; i < 5; i++ {
+			t.p.Publish(i, pubsub.LinearTreeTraverser(nil))
+		}
+
+		Expect(t, sub.Data()).To(Equal([]interface{}{0, 1}))
+		Expect(t, func() int {
+			return t.p.Subscriptions()
+		}).To(ViaPolling(Equal(0)))
+	})
+
+	o.Spec("it only writes once when subscribed with max deliveries of 1", func(t TPS) {
+		sub := newSpySubscrption()
+		t.p.Subscribe(sub, pubsub.WithMaxDeliveries(1))
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				t.p.Publish(i, pubsub.LinearTreeTraverser(nil))
+			}(i)
+		}
+		wg.Wait()
+
+		Expect(t, sub.Len()).To(Equal(1))
+	})
+
+	o.Spec("it removes the subscription after the TTL", func(t TPS) {
+		clock := newSpyClock()
+		p := pubsub.New(pubsub.WithClock(clock))
+
+		var expired int32
+		sub := newSpySubscrption()
+		p.Subscribe(sub,
+			pubsub.WithTTL(time.Millisecond),
+			pubsub.WithExpiryFunc(func() { atomic.AddInt32(&expired, 1) }),
+		)
+
+		clock.advance(time.Millisecond)
+		Expect(t, atomic.LoadInt32(&expired)).To(Equal(int32(1)))
+		Expect(t, p.Subscriptions()).To(Equal(0))
+
+		p.Publish(1, pubsub.LinearTreeTraverser(nil))
+		Expect(t, sub.Len()).To(Equal(0))
+	})
+
+	o.Spec("it does not invoke the expiry func when unsubscribed", func(t TPS) {
+		clock := newSpyClock()
+		p := pubsub.New(pubsub.WithClock(clock))
+
+		var expired int32
+		unsubscribe := p.Subscribe(newSpySubscrption(),
+			pubsub.WithTTL(10*time.Millisecond),
+			pubsub.WithExpiryFunc(func() { atomic.AddInt32(&expired, 1) }),
+		)
+		unsubscribe()
+		Expect(t, clock.pending()).To(Equal(0))
+
+		clock.advance(20 * time.Millisecond)
+		Expect(t, atomic.LoadInt32(&expired)).To(Equal(int32(0)))
+	})
+}
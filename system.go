@@ -0,0 +1,99 @@
+package pubsub
+
+import "strconv"
+
+// The paths that system events are published to (see WithSystemEvents)
+// are made of SystemPrefix and one of the other segments, e.g.,
+// []string{SystemPrefix, SystemSubscriptions}.
+const (
+	SystemPrefix = "$sys"
+
+	// SystemSubscriptions is where SubscriptionAdded and
+	// SubscriptionRemoved events are published.
+	SystemSubscriptions = "subscriptions"
+
+	// SystemPaths is where PathCreated and PathPruned events are
+	// published.
+	SystemPaths = "paths"
+
+	// SystemDrops is where DataDropped events are published.
+	SystemDrops = "drops"
+)
+
+// SystemEventType is the type of a SystemEvent.
+type SystemEventType int
+
+const (
+	// SubscriptionAdded is published when a subscription is added (or
+	// moved to the path).
+	SubscriptionAdded SystemEventType = iota
+
+	// SubscriptionRemoved is published when a subscription is removed (or
+	// moved from the path).
+	SubscriptionRemoved
+
+	// PathCreated is published when a path gains its first subscription.
+	PathCreated
+
+	// PathPruned is published when a path loses its last subscription.
+	PathPruned
+
+	// DataDropped is published when data is dropped because a subscription
+	// could not keep up (see OverflowStrategy).
+	DataDropped
+)
+
+// String implements fmt.Stringer.
+func (t SystemEventType) String() string {
+	switch t {
+	case SubscriptionAdded:
+		return "SubscriptionAdded"
+	case SubscriptionRemoved:
+		return "SubscriptionRemoved"
+	case PathCreated:
+		return "PathCreated"
+	case PathPruned:
+		return "PathPruned"
+	case DataDropped:
+		return "DataDropped"
+	default:
+		return "SystemEventType(" + strconv.Itoa(int(t)) + ")"
+	}
+}
+
+// SystemEvent is published by a PubSub that was configured with
+// WithSystemEvents.
+type SystemEvent struct {
+	Type SystemEventType
+
+	// Path is the path of the subscription (or of the path that was
+	// created or pruned).
+	Path []string
+}
+
+// WithSystemEvents configures a PubSub to publish a SystemEvent to itself
+// for each of its lifecycle events, so that applications can react to
+// changes of the subscription tree by subscribing to the system paths (see
+// SystemPrefix). The events are published in the order they occurred, from
+// whichever goroutine caused them, once it has released the PubSub's
+// locks. Dropped data is only reported for subscriptions that are written
+// to from their own goroutine (see WithAsyncDelivery).
+//
+// Events are not published for the system paths themselves. Subscriptions
+// that are interested in every path (e.g., at the root or with Rest)
+// receive the events as well, unless WithMQTTMatching hides them.
+func WithSystemEvents() PubSubOption {
+	return pubsubConfigFunc(func(p *PubSub) {
+		p.hooks.sys = p.publishSystemEvent
+	})
+}
+
+func (s *PubSub) publishSystemEvent(segment string, e SystemEvent) {
+	s.Publish(e, LinearTreeTraverser{SystemPrefix, segment})
+}
+
+// isSystemPath reports whether the path is one that system events are
+// published to.
+func isSystemPath(path []string) bool {
+	return len(path) > 0 && path[0] == SystemPrefix
+}
@@ -0,0 +1,86 @@
+package pubsub_test
+
+import (
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+func TestPubSubSystemEvents(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	type TS struct {
+		*testing.T
+		p             *pubsub.PubSub
+		subscriptions *spySubscription
+		paths         *spySubscription
+		drops         *spySubscription
+	}
+
+	o.BeforeEach(func(t *testing.T) TS {
+		p := pubsub.New(pubsub.WithSystemEvents())
+		ts := TS{
+			T:             t,
+			p:             p,
+			subscriptions: newSpySubscrption(),
+			paths:         newSpySubscrption(),
+			drops:         newSpySubscrption(),
+		}
+		p.Subscribe(ts.subscriptions, pubsub.WithPath([]string{pubsub.SystemPrefix, pubsub.SystemSubscriptions}))
+		p.Subscribe(ts.paths, pubsub.WithPath([]string{pubsub.SystemPrefix, pubsub.SystemPaths}))
+		p.Subscribe(ts.drops, pubsub.WithPath([]string{pubsub.SystemPrefix, pubsub.SystemDrops}))
+		return ts
+	})
+
+	o.Spec("it publishes subscription events", func(t TS) {
+		unsubscribe := t.p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"a"}))
+		unsubscribe()
+
+		Expect(t, t.subscriptions.Data()).To(Equal([]interface{}{
+			pubsub.SystemEvent{Type: pubsub.SubscriptionAdded, Path: []string{"a"}},
+			pubsub.SystemEvent{Type: pubsub.SubscriptionRemoved, Path: []string{"a"}},
+		}))
+	})
+
+	o.Spec("it publishes path events", func(t TS) {
+		unsubscribe1 := t.p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"a"}))
+		unsubscribe2 := t.p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"a"}))
+		unsubscribe1()
+		unsubscribe2()
+
+		Expect(t, t.paths.Data()).To(Equal([]interface{}{
+			pubsub.SystemEvent{Type: pubsub.PathCreated, Path: []string{"a"}},
+			pubsub.SystemEvent{Type: pubsub.PathPruned, Path: []string{"a"}},
+		}))
+	})
+
+	o.Spec("it publishes drop events", func(t TS) {
+		block := make(chan struct{})
+		defer close(block)
+		t.p.Subscribe(pubsub.SubscriptionFunc(func(interface{}) { <-block }),
+			pubsub.WithPath([]string{"a"}),
+			pubsub.WithBufferSize(1),
+			pubsub.WithOverflowStrategy(pubsub.OverflowDrop),
+		)
+
+		// The first data is written (or queued), the second is queued and
+		// at least the third is dropped.
+		for i := 0; i < 3; i++ {
+			t.p.Publish(i, pubsub.LinearTreeTraverser([]string{"a"}))
+		}
+
+		Expect(t, t.drops.Len()).To(BeAbove(0))
+		Expect(t, t.drops.Data()[0]).To(Equal(pubsub.SystemEvent{Type: pubsub.DataDropped, Path: []string{"a"}}))
+	})
+
+	o.Spec("it does not publish events for the system paths", func(t TS) {
+		t.p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{pubsub.SystemPrefix, pubsub.SystemSubscriptions}))
+		Expect(t, t.subscriptions.Len()).To(Equal(0))
+		Expect(t, t.paths.Len()).To(Equal(0))
+	})
+}
@@ -0,0 +1,130 @@
+package pubsub
+
+import (
+	"sync"
+	"time"
+)
+
+// WithDebounce configures a subscription to only be written the latest data
+// once no new data has been published to it for the given duration.
+func WithDebounce(d time.Duration) SubscribeOption {
+	return subscribeConfigFunc(func(c *subscribeConfig) {
+		c.coalesce = d
+		c.throttle = false
+	})
+}
+
+// WithThrottle configures a subscription to be written at most once per the
+// given interval. Data published while the subscription is throttled is
+// coalesced, with only the latest being written at the end of the interval.
+func WithThrottle(interval time.Duration) SubscribeOption {
+	return subscribeConfigFunc(func(c *subscribeConfig) {
+		c.coalesce = interval
+		c.throttle = true
+	})
+}
+
+// coalescer debounces or throttles data, keeping only the latest.
+type coalescer struct {
+	d        time.Duration
+	throttle bool
+	emit     func(data interface{}, path []string)
+	clock    Clock
+
+	mu          sync.Mutex
+	pending     interface{}
+	pendingPath []string
+	hasPending  bool
+	timer       Timer
+	gen         int
+	stopped     bool
+}
+
+func newCoalescer(d time.Duration, throttle bool, clock Clock, emit func(data interface{}, path []string)) *coalescer {
+	return &coalescer{
+		d:        d,
+		throttle: throttle,
+		emit:     emit,
+		clock:    clock,
+	}
+}
+
+func (c *coalescer) add(data interface{}, path []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.stopped {
+		return
+	}
+
+	if c.throttle && c.timer == nil {
+		c.emit(data, path)
+		c.startTimerLocked()
+		return
+	}
+
+	c.pending = data
+	c.pendingPath = path
+	c.hasPending = true
+
+	if !c.throttle {
+		c.startTimerLocked()
+	}
+}
+
+func (c *coalescer) startTimerLocked() {
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+	c.gen++
+
+	gen := c.gen
+	c.timer = c.clock.AfterFunc(c.d, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		// The timer may have been reset or stopped.
+		if gen != c.gen || c.stopped {
+			return
+		}
+		c.timer = nil
+
+		if !c.hasPending {
+			return
+		}
+		c.flushLocked()
+
+		if c.throttle {
+			c.startTimerLocked()
+		}
+	})
+}
+
+// stop writes any pending data. Anything added afterwards is dropped.
+func (c *coalescer) stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.stopped {
+		return
+	}
+
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	c.gen++
+
+	if c.hasPending {
+		c.flushLocked()
+	}
+	c.stopped = true
+}
+
+func (c *coalescer) flushLocked() {
+	data, path := c.pending, c.pendingPath
+	c.pending = nil
+	c.pendingPath = nil
+	c.hasPending = false
+	c.emit(data, path)
+}
@@ -0,0 +1,170 @@
+package pubsub
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidTopic is returned when a topic string can not be parsed.
+var ErrInvalidTopic = errors.New("invalid topic")
+
+// WithTopicDelimiter configures the rune that separates the segments of a
+// topic string (see SubscribeTopic and PublishTopic). It defaults to '.'.
+func WithTopicDelimiter(delim rune) PubSubOption {
+	return pubsubConfigFunc(func(p *PubSub) {
+		p.topicDelim = delim
+	})
+}
+
+// SubscribeTopic adds a subscription (see SubscribeErr) with the path parsed
+// from the topic string (see ParseTopic and WithMQTTMatching). For example, the topic
+// "orders.us.*.created" is the path
+// []string{"orders", "us", pubsub.Any, "created"}. Any path given with
+// WithPath is replaced.
+func (s *PubSub) SubscribeTopic(sub Subscription, topic string, opts ...SubscribeOption) (Unsubscriber, error) {
+	path, err := s.parseTopic(topic, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.SubscribeErr(sub, append(opts, WithPath(path))...)
+}
+
+// PublishTopic publishes the data (see Publish) with a LinearTreeTraverser
+// of the path parsed from the topic string (see ParseTopic). The topic must
+// not contain any wildcards.
+func (s *PubSub) PublishTopic(d interface{}, topic string, opts ...PublishOption) error {
+	path, err := s.parseTopic(topic, false)
+	if err != nil {
+		return err
+	}
+
+	s.Publish(d, LinearTreeTraverser(path), opts...)
+	return nil
+}
+
+// parseTopic parses the topic with ParseMQTTTopic if the PubSub was
+// configured with WithMQTTMatching and ParseTopic otherwise.
+func (s *PubSub) parseTopic(topic string, wildcards bool) ([]string, error) {
+	if s.mqtt {
+		return parseMQTTTopic(topic, wildcards)
+	}
+
+	delim := s.topicDelim
+	if delim == 0 {
+		delim = '.'
+	}
+	return parseTopic(topic, delim, wildcards)
+}
+
+// ParseTopic splits the topic string into a path at each delimiter. A
+// segment of "*" is Any and a last segment of ">" is Rest. A backslash
+// escapes the next rune, so `a\.b` is the single segment "a.b" (with a '.'
+// delimiter) and `\*` is a literal "*". An empty topic is the root path.
+// Segments must not be empty.
+func ParseTopic(topic string, delim rune) ([]string, error) {
+	return parseTopic(topic, delim, true)
+}
+
+func parseTopic(topic string, delim rune, wildcards bool) ([]string, error) {
+	if topic == "" {
+		return nil, nil
+	}
+
+	var (
+		path    []string
+		seg     strings.Builder
+		escaped bool
+
+		// literal is set if the segment has an escaped rune, in which
+		// case it is not a wildcard.
+		literal bool
+	)
+	end := func() error {
+		s := seg.String()
+		seg.Reset()
+
+		switch {
+		case s == "":
+			return fmt.Errorf("%w %q: empty segment", ErrInvalidTopic, topic)
+		case literal:
+		case s == "*" || s == ">":
+			if !wildcards {
+				return fmt.Errorf("%w %q: wildcards are not allowed", ErrInvalidTopic, topic)
+			}
+
+			if s == "*" {
+				s = Any
+			} else {
+				s = Rest
+			}
+		}
+
+		literal = false
+		path = append(path, s)
+		return nil
+	}
+
+	for _, r := range topic {
+		switch {
+		case escaped:
+			seg.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+			literal = true
+		case r == delim:
+			if err := end(); err != nil {
+				return nil, err
+			}
+		default:
+			seg.WriteRune(r)
+		}
+	}
+
+	if escaped {
+		return nil, fmt.Errorf("%w %q: trailing escape", ErrInvalidTopic, topic)
+	}
+	if err := end(); err != nil {
+		return nil, err
+	}
+
+	for i, s := range path[:len(path)-1] {
+		if s == Rest {
+			return nil, fmt.Errorf("%w %q: '>' must be the last segment (found at %d)", ErrInvalidTopic, topic, i)
+		}
+	}
+
+	return path, nil
+}
+
+// FormatTopic is the inverse of ParseTopic. It joins the path into a topic
+// string, escaping any delimiters, backslashes and literal wildcards.
+func FormatTopic(path []string, delim rune) string {
+	var b strings.Builder
+	for i, s := range path {
+		if i > 0 {
+			b.WriteRune(delim)
+		}
+
+		switch s {
+		case Any:
+			b.WriteByte('*')
+			continue
+		case Rest:
+			b.WriteByte('>')
+			continue
+		case "*", ">":
+			b.WriteByte('\\')
+		}
+
+		for _, r := range s {
+			if r == delim || r == '\\' {
+				b.WriteByte('\\')
+			}
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
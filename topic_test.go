@@ -0,0 +1,119 @@
+package pubsub_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+func TestParseTopic(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.Spec("it splits the topic at each delimiter", func(t *testing.T) {
+		path, err := pubsub.ParseTopic("orders.us.west", '.')
+		Expect(t, err).To(BeNil())
+		Expect(t, path).To(Equal([]string{"orders", "us", "west"}))
+
+		path, err = pubsub.ParseTopic("orders/us", '/')
+		Expect(t, err).To(BeNil())
+		Expect(t, path).To(Equal([]string{"orders", "us"}))
+	})
+
+	o.Spec("it parses wildcards", func(t *testing.T) {
+		path, err := pubsub.ParseTopic("orders.*.created.>", '.')
+		Expect(t, err).To(BeNil())
+		Expect(t, path).To(Equal([]string{"orders", pubsub.Any, "created", pubsub.Rest}))
+	})
+
+	o.Spec("it unescapes", func(t *testing.T) {
+		path, err := pubsub.ParseTopic(`a\.b.\*.\>.c\\`, '.')
+		Expect(t, err).To(BeNil())
+		Expect(t, path).To(Equal([]string{"a.b", "*", ">", `c\`}))
+	})
+
+	o.Spec("it parses an empty topic as the root path", func(t *testing.T) {
+		path, err := pubsub.ParseTopic("", '.')
+		Expect(t, err).To(BeNil())
+		Expect(t, path).To(HaveLen(0))
+	})
+
+	o.Spec("it returns an error for invalid topics", func(t *testing.T) {
+		for _, topic := range []string{"a..b", ".a", "a.", `a\`, "a.>.b"} {
+			_, err := pubsub.ParseTopic(topic, '.')
+			Expect(t, errors.Is(err, pubsub.ErrInvalidTopic)).To(BeTrue())
+		}
+	})
+
+	o.Spec("it formats a path as a topic", func(t *testing.T) {
+		path := []string{"a.b", "*", pubsub.Any, `c\`, pubsub.Rest}
+		topic := pubsub.FormatTopic(path, '.')
+		Expect(t, topic).To(Equal(`a\.b.\*.*.c\\.>`))
+
+		parsed, err := pubsub.ParseTopic(topic, '.')
+		Expect(t, err).To(BeNil())
+		Expect(t, parsed).To(Equal(path))
+	})
+}
+
+func TestPubSubTopics(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) TPS {
+		return TPS{
+			T:            t,
+			p:            pubsub.New(),
+			subscription: newSpySubscrption(),
+		}
+	})
+
+	o.Spec("it publishes to subscriptions with matching topics", func(t TPS) {
+		_, err := t.p.SubscribeTopic(t.subscription, "orders.*.created")
+		Expect(t, err).To(BeNil())
+
+		Expect(t, t.p.PublishTopic(1, "orders.us.created")).To(BeNil())
+		Expect(t, t.p.PublishTopic(2, "orders.us.deleted")).To(BeNil())
+		Expect(t, t.p.PublishTopic(3, "orders.eu.created")).To(BeNil())
+
+		Expect(t, t.subscription.Data()).To(Equal([]interface{}{1, 3}))
+	})
+
+	o.Spec("it unsubscribes", func(t TPS) {
+		unsubscribe, err := t.p.SubscribeTopic(t.subscription, "orders")
+		Expect(t, err).To(BeNil())
+		unsubscribe()
+
+		t.p.PublishTopic(1, "orders")
+		Expect(t, t.subscription.Len()).To(Equal(0))
+	})
+
+	o.Spec("it uses the configured delimiter", func(t TPS) {
+		p := pubsub.New(pubsub.WithTopicDelimiter('/'))
+		p.SubscribeTopic(t.subscription, "orders/us.west")
+
+		p.PublishTopic(1, "orders/us.west")
+		Expect(t, t.subscription.Data()).To(Equal([]interface{}{1}))
+		Expect(t, p.Paths()).To(Equal([][]string{{"orders", "us.west"}}))
+	})
+
+	o.Spec("it does not publish to wildcards", func(t TPS) {
+		t.p.SubscribeTopic(t.subscription, "orders")
+
+		err := t.p.PublishTopic(1, "orders.*")
+		Expect(t, errors.Is(err, pubsub.ErrInvalidTopic)).To(BeTrue())
+		Expect(t, t.subscription.Len()).To(Equal(0))
+	})
+
+	o.Spec("it does not subscribe with an invalid topic", func(t TPS) {
+		_, err := t.p.SubscribeTopic(t.subscription, "orders..us")
+		Expect(t, errors.Is(err, pubsub.ErrInvalidTopic)).To(BeTrue())
+		Expect(t, t.p.Paths()).To(HaveLen(0))
+	})
+}
@@ -0,0 +1,35 @@
+package pubsub
+
+import "context"
+
+// Tracer is used to trace each Publish. See the pubsubotel package for an
+// OpenTelemetry implementation.
+type Tracer interface {
+	// StartPublish is invoked at the start of each Publish. The returned
+	// context is used for the rest of the Publish.
+	StartPublish(ctx context.Context) (context.Context, PublishSpan)
+}
+
+// PublishSpan traces a single Publish. It is not accessed concurrently. The
+// given paths are the paths the publish traversed and must not be modified
+// or retained.
+type PublishSpan interface {
+	// Matched is invoked for each node that has subscriptions with how many
+	// subscriptions it has.
+	Matched(path []string, subscriptions int)
+
+	// Wrote is invoked each time the data is written to a subscription.
+	Wrote(path []string)
+
+	// End is invoked once the Publish is done with the number of
+	// subscriptions the data was written to.
+	End(deliveries int)
+}
+
+// WithTracer configures a PubSub to trace each Publish with the given
+// Tracer.
+func WithTracer(t Tracer) PubSubOption {
+	return pubsubConfigFunc(func(p *PubSub) {
+		p.tracer = t
+	})
+}
@@ -0,0 +1,68 @@
+package pubsub_test
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+func TestPubSubTracer(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.Spec("it traces each publish", func(t *testing.T) {
+		tracer := &spyTracer{}
+		p := pubsub.New(pubsub.WithTracer(tracer))
+		p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"a"}))
+		p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"a", "b"}))
+		p.Subscribe(newSpySubscrption(), pubsub.WithPath([]string{"a", "b"}))
+
+		type key struct{}
+		ctx := context.WithValue(context.Background(), key{}, "value")
+		p.PublishCtx(ctx, "some-data", pubsub.LinearTreeTraverser([]string{"a", "b"}))
+
+		Expect(t, tracer.ctx.Value(key{})).To(Equal("value"))
+		Expect(t, tracer.span.events).To(Equal([]string{
+			"matched a 1",
+			"wrote a",
+			"matched a.b 2",
+			"wrote a.b",
+			"wrote a.b",
+			"end 3",
+		}))
+	})
+}
+
+type spyTracer struct {
+	ctx  context.Context
+	span *spySpan
+}
+
+func (t *spyTracer) StartPublish(ctx context.Context) (context.Context, pubsub.PublishSpan) {
+	t.ctx = ctx
+	t.span = &spySpan{}
+	return ctx, t.span
+}
+
+type spySpan struct {
+	events []string
+}
+
+func (s *spySpan) Matched(path []string, subscriptions int) {
+	s.events = append(s.events, "matched "+strings.Join(path, ".")+" "+strconv.Itoa(subscriptions))
+}
+
+func (s *spySpan) Wrote(path []string) {
+	s.events = append(s.events, "wrote "+strings.Join(path, "."))
+}
+
+func (s *spySpan) End(deliveries int) {
+	s.events = append(s.events, "end "+strconv.Itoa(deliveries))
+}
@@ -0,0 +1,144 @@
+package pubsub
+
+// Batch adds and removes many subscriptions with a single acquisition of
+// the PubSub's lock. It should be constructed with PubSub.Batch(). A Batch
+// is not safe to access concurrently.
+type Batch struct {
+	p   *PubSub
+	ops []batchOp
+}
+
+type batchOp struct {
+	h   *SubscriptionHandle
+	sub Subscription
+	c   subscribeConfig
+	add bool
+}
+
+// Batch returns a new Batch for the PubSub. Nothing happens until Commit is
+// invoked.
+func (s *PubSub) Batch() *Batch {
+	return &Batch{p: s}
+}
+
+// Subscribe queues the subscription to be added (see SubscribeHandle). The
+// returned SubscriptionHandle must not be used until Commit is invoked.
+func (b *Batch) Subscribe(sub Subscription, opts ...SubscribeOption) *SubscriptionHandle {
+	c := newSubscribeConfig(opts)
+	c.pausable = true
+
+	h := &SubscriptionHandle{}
+	b.ops = append(b.ops, batchOp{
+		h:   h,
+		sub: sub,
+		c:   c,
+		add: true,
+	})
+	return h
+}
+
+// Unsubscribe queues the subscription to be removed. The handle may be
+// from an earlier Subscribe in the same Batch.
+func (b *Batch) Unsubscribe(h *SubscriptionHandle) {
+	b.ops = append(b.ops, batchOp{h: h})
+}
+
+// Len returns the number of queued operations.
+func (b *Batch) Len() int {
+	return len(b.ops)
+}
+
+// Commit applies the queued operations in order. Publishes either see all
+// of them or none of them. Subscriptions that can not be added (see
+// SubscribeErr) are closed. The Batch is empty afterwards and may be
+// reused.
+func (b *Batch) Commit() {
+	ops := b.ops[:0]
+	for _, op := range b.ops {
+		if op.add {
+			if err := b.p.checkSubscribe(op.c); err != nil {
+				closeSubscription(op.sub)
+				continue
+			}
+		}
+		ops = append(ops, op)
+	}
+	b.ops = nil
+
+	var (
+		stopped []*subscriber
+
+		// histories write the history of the added subscriptions once
+		// the locks are released.
+		histories []func()
+	)
+
+	t := b.p.lockTree()
+	if t.closed {
+		b.p.unlockTree(t)
+
+		for _, op := range ops {
+			if op.add {
+				closeSubscription(op.sub)
+			}
+		}
+		return
+	}
+
+	b.p.historyLock.Lock()
+	for _, op := range ops {
+		if op.add {
+			if op.c.name != "" {
+				op.c.names = &b.p.names
+				if old := b.p.replaceLocked(t, op.c.name); old != nil {
+					stopped = append(stopped, old)
+				}
+			}
+
+			op.h.sr, _ = b.p.subscribeLocked(t, op.sub, op.c)
+			if op.c.name != "" && op.h.sr != nil {
+				b.p.names.set(op.h.sr)
+			}
+			if history := b.p.historyFor(op.h.sr, op.c); history != nil {
+				sr := op.h.sr
+				histories = append(histories, func() {
+					sr.writeHistory(history)
+				})
+			}
+			continue
+		}
+
+		sr := op.h.sr
+		if sr == nil {
+			continue
+		}
+
+		if sr.p == b.p {
+			if b.p.removeLocked(t, sr) {
+				stopped = append(stopped, sr)
+			}
+			continue
+		}
+
+		// The subscription belongs to a mounted PubSub.
+		mt := sr.p.lockSubscriber(sr)
+		if sr.p.removeLocked(mt, sr) {
+			stopped = append(stopped, sr)
+		}
+		sr.p.unlockTree(mt)
+	}
+	b.p.unlockTree(t)
+	b.p.historyLock.Unlock()
+	for _, f := range histories {
+		f()
+	}
+	b.p.hooks.dispatch()
+	b.p.evict(t)
+
+	for _, sr := range stopped {
+		sr.afterWrites(func() {
+			sr.stop()
+			sr.p.hooks.dispatch()
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package pubsub_test
+
+import (
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+func TestPubSubBatch(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+	o.BeforeEach(func(t *testing.T) TPS {
+		return TPS{
+			T:            t,
+			p:            pubsub.New(),
+			subscription: newSpySubscrption(),
+		}
+	})
+
+	o.Spec("it does nothing until committed", func(t TPS) {
+		b := t.p.Batch()
+		b.Subscribe(t.subscription, pubsub.WithPath([]string{"a"}))
+		b.Subscribe(t.subscription, pubsub.WithPath([]string{"b"}))
+		Expect(t, b.Len()).To(Equal(2))
+		Expect(t, t.p.Paths()).To(HaveLen(0))
+
+		b.Commit()
+		Expect(t, b.Len()).To(Equal(0))
+		Expect(t, t.p.Paths()).To(Equal([][]string{{"a"}, {"b"}}))
+
+		t.p.Publish(1, pubsub.LinearTreeTraverser([]string{"a"}))
+		t.p.Publish(2, pubsub.LinearTreeTraverser([]string{"b"}))
+		Expect(t, t.subscription.Data()).To(Equal([]interface{}{1, 2}))
+	})
+
+	o.Spec("it removes subscriptions", func(t TPS) {
+		b := t.p.Batch()
+		a := b.Subscribe(t.subscription, pubsub.WithPath([]string{"a"}))
+		b.Subscribe(t.subscription, pubsub.WithPath([]string{"b"}))
+		b.Commit()
+
+		b.Unsubscribe(a)
+		c := b.Subscribe(t.subscription, pubsub.WithPath([]string{"c"}))
+		b.Unsubscribe(c)
+		b.Commit()
+
+		Expect(t, t.p.Paths()).To(Equal([][]string{{"b"}}))
+	})
+
+	o.Spec("it closes the subscriptions when the PubSub is closed", func(t TPS) {
+		t.p.Close()
+
+		closer := newSpyCloser()
+		b := t.p.Batch()
+		h := b.Subscribe(closer)
+		b.Commit()
+		h.Unsubscribe()
+
+		Expect(t, closer.closed).To(Equal(1))
+	})
+}
@@ -0,0 +1,38 @@
+package pubsub
+
+import "context"
+
+// WithTransformer configures a subscription to have the result of the
+// given function written to it instead of the published data. Unlike
+// WithMapper, it is given the publish's context and the path that was traversed
+// to reach the subscription (nil if it is not known, e.g., for retained
+// data), and it can fail. If it returns an error, the data is dropped rather than written
+// untransformed. This allows data to be encrypted or redacted before it
+// crosses a trust boundary, e.g., a gateway to external clients.
+//
+// The function is invoked by the publisher after the path matched and
+// after any filters and mappers (see WithFilter and WithMapper), and must
+// not modify the published data or retain the path. Multiple transformers
+// are applied in the order they are given.
+func WithTransformer(f func(ctx context.Context, data interface{}, path []string) (interface{}, error)) SubscribeOption {
+	return subscribeConfigFunc(func(c *subscribeConfig) {
+		c.transformers = append(c.transformers, f)
+	})
+}
+
+// transform applies the mappers and transformers to the data. It returns
+// the error of a transformer that failed.
+func (s *subscriber) transform(ctx context.Context, data interface{}, path []string) (interface{}, error) {
+	for _, f := range s.mappers {
+		data = f(data)
+	}
+
+	for _, f := range s.transformers {
+		var err error
+		if data, err = f(ctx, data, path); err != nil {
+			return nil, err
+		}
+	}
+
+	return data, nil
+}
@@ -0,0 +1,76 @@
+package pubsub_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/apoydence/pubsub"
+	"github.com/poy/onpar"
+	. "github.com/poy/onpar/expect"
+	. "github.com/poy/onpar/matchers"
+)
+
+func TestPubSubTransformer(t *testing.T) {
+	t.Parallel()
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) TPS {
+		return TPS{
+			T:            t,
+			p:            pubsub.New(),
+			subscription: newSpySubscrption(),
+		}
+	})
+
+	type ctxKey struct{}
+
+	o.Spec("it writes the transformed data", func(t TPS) {
+		t.p.Subscribe(t.subscription,
+			pubsub.WithPath([]string{"a", "b"}),
+			pubsub.WithMapper(func(data interface{}) interface{} {
+				return strings.ToUpper(data.(string))
+			}),
+			pubsub.WithTransformer(func(ctx context.Context, data interface{}, path []string) (interface{}, error) {
+				return ctx.Value(ctxKey{}).(string) + ":" + strings.Join(path, ".") + ":" + data.(string), nil
+			}),
+		)
+
+		ctx := context.WithValue(context.Background(), ctxKey{}, "x")
+		t.p.PublishCtx(ctx, "v", pubsub.LinearTreeTraverser([]string{"a", "b", "c"}))
+
+		Expect(t, t.subscription.Data()).To(Equal([]interface{}{"x:a.b:V"}))
+	})
+
+	o.Spec("it drops the data when a transformer fails", func(t TPS) {
+		t.p.Subscribe(t.subscription,
+			pubsub.WithMaxDeliveries(1),
+			pubsub.WithTransformer(func(ctx context.Context, data interface{}, path []string) (interface{}, error) {
+				if data == "secret" {
+					return nil, errors.New("can not redact")
+				}
+				return data, nil
+			}),
+		)
+
+		r, _ := t.p.PublishCtx(context.Background(), "secret", pubsub.LinearTreeTraverser(nil))
+		Expect(t, r.Delivered).To(Equal(0))
+
+		t.p.Publish("public", pubsub.LinearTreeTraverser(nil))
+		Expect(t, t.subscription.Data()).To(Equal([]interface{}{"public"}))
+	})
+
+	o.Spec("it only transforms the data of its subscription", func(t TPS) {
+		other := newSpySubscrption()
+		t.p.Subscribe(other)
+		t.p.Subscribe(t.subscription, pubsub.WithTransformer(func(ctx context.Context, data interface{}, path []string) (interface{}, error) {
+			return "redacted", nil
+		}))
+
+		t.p.Publish("v", pubsub.LinearTreeTraverser(nil))
+		Expect(t, t.subscription.Data()).To(Equal([]interface{}{"redacted"}))
+		Expect(t, other.Data()).To(Equal([]interface{}{"v"}))
+	})
+}